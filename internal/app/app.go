@@ -3,7 +3,7 @@ package app
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"html/template"
@@ -12,8 +12,12 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/jfyne/live"
@@ -25,116 +29,418 @@ func PrintHelp(w io.Writer) {
 
 Usage:
   meatcheck [--host 127.0.0.1] [--port 0] <file1> <file2> ...
+  meatcheck [--exclude <glob>] <dir> ...
   meatcheck --diff <diff-file>
   meatcheck --diff <diff-file> --prompt "Review the changes"
+  meatcheck --git HEAD~1..HEAD [<path> ...]
   meatcheck --groups groups.json <file1> <file2> ...
+  meatcheck schema [--output-format toon|json]
+  meatcheck --headless --auto verdict=approve[,comments-file=x.json] <file1> ...
+  meatcheck --headless --api <file1> ...
 
 Flags:
-  --host   host to bind (default 127.0.0.1)
+  --host   host to bind (default 127.0.0.1); the printed link carries the access token
   --port   port to bind, 0 = random free port (default 0)
-  --prompt review prompt/question to display at top
-  --diff   path to unified diff file (or pipe via stdin)
+  --prompt review prompt/question to display at top; may use {{.FileCount}}, {{.Branch}} etc.
+  --prompt-file read the review prompt from a file
+  --var    key=value available to the prompt as {{.Vars.key}}, repeatable
+  --diff   path to unified diff file (or pipe via stdin); git format-patch or mbox output is reviewed commit by commit,
+           with each commit's message above its files and the commit of each comment in the output
+  --git    review git diff output instead of a diff file: changes since a revision (HEAD, main) or in a range (HEAD~1..HEAD), with a picker for its commits; paths limit the diff
+  --staged review the staged changes; with --git, compared to that revision instead of HEAD
+  --untracked with --git, include untracked files as new files
+  --fetch-pr owner/repo#123 review a GitHub pull request's diff, downloaded with GITHUB_TOKEN if set
+  --fetch-pr-contents with --fetch-pr, also download the changed files so the code around hunks can be expanded
   --range  file section to render (path:start-end), repeatable
   --groups path to JSON file with ordered file groups
+  --rubric path to JSON file with criteria to score (1-5 by default)
+  --summary-file path to a drafted review summary for the reviewer to edit
+  --previous path to the previous round's output, to carry its comments over
+  --comments path to comments to start with, as TOON or JSON (a review document or an array of comments); they show as open threads to reply to or resolve
+  --tab-width columns per tab when rendering code (default 4)
+  --strict-diff fail on malformed diff input instead of showing warnings
+  --skip-missing warn about unreadable paths instead of failing
+  --exclude glob for files to leave out, e.g. 'vendor' or '*.pb.go' (repeatable); directories also skip what .gitignore ignores
+  --collapse-glob glob for files to collapse until loaded, like generated, vendored, lockfile and minified files are (repeatable)
+  --syntax pattern=language highlight matching files as a language, e.g. '*.tpl=html' or 'bin/deploy=bash' (repeatable)
+  --api    serve an HTTP API for posting replies or updated diffs while the session is open
+  --share  serve on the LAN behind a generated token and print a QR code to join
+  --watch  reload files as they change on disk while the review is open, moving comments with their lines (checked with stat every 500ms, without blocking the page)
+  --reviewer-name name to review under in the browser meatcheck opens; others joining pick their own
+  --no-open print the URL instead of opening a browser, e.g. on a remote machine reached over SSH
+  --qr     with --no-open, also print a QR code of the URL
+  --tls    serve over HTTPS with a self-signed certificate (its fingerprint is printed)
+  --tls-cert, --tls-key
+           PEM certificate and key to serve HTTPS with instead
+  --output-format write the review as toon (default), json, markdown for pasting into a PR or chat,
+           or sarif for GitHub code scanning
+  --output path to write the review to instead of stdout; written in one go when the review finishes
+  --validate check the printed review against the output schema (toon and json output only)
+  --emit llm-context print each comment with a code excerpt for pasting into a model
+  --token-budget approximate token limit for --emit llm-context; trims excerpts
+  --headless skip the server and browser; print a synthetic result for CI and agent tests. With --api, serve only the API (GET /api/state, POST /api/comments, POST /api/finish) and print the review once it is finished there
+  --auto   result for --headless: verdict=approve|comment|request-changes[,comments-file=x.json]
+  --events write machine-readable lifecycle events (event=ready, event=finished, ...) to stderr
+  --events-fd write lifecycle events to this file descriptor instead of stderr
+  --deadline how long the reviewer has, e.g. 15m; a countdown is shown in the header
+  --deadline-action what happens when the deadline passes: warn (default) or finish to submit the review as it stands
+  --timeout finish the review after this long, e.g. 30m, writing the comments so far with metadata.timed_out set; not shown to the reviewer
+  --logo   path to a PNG (or JPEG, GIF, WebP) replacing the meatcheck logo
+  --avatar path to an image replacing the agent avatar in the header
+  --accent-color #rrggbb colour replacing the theme accent
+  --lint-comments warn before saving comments that are too short, have an empty suggestion block or an unclosed code fence
+  --lint-min-length shortest comment --lint-comments accepts (default 15)
+  --mermaid draw mermaid code blocks in rendered markdown as diagrams, loading Mermaid from jsDelivr
+  --mermaid-js path to a local Mermaid build (mermaid.min.js) to use instead; implies --mermaid
+  --math   typeset $...$ and $$...$$ in rendered markdown and comments with KaTeX, loaded from jsDelivr
+  --katex-dir path to a local KaTeX dist directory (katex.min.js, katex.min.css, fonts/) to use instead; implies --math
+  --export-pdf path to archive the finished review as a PDF (needs Chrome or Chromium), or as HTML if it ends in .html
+  --export-html path to write a standalone HTML report of the files or diff with every comment inline
+  --github-pr owner/repo#123 to post the comments to on finish as a pending review (token in GITHUB_TOKEN)
+  --gitlab-mr group/project!123 to post the comments to on finish as discussions (token in GITLAB_TOKEN)
+  --lang   UI language: en, de or es (default: the browser's Accept-Language, then en)
   --help   show this help and exit
-  --skill  print agent skill markdown and exit
+  --skill  print agent skill markdown and exit; with --emit llm-context the skill asks for that output
+  --skill-template path to a SKILL.md template to render instead of the built-in one
+  --skill-name skill name for --skill (default meatcheck)
+  --skill-notes path to markdown appended to --skill under "Team notes"
 `)
 }
 
+// Run holds the review cfg describes and writes the result.
 func Run(ctx context.Context, cfg Config) error {
+	cfg.handleSignals = true
+	model, err := serve(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if err := writeResult(model, cfg); err != nil || !model.Aborted {
+		return err
+	}
+	return ErrAborted
+}
+
+// serve sets up the review cfg describes and returns its model once the
+// review is finished, or ctx is done.
+func serve(ctx context.Context, cfg Config) (*ReviewModel, error) {
 	gitCtx := detectGitContext()
 
+	tabWidth := cfg.TabWidth
+	if tabWidth == 0 {
+		tabWidth = defaultTabWidth
+	}
+	if tabWidth < 1 {
+		return nil, fmt.Errorf("invalid tab width: %d", cfg.TabWidth)
+	}
+	if cfg.Emit != "" && cfg.Emit != emitLLMContext {
+		return nil, fmt.Errorf("unknown --emit value: %s", cfg.Emit)
+	}
+	if f := cfg.OutputFormat; f != "" && f != outputTOON && f != outputJSON && f != outputMarkdown && f != outputSARIF {
+		return nil, fmt.Errorf("unknown --output-format value: %s", f)
+	}
+	if cfg.Validate && (cfg.Emit != "" || cfg.OutputFormat == outputMarkdown || cfg.OutputFormat == outputSARIF) {
+		// Only the review document has a schema to check against.
+		return nil, errors.New("--validate checks the toon or json review document; it cannot be used with --emit or --output-format markdown or sarif")
+	}
+	if cfg.Output != "" {
+		if err := checkOutputDir("--output", cfg.Output); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.ExportHTML != "" {
+		if err := checkOutputDir("--export-html", cfg.ExportHTML); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.GitHubPR != nil && cfg.GitHubToken == "" {
+		return nil, fmt.Errorf("--github-pr needs a token in %s", githubTokenEnv)
+	}
+	if cfg.GitLabMR != nil && cfg.GitLabToken == "" {
+		return nil, fmt.Errorf("--gitlab-mr needs a token in %s", gitlabTokenEnv)
+	}
+	brand, err := loadBranding(cfg)
+	if err != nil {
+		return nil, err
+	}
+	mermaid, err := mermaidScript(cfg)
+	if err != nil {
+		return nil, err
+	}
+	katex, err := mathAssets(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	diffInput := strings.TrimSpace(cfg.StdDiff)
+	// largeDiff is set to a --diff file too large to read into memory,
+	// which is parsed as a stream unless another diff replaces it.
+	var largeDiff string
 	if cfg.Diff != "" {
-		data, err := os.ReadFile(cfg.Diff)
+		large, err := isLargeDiff(cfg.Diff)
+		if err != nil {
+			return nil, fmt.Errorf("read diff: %w", err)
+		}
+		if large {
+			largeDiff, diffInput = cfg.Diff, ""
+		} else {
+			data, err := os.ReadFile(cfg.Diff)
+			if err != nil {
+				return nil, fmt.Errorf("read diff: %w", err)
+			}
+			diffInput = string(data)
+		}
+	}
+	if cfg.Git != "" || cfg.GitStaged {
+		data, err := gitDiff(cfg.Git, cfg.GitStaged, cfg.GitUntracked, cfg.Paths)
+		if err != nil {
+			return nil, err
+		}
+		if diffInput = strings.TrimSpace(data); diffInput == "" {
+			return nil, errors.New("git diff: no changes to review")
+		}
+	}
+	// A range is also read commit by commit for the commit picker and the
+	// commit messages, unless --groups lays out the files instead.
+	var series string
+	if strings.Contains(cfg.Git, "..") && !cfg.GitStaged && len(cfg.Groups) == 0 {
+		if series, err = gitSeries(cfg.Git, cfg.Paths); err != nil {
+			return nil, err
+		}
+	}
+	var prHead string
+	if cfg.FetchPR != nil {
+		fetchCtx, cancel := context.WithTimeout(ctx, forgeTimeout)
+		data, head, err := fetchGitHubDiff(fetchCtx, newGitHubClient(cfg.GitHubToken), *cfg.FetchPR)
+		cancel()
 		if err != nil {
-			return fmt.Errorf("read diff: %w", err)
+			return nil, err
+		}
+		if diffInput = strings.TrimSpace(data); diffInput == "" {
+			return nil, fmt.Errorf("%s: no changes to review", cfg.FetchPR)
 		}
-		diffInput = string(data)
+		prHead = head
 	}
 
+	if err := validateCollapseGlobs(cfg.CollapseGlobs); err != nil {
+		return nil, err
+	}
 	var files []File
 	var diffFiles []DiffFile
+	var commits []PatchCommit
+	var skipped []SkippedFile
+	groups := cfg.Groups
 	mode := ModeFile
-	if diffInput != "" {
-		parsed, err := parseUnifiedDiff(diffInput)
+	if diffInput != "" || largeDiff != "" {
+		if cfg.Watch {
+			return nil, errors.New("--watch reloads the files under review; it cannot be used with a diff")
+		}
+		parseMode := diffLenient
+		if cfg.StrictDiff {
+			parseMode = diffStrict
+		}
+		var parsed []DiffFile
+		if isPatchSeries(diffInput) {
+			if len(groups) > 0 {
+				return nil, errors.New("--groups cannot be used with a patch series; its commits are the groups")
+			}
+			parsed, commits, err = parsePatchSeries(diffInput, parseMode)
+			parsed = withCommitMessages(parsed, commits)
+			groups = seriesGroups(commits, parsed)
+		} else if diffInput == "" {
+			parsed, err = parseUnifiedDiffFile(largeDiff, parseMode)
+		} else {
+			parsed, err = parseUnifiedDiff(diffInput, parseMode)
+		}
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if len(parsed) == 0 {
-			return errors.New("no files in diff")
+			return nil, errors.New("no files in diff")
 		}
 		diffFiles = parsed
 		mode = ModeDiff
+		if series != "" {
+			seriesFiles, seriesCommits, err := parsePatchSeries(series, parseMode)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case len(seriesCommits) > 1:
+				diffFiles = append(diffFiles, withCommitMessages(seriesFiles, seriesCommits)...)
+				commits = seriesCommits
+			case len(seriesCommits) == 1:
+				// The range is one commit, which its diff is already.
+				diffFiles = append([]DiffFile{seriesCommits[0].messageFile(commitMessagePath)}, diffFiles...)
+			}
+		}
+		if cfg.FetchPR != nil && cfg.FetchPRContents {
+			fetchCtx, cancel := context.WithTimeout(ctx, forgeTimeout)
+			err := fetchGitHubSources(fetchCtx, newGitHubClient(cfg.GitHubToken), *cfg.FetchPR, prHead, diffFiles)
+			cancel()
+			if err != nil {
+				return nil, err
+			}
+		}
 	} else {
 		if len(cfg.Paths) == 0 {
-			return errors.New("no files provided")
+			return nil, errors.New("no files provided")
 		}
-		loaded, err := loadFiles(cfg.Paths)
+		if err := validateExcludes(cfg.Exclude); err != nil {
+			return nil, err
+		}
+		paths, err := expandPaths(cfg.Paths, cfg.Exclude)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if len(paths) == 0 {
+			return nil, errors.New("no files to review after exclusions")
+		}
+		if cfg.SkipMissing {
+			files, skipped = loadFilesSkipping(paths)
+			for _, s := range skipped {
+				fmt.Fprintf(os.Stderr, "warning: skipped %s\n", s.Reason)
+			}
+			if len(files) == 0 {
+				return nil, errors.New("no readable files provided")
+			}
+		} else {
+			loaded, err := loadFiles(paths)
+			if err != nil {
+				return nil, err
+			}
+			files = loaded
 		}
-		files = loaded
 	}
 
 	model := &ReviewModel{
-		Files:                files,
-		DiffFiles:            diffFiles,
-		Viewed:               make(map[string]bool),
-		Groups:               cfg.Groups,
-		HasGroups:            len(cfg.Groups) > 0,
-		SelectedPath:         "",
-		SelectedLabel:        "",
-		Mode:                 mode,
-		DiffFormat:           preferredDiffFormat(),
-		SidebarWidth:         loadPreferences().SidebarWidth,
-		RenderFile:           true,
-		RenderComments:       true,
-		Prompt:               cfg.Prompt,
-		Ranges:               cfg.Ranges,
-		MarkdownRenderByPath: make(map[string]bool),
-		Git:                  gitCtx,
+		Files:        files,
+		DiffFiles:    diffFiles,
+		Commits:      commits,
+		CommitPicker: series != "" && len(commits) > 0,
+		Viewed:       make(map[string]bool),
+		Groups:       groups,
+		HasGroups:    len(groups) > 0,
+		Rubric:       cfg.Rubric,
+		Mode:         mode,
+		TabWidth:     tabWidth,
+		Prompt:       cfg.Prompt,
+		Ranges:       cfg.Ranges,
+		Git:          gitCtx,
+		SkippedFiles: skipped,
+		SyntaxRules:  cfg.Syntax,
+		render:       newRenderers(tabWidth, katex != ""),
+		loader:       newFileLoader(),
+	}
+	applyLanguages(model)
+	model.Collapsed = collapsedFiles(model, cfg.CollapseGlobs)
+	if draft := strings.TrimSpace(cfg.Summary); draft != "" {
+		model.Summary = ReviewSummary{Text: draft, Drafted: true}
 	}
 	if strings.TrimSpace(cfg.Prompt) != "" {
-		model.PromptHTML = renderMarkdown(cfg.Prompt)
+		prompt, err := expandPrompt(cfg.Prompt, model, cfg.Vars)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v; showing the prompt as written\n", err)
+		}
+		model.Prompt = prompt
+		model.PromptHTML = model.renderers().renderPrompt(prompt)
 	}
-	model.CodeViewKey = fmt.Sprintf("%d", time.Now().UnixNano())
-	if model.HasGroups {
-		// Select first file from first group to respect defined order.
-		if mode == ModeDiff {
-			df := diffFilesAsFiles(diffFiles)
-			if f := findFileBySlash(df, cfg.Groups[0].Files[0]); f != nil {
-				model.SelectedPath = f.Path
-			} else {
-				model.SelectedPath = diffFiles[0].Path
-			}
-		} else {
-			if f := findFileBySlash(files, cfg.Groups[0].Files[0]); f != nil {
-				model.SelectedPath = f.Path
-			} else {
-				model.SelectedPath = files[0].Path
-			}
+	if cfg.Previous != nil {
+		carryOverComments(model, cfg.Previous)
+	}
+	if cfg.Comments != nil {
+		carryOverComments(model, cfg.Comments)
+	}
+	start := startView(model)
+	if cfg.Headless && !cfg.API {
+		applyAutoReview(model, cfg.Auto)
+		newEventLog(cfg.Events).emit(eventFinished, "comments", len(model.Comments), "verdict", model.Verdict, "by", model.CompletedBy)
+		return model, nil
+	}
+	prefetchNextFile(start)
+	model.warmup = prehighlight(start.highlighter(), model.Files, model.DiffFiles, model.Collapsed)
+	model.WarmDone, model.WarmTotal = model.warmup.counts()
+	model.Warming = model.WarmDone < model.WarmTotal
+	model.Watching = cfg.Watch
+	if cfg.LintComments {
+		model.Lint = true
+		model.LintMinLength = cfg.LintMinLength
+	}
+	if cfg.Deadline > 0 {
+		model.Deadline = time.Now().Add(cfg.Deadline)
+		model.DeadlineAction = cfg.DeadlineAction
+		if model.DeadlineAction == "" {
+			model.DeadlineAction = DeadlineWarn
 		}
-	} else if mode == ModeDiff {
-		model.SelectedPath = diffFiles[0].Path
-	} else {
-		model.SelectedPath = files[0].Path
 	}
-	rebuildTree(model)
-	updateView(model)
 
 	meatcheckServer := &ReviewServer{
-		Model:  model,
-		DoneCh: make(chan struct{}),
+		Model:   model,
+		DoneCh:  make(chan struct{}),
+		start:   start,
+		events:  newEventLog(cfg.Events),
+		lang:    cfg.Lang,
+		brand:   brand,
+		mermaid: mermaid,
+		katex:   katex,
 	}
 
 	h := buildLiveHandler(meatcheckServer)
 
-	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", cfg.Host, cfg.Port))
+	host := cfg.Host
+	token, err := newAccessToken()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	observerToken, err := newAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	var lanHost string
+	if cfg.Share {
+		if lanHost, err = lanAddress(); err != nil {
+			return nil, err
+		}
+		if host == "" || host == "127.0.0.1" || host == "localhost" {
+			host = "0.0.0.0"
+		}
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(cfg.Port)))
+	if err != nil {
+		return nil, err
 	}
 	addr := listener.Addr().String()
+	_, port, _ := net.SplitHostPort(addr)
+	scheme := "http"
+	if cfg.TLS {
+		hosts := []string{host, lanHost}
+		if lanHost == "" {
+			// Reviewers on other machines reach a wildcard bind through
+			// the LAN address, so name it in the certificate as well.
+			if ip, err := lanAddress(); err == nil {
+				hosts = append(hosts, ip)
+			}
+		}
+		if name, err := os.Hostname(); err == nil {
+			hosts = append(hosts, name)
+		}
+		tlsCfg, err := tlsConfig(cfg.TLSCert, cfg.TLSKey, hosts)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+		if cfg.TLSCert == "" {
+			fmt.Fprintf(os.Stderr, "self-signed certificate, SHA-256 fingerprint %s\n", certFingerprint(tlsCfg.Certificates[0]))
+		}
+		listener = tls.NewListener(listener, tlsCfg)
+		scheme = "https"
+	}
+	if cfg.Share {
+		addr = net.JoinHostPort(lanHost, port)
+		if model.Share, err = shareInfo(fmt.Sprintf("%s://%s/?%s=%s", scheme, addr, tokenParam, token), fmt.Sprintf("%s://%s/?%s=%s", scheme, addr, tokenParam, observerToken)); err != nil {
+			return nil, err
+		}
+	}
 
 	mux := http.NewServeMux()
 	mux.Handle("/live.js", live.Javascript{})
@@ -146,57 +452,283 @@ func Run(ctx context.Context, cfg Config) error {
 		var err error
 		wd, err = os.Getwd()
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 	mux.Handle("/file", localFileHandler(wd))
-	mux.Handle("/", live.NewHttpHandler(ctx, h))
+	mux.Handle("/print", printHandler(meatcheckServer))
+	if cfg.MermaidJS != "" {
+		mux.Handle(mermaidPath, mermaidHandler(cfg.MermaidJS))
+	}
+	if cfg.KaTeXDir != "" {
+		mux.Handle(katexPath, katexHandler(cfg.KaTeXDir))
+	}
+	engine := live.NewHttpHandler(ctx, h)
+	notify := func() {
+		if err := engine.Broadcast(eventReviewChanged, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: broadcast review change: %v\n", err)
+		}
+	}
+	if cfg.API {
+		mux.Handle("/api/", forbidObservers(apiHandler(meatcheckServer, notify)))
+	}
+	mux.Handle("/", engine)
 
-	srv := &http.Server{Handler: mux}
+	handler := requireToken(token, observerToken, tokenCookieName(port), compressHandler(mux))
+	srv := &http.Server{Handler: handler}
 
 	go func() {
 		_ = srv.Serve(listener)
 	}()
 
-	urlStr := fmt.Sprintf("http://%s/", addr)
-	if err := browser.OpenURL(urlStr); err != nil {
-		fmt.Fprintf(os.Stderr, "open this URL in your browser: %s\n", urlStr)
+	urlStr := fmt.Sprintf("%s://%s/?%s=%s", scheme, addr, tokenParam, token)
+	apiURL := fmt.Sprintf("%s://%s/api/?%s=%s", scheme, addr, tokenParam, token)
+	if cfg.Share {
+		fmt.Fprintf(os.Stderr, "share this review: %s\n", urlStr)
+		if code, err := qrText(urlStr); err == nil {
+			fmt.Fprint(os.Stderr, code)
+		}
+		fmt.Fprintf(os.Stderr, "watch it read-only: %s\n", model.Share.ObserverURL)
+	}
+	readyAPI := ""
+	if cfg.API {
+		fmt.Fprintf(os.Stderr, "agent API: %s\n", apiURL)
+		readyAPI = apiURL
+	}
+	meatcheckServer.events.emit(eventReady, "url", urlStr, "api", readyAPI)
+	// With --headless --api the review is driven through the API and ends
+	// with POST /api/finish; the UI is still served for anyone watching.
+	if cfg.Ready != nil {
+		cfg.Ready(urlStr)
+	}
+	if !cfg.Headless && !cfg.NoBrowser {
+		// Only the browser opened here reviews under --reviewer-name; the
+		// shared links leave others to pick their own.
+		own := urlStr
+		if name := strings.TrimSpace(cfg.ReviewerName); name != "" {
+			own += "&" + url.Values{reviewerParam: {name}}.Encode()
+		}
+		if cfg.NoOpen {
+			fmt.Fprintf(os.Stderr, "open this URL in your browser: %s\n", own)
+			if cfg.QR {
+				if code, err := qrText(own); err == nil {
+					fmt.Fprint(os.Stderr, code)
+				}
+			}
+		} else if err := browser.OpenURL(own); err != nil {
+			fmt.Fprintf(os.Stderr, "open this URL in your browser: %s\n", own)
+		}
+	}
+
+	if !model.Deadline.IsZero() {
+		stop := meatcheckServer.watchDeadline(notify)
+		defer stop()
+	}
+	if cfg.Timeout > 0 {
+		stop := meatcheckServer.watchTimeout(cfg.Timeout, notify)
+		defer stop()
+	}
+	if model.Watching {
+		stop := meatcheckServer.watchFiles(notify)
+		defer stop()
+	}
+	if model.Warming {
+		stop := meatcheckServer.watchWarmup(func() {
+			if err := engine.Broadcast(eventWarmupProgress, nil); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: broadcast warm-up progress: %v\n", err)
+			}
+		})
+		defer stop()
+	}
+	if cfg.handleSignals {
+		// Only once the review is open; until then a signal stops
+		// meatcheck as usual.
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(signals)
+		stop := meatcheckServer.watchSignals(signals, notify)
+		defer stop()
 	}
 
-	<-meatcheckServer.DoneCh
+	var cancelled error
+	select {
+	case <-meatcheckServer.DoneCh:
+	case <-ctx.Done():
+		cancelled = ctx.Err()
+	}
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	_ = srv.Shutdown(shutdownCtx)
 	cancel()
+	if cancelled != nil {
+		return nil, cancelled
+	}
 
-	if err := emitToon(os.Stdout, meatcheckServer.Model.Comments); err != nil {
+	meatcheckServer.mu.Lock()
+	defer meatcheckServer.mu.Unlock()
+	return meatcheckServer.Model, nil
+}
+
+// writeResult prints the finished review to stdout, or writes it to
+// cfg.Output, in the format cfg asks for. It returns ErrChangesRequested
+// when that is the overall verdict.
+func writeResult(model *ReviewModel, cfg Config) error {
+	_, invalid := validateComments(model)
+	for _, ic := range invalid {
+		fmt.Fprintf(os.Stderr, "warning: dropping comment %d on %s: %s\n", ic.ID, ic.Path, ic.Reason)
+	}
+	doc := reviewDocument(model)
+	if cfg.OutputFormat == outputJSON {
+		setTokenEstimate(doc, outputJSON)
+	}
+	var out bytes.Buffer
+	if cfg.Emit == emitLLMContext {
+		if err := writeLLMContext(&out, model, cfg.TokenBudget); err != nil {
+			return err
+		}
+	} else if cfg.OutputFormat == outputMarkdown {
+		if err := writeMarkdownReport(&out, model); err != nil {
+			return err
+		}
+	} else if cfg.OutputFormat == outputSARIF {
+		if err := writeSARIF(&out, model); err != nil {
+			return err
+		}
+	} else if err := emitDocument(&out, doc, cfg.OutputFormat); err != nil {
+		return err
+	}
+	if cfg.Output != "" {
+		if err := writeFileAtomic(cfg.Output, out.Bytes()); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+	} else if _, err := os.Stdout.Write(out.Bytes()); err != nil {
 		return err
 	}
+	if cfg.Validate {
+		if err := validateDocument(doc); err != nil {
+			return err
+		}
+	}
+	if cfg.ExportPDF != "" {
+		lang := cfg.Lang
+		if lang == "" {
+			lang = defaultLang
+		}
+		brand, err := loadBranding(cfg)
+		if err != nil {
+			return err
+		}
+		if err := exportReview(model, cfg.ExportPDF, lang, brand); err != nil {
+			return err
+		}
+	}
+	if cfg.ExportHTML != "" {
+		lang := cfg.Lang
+		if lang == "" {
+			lang = defaultLang
+		}
+		brand, err := loadBranding(cfg)
+		if err != nil {
+			return err
+		}
+		if err := exportHTMLReport(model, cfg.ExportHTML, lang, brand); err != nil {
+			return err
+		}
+	}
+	if cfg.GitHubPR != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), forgeTimeout)
+		defer cancel()
+		link, err := postGitHubReview(ctx, newGitHubClient(cfg.GitHubToken), *cfg.GitHubPR, model)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "posted a pending review to %s: %s\n", cfg.GitHubPR, link)
+	}
+	if cfg.GitLabMR != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), forgeTimeout)
+		defer cancel()
+		link, err := postGitLabDiscussions(ctx, newGitLabClient(cfg.GitLabToken), *cfg.GitLabMR, model)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "posted the review to %s: %s\n", cfg.GitLabMR, link)
+	}
+	if model.Verdict == VerdictRequestChanges {
+		return ErrChangesRequested
+	}
 	return nil
 }
 
-func rebuildTree(model *ReviewModel) {
+func rebuildTree(view *ReviewView) {
 	var files []File
+	if view.Mode == ModeDiff {
+		files = diffFilesAsFiles(view.DiffFiles)
+	} else {
+		files = view.Files
+	}
+	if view.CommitPicker {
+		view.Tree = buildCommitTree(view)
+	} else if view.HasGroups {
+		view.Tree = buildGroupedTree(view.Groups, files, view.SelectedPath, view.Viewed, view.Comments)
+	} else {
+		view.Tree = buildTree(files, view.SelectedPath, view.Viewed, view.Comments)
+	}
+	counts := commentCounts(view.Comments)
+	for i := range view.Tree {
+		view.Tree[i].Assignee = view.Assignments[view.Tree[i].Path]
+		view.Tree[i].Updated = view.UpdatedFiles[view.Tree[i].Path]
+		view.Tree[i].Collapsed = view.collapsedReason(view.Tree[i].Path)
+		view.Tree[i].Comments = counts[view.Tree[i].Path]
+		if df := view.lookupDiffFile(view.Tree[i].Path); df != nil && view.Mode == ModeDiff {
+			view.Tree[i].Status = df.Status
+			view.Tree[i].Added, view.Tree[i].Removed = diffLineStats(df)
+		}
+	}
+	view.Tree = sortTree(view, view.Tree)
+}
+
+// startView builds the view the session starts on: the first file, or
+// the first of the first group, shown with the saved preferences.
+func startView(model *ReviewModel) *ReviewView {
+	prefs := loadPreferences()
+	view := &ReviewView{
+		ReviewModel:          model,
+		DiffFormat:           preferredDiffFormat(),
+		SidebarWidth:         prefs.SidebarWidth,
+		HideWhitespace:       prefs.HideWhitespace,
+		ShowWhitespace:       prefs.ShowWhitespace,
+		TreeSort:             parseTreeSort(string(prefs.TreeSort), model.Mode),
+		RenderFile:           true,
+		RenderComments:       true,
+		MarkdownRenderByPath: make(map[string]bool),
+	}
+	files := model.Files
 	if model.Mode == ModeDiff {
 		files = diffFilesAsFiles(model.DiffFiles)
-	} else {
-		files = model.Files
 	}
+	view.SelectedPath = files[0].Path
 	if model.HasGroups {
-		model.Tree = buildGroupedTree(model.Groups, files, model.SelectedPath, model.Viewed, model.Comments)
-	} else {
-		model.Tree = buildTree(files, model.SelectedPath, model.Viewed, model.Comments)
+		// Select first file from first group to respect defined order.
+		if f := findFileBySlash(files, model.Groups[0].Files[0]); f != nil {
+			view.SelectedPath = f.Path
+		}
 	}
+	rebuildTree(view)
+	updateView(view)
+	return view
 }
 
-func selectFile(model *ReviewModel, path string) {
-	model.SelectedPath = path
-	model.CodeViewKey = fmt.Sprintf("%d", time.Now().UnixNano())
-	model.SelectionStart = 0
-	model.SelectionEnd = 0
-	model.Error = ""
-	rebuildTree(model)
-	updateView(model)
+func selectFile(view *ReviewView, path string) {
+	view.SelectedPath = path
+	view.SelectionStart = 0
+	view.SelectionEnd = 0
+	view.FileCommentOpen = false
+	view.WindowStart = 0
+	view.WindowLines = 0
+	view.Error = ""
+	refreshTree(view)
+	updateView(view)
+	prefetchNextFile(view)
 }
 
 func buildLiveHandler(rs *ReviewServer) *live.Handler {
@@ -215,270 +747,447 @@ func buildLiveHandler(rs *ReviewServer) *live.Handler {
 			}
 			return b.String()
 		},
-		"commentThreadData": func(root *ReviewModel, comments []ViewComment, logo template.URL) map[string]any {
-			return map[string]any{"Root": root, "Comments": comments, "Logo": logo}
+		"replies":      repliesTo,
+		"identicon":    identicon,
+		"shortUID":     shortUID,
+		"deadlineLeft": deadlineLeft,
+		"tabTitle":     tabTitle,
+		"progress":     viewedProgress,
+		"languages":    func() []string { return languageChoices },
+		"suggestion":   suggestionSeed,
+		"t":            translator(defaultLang),
+		"commentTags":  commentTags,
+		"contains":     slices.Contains[[]string],
+		"commentThreadData": func(root *ReviewView, comments []ViewComment, logo template.URL, tags []string) map[string]any {
+			return map[string]any{"Root": root, "Comments": filterByTags(comments, tags), "Logo": logo}
 		},
 	}).Parse(templateHTML))
+	tmpls := map[string]*template.Template{defaultLang: tmpl}
+	for _, lang := range Languages() {
+		if lang != defaultLang {
+			tmpls[lang] = template.Must(tmpl.Clone()).Funcs(template.FuncMap{"t": translator(lang)})
+		}
+	}
 
 	h := live.NewHandler()
 	h.RenderHandler = func(ctx context.Context, rc *live.RenderContext) (io.Reader, error) {
-		css := buildCSS()
-		logoData := template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(logoBytes))
-		avatarData := template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(avatarBytes))
+		brand := rs.branding()
+		css := buildCSS() + brand.css()
+		rs.mu.Lock()
+		lang := rs.uiLang(rs.view(rc.Socket).Lang)
 		data := struct {
-			CSS    template.CSS
-			Logo   template.URL
-			Avatar template.URL
+			CSS     template.CSS
+			Logo    template.URL
+			Avatar  template.URL
+			Lang    string
+			Mermaid string
+			KaTeX   string
 			*live.RenderContext
 		}{
 			CSS:           template.CSS(css),
-			Logo:          logoData,
-			Avatar:        avatarData,
+			Logo:          brand.Logo,
+			Avatar:        brand.Avatar,
+			Lang:          lang,
+			Mermaid:       rs.mermaid,
+			KaTeX:         rs.katex,
 			RenderContext: rc,
 		}
 		var buf bytes.Buffer
-		if err := tmpl.Execute(&buf, data); err != nil {
+		err := tmpls[lang].Execute(&buf, data)
+		rs.mu.Unlock()
+		if err != nil {
 			return nil, err
 		}
 		return &buf, nil
 	}
 
 	h.MountHandler = func(ctx context.Context, s *live.Socket) (any, error) {
-		return rs.Model, nil
+		return rs.mountView(live.Request(ctx)), nil
 	}
 
-	h.HandleEvent("select-file", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
+	registerReviewerHandlers(h, rs)
+	registerVerdictHandlers(h, rs)
+	registerDispositionHandlers(h, rs)
+	registerRubricHandlers(h, rs)
+	registerSummaryHandlers(h, rs)
+	registerPreviousHandlers(h, rs)
+	registerChatHandlers(h, rs)
+	registerShareHandlers(h, rs)
+	registerThemeHandlers(h, rs)
+	registerSyntaxHandlers(h, rs)
+	registerCommitHandlers(h, rs)
+	registerWhitespaceHandlers(h, rs)
+	registerTreeHandlers(h, rs)
+	registerTagHandlers(h, rs)
+
+	h.HandleEvent("select-file", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
 		path := p.String("path")
 		if path == "" {
-			return model, nil
+			return view, nil
 		}
-		switch model.Mode {
+		switch view.Mode {
 		case ModeDiff:
-			if hasDiffFile(model.DiffFiles, path) {
-				selectFile(model, path)
+			if view.lookupDiffFile(path) != nil {
+				selectFile(view, path)
 			}
 		default:
-			if hasFile(model.Files, path) {
-				selectFile(model, path)
+			if view.lookupFile(path) != nil {
+				selectFile(view, path)
 			}
 		}
-		return model, nil
-	})
+		return view, nil
+	}))
 
-	h.HandleEvent("toggle-file-render", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
-		if model.Mode == ModeFile && isMarkdownPath(model.SelectedPath) {
-			current, ok := model.MarkdownRenderByPath[model.SelectedPath]
+	h.HandleEvent("toggle-file-render", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		if view.Mode == ModeFile && isMarkdownPath(view.SelectedPath) {
+			current, ok := view.MarkdownRenderByPath[view.SelectedPath]
 			if !ok {
 				current = true
 			}
-			model.MarkdownRenderByPath[model.SelectedPath] = !current
+			view.MarkdownRenderByPath[view.SelectedPath] = !current
 		} else {
-			model.RenderFile = !model.RenderFile
+			view.RenderFile = !view.RenderFile
 		}
-		updateView(model)
-		return model, nil
-	})
+		updateView(view)
+		return view, nil
+	}))
 
-	h.HandleEvent("toggle-comment-render", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
-		model.RenderComments = !model.RenderComments
-		updateView(model)
-		return model, nil
-	})
+	h.HandleEvent("load-collapsed", rs.lockedReveal(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		if view.collapsedReason(view.SelectedPath) == "" {
+			return view, nil
+		}
+		if view.Expanded == nil {
+			view.Expanded = make(map[string]bool)
+		}
+		view.Expanded[view.SelectedPath] = true
+		refreshTree(view)
+		updateView(view)
+		return view, nil
+	}))
+
+	h.HandleEvent("render-hunk", rs.lockedReveal(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		file := view.lookupDiffFile(view.SelectedPath)
+		idx := p.Int("hunk")
+		if file == nil || idx < 0 || idx >= len(file.Hunks) {
+			return view, nil
+		}
+		revealHunk(view, idx)
+		updateView(view)
+		return view, nil
+	}))
+
+	h.HandleEvent("expand-context", rs.lockedReveal(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		file := view.lookupDiffFile(view.SelectedPath)
+		if file == nil || !expandHunk(view, file, p.Int("hunk"), p.String("direction") == "down") {
+			return view, nil
+		}
+		updateView(view)
+		rs.markChanged()
+		return view, nil
+	}))
 
-	h.HandleEvent("select-line", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
+	h.HandleEvent("shift-window", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		file := view.lookupFile(view.SelectedPath)
+		if file == nil || !windowed(file) {
+			return view, nil
+		}
+		start := max(view.WindowStart, 1)
+		if p.String("dir") == "prev" {
+			start -= largeFileWindow
+		} else {
+			start += max(view.WindowLines, largeFileWindow)
+		}
+		view.WindowStart = max(1, min(start, fileLineCount(file)))
+		view.WindowLines = 0
+		updateView(view)
+		return view, nil
+	}))
+
+	h.HandleEvent("extend-window", rs.lockedReveal(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		file := view.lookupFile(view.SelectedPath)
+		if file == nil || !windowed(file) {
+			return view, nil
+		}
+		// The hook reports the window end it saw; a repeat send for a window
+		// that has already grown is ignored.
+		shown := max(view.WindowLines, largeFileWindow)
+		if p.Int("end") < max(view.WindowStart, 1)+shown-1 {
+			return view, nil
+		}
+		view.WindowLines = shown + largeFileWindow
+		updateView(view)
+		return view, nil
+	}))
+
+	h.HandleEvent("toggle-comment-render", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		view.RenderComments = !view.RenderComments
+		updateView(view)
+		return view, nil
+	}))
+
+	h.HandleEvent("select-line", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
 		line := p.Int("line")
 		lineEnd := p.Int("line_end")
 		oldLine := p.Int("old_line")
 		shift := p.String("shift") == "1"
-		if model.Mode == ModeDiff && oldLine > 0 {
-			if !diffOldLineExists(model.DiffFiles, model.SelectedPath, oldLine) {
-				return model, nil
+		if view.Mode == ModeDiff && oldLine > 0 {
+			if !hasOldLine(view.lookupDiffFile(view.SelectedPath), oldLine) {
+				return view, nil
 			}
 			line = oldLine
 			lineEnd = oldLine
-			model.SelectionSide = "old"
+			view.SelectionSide = "old"
 		} else {
 			if line <= 0 {
-				return model, nil
+				return view, nil
 			}
-			if model.Mode == ModeDiff {
-				if !diffLineExists(model.DiffFiles, model.SelectedPath, line) {
-					return model, nil
+			if view.Mode == ModeDiff {
+				if !hasNewLine(view.lookupDiffFile(view.SelectedPath), line) {
+					return view, nil
 				}
+			} else {
+				count := 0
+				if f := view.lookupFile(view.SelectedPath); f != nil {
+					count = fileLineCount(f)
+				}
+				if count == 0 {
+					return view, nil
+				}
+				line = min(line, count)
+				lineEnd = min(lineEnd, count)
 			}
-			model.SelectionSide = ""
+			view.SelectionSide = ""
 		}
 		if lineEnd < line {
 			lineEnd = line
 		}
-		if shift && model.SelectionStart > 0 {
-			start := model.SelectionStart
+		if shift && view.SelectionStart > 0 {
+			start := view.SelectionStart
 			end := lineEnd
 			if end < start {
 				start, end = end, start
 			}
-			model.SelectionStart = start
-			model.SelectionEnd = end
+			view.SelectionStart = start
+			view.SelectionEnd = end
 		} else {
-			model.SelectionStart = line
-			model.SelectionEnd = lineEnd
+			view.SelectionStart = line
+			view.SelectionEnd = lineEnd
 		}
-		model.Error = ""
-		updateView(model)
-		return model, nil
-	})
+		view.Error = ""
+		clearLint(view)
+		view.FileCommentOpen = false
+		updateSelection(view)
+		return view, nil
+	}))
 
-	h.HandleEvent("add-comment", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
+	h.HandleEvent("start-file-comment", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		view.FileCommentOpen = true
+		view.SelectionStart = 0
+		view.SelectionEnd = 0
+		view.SelectionSide = ""
+		view.Error = ""
+		clearLint(view)
+		updateSelection(view)
+		return view, nil
+	}))
+
+	h.HandleEvent("add-comment", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
 		text := strings.TrimSpace(p.String("comment"))
 		if text == "" {
-			model.Error = "comment text is required"
-			return model, nil
-		}
-		if model.SelectionStart == 0 || model.SelectionEnd == 0 {
-			model.Error = "select a line or range first"
-			return model, nil
-		}
-		model.NextCommentID++
-		model.Comments = append(model.Comments, Comment{
-			ID:        model.NextCommentID,
-			Path:      model.SelectedPath,
-			StartLine: model.SelectionStart,
-			EndLine:   model.SelectionEnd,
-			Side:      model.SelectionSide,
+			view.Error = "comment text is required"
+			return view, nil
+		}
+		if !view.FileCommentOpen && (view.SelectionStart == 0 || view.SelectionEnd == 0) {
+			view.Error = "select a line or range first"
+			return view, nil
+		}
+		c := Comment{
+			UID:       newCommentUID(),
+			Path:      view.SelectedPath,
+			StartLine: view.SelectionStart,
+			EndLine:   view.SelectionEnd,
+			Side:      view.SelectionSide,
 			Text:      text,
-		})
-		model.CommentDraft = ""
-		model.Error = ""
-		model.SelectionStart = 0
-		model.SelectionEnd = 0
-		model.SelectionSide = ""
-		rebuildTree(model)
-		updateView(model)
-		return model, nil
-	})
+			Author:    view.Reviewer,
+		}
+		if err := weightFromParams(&c, p); err != nil {
+			view.Error = err.Error()
+			return view, nil
+		}
+		if lintBeforeSaving(view, text) {
+			view.CommentDraft = text
+			view.Error = ""
+			return view, nil
+		}
+		view.NextCommentID++
+		c.ID = view.NextCommentID
+		view.Comments = append(view.Comments, c)
+		rs.events.emit(eventCommentAdded, "id", view.NextCommentID, "path", view.SelectedPath, "count", len(view.Comments))
+		view.CommentDraft = ""
+		view.Error = ""
+		view.SelectionStart = 0
+		view.SelectionEnd = 0
+		view.SelectionSide = ""
+		view.FileCommentOpen = false
+		refreshTree(view)
+		updateView(view)
+		rs.markChanged()
+		return view, nil
+	}))
 
-	h.HandleEvent("cancel-comment", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
-		model.CommentDraft = ""
-		model.Error = ""
-		model.SelectionStart = 0
-		model.SelectionEnd = 0
-		model.SelectionSide = ""
-		updateView(model)
-		return model, nil
-	})
+	h.HandleEvent("cancel-comment", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		view.CommentDraft = ""
+		view.Error = ""
+		clearLint(view)
+		view.FileCommentOpen = false
+		view.SelectionStart = 0
+		view.SelectionEnd = 0
+		view.SelectionSide = ""
+		updateSelection(view)
+		return view, nil
+	}))
 
-	h.HandleEvent("start-edit-comment", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
-		model.EditingCommentID = p.Int("id")
-		model.Error = ""
-		updateView(model)
-		return model, nil
-	})
+	h.HandleEvent("start-edit-comment", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		view.EditingCommentID = eventCommentID(view.ReviewModel, p)
+		view.Error = ""
+		clearLint(view)
+		updateView(view)
+		return view, nil
+	}))
 
-	h.HandleEvent("edit-comment", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
-		id := p.Int("id")
+	h.HandleEvent("edit-comment", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		id := eventCommentID(view.ReviewModel, p)
 		text := strings.TrimSpace(p.String("comment"))
-		if err := editComment(model, id, text); err != nil {
-			model.Error = err.Error()
-			return model, nil
+		var weight Comment
+		if err := weightFromParams(&weight, p); err != nil {
+			view.Error = err.Error()
+			return view, nil
 		}
-		model.Error = ""
-		rebuildTree(model)
-		updateView(model)
-		return model, nil
-	})
+		if text != "" && lintBeforeSaving(view, text) {
+			view.Error = ""
+			return view, nil
+		}
+		if err := editComment(view.ReviewModel, id, text); err != nil {
+			view.Error = err.Error()
+			return view, nil
+		}
+		if c := findComment(view.ReviewModel, id); c != nil {
+			c.Priority, c.Confidence, c.Tags = weight.Priority, weight.Confidence, weight.Tags
+		}
+		view.EditingCommentID = 0
+		view.Error = ""
+		refreshTree(view)
+		updateView(view)
+		rs.markChanged()
+		return view, nil
+	}))
 
-	h.HandleEvent("delete-comment", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
-		id := p.Int("id")
-		deleteComment(model, id)
-		model.Error = ""
-		rebuildTree(model)
-		updateView(model)
-		return model, nil
-	})
+	h.HandleEvent("delete-comment", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		id := eventCommentID(view.ReviewModel, p)
+		deleteComment(view.ReviewModel, id)
+		if view.EditingCommentID == id {
+			view.EditingCommentID = 0
+		}
+		view.Error = ""
+		refreshTree(view)
+		updateView(view)
+		rs.markChanged()
+		return view, nil
+	}))
 
-	h.HandleEvent("cancel-edit-comment", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
-		model.EditingCommentID = 0
-		model.Error = ""
-		updateView(model)
-		return model, nil
+	// Each socket rebuilds its own view of a peer's change; the engine
+	// renders it after this returns.
+	h.HandleSelf(eventReviewChanged, func(ctx context.Context, s *live.Socket, _ any) (any, error) {
+		view := rs.view(s)
+		rs.mu.Lock()
+		refreshView(view)
+		rs.mu.Unlock()
+		return view, nil
 	})
 
-	h.HandleEvent("toggle-sidebar", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
-		model.SidebarCollapsed = !model.SidebarCollapsed
-		return model, nil
+	// Warm-up progress only changes the status bar, so it re-renders the
+	// socket without rebuilding its view.
+	h.HandleSelf(eventWarmupProgress, func(ctx context.Context, s *live.Socket, _ any) (any, error) {
+		return rs.view(s), nil
 	})
 
-	h.HandleEvent("mark-viewed", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
-		wasViewed := model.Viewed[model.SelectedPath]
-		model.Viewed[model.SelectedPath] = !wasViewed
+	h.HandleEvent("cancel-edit-comment", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		view.EditingCommentID = 0
+		view.Error = ""
+		clearLint(view)
+		updateView(view)
+		return view, nil
+	}))
+
+	h.HandleEvent("toggle-sidebar", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		view.SidebarCollapsed = !view.SidebarCollapsed
+		return view, nil
+	}))
+
+	h.HandleEvent("mark-viewed", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		wasViewed := view.Viewed[view.SelectedPath]
+		view.Viewed[view.SelectedPath] = !wasViewed
 		if !wasViewed {
 			// Just marked as viewed — advance to next unviewed
-			next := nextUnviewedFile(model)
+			next := nextUnviewedFile(view)
 			if next != "" {
-				selectFile(model, next)
+				selectFile(view, next)
 			} else {
-				rebuildTree(model)
-				updateView(model)
+				refreshTree(view)
+				updateView(view)
 			}
 		} else {
 			// Unmarked — stay on current file
-			rebuildTree(model)
-			updateView(model)
+			refreshTree(view)
+			updateView(view)
 		}
-		return model, nil
-	})
+		return view, nil
+	}))
 
-	h.HandleEvent("toggle-diff-format", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
-		if model.DiffFormat == DiffFormatSplit {
-			model.DiffFormat = DiffFormatUnified
+	h.HandleEvent("toggle-diff-format", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		if view.DiffFormat == DiffFormatSplit {
+			view.DiffFormat = DiffFormatUnified
 		} else {
-			model.DiffFormat = DiffFormatSplit
+			view.DiffFormat = DiffFormatSplit
 		}
-		model.SelectionStart = 0
-		model.SelectionEnd = 0
-		model.SelectionSide = ""
-		savePreference(func(p *Preferences) { p.DiffFormat = model.DiffFormat })
-		updateView(model)
-		return model, nil
-	})
+		view.SelectionStart = 0
+		view.SelectionEnd = 0
+		view.SelectionSide = ""
+		savePreference(func(p *Preferences) { p.DiffFormat = view.DiffFormat })
+		updateView(view)
+		return view, nil
+	}))
 
-	h.HandleEvent("save-sidebar-width", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
+	h.HandleEvent("save-sidebar-width", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
 		w := p.String("width")
 		if w != "" {
-			model.SidebarWidth = w
+			view.SidebarWidth = w
 			savePreference(func(p *Preferences) { p.SidebarWidth = w })
 		}
-		return model, nil
-	})
+		return view, nil
+	}))
 
-	h.HandleEvent("finish", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
-		model := getModel(s, rs.Model)
-		if s != nil {
-			_ = s.Send("close-tab", map[string]any{})
-			about, _ := url.Parse("about:blank")
-			if about != nil {
-				s.Redirect(about)
-			}
-		}
-		rs.DoneOnce.Do(func() {
-			close(rs.DoneCh)
-		})
-		return model, nil
-	})
+	registerFinishHandler(h, rs)
 
 	return h
 }
@@ -487,9 +1196,7 @@ func deleteComment(model *ReviewModel, id int) {
 	for i := range model.Comments {
 		if model.Comments[i].ID == id {
 			model.Comments = append(model.Comments[:i], model.Comments[i+1:]...)
-			if model.EditingCommentID == id {
-				model.EditingCommentID = 0
-			}
+			dropReplies(model, id)
 			return
 		}
 	}
@@ -502,29 +1209,56 @@ func editComment(model *ReviewModel, id int, text string) error {
 	for i := range model.Comments {
 		if model.Comments[i].ID == id {
 			model.Comments[i].Text = text
-			model.EditingCommentID = 0
+			model.Comments[i].rendered = ""
 			return nil
 		}
 	}
 	return fmt.Errorf("comment not found")
 }
 
-func getModel(s *live.Socket, fallback *ReviewModel) *ReviewModel {
+// eventReviewChanged is broadcast to every connected socket whenever a
+// comment or file assignment is added, edited or removed.
+const eventReviewChanged = "review-changed"
+
+// eventWarmupProgress is broadcast as background highlighting advances.
+const eventWarmupProgress = "warmup-progress"
+
+// broadcastReviewChanged re-renders all connected clients so every reviewer
+// sees the same comments and assignments without refreshing.
+func broadcastReviewChanged(s *live.Socket) {
 	if s == nil {
-		return fallback
+		return
 	}
-	if assigns := s.Assigns(); assigns != nil {
-		if model, ok := assigns.(*ReviewModel); ok {
-			return model
-		}
+	if err := s.Broadcast(eventReviewChanged, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: broadcast review change: %v\n", err)
 	}
-	return fallback
 }
 
+// markdownAssetExts lists the file types rendered markdown can reference
+// through /file; see rewriteMarkdownImageSources.
+var markdownAssetExts = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+	".svg":  true,
+	".webp": true,
+	".avif": true,
+	".bmp":  true,
+	".ico":  true,
+}
+
+// localFileHandler serves images referenced by rendered markdown from root.
+// Both root and the requested path are resolved through symlinks before the
+// containment check, so a link inside root cannot expose files outside it.
 func localFileHandler(root string) http.Handler {
-	rootAbs, err := filepath.Abs(root)
-	if err != nil {
-		rootAbs = root
+	rootReal, err := filepath.Abs(root)
+	if err == nil {
+		if resolved, err := filepath.EvalSymlinks(rootReal); err == nil {
+			rootReal = resolved
+		}
+	} else {
+		rootReal = root
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		rel := strings.TrimSpace(r.URL.Query().Get("path"))
@@ -537,17 +1271,36 @@ func localFileHandler(root string) http.Handler {
 			http.Error(w, "invalid path", http.StatusBadRequest)
 			return
 		}
-		target := filepath.Join(rootAbs, rel)
-		targetAbs, err := filepath.Abs(target)
+		if !markdownAssetExts[strings.ToLower(filepath.Ext(rel))] {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		resolved, err := filepath.EvalSymlinks(filepath.Join(rootReal, rel))
 		if err != nil {
-			http.Error(w, "invalid path", http.StatusBadRequest)
+			http.NotFound(w, r)
 			return
 		}
-		relToRoot, err := filepath.Rel(rootAbs, targetAbs)
-		if err != nil || strings.HasPrefix(relToRoot, "..") {
+		if !withinRoot(rootReal, resolved) || !markdownAssetExts[strings.ToLower(filepath.Ext(resolved))] {
 			http.Error(w, "forbidden", http.StatusForbidden)
 			return
 		}
-		http.ServeFile(w, r, targetAbs)
+		if info, err := os.Stat(resolved); err != nil || !info.Mode().IsRegular() {
+			http.NotFound(w, r)
+			return
+		}
+		// Served files are only ever embedded as images; never let one (an
+		// SVG, say) run script in the review page's origin.
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeFile(w, r, resolved)
 	})
 }
+
+// withinRoot reports whether path is root or lies beneath it.
+func withinRoot(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
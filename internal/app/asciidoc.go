@@ -0,0 +1,438 @@
+package app
+
+import (
+	"cmp"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	adocHeading     = regexp.MustCompile(`^(={1,6})\s+(.+?)\s*$`)
+	adocAttrEntry   = regexp.MustCompile(`^:!?[\w-]+!?:(\s.*)?$`)
+	adocBlockAttrs  = regexp.MustCompile(`^\[([^\[\]]*)\]\s*$`)
+	adocAnchor      = regexp.MustCompile(`^\[\[[^\]]*\]\]\s*$`)
+	adocBlockTitle  = regexp.MustCompile(`^\.([^.\s].*)$`)
+	adocBlockImage  = regexp.MustCompile(`^image::([^\[]+)\[(.*)\]\s*$`)
+	adocAdmonition  = regexp.MustCompile(`^(NOTE|TIP|IMPORTANT|WARNING|CAUTION):\s+(.*)$`)
+	adocListItem    = regexp.MustCompile(`^\s*(\*+|-|\.+|\d+\.)\s+(.*)$`)
+	adocDescription = regexp.MustCompile(`^(\S.*?)(:{2,4}|;;)(?:\s+(.*))?$`)
+
+	adocInlineRules = []inlineRule{
+		{regexp.MustCompile("`\\+?([^`]+?)\\+?`"), func(m []string) string { return "<code>" + html.EscapeString(m[1]) + "</code>" }},
+		{regexp.MustCompile(`(?:link:)?(https?://[^\s\[]+)\[([^\]]*)\]`), func(m []string) string { return linkHTML(m[1], cmp.Or(m[2], m[1])) }},
+		{regexp.MustCompile(`link:([^\s\[]+)\[([^\]]*)\]`), func(m []string) string { return linkHTML(m[1], cmp.Or(m[2], m[1])) }},
+		{regexp.MustCompile(`image:([^:\s\[][^\s\[]*)\[([^\]]*)\]`), func(m []string) string { return imageHTML(m[1], m[2]) }},
+		{regexp.MustCompile(`<<([^,>]+)(?:,\s*([^>]+))?>>`), func(m []string) string { return html.EscapeString(cmp.Or(m[2], m[1])) }},
+		{regexp.MustCompile(`xref:([^\[\s]+)\[([^\]]*)\]`), func(m []string) string { return html.EscapeString(cmp.Or(m[2], m[1])) }},
+		{regexp.MustCompile(`https?://[^\s<>\[]*[^\s<>\[.,;:!?)]`), func(m []string) string { return linkHTML(m[0], m[0]) }},
+		{regexp.MustCompile(`\*\*(.+?)\*\*`), func(m []string) string { return "<strong>" + html.EscapeString(m[1]) + "</strong>" }},
+		{regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*\n]*[^*\s])?)\*`), func(m []string) string {
+			return html.EscapeString(m[1]) + "<strong>" + html.EscapeString(m[2]) + "</strong>"
+		}},
+		{regexp.MustCompile(`__(.+?)__`), func(m []string) string { return "<em>" + html.EscapeString(m[1]) + "</em>" }},
+		{regexp.MustCompile(`(^|[^\w_])_([^_\s](?:[^_\n]*[^_\s])?)_`), func(m []string) string {
+			return html.EscapeString(m[1]) + "<em>" + html.EscapeString(m[2]) + "</em>"
+		}},
+	}
+)
+
+// renderAsciiDocBlocks renders the common parts of AsciiDoc: section
+// titles, paragraphs, lists, delimited blocks, admonitions, images and
+// tables. Attribute entries and comments are not shown.
+func renderAsciiDocBlocks(r *renderers, path, input string) []MarkdownBlock {
+	p := &adocParser{b: newDocBuilder(r, path)}
+	p.parse(strings.Split(input, "\n"), 1)
+	return p.b.blocks
+}
+
+type adocParser struct {
+	b *docBuilder
+	// attrs and title are set by the lines above a block and apply to it;
+	// from is where those lines start, or -1.
+	attrs []string
+	title string
+	from  int
+}
+
+func (p *adocParser) nested(lines []string) string {
+	outer, attrs, title, from := p.b, p.attrs, p.title, p.from
+	p.b = &docBuilder{render: outer.render, baseDir: outer.baseDir}
+	p.parse(lines, 1)
+	out := p.b.html()
+	p.b, p.attrs, p.title, p.from = outer, attrs, title, from
+	return out
+}
+
+// style is the block style set by the attribute line, such as "source" or
+// "NOTE".
+func (p *adocParser) style() string {
+	if len(p.attrs) == 0 || strings.Contains(p.attrs[0], "=") {
+		return ""
+	}
+	style, _, _ := strings.Cut(p.attrs[0], "%")
+	return style
+}
+
+// attr returns the positional attribute at i, or the named attribute name.
+func (p *adocParser) attr(i int, name string) string {
+	for j, a := range p.attrs {
+		if k, v, ok := strings.Cut(a, "="); ok {
+			if strings.TrimSpace(k) == name {
+				return strings.Trim(strings.TrimSpace(v), `"`)
+			}
+		} else if j == i {
+			return a
+		}
+	}
+	return ""
+}
+
+// emit adds a block, taking in the attribute and title lines above it.
+func (p *adocParser) emit(start, end int, body string) {
+	if p.from >= 0 {
+		start = p.from
+	}
+	if p.title != "" {
+		body = "<p><em>" + renderInline(p.title, adocInlineRules) + "</em></p>" + body
+	}
+	p.b.add(start, end, body)
+	p.attrs, p.title, p.from = nil, "", -1
+}
+
+func (p *adocParser) parse(lines []string, first int) {
+	p.attrs, p.title, p.from = nil, "", -1
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		n := first + i
+		switch {
+		case isBlank(line):
+			i++
+		case strings.HasPrefix(line, "//") && !strings.HasPrefix(line, "////"):
+			i++
+		case adocDelimiter(line) != "":
+			i = p.delimited(lines, i, first)
+		case adocAttrEntry.MatchString(line), adocAnchor.MatchString(line), strings.TrimSpace(line) == "<<<":
+			i++
+		case adocBlockAttrs.MatchString(line):
+			p.attrs = nil
+			for a := range strings.SplitSeq(adocBlockAttrs.FindStringSubmatch(line)[1], ",") {
+				p.attrs = append(p.attrs, strings.TrimSpace(a))
+			}
+			if p.from < 0 {
+				p.from = n
+			}
+			i++
+		case adocBlockTitle.MatchString(line):
+			p.title = adocBlockTitle.FindStringSubmatch(line)[1]
+			if p.from < 0 {
+				p.from = n
+			}
+			i++
+		case adocHeading.MatchString(line):
+			m := adocHeading.FindStringSubmatch(line)
+			p.emit(n, n, headingHTML(len(m[1]), renderInline(m[2], adocInlineRules)))
+			i++
+		case strings.TrimSpace(line) == "'''":
+			p.emit(n, n, "<hr>")
+			i++
+		case adocBlockImage.MatchString(line):
+			m := adocBlockImage.FindStringSubmatch(line)
+			alt, _, _ := strings.Cut(m[2], ",")
+			p.emit(n, n, "<p>"+imageHTML(m[1], alt)+"</p>")
+			i++
+		case adocListItem.MatchString(line):
+			i = p.list(lines, i, first)
+		case indentOf(line) > 0:
+			j := i
+			for j < len(lines) && !isBlank(lines[j]) {
+				j++
+			}
+			p.emit(n, first+j-1, preHTML(strings.Join(dedent(lines[i:j]), "\n")))
+			i = j
+		case adocDescription.MatchString(line):
+			i = p.description(lines, i, first)
+		default:
+			i = p.paragraph(lines, i, first)
+		}
+	}
+}
+
+// adocDelimiter returns line if it opens or closes a delimited block.
+func adocDelimiter(line string) string {
+	line = strings.TrimRight(line, " ")
+	if line == "--" || line == "|===" {
+		return line
+	}
+	if len(line) >= 4 && strings.ContainsRune("-._=*+/", rune(line[0])) && strings.Count(line, line[:1]) == len(line) {
+		return line
+	}
+	return ""
+}
+
+func isAdmonition(style string) bool {
+	switch style {
+	case "NOTE", "TIP", "IMPORTANT", "WARNING", "CAUTION":
+		return true
+	}
+	return false
+}
+
+// paragraph renders the paragraph at i in the style set above it. A line
+// ending in " +" is a hard line break.
+func (p *adocParser) paragraph(lines []string, i, first int) int {
+	j := i
+	for j < len(lines) && !isBlank(lines[j]) && adocDelimiter(lines[j]) == "" {
+		j++
+	}
+	text := lines[i:j]
+	var body string
+	switch style := p.style(); {
+	case style == "source" || style == "listing":
+		body = p.b.codeBlockHTML(p.attr(1, "language"), strings.Join(text, "\n"))
+	case style == "literal":
+		body = preHTML(strings.Join(text, "\n"))
+	default:
+		kind, inline := style, adocParagraphHTML(text)
+		if m := adocAdmonition.FindStringSubmatch(text[0]); m != nil {
+			kind, inline = m[1], adocParagraphHTML(append([]string{m[2]}, text[1:]...))
+		}
+		switch {
+		case isAdmonition(kind):
+			body = admonitionHTML(kind, "<p>"+inline+"</p>")
+		case kind == "quote":
+			body = "<blockquote><p>" + inline + "</p>" + attribution(p.attr(1, "attribution")) + "</blockquote>"
+		default:
+			body = "<p>" + inline + "</p>"
+		}
+	}
+	p.emit(first+i, first+j-1, body)
+	return j
+}
+
+func adocParagraphHTML(lines []string) string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if trimmed, ok := strings.CutSuffix(strings.TrimRight(line, " "), " +"); ok {
+			out[i] = renderInline(trimmed, adocInlineRules) + "<br>"
+			continue
+		}
+		out[i] = renderInline(line, adocInlineRules)
+	}
+	return strings.Join(out, "\n")
+}
+
+func attribution(who string) string {
+	if who == "" {
+		return ""
+	}
+	return "<p>— " + html.EscapeString(who) + "</p>"
+}
+
+// delimited renders the delimited block opening at i, up to its matching
+// closing line or the end of the file.
+func (p *adocParser) delimited(lines []string, i, first int) int {
+	delim := adocDelimiter(lines[i])
+	j := i + 1
+	for j < len(lines) && strings.TrimRight(lines[j], " ") != delim {
+		j++
+	}
+	body := lines[i+1 : j]
+	end, next := min(j, len(lines)-1), min(j+1, len(lines))
+
+	var out string
+	style := p.style()
+	switch {
+	case delim == "|===":
+		out = p.table(body)
+	case delim == "--":
+		out = p.nested(body)
+		if isAdmonition(style) {
+			out = admonitionHTML(style, out)
+		}
+	case delim[0] == '/':
+		p.attrs, p.title, p.from = nil, "", -1
+		return next
+	case delim[0] == '-':
+		out = p.b.codeBlockHTML(p.attr(1, "language"), strings.Join(body, "\n"))
+	case delim[0] == '.':
+		out = preHTML(strings.Join(body, "\n"))
+	case delim[0] == '+':
+		out = strings.Join(body, "\n")
+	case delim[0] == '_':
+		out = "<blockquote>" + p.nested(body) + attribution(p.attr(1, "attribution")) + "</blockquote>"
+	case isAdmonition(style):
+		out = admonitionHTML(style, p.nested(body))
+	default:
+		out = "<blockquote>" + p.nested(body) + "</blockquote>"
+	}
+	p.emit(first+i, first+end, out)
+	return next
+}
+
+// table renders a |=== table. Cells start with "|" and fill the rows left
+// to right; the column count comes from the cols attribute or the first
+// line. The first row is the header when it is followed by a blank line
+// or the header option is set.
+func (p *adocParser) table(body []string) string {
+	cols := 0
+	if spec := p.attr(-1, "cols"); spec != "" {
+		if n, err := strconv.Atoi(strings.TrimSuffix(spec, "*")); err == nil && strings.HasSuffix(spec, "*") {
+			cols = n
+		} else {
+			cols = strings.Count(spec, ",") + 1
+		}
+	}
+	options := p.attr(-1, "options")
+	header := strings.Contains(options, "header") || strings.Contains(strings.Join(p.attrs, ","), "%header")
+
+	var cells []string
+	firstLine := -1
+	for k, line := range body {
+		if isBlank(line) {
+			if firstLine >= 0 && k == firstLine+1 && !strings.Contains(options, "noheader") && !strings.Contains(strings.Join(p.attrs, ","), "%noheader") {
+				header = true
+			}
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "|") {
+			if len(cells) > 0 {
+				cells[len(cells)-1] += " " + trimmed
+			}
+			continue
+		}
+		parts := strings.Split(trimmed, "|")[1:]
+		if firstLine < 0 {
+			firstLine = k
+			if cols == 0 {
+				cols = len(parts)
+			}
+		}
+		for _, part := range parts {
+			cells = append(cells, strings.TrimSpace(part))
+		}
+	}
+	cols = max(cols, 1)
+	var rows [][]string
+	for start := 0; start < len(cells); start += cols {
+		row := make([]string, cols)
+		for c := range row {
+			if start+c < len(cells) {
+				row[c] = renderInline(cells[start+c], adocInlineRules)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return tableHTML(rows, header)
+}
+
+// listMarker returns the marker of a list item line, with numbers
+// normalised so that "1." and "2." are siblings.
+func listMarker(line string) (marker, text string) {
+	m := adocListItem.FindStringSubmatch(line)
+	if m == nil {
+		return "", ""
+	}
+	marker = m[1]
+	if marker[0] >= '0' && marker[0] <= '9' {
+		marker = "1."
+	}
+	return marker, m[2]
+}
+
+// list renders the list at i. Items with a deeper marker, and blocks
+// attached with a "+" line, belong to the item above them.
+func (p *adocParser) list(lines []string, i, first int) int {
+	top, _ := listMarker(lines[i])
+	type item struct {
+		start, end int
+		text, rest []string
+	}
+	var items []*item
+	for i < len(lines) {
+		line := lines[i]
+		if isBlank(line) {
+			k := i
+			for k < len(lines) && isBlank(lines[k]) {
+				k++
+			}
+			if marker, _ := listMarker(lines[min(k, len(lines)-1)]); k == len(lines) || !strings.HasPrefix(marker, top) {
+				break
+			}
+			i = k
+			continue
+		}
+		marker, text := listMarker(line)
+		cur := (*item)(nil)
+		if len(items) > 0 {
+			cur = items[len(items)-1]
+		}
+		switch {
+		case marker == top:
+			items = append(items, &item{start: i, end: i, text: []string{text}})
+		case cur == nil:
+			// Only reached for the first line, which always has the top marker.
+		case strings.TrimSpace(line) == "+":
+			if i+1 < len(lines) && adocDelimiter(lines[i+1]) != "" {
+				delim := adocDelimiter(lines[i+1])
+				j := i + 2
+				for j < len(lines) && strings.TrimRight(lines[j], " ") != delim {
+					j++
+				}
+				j = min(j, len(lines)-1)
+				cur.rest = append(cur.rest, lines[i+1:j+1]...)
+				cur.end = j
+				i = j + 1
+				continue
+			}
+		case marker == "" && len(cur.rest) == 0 && adocDelimiter(line) == "":
+			cur.text = append(cur.text, strings.TrimSpace(line))
+		default:
+			cur.rest = append(cur.rest, strings.TrimLeft(line, " \t"))
+		}
+		items[len(items)-1].end = i
+		i++
+	}
+
+	docItems := make([]docItem, len(items))
+	for k, it := range items {
+		body := adocParagraphHTML(it.text)
+		if len(it.rest) > 0 {
+			body += p.nested(it.rest)
+		}
+		docItems[k] = docItem{start: first + it.start, end: first + it.end, body: body}
+	}
+	if p.title != "" {
+		docItems[0].body = "<p><em>" + renderInline(p.title, adocInlineRules) + "</em></p>" + docItems[0].body
+	}
+	if p.from >= 0 {
+		docItems[0].start = p.from
+	}
+	p.attrs, p.title, p.from = nil, "", -1
+	p.b.addList(top[0] == '.' || top == "1.", docItems)
+	return i
+}
+
+// description renders a "term:: definition" list, one block per term.
+func (p *adocParser) description(lines []string, i, first int) int {
+	for i < len(lines) {
+		m := adocDescription.FindStringSubmatch(lines[i])
+		if m == nil {
+			break
+		}
+		j := i + 1
+		def := []string{m[3]}
+		for j < len(lines) && !isBlank(lines[j]) && !adocDescription.MatchString(lines[j]) {
+			def = append(def, strings.TrimSpace(lines[j]))
+			j++
+		}
+		p.emit(first+i, first+j-1, "<dl><dt>"+renderInline(m[1], adocInlineRules)+"</dt><dd>"+adocParagraphHTML(trimBlankEdges(def))+"</dd></dl>")
+		for j < len(lines) && isBlank(lines[j]) {
+			j++
+		}
+		i = j
+	}
+	return i
+}
@@ -10,9 +10,13 @@ import (
 	"sort"
 	"strings"
 
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
 	"github.com/jfyne/meatcheck/internal/highlight"
 	"github.com/jfyne/meatcheck/internal/ui"
 	"github.com/yuin/goldmark"
+	emoji "github.com/yuin/goldmark-emoji"
+	emojidef "github.com/yuin/goldmark-emoji/definition"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
 	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
@@ -23,17 +27,101 @@ import (
 var (
 	templateHTML = mustReadEmbedded("template.html")
 	stylesCSS    = mustReadEmbedded("styles.css")
+	printHTML    = mustReadEmbedded("print.html")
+	printCSS     = mustReadEmbedded("print.css")
+	reportHTML   = mustReadEmbedded("report.html")
+	reportCSS    = mustReadEmbedded("report.css")
 	logoBytes    = mustReadEmbeddedBytes("logo.png")
 	avatarBytes  = mustReadEmbeddedBytes("ai.png")
 )
 
-var (
-	markdownRenderer = goldmark.New(
-		goldmark.WithExtensions(extension.GFM),
+// renderers highlight code and render markdown for one review, with its
+// tab width and whether it typesets math. Each review builds its own, so
+// several can be served from one process.
+type renderers struct {
+	// code highlights code, and whitespace does too while marking spaces
+	// and tabs. Showing whitespace picks between them rather than
+	// replacing either, so background highlighting can use them freely.
+	code       *highlight.Renderer
+	whitespace *highlight.Renderer
+	// markdown shows raw HTML as text, as comments, replies and documents
+	// come from reviewers and the files under review; prompt passes it
+	// through for the prompt, which the operator wrote.
+	markdown goldmark.Markdown
+	prompt   goldmark.Markdown
+}
+
+// defaultRenderers render with the default tab width and no math, for
+// models built without a review, as in tests, and for the page's CSS,
+// which does not depend on either.
+var defaultRenderers = newRenderers(defaultTabWidth, false)
+
+// newRenderers returns renderers expanding tabs to tabWidth columns and
+// parsing $...$ and $$...$$ as math when math is set.
+func newRenderers(tabWidth int, math bool) *renderers {
+	return &renderers{
+		code:       newCodeRenderer(tabWidth, false),
+		whitespace: newCodeRenderer(tabWidth, true),
+		markdown:   newMarkdownRenderer(tabWidth, math, false),
+		prompt:     newMarkdownRenderer(tabWidth, math, true),
+	}
+}
+
+// codeFor returns the code renderer marking whitespace or not.
+func (r *renderers) codeFor(whitespace bool) *highlight.Renderer {
+	if whitespace {
+		return r.whitespace
+	}
+	return r.code
+}
+
+const defaultTabWidth = 4
+
+// emojis are the GitHub shortcodes, plus :shipit:, which GitHub draws as
+// its squirrel and is not a Unicode emoji.
+var emojis = func() emojidef.Emojis {
+	e := emojidef.Github().Clone()
+	e.Add(emojidef.NewEmojis(emojidef.NewEmoji("Ship it", []rune{0x1F43F, 0xFE0F}, "shipit")))
+	return e
+}()
+
+func newCodeRenderer(width int, whitespace bool) *highlight.Renderer {
+	opts := []highlight.Option{highlight.CacheBytes(highlightCacheBytes)}
+	if whitespace {
+		opts = append(opts, highlight.VisibleWhitespace())
+	}
+	return highlight.NewRenderer("github", "dracula", width, opts...)
+}
+
+// newMarkdownRenderer renders GitHub flavoured markdown, emoji shortcodes
+// included, with fenced code blocks highlighted by chroma. It emits classes rather than inline styles
+// so the blocks pick up the same theme-scoped CSS as the file view. With
+// math set, TeX between dollar signs is kept apart for the page to typeset.
+// Raw HTML is passed through only when unsafe is set, and shown as text
+// otherwise.
+func newMarkdownRenderer(tabWidth int, math, unsafe bool) goldmark.Markdown {
+	extensions := []goldmark.Extender{
+		extension.GFM,
+		highlighting.NewHighlighting(
+			highlighting.WithFormatOptions(
+				chromahtml.WithClasses(true),
+				chromahtml.TabWidth(tabWidth),
+			),
+			highlighting.WithWrapperRenderer(codeBlockWrapper),
+		),
+		emoji.New(emoji.WithEmojis(emojis)),
+	}
+	if math {
+		extensions = append(extensions, mathExtension{})
+	}
+	if !unsafe {
+		return goldmark.New(goldmark.WithExtensions(append(extensions, escapeHTMLExtension{})...))
+	}
+	return goldmark.New(
+		goldmark.WithExtensions(extensions...),
 		goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
 	)
-	codeRenderer = highlight.NewRenderer("github", "dracula", 4)
-)
+}
 
 // renderFrontmatter extracts YAML frontmatter from the start of input (if present),
 // renders it as an HTML table, and returns the table HTML along with the remaining content.
@@ -94,21 +182,35 @@ func renderFrontmatter(input string) (fmHTML string, rest string) {
 	return fmHTML, afterClose
 }
 
-func renderMarkdown(input string) template.HTML {
+// renderMarkdown renders reviewer or file supplied markdown, escaping any
+// raw HTML in it.
+func (r *renderers) renderMarkdown(input string) template.HTML {
+	return renderMarkdownWith(r.markdown, input)
+}
+
+// renderPrompt renders the operator's prompt, raw HTML included.
+func (r *renderers) renderPrompt(input string) template.HTML {
+	return renderMarkdownWith(r.prompt, input)
+}
+
+func renderMarkdownWith(md goldmark.Markdown, input string) template.HTML {
 	fmHTML, rest := renderFrontmatter(input)
 	var buf bytes.Buffer
-	if err := markdownRenderer.Convert([]byte(rest), &buf); err != nil {
+	if err := md.Convert([]byte(rest), &buf); err != nil {
 		return template.HTML(fmHTML + html.EscapeString(rest))
 	}
 	return template.HTML(fmHTML + buf.String())
 }
 
-func renderMarkdownDocument(path string, input string) template.HTML {
+func (r *renderers) renderMarkdownDocument(path string, input string) template.HTML {
 	baseDir := filepath.Dir(path)
 	if baseDir == "." {
 		baseDir = ""
 	}
-	rendered := renderMarkdown(input)
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".md" && ext != ".markdown" && docRenderers[ext] != nil {
+		return joinBlocks(docRenderers[ext](r, path, input))
+	}
+	rendered := r.renderMarkdown(input)
 	return rewriteMarkdownImageSources(string(rendered), baseDir)
 }
 
@@ -132,7 +234,7 @@ func resolveLineRange(node ast.Node, lastLine int, byteToLine func(int) int) (st
 }
 
 // renderMarkdownBlocks parses markdown into per-block HTML chunks with source line mappings.
-func renderMarkdownBlocks(path, input string) []MarkdownBlock {
+func renderMarkdownBlocks(rs *renderers, path, input string) []MarkdownBlock {
 	baseDir := filepath.Dir(path)
 	if baseDir == "." {
 		baseDir = ""
@@ -164,8 +266,8 @@ func renderMarkdownBlocks(path, input string) []MarkdownBlock {
 
 	// Parse AST.
 	reader := text.NewReader(source)
-	doc := markdownRenderer.Parser().Parse(reader)
-	r := markdownRenderer.Renderer()
+	doc := rs.markdown.Parser().Parse(reader)
+	r := rs.markdown.Renderer()
 
 	var blocks []MarkdownBlock
 
@@ -343,16 +445,17 @@ func isExternalAssetURL(s string) bool {
 		strings.HasPrefix(lower, "/")
 }
 
+// isMarkdownPath reports whether path is markdown or another documentation
+// format with a rendered preview.
 func isMarkdownPath(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	return ext == ".md" || ext == ".markdown"
+	return docRendererFor(path) != nil
 }
 
 func buildCSS() string {
 	var buf bytes.Buffer
 	buf.WriteString(stylesCSS)
 	buf.WriteString("\n")
-	buf.WriteString(codeRenderer.BuildCSS())
+	buf.WriteString(defaultRenderers.code.BuildCSS())
 	return buf.String()
 }
 
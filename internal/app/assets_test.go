@@ -12,7 +12,7 @@ import (
 // Scenario: YAML frontmatter rendered as metadata table
 func TestRenderMarkdownFrontmatterTable(t *testing.T) {
 	input := "---\ntitle: Test\ndate: 2024-01-01\n---\n# Hello"
-	got := string(renderMarkdown(input))
+	got := string(defaultRenderers.renderMarkdown(input))
 
 	if !strings.Contains(got, `<table`) {
 		t.Fatalf("expected <table in output, got: %q", got)
@@ -43,7 +43,7 @@ func TestRenderMarkdownFrontmatterTable(t *testing.T) {
 // Scenario: Document without frontmatter renders unchanged
 func TestRenderMarkdownNoFrontmatter(t *testing.T) {
 	input := "# Hello\nworld"
-	got := string(renderMarkdown(input))
+	got := string(defaultRenderers.renderMarkdown(input))
 
 	if strings.Contains(got, "frontmatter") {
 		t.Fatalf("expected no frontmatter class in output, got: %q", got)
@@ -62,7 +62,7 @@ func TestRenderMarkdownNoFrontmatter(t *testing.T) {
 // Scenario: YAML frontmatter rendered as metadata table (frontmatter-only case)
 func TestRenderMarkdownFrontmatterOnly(t *testing.T) {
 	input := "---\ntitle: Only\n---\n"
-	got := string(renderMarkdown(input))
+	got := string(defaultRenderers.renderMarkdown(input))
 
 	if !strings.Contains(got, `frontmatter`) {
 		t.Fatalf("expected frontmatter class in output even with no body, got: %q", got)
@@ -76,7 +76,7 @@ func TestRenderMarkdownFrontmatterOnly(t *testing.T) {
 // valid key-value pairs does not inject an empty table.
 func TestRenderMarkdownEmptyFrontmatter(t *testing.T) {
 	input := "---\n\n---\n# Hello"
-	got := string(renderMarkdown(input))
+	got := string(defaultRenderers.renderMarkdown(input))
 
 	if strings.Contains(got, "frontmatter") {
 		t.Fatalf("expected no frontmatter table for empty frontmatter block, got: %q", got)
@@ -93,7 +93,7 @@ func TestRenderMarkdownEmptyFrontmatter(t *testing.T) {
 // Scenario: Document without frontmatter renders unchanged (HR case)
 func TestRenderMarkdownNotFrontmatter(t *testing.T) {
 	input := "Some content\n\n---\n\nMore content"
-	got := string(renderMarkdown(input))
+	got := string(defaultRenderers.renderMarkdown(input))
 
 	if strings.Contains(got, "frontmatter") {
 		t.Fatalf("expected no frontmatter table when --- is not at line 0, got: %q", got)
@@ -105,3 +105,19 @@ func TestRenderMarkdownNotFrontmatter(t *testing.T) {
 		t.Fatalf("expected 'More content' in rendered output, got: %q", got)
 	}
 }
+
+func TestRenderMarkdownEscapesRawHTML(t *testing.T) {
+	c := &Comment{Text: "hi <script>alert(1)</script>\n\n<div onclick=\"x()\">block</div>\n\n[x](javascript:alert(1))"}
+	got := string(c.renderedHTML())
+	for _, bad := range []string{"<script", "<div", "javascript:"} {
+		if strings.Contains(got, bad) {
+			t.Errorf("expected %q to be escaped or dropped, got %q", bad, got)
+		}
+	}
+	if !strings.Contains(got, "&lt;script&gt;") {
+		t.Errorf("expected the tag to show as text, got %q", got)
+	}
+	if got := string(defaultRenderers.renderPrompt("<details>more</details>")); !strings.Contains(got, "<details>") {
+		t.Errorf("expected the prompt to keep raw HTML, got %q", got)
+	}
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+const (
+	tokenParam  = "token"
+	tokenCookie = "meatcheck_token"
+)
+
+// newAccessToken returns a random token that must accompany every request to
+// the session, so that nobody who can reach the port but was not given the
+// launch URL can read the review or files through /file.
+func newAccessToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate access token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// tokenCookieName is the cookie holding the token for the session on port.
+// Browsers do not separate cookies by port, so two sessions on the same host
+// would otherwise overwrite each other's.
+func tokenCookieName(port string) string {
+	return tokenCookie + "_" + port
+}
+
+// requireToken rejects requests that carry neither a token as a query
+// parameter nor the cookie named cookie, which is set on the first valid
+// request. token grants full access; observerToken, when not empty, only
+// lets the request watch, and the role is bound to the request so handlers
+// can read it with isObserver. A token in the URL wins over the cookie, so
+// opening the other link switches roles.
+func requireToken(token, observerToken, cookie string, next http.Handler) http.Handler {
+	role := func(got string) (observer, ok bool) {
+		switch {
+		case subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1:
+			return false, true
+		case observerToken != "" && subtle.ConstantTimeCompare([]byte(got), []byte(observerToken)) == 1:
+			return true, true
+		}
+		return false, false
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.URL.Query().Get(tokenParam)
+		observer, ok := role(got)
+		if ok {
+			http.SetCookie(w, &http.Cookie{
+				Name:     cookie,
+				Value:    got,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteStrictMode,
+			})
+		} else if c, err := r.Cookie(cookie); err == nil {
+			observer, ok = role(c.Value)
+		}
+		if !ok {
+			http.Error(w, "this review needs its access token; use the full link meatcheck printed", http.StatusForbidden)
+			return
+		}
+		if observer {
+			r = r.WithContext(context.WithValue(r.Context(), observerKey{}, true))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// observerKey marks, in a request's context, that it came with the observer
+// token.
+type observerKey struct{}
+
+// isObserver reports whether r was let in with the observer token.
+func isObserver(r *http.Request) bool {
+	observer, _ := r.Context().Value(observerKey{}).(bool)
+	return observer
+}
+
+// forbidObservers rejects requests made with the observer token, for
+// endpoints that change the review.
+func forbidObservers(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isObserver(r) {
+			http.Error(w, "observers cannot change the review", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRequireToken verifies that a session only serves requests that
+// present its token, either in the URL or through the cookie set by the first
+// valid request.
+func TestRequireToken(t *testing.T) {
+	observer := false
+	h := requireToken("secret", "watch", tokenCookieName("8080"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		observer = isObserver(r)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/file?path=main.go", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("no token: got status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/?token=wrong", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("wrong token: got status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/?token=secret", nil))
+	if rec.Code != http.StatusOK || observer {
+		t.Fatalf("valid token: got status %d, observer %v", rec.Code, observer)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "meatcheck_token_8080" {
+		t.Fatalf("expected the token cookie to be set, got %v", cookies)
+	}
+
+	req := httptest.NewRequest("GET", "/live.js", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cookie: got status %d", rec.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/live.js", nil)
+	req.AddCookie(&http.Cookie{Name: tokenCookieName("9090"), Value: "secret"})
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("another session's cookie: got status %d", rec.Code)
+	}
+}
+
+// TestObserverToken verifies that the observer token lets a request in as an
+// observer, through the URL and then the cookie alone, and that the full
+// token in the URL switches back.
+func TestObserverToken(t *testing.T) {
+	observer := false
+	h := requireToken("secret", "watch", tokenCookieName("8080"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		observer = isObserver(r)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/?token=watch", nil))
+	if rec.Code != http.StatusOK || !observer {
+		t.Fatalf("observer token: got status %d, observer %v", rec.Code, observer)
+	}
+	cookie := rec.Result().Cookies()[0]
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookie)
+	observer = false
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if !observer {
+		t.Fatal("expected the observer cookie to keep the role without a parameter")
+	}
+
+	req = httptest.NewRequest("GET", "/?token=secret", nil)
+	req.AddCookie(cookie)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if observer {
+		t.Fatal("expected the full token in the URL to win over the observer cookie")
+	}
+
+	api := requireToken("secret", "watch", tokenCookieName("8080"), forbidObservers(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	rec = httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest("POST", "/api/comments?token=watch", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("observer API write: got status %d", rec.Code)
+	}
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// binarySniffLen is how much of a file is checked for binary content, the
+// same amount git looks at.
+const binarySniffLen = 8000
+
+// looksBinary reports whether head, the start of a file, is binary: it
+// holds a NUL byte and is not UTF-16 text, whose NULs are part of the
+// encoding.
+func looksBinary(head []byte) bool {
+	switch detectBOM(head) {
+	case BOMUTF16LE, BOMUTF16BE:
+		return false
+	}
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+// sniffFile reads the start of the file at path and returns its byte order
+// mark and whether it is binary.
+func sniffFile(path string) (ByteOrderMark, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return BOMNone, false, fmt.Errorf("read %s: %w", path, err)
+	}
+	defer f.Close()
+	head := make([]byte, binarySniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return BOMNone, false, fmt.Errorf("read %s: %w", path, err)
+	}
+	return detectBOM(head[:n]), looksBinary(head[:n]), nil
+}
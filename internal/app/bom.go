@@ -0,0 +1,63 @@
+package app
+
+import (
+	"bytes"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ByteOrderMark identifies the Unicode byte order mark a file started with on
+// disk. The mark is stripped for display and recorded so it can be restored
+// when the file is written back.
+type ByteOrderMark string
+
+const (
+	BOMNone    ByteOrderMark = ""
+	BOMUTF8    ByteOrderMark = "utf-8"
+	BOMUTF16LE ByteOrderMark = "utf-16le"
+	BOMUTF16BE ByteOrderMark = "utf-16be"
+)
+
+var bomBytes = map[ByteOrderMark][]byte{
+	BOMUTF8:    {0xEF, 0xBB, 0xBF},
+	BOMUTF16LE: {0xFF, 0xFE},
+	BOMUTF16BE: {0xFE, 0xFF},
+}
+
+// detectBOM returns the byte order mark data starts with.
+func detectBOM(data []byte) ByteOrderMark {
+	for _, bom := range []ByteOrderMark{BOMUTF8, BOMUTF16LE, BOMUTF16BE} {
+		if bytes.HasPrefix(data, bomBytes[bom]) {
+			return bom
+		}
+	}
+	return BOMNone
+}
+
+// decodeText strips a leading byte order mark from data and converts UTF-16
+// content to UTF-8.
+func decodeText(data []byte) ([]byte, ByteOrderMark) {
+	bom := detectBOM(data)
+	body := data[len(bomBytes[bom]):]
+	switch bom {
+	case BOMUTF16LE, BOMUTF16BE:
+		return decodeUTF16(body, bom == BOMUTF16BE), bom
+	}
+	return body, bom
+}
+
+func decodeUTF16(data []byte, bigEndian bool) []byte {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		} else {
+			units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+		}
+	}
+	out := make([]byte, 0, len(units))
+	for _, r := range utf16.Decode(units) {
+		out = utf8.AppendRune(out, r)
+	}
+	return out
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// maxBrandImageSize bounds a custom logo or avatar. Both are inlined into
+// every render, so they should stay small; the built-in ones are under 1 MiB.
+const maxBrandImageSize = 2 << 20
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// branding is the logo, avatar and accent colour the UI is drawn with.
+type branding struct {
+	Logo   template.URL
+	Avatar template.URL
+	// Accent is a #rgb or #rrggbb colour, or "" for the built-in theme.
+	Accent string
+}
+
+// defaultBranding is the embedded meatcheck look.
+var defaultBranding = branding{
+	Logo:   imageURL("image/png", logoBytes),
+	Avatar: imageURL("image/png", avatarBytes),
+}
+
+func imageURL(mime string, data []byte) template.URL {
+	return template.URL("data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data))
+}
+
+// branding returns the server's branding, or the built-in one when none
+// was loaded.
+func (rs *ReviewServer) branding() branding {
+	if rs.brand.Logo == "" {
+		return defaultBranding
+	}
+	return rs.brand
+}
+
+// loadBranding resolves the branding from cfg, falling back to the logo,
+// avatar and accent_color preferences and then to the built-in assets.
+func loadBranding(cfg Config) (branding, error) {
+	prefs := loadPreferences()
+	pick := func(flag, pref string) string {
+		if flag != "" {
+			return flag
+		}
+		return pref
+	}
+	b := defaultBranding
+	if path := pick(cfg.Logo, prefs.Logo); path != "" {
+		url, err := readBrandImage(path)
+		if err != nil {
+			return branding{}, fmt.Errorf("logo: %w", err)
+		}
+		b.Logo = url
+	}
+	if path := pick(cfg.Avatar, prefs.Avatar); path != "" {
+		url, err := readBrandImage(path)
+		if err != nil {
+			return branding{}, fmt.Errorf("avatar: %w", err)
+		}
+		b.Avatar = url
+	}
+	if accent := pick(cfg.AccentColor, prefs.AccentColor); accent != "" {
+		if !hexColor.MatchString(accent) {
+			return branding{}, fmt.Errorf("accent color must be #rgb or #rrggbb, got %q", accent)
+		}
+		b.Accent = accent
+	}
+	return b, nil
+}
+
+// readBrandImage reads a PNG, JPEG, GIF or WebP image as a data URL.
+func readBrandImage(path string) (template.URL, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxBrandImageSize {
+		return "", fmt.Errorf("%s is larger than %d MiB", path, maxBrandImageSize>>20)
+	}
+	switch mime := http.DetectContentType(data); mime {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return imageURL(mime, data), nil
+	default:
+		return "", fmt.Errorf("%s is not a PNG, JPEG, GIF or WebP image (%s)", path, mime)
+	}
+}
+
+// css overrides the theme's accent variables, or returns "" for the
+// built-in theme. The soft accent is the accent blended into each theme's
+// background, as the built-in pairs are.
+func (b branding) css() string {
+	if b.Accent == "" {
+		return ""
+	}
+	r, g, bl := parseHexColor(b.Accent)
+	const mix = 0.25
+	soft := func(c, bg int) int { return int(float64(bg) + (float64(c)-float64(bg))*mix) }
+	return fmt.Sprintf("\n:root {\n  --accent: %s;\n  --accent-soft: #%02x%02x%02x;\n}\n", b.Accent, soft(r, 0x0f), soft(g, 0x0b), soft(bl, 0x0c)) +
+		fmt.Sprintf("body.theme-light {\n  --accent-soft: #%02x%02x%02x;\n}\n", soft(r, 0xf7), soft(g, 0xf4), soft(bl, 0xf4))
+}
+
+// parseHexColor splits a colour matched by hexColor into its components.
+func parseHexColor(s string) (r, g, b int) {
+	s = s[1:]
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	n, _ := strconv.ParseUint(s, 16, 32)
+	return int(n >> 16 & 0xff), int(n >> 8 & 0xff), int(n & 0xff)
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/jfyne/live"
+)
+
+// isolatePreferences points the preferences file at an empty directory for
+// the rest of the test.
+func isolatePreferences(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+	return preferencesPath()
+}
+
+func TestLoadBranding(t *testing.T) {
+	isolatePreferences(t)
+	b, err := loadBranding(Config{})
+	if err != nil || b != defaultBranding || b.css() != "" {
+		t.Fatalf("expected the built-in branding, got %v", err)
+	}
+
+	logo := writeTempFile(t, "logo.png", string(avatarBytes))
+	b, err = loadBranding(Config{Logo: logo, AccentColor: "#0a6"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Logo != imageURL("image/png", avatarBytes) || b.Avatar != defaultBranding.Avatar {
+		t.Fatal("expected only the logo to be replaced")
+	}
+	if css := b.css(); !strings.Contains(css, "--accent: #0a6;") || !strings.Contains(css, "--accent-soft: #0b3222;") {
+		t.Fatalf("unexpected accent css %q", css)
+	}
+
+	for _, cfg := range []Config{
+		{Logo: writeTempFile(t, "logo.txt", "not an image")},
+		{Avatar: filepath.Join(t.TempDir(), "missing.png")},
+		{AccentColor: "red"},
+		{AccentColor: "#12345"},
+	} {
+		if _, err := loadBranding(cfg); err == nil {
+			t.Errorf("loadBranding(%+v) succeeded, want error", cfg)
+		}
+	}
+}
+
+func TestBrandingPreferences(t *testing.T) {
+	path := isolatePreferences(t)
+	avatar := writeTempFile(t, "avatar.png", string(logoBytes))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"avatar": "`+filepath.ToSlash(avatar)+`", "accent_color": "#336699"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := loadBranding(Config{AccentColor: "#ff8800"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Avatar != imageURL("image/png", logoBytes) || b.Accent != "#ff8800" {
+		t.Fatalf("expected the avatar from preferences and the accent from the flag, got %+v", b.Accent)
+	}
+
+	// Saving another preference keeps the branding.
+	savePreference(func(p *Preferences) { p.DiffFormat = DiffFormatSplit })
+	if p := loadPreferences(); p.AccentColor != "#336699" || p.Avatar == "" {
+		t.Fatalf("branding lost on save: %+v", p)
+	}
+}
+
+func TestRenderBranding(t *testing.T) {
+	model := buildCommentModel()
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	rs.brand = defaultBranding
+	rs.brand.Avatar = imageURL("image/png", logoBytes)
+	rs.brand.Accent = "#123456"
+	out, err := buildLiveHandler(rs).RenderHandler(t.Context(), &live.RenderContext{Assigns: model})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(b)
+	if !strings.Contains(html, "--accent: #123456;") {
+		t.Error("expected the accent override in the page css")
+	}
+	avatar := strings.ReplaceAll(string(rs.brand.Avatar), "+", "&#43;")
+	if !strings.Contains(html, `<img src="`+avatar+`" alt="AI avatar"`) {
+		t.Error("expected the custom avatar in the header")
+	}
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jfyne/live"
+)
+
+// ChatMessage is one message in the session chat, which is shared by all
+// connected reviewers and not anchored to any file or line.
+type ChatMessage struct {
+	Author string
+	Text   string
+	Sent   time.Time
+}
+
+// chatTranscript converts the session chat to the output document shape.
+func chatTranscript(messages []ChatMessage) []map[string]any {
+	out := make([]map[string]any, 0, len(messages))
+	for _, m := range messages {
+		out = append(out, map[string]any{
+			"author": m.Author,
+			"time":   m.Sent.UTC().Format(time.RFC3339),
+			"text":   m.Text,
+		})
+	}
+	return out
+}
+
+func registerChatHandlers(h *live.Handler, rs *ReviewServer) {
+	h.HandleEvent("toggle-chat", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		return rs.withView(s, func(view *ReviewView) { view.ChatOpen = !view.ChatOpen }), nil
+	})
+
+	h.HandleEvent("send-chat", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		text := strings.TrimSpace(p.String("message"))
+		if text == "" {
+			return view, nil
+		}
+		view.Chat = append(view.Chat, ChatMessage{
+			Author: view.Reviewer,
+			Text:   text,
+			Sent:   time.Now(),
+		})
+		if s != nil {
+			// Only the sender's input is cleared; the broadcast re-renders
+			// everyone else's form with what they are typing intact.
+			_ = s.Send("chat-sent", map[string]any{})
+		}
+		rs.markChanged()
+		return view, nil
+	}))
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+// TestSessionChat verifies that chat messages are attributed to the sending
+// reviewer, that the panel is opened per connection and that the transcript
+// is written to the output.
+func TestSessionChat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	alice := live.NewSocket(ctx, engine, "alice")
+	bob := live.NewSocket(ctx, engine, "bob")
+	for _, s := range []*live.Socket{alice, bob} {
+		s.Assign(rs.newView())
+	}
+	callEvent(t, engine, alice, live.EventParams, map[string]string{"reviewer": "alice"})
+	callEvent(t, engine, alice, "toggle-chat", nil)
+	callEvent(t, engine, alice, "send-chat", map[string]string{"message": "  who takes the parser?  "})
+	callEvent(t, engine, bob, "send-chat", map[string]string{"message": "   "})
+
+	if len(model.Chat) != 1 || model.Chat[0].Author != "alice" || model.Chat[0].Text != "who takes the parser?" {
+		t.Fatalf("unexpected chat: %+v", model.Chat)
+	}
+
+	render := func(s *live.Socket) string {
+		out, err := engine.Handler.RenderHandler(ctx, &live.RenderContext{Socket: s, Assigns: rs.view(s)})
+		if err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(out)
+		return buf.String()
+	}
+	if html := render(alice); !strings.Contains(html, `class="chat-panel"`) || !strings.Contains(html, "who takes the parser?") {
+		t.Fatal("expected alice to see the chat panel with her message")
+	}
+	if html := render(bob); strings.Contains(html, `class="chat-panel"`) {
+		t.Fatal("expected the chat panel to stay closed for bob")
+	}
+
+	var buf bytes.Buffer
+	if err := emitReview(&buf, model.ReviewModel); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "chat[1]") || !strings.Contains(out, "who takes the parser?") {
+		t.Fatalf("expected chat transcript in output, got:\n%s", out)
+	}
+}
@@ -2,8 +2,13 @@ package app
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/jfyne/live"
 )
 
 // TestCommentIDAssignment verifies that adding comments to ReviewModel assigns
@@ -123,43 +128,55 @@ func TestDeleteNonexistentComment(t *testing.T) {
 	}
 }
 
-// TestDeleteClearsEditingID verifies that deleting a comment whose ID matches
-// EditingCommentID clears EditingCommentID to 0.
+// TestDeleteClearsEditingID verifies that a view editing a comment that is
+// deleted, by its own connection or another, stops editing it.
 //
 // Scenario: Delete a comment clears EditingCommentID when it matches
 func TestDeleteClearsEditingID(t *testing.T) {
-	model := &ReviewModel{
-		NextCommentID:    2,
-		EditingCommentID: 2,
-		Comments: []Comment{
-			{ID: 1, Path: "a.go", StartLine: 1, EndLine: 1, Text: "one"},
-			{ID: 2, Path: "a.go", StartLine: 2, EndLine: 2, Text: "two"},
+	view := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files:         []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"one", "two"}}},
+			Mode:          ModeFile,
+			NextCommentID: 2,
+			Comments: []Comment{
+				{ID: 1, Path: "a.go", StartLine: 1, EndLine: 1, Text: "one"},
+				{ID: 2, Path: "a.go", StartLine: 2, EndLine: 2, Text: "two"},
+			},
 		},
+		SelectedPath:     "a.go",
+		EditingCommentID: 2,
 	}
 
-	deleteComment(model, 2)
+	deleteComment(view.ReviewModel, 2)
+	refreshView(view)
 
-	if model.EditingCommentID != 0 {
-		t.Errorf("EditingCommentID should be cleared to 0, got %d", model.EditingCommentID)
+	if view.EditingCommentID != 0 {
+		t.Errorf("EditingCommentID should be cleared to 0, got %d", view.EditingCommentID)
 	}
 }
 
 // TestDeleteDoesNotClearOtherEditingID verifies that deleting a comment does
 // not clear EditingCommentID when the IDs don't match.
 func TestDeleteDoesNotClearOtherEditingID(t *testing.T) {
-	model := &ReviewModel{
-		NextCommentID:    2,
-		EditingCommentID: 1,
-		Comments: []Comment{
-			{ID: 1, Path: "a.go", StartLine: 1, EndLine: 1, Text: "one"},
-			{ID: 2, Path: "a.go", StartLine: 2, EndLine: 2, Text: "two"},
+	view := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files:         []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"one", "two"}}},
+			Mode:          ModeFile,
+			NextCommentID: 2,
+			Comments: []Comment{
+				{ID: 1, Path: "a.go", StartLine: 1, EndLine: 1, Text: "one"},
+				{ID: 2, Path: "a.go", StartLine: 2, EndLine: 2, Text: "two"},
+			},
 		},
+		SelectedPath:     "a.go",
+		EditingCommentID: 1,
 	}
 
-	deleteComment(model, 2)
+	deleteComment(view.ReviewModel, 2)
+	refreshView(view)
 
-	if model.EditingCommentID != 1 {
-		t.Errorf("EditingCommentID should remain 1, got %d", model.EditingCommentID)
+	if view.EditingCommentID != 1 {
+		t.Errorf("EditingCommentID should remain 1, got %d", view.EditingCommentID)
 	}
 }
 
@@ -177,13 +194,12 @@ func TestViewCommentInheritsID(t *testing.T) {
 }
 
 // TestEditCommentSuccess verifies that editing a comment by ID updates its
-// text and clears EditingCommentID.
+// text.
 //
 // Scenario: Edit a comment with valid text updates the comment
 func TestEditCommentSuccess(t *testing.T) {
 	model := &ReviewModel{
-		NextCommentID:    1,
-		EditingCommentID: 1,
+		NextCommentID: 1,
 		Comments: []Comment{
 			{ID: 1, Path: "a.go", StartLine: 5, EndLine: 5, Text: "original"},
 		},
@@ -196,9 +212,6 @@ func TestEditCommentSuccess(t *testing.T) {
 	if model.Comments[0].Text != "updated" {
 		t.Errorf("comment text: got %q, want %q", model.Comments[0].Text, "updated")
 	}
-	if model.EditingCommentID != 0 {
-		t.Errorf("EditingCommentID should be cleared to 0, got %d", model.EditingCommentID)
-	}
 }
 
 // TestEditCommentEmptyTextReturnsError verifies that editing a comment with
@@ -207,8 +220,7 @@ func TestEditCommentSuccess(t *testing.T) {
 // Scenario: Edit with empty text shows error
 func TestEditCommentEmptyTextReturnsError(t *testing.T) {
 	model := &ReviewModel{
-		NextCommentID:    1,
-		EditingCommentID: 1,
+		NextCommentID: 1,
 		Comments: []Comment{
 			{ID: 1, Path: "a.go", StartLine: 5, EndLine: 5, Text: "original"},
 		},
@@ -244,11 +256,13 @@ func TestEditCommentNonexistentIDReturnsError(t *testing.T) {
 
 // TestCancelEditComment verifies that cancelling an edit clears EditingCommentID.
 func TestCancelEditComment(t *testing.T) {
-	model := &ReviewModel{
-		EditingCommentID: 3,
-		Comments: []Comment{
-			{ID: 3, Path: "a.go", StartLine: 1, EndLine: 1, Text: "hello"},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Comments: []Comment{
+				{ID: 3, Path: "a.go", StartLine: 1, EndLine: 1, Text: "hello"},
+			},
 		},
+		EditingCommentID: 3,
 	}
 
 	// Simulate cancel-edit-comment handler logic.
@@ -280,3 +294,74 @@ func TestEmitToonIncludesCommentID(t *testing.T) {
 		t.Errorf("TOON output should contain 'id' field, got: %s", output)
 	}
 }
+
+// TestProjectLineCommentsCachesRendering verifies that comment markdown is
+// rendered once and cached on the model's comment, and that editing the
+// comment invalidates the cache.
+//
+// Scenario: Rendered comment HTML is reused across view updates and refreshed on edit
+func TestProjectLineCommentsCachesRendering(t *testing.T) {
+	model := &ReviewModel{
+		Comments: []Comment{{ID: 1, Path: "a.go", StartLine: 1, EndLine: 1, Text: "**bold**"}},
+	}
+
+	_, got := projectLineComments("a.go", 1, model.Comments, 0, "")
+	if len(got) != 1 || !strings.Contains(string(got[0].Rendered), "<strong>bold</strong>") {
+		t.Fatalf("unexpected rendered comment: %+v", got)
+	}
+	if model.Comments[0].rendered == "" {
+		t.Fatal("expected rendered HTML to be cached on the comment")
+	}
+
+	if err := editComment(model, 1, "_italic_"); err != nil {
+		t.Fatalf("editComment: %v", err)
+	}
+	_, got = projectLineComments("a.go", 1, model.Comments, 0, "")
+	if !strings.Contains(string(got[0].Rendered), "<em>italic</em>") {
+		t.Fatalf("expected re-rendered comment after edit, got %q", got[0].Rendered)
+	}
+}
+
+// TestCommentChangesBroadcast verifies that a comment added on one socket is
+// pushed to every other connected socket without any action on their side.
+func TestCommentChangesBroadcast(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	model.Comments = nil
+	model.SelectionStart, model.SelectionEnd = 1, 1
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+
+	author := live.NewSocket(ctx, engine, "author")
+	observer := live.NewSocket(ctx, engine, "observer")
+	for _, s := range []*live.Socket{author, observer} {
+		engine.AddSocket(s)
+		s.Assign(rs.newView())
+		if err := s.Render(ctx); err != nil {
+			t.Fatalf("initial render: %v", err)
+		}
+	}
+
+	data, _ := json.Marshal(map[string]string{"comment": "seen by everyone"})
+	if err := engine.CallEvent(ctx, "add-comment", author, live.Event{T: "add-comment", Data: data}); err != nil {
+		t.Fatalf("add-comment: %v", err)
+	}
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case msg := <-observer.Messages():
+			if msg.T != live.EventPatch {
+				continue
+			}
+			if strings.Contains(string(msg.Data), "seen by everyone") {
+				return
+			}
+		case <-timeout:
+			t.Fatal("observer socket never received the new comment")
+		}
+	}
+}
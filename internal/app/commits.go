@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/jfyne/live"
+)
+
+// buildCommitTree lists the files of the combined diff as a directory tree,
+// then those of each commit. Items carry their commit, and the template
+// shows those of the commit being viewed.
+func buildCommitTree(view *ReviewView) []TreeItem {
+	scopes := make([][]File, len(view.Commits)+1)
+	for _, df := range view.DiffFiles {
+		path := filepath.ToSlash(pickDiffPath(df.OldPath, df.NewPath))
+		scopes[df.Commit] = append(scopes[df.Commit], File{Path: df.Path, PathSlash: path})
+	}
+	var items []TreeItem
+	for n, files := range scopes {
+		for _, item := range buildTree(files, view.SelectedPath, view.Viewed, view.Comments) {
+			item.Commit = n
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// commitFile returns the path the file at path is reviewed under in commit
+// n, or 0 for the combined diff, falling back to the first file of n when
+// the commit does not change it. It returns "" for an unknown commit.
+func commitFile(model *ReviewModel, path string, n int) string {
+	want := model.seriesPath(path)
+	first := ""
+	for _, df := range model.DiffFiles {
+		if df.Commit != n {
+			continue
+		}
+		if pickDiffPath(df.OldPath, df.NewPath) == want {
+			return df.Path
+		}
+		if first == "" {
+			first = df.Path
+		}
+	}
+	return first
+}
+
+func registerCommitHandlers(h *live.Handler, rs *ReviewServer) {
+	// Like the selected file, the commit is part of one reviewer's view.
+	h.HandleEvent("select-commit", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		if !view.CommitPicker {
+			return view, nil
+		}
+		if path := commitFile(view.ReviewModel, view.SelectedPath, p.Int("commit")); path != "" {
+			selectFile(view, path)
+		}
+		return view, nil
+	}))
+}
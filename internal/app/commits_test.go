@@ -0,0 +1,112 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+// TestCommitPicker verifies that a git range is reviewed as the combined
+// diff plus each commit, and that picking a commit shows its version of the
+// file being viewed.
+func TestCommitPicker(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	repo := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test",
+			"GIT_AUTHOR_EMAIL=test@test.com",
+			"GIT_COMMITTER_NAME=test",
+			"GIT_COMMITTER_EMAIL=test@test.com",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("init")
+	write("a.go", "package a\n")
+	run("add", "a.go")
+	run("commit", "-m", "init")
+	write("a.go", "package a\n\n// one\n")
+	run("commit", "-am", "Add one")
+	write("a.go", "package a\n\n// one\n// two\n")
+	write("b.go", "package a\n")
+	run("add", "b.go")
+	run("commit", "-am", "Add two", "-m", "And a second file.")
+	t.Chdir(repo)
+
+	model, err := serve(context.Background(), Config{Git: "HEAD~2..HEAD", Headless: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !model.CommitPicker || len(model.Commits) != 2 || model.Commits[1].Message != "And a second file." {
+		t.Fatalf("expected two commits to pick from, got %+v", model.Commits)
+	}
+	var paths []string
+	for _, df := range model.DiffFiles {
+		paths = append(paths, df.Path)
+	}
+	if strings.Join(paths, " ") != "a.go b.go 0001/commit_message 0001/a.go 0002/commit_message 0002/a.go 0002/b.go" {
+		t.Fatalf("unexpected files: %v", paths)
+	}
+	view := startView(model)
+	if view.SelectedPath != "a.go" || view.ViewDiff.Commit != nil {
+		t.Fatalf("expected the combined diff first, got %q", view.SelectedPath)
+	}
+
+	// A headless run finishes the review; reopen it to drive the UI.
+	model.Completed = false
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rs := &ReviewServer{Model: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	s := live.NewSocket(ctx, engine, "alice")
+	s.Assign(view)
+
+	callEvent(t, engine, s, "select-commit", map[string]string{"commit": "2"})
+	if view.SelectedPath != "0002/a.go" || view.ViewDiff.Commit.Number != 2 {
+		t.Fatalf("expected a.go in the second commit, got %q", view.SelectedPath)
+	}
+	out, err := engine.Handler.RenderHandler(ctx, &live.RenderContext{Socket: s, Assigns: view})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(out)
+	html := buf.String()
+	if !strings.Contains(html, `<option value="2" selected>[2/2] Add two</option>`) || !strings.Contains(html, "And a second file.") {
+		t.Fatal("expected the second commit to be picked and its message shown")
+	}
+	if strings.Contains(html, `live-value-path="0001/a.go"`) || !strings.Contains(html, `live-value-path="0002/b.go"`) {
+		t.Fatal("expected only the second commit's files in the tree")
+	}
+
+	// A commit that does not change the file opens its message.
+	callEvent(t, engine, s, "select-file", map[string]string{"path": "0002/b.go"})
+	callEvent(t, engine, s, "select-commit", map[string]string{"commit": "1"})
+	if view.SelectedPath != "0001/commit_message" {
+		t.Fatalf("expected the first commit's message, got %q", view.SelectedPath)
+	}
+	callEvent(t, engine, s, "select-commit", map[string]string{"commit": "0"})
+	if view.SelectedPath != "a.go" {
+		t.Fatalf("expected the combined diff, got %q", view.SelectedPath)
+	}
+}
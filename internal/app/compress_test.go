@@ -0,0 +1,102 @@
+package app
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip, deflate, br", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip;q=0, deflate", "deflate"},
+		{"br", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompressHandlerGzipsFileResponses(t *testing.T) {
+	tmp := t.TempDir()
+	content := strings.Repeat("hello world\n", 100)
+	if err := os.WriteFile(filepath.Join(tmp, "a.svg"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	h := compressHandler(localFileHandler(tmp))
+	req := httptest.NewRequest(http.MethodGet, "/file?path=a.svg", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", rr.Header().Get("Content-Encoding"))
+	}
+	if rr.Header().Get("Content-Length") != "" {
+		t.Fatal("expected Content-Length to be dropped for compressed body")
+	}
+	zr, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	body, _ := io.ReadAll(zr)
+	if string(body) != content {
+		t.Fatalf("unexpected decompressed body: %q", body)
+	}
+}
+
+func TestCompressHandlerDeflate(t *testing.T) {
+	h := compressHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "<html></html>")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "deflate")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("expected deflate encoding, got %q", rr.Header().Get("Content-Encoding"))
+	}
+	zr, err := zlib.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("expected a zlib stream: %v", err)
+	}
+	body, _ := io.ReadAll(zr)
+	if string(body) != "<html></html>" {
+		t.Fatalf("unexpected decompressed body: %q", body)
+	}
+}
+
+func TestCompressHandlerSkipsWebsocketAndUnsupported(t *testing.T) {
+	h := compressHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "plain")
+	}))
+	for _, hdr := range []map[string]string{
+		{"Accept-Encoding": "gzip", "Upgrade": "websocket"},
+		{"Accept-Encoding": "br"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range hdr {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Header().Get("Content-Encoding") != "" || rr.Body.String() != "plain" {
+			t.Fatalf("expected uncompressed response for %v, got %q", hdr, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// DeadlineAction is what happens when the --deadline passes.
+type DeadlineAction string
+
+const (
+	// DeadlineWarn marks the deadline as passed and keeps the review open.
+	DeadlineWarn DeadlineAction = "warn"
+	// DeadlineFinish submits the review as it stands.
+	DeadlineFinish DeadlineAction = "finish"
+)
+
+// deadlineReviewer and timeoutReviewer are recorded as CompletedBy when
+// the deadline or --timeout finishes the review.
+const (
+	deadlineReviewer = "deadline"
+	timeoutReviewer  = "timeout"
+)
+
+// ParseDeadlineAction parses the --deadline-action flag.
+func ParseDeadlineAction(s string) (DeadlineAction, error) {
+	switch a := DeadlineAction(s); a {
+	case DeadlineWarn, DeadlineFinish:
+		return a, nil
+	}
+	return "", fmt.Errorf("deadline action must be %s or %s", DeadlineWarn, DeadlineFinish)
+}
+
+// deadlineLeft formats the time left until deadline as m:ss, or h:mm:ss
+// from an hour out.
+func deadlineLeft(deadline time.Time) string {
+	left := max(0, time.Until(deadline).Round(time.Second))
+	h, m, s := int(left.Hours()), int(left.Minutes())%60, int(left.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// expireDeadline applies the deadline action to an open review. It reports
+// whether the review was finished.
+func (rs *ReviewServer) expireDeadline() bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	model := rs.Model
+	if model.Completed || model.DeadlineExpired {
+		return false
+	}
+	model.DeadlineExpired = true
+	rs.events.emit(eventDeadlineExpired, "action", model.DeadlineAction)
+	if model.DeadlineAction != DeadlineFinish {
+		fmt.Fprintln(os.Stderr, "warning: the review deadline has passed")
+		return false
+	}
+	model.Completed = true
+	model.CompletedBy = deadlineReviewer
+	rs.events.emit(eventFinished, "comments", len(model.Comments), "verdict", model.Verdict, "by", model.CompletedBy)
+	return true
+}
+
+// watchDeadline arms a timer for the model's deadline. When it fires every
+// client is re-rendered through notify, and the session ends if the review
+// was finished. The returned function disarms the timer.
+func (rs *ReviewServer) watchDeadline(notify func()) (stop func() bool) {
+	timer := time.AfterFunc(time.Until(rs.Model.Deadline), func() {
+		finished := rs.expireDeadline()
+		notify()
+		if finished {
+			rs.DoneOnce.Do(func() {
+				close(rs.DoneCh)
+			})
+		}
+	})
+	return timer.Stop
+}
+
+// expireTimeout finishes an open review when --timeout runs out. It
+// reports whether the review was finished.
+func (rs *ReviewServer) expireTimeout() bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	model := rs.Model
+	if model.Completed {
+		return false
+	}
+	model.TimedOut = true
+	model.Completed = true
+	model.CompletedBy = timeoutReviewer
+	rs.events.emit(eventTimedOut)
+	rs.events.emit(eventFinished, "comments", len(model.Comments), "verdict", model.Verdict, "by", model.CompletedBy)
+	return true
+}
+
+// watchTimeout finishes the review after d, re-rendering every client
+// through notify first. The returned function disarms the timer.
+func (rs *ReviewServer) watchTimeout(d time.Duration, notify func()) (stop func() bool) {
+	timer := time.AfterFunc(d, func() {
+		if !rs.expireTimeout() {
+			return
+		}
+		fmt.Fprintln(os.Stderr, "warning: the review timed out; writing the comments so far")
+		notify()
+		rs.DoneOnce.Do(func() {
+			close(rs.DoneCh)
+		})
+	})
+	return timer.Stop
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDeadlineAction(t *testing.T) {
+	for _, s := range []string{"warn", "finish"} {
+		if a, err := ParseDeadlineAction(s); err != nil || string(a) != s {
+			t.Errorf("ParseDeadlineAction(%q) = %q, %v", s, a, err)
+		}
+	}
+	if _, err := ParseDeadlineAction("exit"); err == nil {
+		t.Error("expected an unknown action to be rejected")
+	}
+}
+
+func TestDeadlineLeft(t *testing.T) {
+	now := time.Now()
+	for d, want := range map[time.Duration]string{
+		-time.Minute:                "0:00",
+		90 * time.Second:            "1:30",
+		15 * time.Minute:            "15:00",
+		time.Hour + 5*time.Second:   "1:00:05",
+		2*time.Hour + 3*time.Minute: "2:03:00",
+	} {
+		if got := deadlineLeft(now.Add(d + 400*time.Millisecond)); got != want {
+			t.Errorf("deadlineLeft(+%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestExpireDeadline(t *testing.T) {
+	var events bytes.Buffer
+	model := buildCommentModel()
+	model.Deadline = time.Now()
+	model.DeadlineAction = DeadlineWarn
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{}), events: newEventLog(&events)}
+
+	if rs.expireDeadline() || model.Completed || !model.DeadlineExpired {
+		t.Fatal("expected warn to keep the review open and mark the deadline as passed")
+	}
+	if !strings.Contains(events.String(), "event=deadline_expired action=warn") {
+		t.Fatalf("unexpected events:\n%s", events.String())
+	}
+	if html := renderReviewHTML(t, model); !strings.Contains(html, `class="deadline expired"`) || !strings.Contains(html, "Time is up") {
+		t.Fatal("expected the header to show that time is up")
+	}
+	if meta := reviewDocument(model.ReviewModel)["metadata"].(map[string]any); meta["deadline_expired"] != true {
+		t.Fatalf("expected deadline_expired in the metadata, got %v", meta)
+	}
+	if err := validateDocument(reviewDocument(model.ReviewModel)); err != nil {
+		t.Fatal(err)
+	}
+
+	model.DeadlineExpired = false
+	model.DeadlineAction = DeadlineFinish
+	if !rs.expireDeadline() || !model.Completed || model.CompletedBy != deadlineReviewer {
+		t.Fatal("expected finish to complete the review")
+	}
+	if !strings.Contains(events.String(), "event=finished comments=1 by=deadline") {
+		t.Fatalf("expected a finished event:\n%s", events.String())
+	}
+}
+
+func TestWatchDeadlineFinishes(t *testing.T) {
+	model := buildCommentModel()
+	model.Deadline = time.Now().Add(10 * time.Millisecond)
+	model.DeadlineAction = DeadlineFinish
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	notified := make(chan struct{}, 1)
+	stop := rs.watchDeadline(func() { notified <- struct{}{} })
+	defer stop()
+
+	select {
+	case <-rs.DoneCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the deadline to end the session")
+	}
+	select {
+	case <-notified:
+	default:
+		t.Fatal("expected clients to be notified")
+	}
+}
+
+// TestWatchTimeout verifies that --timeout finishes the review with the
+// comments so far and marks the output as timed out.
+func TestWatchTimeout(t *testing.T) {
+	var events bytes.Buffer
+	model := buildCommentModel()
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{}), events: newEventLog(&events)}
+	stop := rs.watchTimeout(10*time.Millisecond, func() {})
+	defer stop()
+
+	select {
+	case <-rs.DoneCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the timeout to end the session")
+	}
+	rs.mu.Lock()
+	if !model.Completed || !model.TimedOut || model.CompletedBy != timeoutReviewer {
+		t.Fatalf("expected the review finished by the timeout, got completed %v by %q", model.Completed, model.CompletedBy)
+	}
+	if !strings.Contains(events.String(), "event=timed_out\n") || !strings.Contains(events.String(), "by=timeout") {
+		t.Fatalf("unexpected events:\n%s", events.String())
+	}
+	doc := reviewDocument(model.ReviewModel)
+	if meta := doc["metadata"].(map[string]any); meta["timed_out"] != true {
+		t.Fatalf("expected timed_out in the metadata, got %v", meta)
+	}
+	if comments := doc["comments"].([]commentRecord); len(comments) != 1 {
+		t.Fatalf("expected the comment so far in the output, got %v", comments)
+	}
+	if err := validateDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+	rs.mu.Unlock()
+	if rs.expireTimeout() {
+		t.Fatal("expected an already finished review to stay as it is")
+	}
+}
+
+func TestRenderDeadlineCountdown(t *testing.T) {
+	model := buildCommentModel()
+	if strings.Contains(renderReviewHTML(t, model), `live-hook="deadline"`) {
+		t.Fatal("expected no countdown without a deadline")
+	}
+	model.Deadline = time.Now().Add(15*time.Minute + time.Second)
+	model.DeadlineAction = DeadlineWarn
+	html := renderReviewHTML(t, model)
+	for _, want := range []string{`live-hook="deadline"`, `<span class="deadline-left">15:0`, "Time left before the agent stops waiting"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in the header", want)
+		}
+	}
+}
+
+// TestWatchSignals verifies that an interrupt finishes the review with the
+// comments so far and marks the output as aborted.
+func TestWatchSignals(t *testing.T) {
+	var events bytes.Buffer
+	model := buildCommentModel()
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{}), events: newEventLog(&events)}
+	signals := make(chan os.Signal, 1)
+	stop := rs.watchSignals(signals, func() {})
+	defer stop()
+	signals <- os.Interrupt
+
+	select {
+	case <-rs.DoneCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the signal to end the session")
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if !model.Completed || !model.Aborted || model.CompletedBy != signalReviewer {
+		t.Fatalf("expected the review finished by the signal, got completed %v by %q", model.Completed, model.CompletedBy)
+	}
+	if !strings.Contains(events.String(), "event=aborted signal=interrupt\n") {
+		t.Fatalf("unexpected events:\n%s", events.String())
+	}
+	doc := reviewDocument(model.ReviewModel)
+	if meta := doc["metadata"].(map[string]any); meta["aborted"] != true {
+		t.Fatalf("expected aborted in the metadata, got %v", meta)
+	}
+	if err := validateDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,7 +1,10 @@
 package app
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -31,112 +34,393 @@ type DiffHunk struct {
 	Lines    []DiffLine
 }
 
+// DiffFileStatus describes what a diff does to a file as a whole.
+type DiffFileStatus string
+
+const (
+	DiffModified DiffFileStatus = ""
+	DiffAdded    DiffFileStatus = "added"
+	DiffDeleted  DiffFileStatus = "deleted"
+	DiffRenamed  DiffFileStatus = "renamed"
+)
+
 type DiffFile struct {
 	OldPath string
 	NewPath string
 	Path    string
-	Hunks   []DiffHunk
+	Status  DiffFileStatus
+	// OldMode and NewMode are the file modes from git's extended headers,
+	// e.g. "100644"; either is "" when the diff does not say.
+	OldMode string
+	NewMode string
+	// Binary is set when the diff only says the file changed, as git does
+	// for binary files; it has no hunks.
+	Binary bool
+	// Language is the lexer the file is highlighted with; see File.
+	Language string
+	Hunks    []DiffHunk
+	// Warnings lists problems found while parsing this file leniently.
+	Warnings []DiffParseError
+	// Commit is the number of the PatchCommit the file is changed in when
+	// the diff is a patch series, or 0.
+	Commit int
+
+	// parsedHunks keeps Hunks as they were in the diff once context has
+	// been expanded into them; nil until then.
+	parsedHunks []DiffHunk
+	// source is the file as it is on disk, read once by diffSource.
+	source     fileContent
+	sourceRead bool
+	// section locates the file in a diff too large to keep in memory; its
+	// hunks have no lines until ensureDiffLoaded reads them back.
+	section *diffSection
+	// sum hashes Hunks for the code view key; it is reset whenever they
+	// change. See contentSum.
+	sum uint64
+}
+
+// diffSection is the byte range of one file's part of a diff on disk, and
+// the number of lines it adds and removes.
+type diffSection struct {
+	path           string
+	offset         int64
+	size           int64
+	added, removed int
 }
 
+// DiffParseError describes malformed diff input. Line is the 1-based line in
+// the diff where the problem was found.
+type DiffParseError struct {
+	Line   int
+	Reason string
+	Text   string
+}
+
+func (e *DiffParseError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("diff line %d: %s", e.Line, e.Reason)
+	}
+	return fmt.Sprintf("diff line %d: %s: %q", e.Line, e.Reason, e.Text)
+}
+
+// diffParseMode selects how parseUnifiedDiff treats malformed input.
+type diffParseMode int
+
+const (
+	// diffLenient records problems as warnings on the affected file and
+	// carries on with a best-effort interpretation.
+	diffLenient diffParseMode = iota
+	// diffStrict fails on the first problem with a *DiffParseError.
+	diffStrict
+)
+
 var hunkHeaderRE = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
 
-func parseUnifiedDiff(input string) ([]DiffFile, error) {
-	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+// parseUnifiedDiff parses unified diff input. Hunk bodies are bounded by the
+// line counts in their headers, so deleted or added lines that happen to
+// look like file headers are still read as hunk content.
+func parseUnifiedDiff(input string, mode diffParseMode) ([]DiffFile, error) {
+	return parseDiffStream(strings.NewReader(input), mode, "")
+}
+
+// parseUnifiedDiffFile parses the diff at path as a stream, for diffs too
+// large to hold in memory. Every line is checked, but only the hunk headers
+// are kept; each file records its section of the diff for ensureDiffLoaded.
+func parseUnifiedDiffFile(path string, mode diffParseMode) ([]DiffFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseDiffStream(f, mode, path)
+}
+
+// ensureDiffLoaded reads back the hunk lines of a file parsed by
+// parseUnifiedDiffFile. It does nothing for files that have them.
+func ensureDiffLoaded(file *DiffFile) error {
+	if file == nil || file.section == nil {
+		return nil
+	}
+	sec := file.section
+	f, err := os.Open(sec.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	parsed, err := parseDiffStream(io.NewSectionReader(f, sec.offset, sec.size), diffLenient, "")
+	if err != nil {
+		return err
+	}
+	if len(parsed) != 1 || len(parsed[0].Hunks) != len(file.Hunks) {
+		return fmt.Errorf("%s: changed since it was read", sec.path)
+	}
+	for i := range file.Hunks {
+		file.Hunks[i].Lines = parsed[0].Hunks[i].Lines
+	}
+	file.section, file.sum = nil, 0
+	return nil
+}
+
+// parseDiffStream parses a unified diff read from r. With a path, hunk
+// lines are dropped once each file is parsed and the file's section of
+// path recorded instead.
+func parseDiffStream(r io.Reader, mode diffParseMode, path string) ([]DiffFile, error) {
 	var files []DiffFile
 	var curFile *DiffFile
+	// pos is the offset of the line being parsed, and fileStart that of
+	// the line curFile began at.
+	var pos, fileStart int64
 	var curHunk *DiffHunk
-	var oldLine, newLine int
+	// lastHunk is the most recent hunk of curFile, kept after curHunk is
+	// complete so overlong hunks can be diagnosed.
+	var lastHunk *DiffHunk
+	var oldLine, newLine, oldLeft, newLeft, hunkLine int
 
-	flushFile := func() {
+	report := func(lineNo int, reason, text string) error {
+		perr := DiffParseError{Line: lineNo, Reason: reason, Text: text}
+		if mode == diffStrict {
+			return &perr
+		}
 		if curFile == nil {
-			return
+			curFile, fileStart = &DiffFile{}, pos
+		}
+		curFile.Warnings = append(curFile.Warnings, perr)
+		return nil
+	}
+	closeHunk := func() error {
+		if curHunk != nil && (oldLeft > 0 || newLeft > 0) {
+			reason := fmt.Sprintf("hunk ends %d old and %d new lines short of its header", oldLeft, newLeft)
+			if err := report(hunkLine, reason, hunkHeader(*curHunk)); err != nil {
+				return err
+			}
 		}
-		files = append(files, *curFile)
-		curFile = nil
 		curHunk = nil
+		return nil
+	}
+	flushFile := func() error {
+		if err := closeHunk(); err != nil {
+			return err
+		}
+		if curFile != nil {
+			if path != "" && len(curFile.Hunks) > 0 {
+				added, removed := diffLineStats(curFile)
+				curFile.section = &diffSection{path: path, offset: fileStart, size: pos - fileStart, added: added, removed: removed}
+				for i := range curFile.Hunks {
+					curFile.Hunks[i].Lines = nil
+				}
+			}
+			files = append(files, *curFile)
+		}
+		curFile = nil
+		lastHunk = nil
+		return nil
+	}
+	appendLine := func(h *DiffHunk, kind DiffLineKind, text string) {
+		dl := DiffLine{Kind: kind, Text: text}
+		if kind != DiffAdd {
+			dl.OldLine = oldLine
+			oldLine++
+			oldLeft--
+		}
+		if kind != DiffDel {
+			dl.NewLine = newLine
+			newLine++
+			newLeft--
+		}
+		h.Lines = append(h.Lines, dl)
 	}
 
-	for _, raw := range lines {
-		if strings.HasPrefix(raw, "diff --git ") {
-			flushFile()
-			curFile = &DiffFile{}
+	br := bufio.NewReaderSize(r, 64<<10)
+	var next int64
+	for lineNo := 1; ; lineNo++ {
+		raw, err := br.ReadString('\n')
+		if raw == "" {
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		pos, next = next, next+int64(len(raw))
+		if line, ok := strings.CutSuffix(raw, "\n"); ok {
+			raw = strings.TrimSuffix(line, "\r")
+		}
+		if strings.HasPrefix(raw, "\\ No newline at end of file") {
+			continue
+		}
+		if curHunk != nil && !strings.HasPrefix(raw, "@@ ") && !strings.HasPrefix(raw, "diff --git ") {
+			if raw == "" {
+				// Some tools strip the single space from empty context lines.
+				appendLine(curHunk, DiffContext, "")
+			} else if kind, ok := diffLineKind(raw[0]); ok {
+				appendLine(curHunk, kind, raw[1:])
+			} else {
+				if err := report(lineNo, "unexpected line in hunk", raw); err != nil {
+					return nil, err
+				}
+				appendLine(curHunk, DiffContext, raw)
+			}
+			if oldLeft <= 0 && newLeft <= 0 {
+				curHunk = nil
+			}
+			continue
+		}
+		if after, ok := strings.CutPrefix(raw, "diff --git "); ok {
+			if err := flushFile(); err != nil {
+				return nil, err
+			}
+			curFile, fileStart = &DiffFile{}, pos
+			// Paths from the git header are used when the diff has no
+			// ---/+++ lines, e.g. for deleted or added empty files.
+			if oldPath, newPath, ok := splitGitHeaderPaths(after); ok {
+				curFile.OldPath, curFile.NewPath = oldPath, newPath
+				curFile.Path = pickDiffPath(oldPath, newPath)
+			}
 			continue
 		}
+		if curFile != nil && len(curFile.Hunks) == 0 {
+			if after, ok := strings.CutPrefix(raw, "deleted file mode "); ok {
+				curFile.Status = DiffDeleted
+				curFile.OldMode = after
+				continue
+			}
+			if after, ok := strings.CutPrefix(raw, "new file mode "); ok {
+				curFile.Status = DiffAdded
+				curFile.NewMode = after
+				continue
+			}
+			if after, ok := strings.CutPrefix(raw, "old mode "); ok {
+				curFile.OldMode = after
+				continue
+			}
+			if after, ok := strings.CutPrefix(raw, "new mode "); ok {
+				curFile.NewMode = after
+				continue
+			}
+			if after, ok := strings.CutPrefix(raw, "rename from "); ok {
+				curFile.Status = DiffRenamed
+				curFile.OldPath = filepath.ToSlash(after)
+				continue
+			}
+			if after, ok := strings.CutPrefix(raw, "rename to "); ok {
+				curFile.Status = DiffRenamed
+				curFile.NewPath = filepath.ToSlash(after)
+				curFile.Path = curFile.NewPath
+				continue
+			}
+			if strings.HasPrefix(raw, "Binary files ") || raw == "GIT binary patch" {
+				curFile.Binary = true
+				continue
+			}
+		}
 		if after, ok := strings.CutPrefix(raw, "--- "); ok {
-			path := strings.TrimSpace(after)
+			if curFile != nil && len(curFile.Hunks) > 0 {
+				if err := flushFile(); err != nil {
+					return nil, err
+				}
+			}
 			if curFile == nil {
-				curFile = &DiffFile{}
+				curFile, fileStart = &DiffFile{}, pos
+			}
+			curFile.OldPath = normalizeDiffPath(strings.TrimSpace(after))
+			if curFile.OldPath == "" {
+				curFile.Status = DiffAdded
 			}
-			curFile.OldPath = normalizeDiffPath(path)
 			continue
 		}
 		if after, ok := strings.CutPrefix(raw, "+++ "); ok {
-			path := strings.TrimSpace(after)
 			if curFile == nil {
-				curFile = &DiffFile{}
+				curFile, fileStart = &DiffFile{}, pos
 			}
-			curFile.NewPath = normalizeDiffPath(path)
+			curFile.NewPath = normalizeDiffPath(strings.TrimSpace(after))
 			curFile.Path = pickDiffPath(curFile.OldPath, curFile.NewPath)
+			if curFile.NewPath == "" {
+				curFile.Status = DiffDeleted
+			}
 			continue
 		}
 		if strings.HasPrefix(raw, "@@ ") {
-			if curFile == nil {
-				curFile = &DiffFile{}
+			if err := closeHunk(); err != nil {
+				return nil, err
+			}
+			if curFile == nil || curFile.Path == "" {
+				if err := report(lineNo, "hunk without file header", raw); err != nil {
+					return nil, err
+				}
 			}
 			m := hunkHeaderRE.FindStringSubmatch(raw)
 			if m == nil {
-				return nil, fmt.Errorf("invalid hunk header: %s", raw)
+				if err := report(lineNo, "invalid hunk header", raw); err != nil {
+					return nil, err
+				}
+				lastHunk = nil
+				continue
+			}
+			h := DiffHunk{
+				OldStart: mustAtoi(m[1]),
+				OldCount: parseOptionalCount(m[2]),
+				NewStart: mustAtoi(m[3]),
+				NewCount: parseOptionalCount(m[4]),
 			}
-			oldStart := mustAtoi(m[1])
-			oldCount := parseOptionalCount(m[2])
-			newStart := mustAtoi(m[3])
-			newCount := parseOptionalCount(m[4])
-			h := DiffHunk{OldStart: oldStart, OldCount: oldCount, NewStart: newStart, NewCount: newCount}
 			curFile.Hunks = append(curFile.Hunks, h)
 			curHunk = &curFile.Hunks[len(curFile.Hunks)-1]
-			oldLine = oldStart
-			newLine = newStart
-			continue
-		}
-		if curHunk == nil {
-			continue
-		}
-		if strings.HasPrefix(raw, "\\ No newline at end of file") {
+			lastHunk = curHunk
+			oldLine, newLine = h.OldStart, h.NewStart
+			oldLeft, newLeft = h.OldCount, h.NewCount
+			hunkLine = lineNo
+			if oldLeft <= 0 && newLeft <= 0 {
+				curHunk = nil
+			}
 			continue
 		}
-		if raw == "" {
-			// Empty context line in a diff is represented as a single space; if not,
-			// treat as context without prefix.
-			curHunk.Lines = append(curHunk.Lines, DiffLine{Kind: DiffContext, OldLine: oldLine, NewLine: newLine, Text: ""})
-			oldLine++
-			newLine++
+		// "-- " is the signature separator at the end of git format-patch
+		// output, not hunk content.
+		if lastHunk == nil || raw == "" || raw == "-- " {
 			continue
 		}
-		prefix := raw[0]
-		text := raw[1:]
-		switch prefix {
-		case ' ':
-			curHunk.Lines = append(curHunk.Lines, DiffLine{Kind: DiffContext, OldLine: oldLine, NewLine: newLine, Text: text})
-			oldLine++
-			newLine++
-		case '+':
-			curHunk.Lines = append(curHunk.Lines, DiffLine{Kind: DiffAdd, OldLine: 0, NewLine: newLine, Text: text})
-			newLine++
-		case '-':
-			curHunk.Lines = append(curHunk.Lines, DiffLine{Kind: DiffDel, OldLine: oldLine, NewLine: 0, Text: text})
-			oldLine++
-		default:
-			// treat as context if malformed
-			curHunk.Lines = append(curHunk.Lines, DiffLine{Kind: DiffContext, OldLine: oldLine, NewLine: newLine, Text: raw})
-			oldLine++
-			newLine++
+		if kind, ok := diffLineKind(raw[0]); ok {
+			// Body lines past the end of the hunk: the header undercounts.
+			if err := report(lineNo, "hunk is longer than its header", raw); err != nil {
+				return nil, err
+			}
+			appendLine(lastHunk, kind, raw[1:])
 		}
 	}
 
-	flushFile()
+	pos = next
+	if err := flushFile(); err != nil {
+		return nil, err
+	}
 	return files, nil
 }
 
+// splitGitHeaderPaths extracts the paths from the rest of a
+// "diff --git a/<old> b/<new>" line. Quoted paths are not handled.
+func splitGitHeaderPaths(rest string) (string, string, bool) {
+	if !strings.HasPrefix(rest, "a/") {
+		return "", "", false
+	}
+	i := strings.LastIndex(rest, " b/")
+	if i < 0 {
+		return "", "", false
+	}
+	return normalizeDiffPath(rest[:i]), normalizeDiffPath(rest[i+1:]), true
+}
+
+// diffLineKind maps a hunk body prefix to its line kind.
+func diffLineKind(prefix byte) (DiffLineKind, bool) {
+	switch prefix {
+	case ' ':
+		return DiffContext, true
+	case '+':
+		return DiffAdd, true
+	case '-':
+		return DiffDel, true
+	}
+	return "", false
+}
+
 func normalizeDiffPath(path string) string {
 	if path == "/dev/null" {
 		return ""
@@ -169,3 +453,24 @@ func parseOptionalCount(s string) int {
 	}
 	return mustAtoi(s)
 }
+
+// isLargeDiff reports whether the diff at path is over largeFileThreshold
+// and so parsed as a stream. Patch series are always read whole, since
+// their commits are split apart in memory.
+func isLargeDiff(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.Size() <= largeFileThreshold {
+		return false, err
+	}
+	head := make([]byte, 4096)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return !isPatchSeries(string(head[:n])), nil
+}
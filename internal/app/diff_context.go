@@ -0,0 +1,177 @@
+package app
+
+import (
+	"os"
+	"slices"
+)
+
+// diffExpandLines is how many unchanged lines one click on a hunk's expand
+// button reveals.
+const diffExpandLines = 20
+
+// diffSource returns the lines of file as it is on disk, for showing the
+// unchanged code around its hunks, or nil when that is not possible: the
+// file is deleted, missing, too large to hold in memory, or does not match
+// the new side of the diff. The result is read once and kept on file.
+func diffSource(file *DiffFile) []string {
+	if file.sourceRead {
+		return file.source.lines
+	}
+	file.sourceRead = true
+	if file.Status == DiffDeleted || file.Binary {
+		return nil
+	}
+	// The files of a patch series are reviewed under another path.
+	path := pickDiffPath(file.OldPath, file.NewPath)
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() || info.Size() > largeFileThreshold {
+		return nil
+	}
+	content, err := readFileContent(path)
+	if err != nil || !matchesNewSide(file, content.lines) {
+		return nil
+	}
+	file.source = content
+	return content.lines
+}
+
+// matchesNewSide reports whether lines hold every line on the new side of
+// file's hunks at its line number.
+func matchesNewSide(file *DiffFile, lines []string) bool {
+	for _, h := range file.Hunks {
+		for _, dl := range h.Lines {
+			if dl.Kind != DiffDel && (dl.NewLine > len(lines) || lines[dl.NewLine-1] != dl.Text) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hunkBounds returns the first line of h on each side. A side with no
+// lines starts after the line its header names.
+func hunkBounds(h DiffHunk) (oldTop, newTop int) {
+	oldTop, newTop = h.OldStart, h.NewStart
+	if h.OldCount == 0 {
+		oldTop++
+	}
+	if h.NewCount == 0 {
+		newTop++
+	}
+	return oldTop, newTop
+}
+
+// hunkGapAbove returns the new-side lines between hunk i and the hunk
+// before it, or the start of the file.
+func hunkGapAbove(file *DiffFile, i int) (from, to int) {
+	_, newTop := hunkBounds(file.Hunks[i])
+	from = 1
+	if i > 0 {
+		prev := file.Hunks[i-1]
+		_, prevTop := hunkBounds(prev)
+		from = prevTop + prev.NewCount
+	}
+	return from, newTop - 1
+}
+
+// hunkGapBelow returns the new-side lines between the last hunk and the
+// end of source.
+func hunkGapBelow(file *DiffFile, source []string) (from, to int) {
+	h := file.Hunks[len(file.Hunks)-1]
+	_, newTop := hunkBounds(h)
+	return newTop + h.NewCount, len(source)
+}
+
+// expandHunk reveals up to diffExpandLines unchanged lines above hunk i of
+// file, or below it with down, which only applies to the last hunk. The
+// lines become context lines of the hunk, so they can be selected and
+// commented on like the rest of the diff; a hunk that reaches the one
+// before it is merged into it. It reports whether anything was revealed.
+func expandHunk(view *ReviewView, file *DiffFile, i int, down bool) bool {
+	source := diffSource(file)
+	if source == nil || i < 0 || i >= len(file.Hunks) || (down && i != len(file.Hunks)-1) {
+		return false
+	}
+	if file.parsedHunks == nil {
+		file.parsedHunks = slices.Clone(file.Hunks)
+		for j := range file.parsedHunks {
+			file.parsedHunks[j].Lines = slices.Clone(file.Hunks[j].Lines)
+		}
+	}
+	file.sum = 0
+	h := &file.Hunks[i]
+	oldTop, newTop := hunkBounds(*h)
+	if down {
+		from, to := hunkGapBelow(file, source)
+		to = min(to, from+diffExpandLines-1)
+		if from > to {
+			return false
+		}
+		offset := (newTop + h.NewCount) - (oldTop + h.OldCount)
+		for n := from; n <= to; n++ {
+			h.Lines = append(h.Lines, DiffLine{Kind: DiffContext, OldLine: n - offset, NewLine: n, Text: source[n-1]})
+		}
+		h.OldStart, h.NewStart = oldTop, newTop
+		h.OldCount += to - from + 1
+		h.NewCount += to - from + 1
+		return true
+	}
+
+	from, to := hunkGapAbove(file, i)
+	reachesPrev := from >= to-diffExpandLines+1
+	from = max(from, to-diffExpandLines+1)
+	if from > to {
+		// Nothing left between the hunks; join them.
+		reachesPrev = i > 0
+	} else {
+		offset := newTop - oldTop
+		lines := make([]DiffLine, 0, to-from+1+len(h.Lines))
+		for n := from; n <= to; n++ {
+			lines = append(lines, DiffLine{Kind: DiffContext, OldLine: n - offset, NewLine: n, Text: source[n-1]})
+		}
+		h.Lines = append(lines, h.Lines...)
+		h.OldStart, h.NewStart = from-offset, from
+		h.OldCount += to - from + 1
+		h.NewCount += to - from + 1
+	}
+	if reachesPrev && i > 0 {
+		prev := &file.Hunks[i-1]
+		prevOld, prevNew := hunkBounds(*prev)
+		prev.Lines = append(prev.Lines, h.Lines...)
+		prev.OldStart, prev.NewStart = prevOld, prevNew
+		prev.OldCount += h.OldCount
+		prev.NewCount += h.NewCount
+		file.Hunks = slices.Delete(file.Hunks, i, i+1)
+		shiftRenderedHunks(view, file.Path, i)
+	}
+	return from <= to || reachesPrev
+}
+
+// shiftRenderedHunks updates the built hunks of path after hunk i was
+// merged into the one before it.
+func shiftRenderedHunks(view *ReviewView, path string, i int) {
+	rendered := view.RenderedHunks[path]
+	if rendered == nil {
+		return
+	}
+	shifted := make(map[int]bool, len(rendered))
+	for k, v := range rendered {
+		switch {
+		case k < i:
+			shifted[k] = shifted[k] || v
+		case k == i:
+			shifted[i-1] = shifted[i-1] || v
+		default:
+			shifted[k-1] = v
+		}
+	}
+	view.RenderedHunks[path] = shifted
+}
+
+// hunksAsParsed returns the hunks of file as they were in the diff, before
+// any context was expanded.
+func hunksAsParsed(file DiffFile) []DiffHunk {
+	if file.parsedHunks != nil {
+		return file.parsedHunks
+	}
+	return file.Hunks
+}
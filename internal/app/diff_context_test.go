@@ -0,0 +1,128 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// diffContextModel writes a 60-line file to a temp dir and returns a diff
+// session with two hunks changing lines 10 and 40.
+func diffContextModel(t *testing.T) *ReviewView {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	var content strings.Builder
+	for i := 1; i <= 60; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	if err := os.WriteFile("c.go", []byte(content.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	diff := "diff --git a/c.go b/c.go\n--- a/c.go\n+++ b/c.go\n" +
+		"@@ -9,3 +9,3 @@\n line 9\n-old 10\n+line 10\n line 11\n" +
+		"@@ -39,3 +39,3 @@\n line 39\n-old 40\n+line 40\n line 41\n"
+	files, err := parseUnifiedDiff(diff, diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{ReviewModel: &ReviewModel{Mode: ModeDiff, DiffFiles: files}, SelectedPath: "c.go"}
+	updateView(model)
+	return model
+}
+
+func TestExpandHunk(t *testing.T) {
+	model := diffContextModel(t)
+	file := model.lookupDiffFile("c.go")
+	if !model.ViewDiff.Hunks[0].ExpandUp || model.ViewDiff.Hunks[0].ExpandDown || !model.ViewDiff.Hunks[1].ExpandDown {
+		t.Fatalf("unexpected expand buttons: %+v", model.ViewDiff.Hunks)
+	}
+
+	if !expandHunk(model, file, 0, false) {
+		t.Fatal("expected lines above the first hunk")
+	}
+	if h := file.Hunks[0]; h.OldStart != 1 || h.NewStart != 1 || h.NewCount != 11 || h.Lines[0].Text != "line 1" {
+		t.Fatalf("unexpected first hunk %+v", h)
+	}
+	if expandHunk(model, file, 0, false) {
+		t.Fatal("expected nothing left above the first hunk")
+	}
+
+	if !expandHunk(model, file, 1, true) {
+		t.Fatal("expected lines below the last hunk")
+	}
+	if h := file.Hunks[1]; h.NewCount != 3+19 || h.Lines[len(h.Lines)-1].NewLine != 60 || h.Lines[len(h.Lines)-1].OldLine != 60 {
+		t.Fatalf("unexpected last hunk %+v", h)
+	}
+	if expandHunk(model, file, 0, true) {
+		t.Fatal("only the last hunk expands downwards")
+	}
+
+	// Lines 12-38 lie between the hunks: the first click shows 19-38, the
+	// second closes the gap and merges the hunks.
+	if !expandHunk(model, file, 1, false) || len(file.Hunks) != 2 {
+		t.Fatalf("expected a partial expansion, got %d hunks", len(file.Hunks))
+	}
+	if !expandHunk(model, file, 1, false) || len(file.Hunks) != 1 {
+		t.Fatalf("expected the hunks to merge, got %d hunks", len(file.Hunks))
+	}
+	h := file.Hunks[0]
+	if h.OldStart != 1 || h.NewStart != 1 || h.OldCount != 60 || h.NewCount != 60 || len(h.Lines) != 62 {
+		t.Fatalf("unexpected merged hunk %+v", h)
+	}
+	for i, dl := range h.Lines {
+		if dl.Kind != DiffDel && dl.Text != fmt.Sprintf("line %d", dl.NewLine) {
+			t.Fatalf("line %d: %+v", i, dl)
+		}
+	}
+	updateView(model)
+	if len(model.ViewDiff.Hunks) != 1 || model.ViewDiff.Hunks[0].ExpandUp || model.ViewDiff.Hunks[0].ExpandDown {
+		t.Fatalf("expected one hunk without expand buttons, got %+v", model.ViewDiff.Hunks)
+	}
+
+	model.Comments = []Comment{{ID: 1, Path: "c.go", StartLine: 25, EndLine: 30, Text: "expanded"}}
+	if _, invalid := validateComments(model.ReviewModel); len(invalid) != 0 {
+		t.Fatalf("comment on expanded lines rejected: %+v", invalid)
+	}
+}
+
+// TestExpandHunkStaleFile verifies that context is only offered when the
+// file on disk matches the new side of the diff.
+func TestExpandHunkStaleFile(t *testing.T) {
+	model := diffContextModel(t)
+	if err := os.WriteFile("c.go", []byte("line 1\nsomething else\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file := model.lookupDiffFile("c.go")
+	file.source, file.sourceRead = fileContent{}, false
+	if diffSource(file) != nil || expandHunk(model, file, 0, false) {
+		t.Fatal("expected no context from a file that does not match the diff")
+	}
+	updateView(model)
+	if model.ViewDiff.Hunks[0].ExpandUp {
+		t.Fatal("expected no expand button")
+	}
+}
+
+// TestExpandHunkSurvivesUpdate verifies that resending the same diff keeps
+// the expanded context and does not flag the file as updated.
+func TestExpandHunkSurvivesUpdate(t *testing.T) {
+	model := diffContextModel(t)
+	model.Viewed = map[string]bool{"c.go": true}
+	file := model.lookupDiffFile("c.go")
+	if !expandHunk(model, file, 0, false) {
+		t.Fatal("expected lines above the first hunk")
+	}
+	diff := "diff --git a/c.go b/c.go\n--- a/c.go\n+++ b/c.go\n" +
+		"@@ -9,3 +9,3 @@\n line 9\n-old 10\n+line 10\n line 11\n" +
+		"@@ -39,3 +39,3 @@\n line 39\n-old 40\n+line 40\n line 41\n"
+	if _, err := applyDiffUpdate(model.ReviewModel, diff, false); err != nil {
+		t.Fatal(err)
+	}
+	if model.UpdatedFiles["c.go"] || !model.Viewed["c.go"] {
+		t.Fatal("expected the unchanged file to stay viewed and not updated")
+	}
+	if h := model.lookupDiffFile("c.go").Hunks[0]; h.NewStart != 1 {
+		t.Fatalf("expected the expansion to survive, got %+v", h)
+	}
+}
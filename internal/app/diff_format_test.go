@@ -84,10 +84,10 @@ func TestDiffOldLineExists(t *testing.T) {
 	}
 }
 
-// TestReviewModelHasDiffFormat verifies that ReviewModel carries a DiffFormat
+// TestReviewViewHasDiffFormat verifies that ReviewView carries a DiffFormat
 // field and that it can be set to the known constants.
-func TestReviewModelHasDiffFormat(t *testing.T) {
-	m := ReviewModel{}
+func TestReviewViewHasDiffFormat(t *testing.T) {
+	m := ReviewView{}
 
 	// Zero value — DiffFormat field must exist (compiler enforces this).
 	if m.DiffFormat != DiffFormat("") {
@@ -10,24 +10,26 @@ import (
 //
 // Scenario: Toggle between unified and side-by-side diff view (unified path)
 func TestUpdateDiffViewUnifiedFormat(t *testing.T) {
-	model := &ReviewModel{
-		Mode:         ModeDiff,
-		DiffFormat:   DiffFormatUnified,
-		SelectedPath: "a.go",
-		DiffFiles: []DiffFile{{
-			Path: "a.go",
-			Hunks: []DiffHunk{{
-				OldStart: 1,
-				OldCount: 1,
-				NewStart: 1,
-				NewCount: 1,
-				Lines: []DiffLine{
-					{Kind: DiffContext, OldLine: 1, NewLine: 1, Text: "package main"},
-				},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode: ModeDiff,
+			DiffFiles: []DiffFile{{
+				Path: "a.go",
+				Hunks: []DiffHunk{{
+					OldStart: 1,
+					OldCount: 1,
+					NewStart: 1,
+					NewCount: 1,
+					Lines: []DiffLine{
+						{Kind: DiffContext, OldLine: 1, NewLine: 1, Text: "package main"},
+					},
+				}},
 			}},
-		}},
-		Viewed:               make(map[string]bool),
-		Ranges:               map[string][]LineRange{},
+			Viewed: make(map[string]bool),
+			Ranges: map[string][]LineRange{},
+		},
+		DiffFormat:           DiffFormatUnified,
+		SelectedPath:         "a.go",
 		MarkdownRenderByPath: map[string]bool{},
 	}
 
@@ -55,25 +57,27 @@ func TestUpdateDiffViewUnifiedFormat(t *testing.T) {
 //
 // Scenario: Toggle between unified and side-by-side diff view (split path)
 func TestUpdateDiffViewSplitFormat(t *testing.T) {
-	model := &ReviewModel{
-		Mode:         ModeDiff,
-		DiffFormat:   DiffFormatSplit,
-		SelectedPath: "b.go",
-		DiffFiles: []DiffFile{{
-			Path: "b.go",
-			Hunks: []DiffHunk{{
-				OldStart: 3,
-				OldCount: 2,
-				NewStart: 3,
-				NewCount: 2,
-				Lines: []DiffLine{
-					{Kind: DiffDel, OldLine: 3, NewLine: 0, Text: "old line"},
-					{Kind: DiffAdd, OldLine: 0, NewLine: 3, Text: "new line"},
-				},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode: ModeDiff,
+			DiffFiles: []DiffFile{{
+				Path: "b.go",
+				Hunks: []DiffHunk{{
+					OldStart: 3,
+					OldCount: 2,
+					NewStart: 3,
+					NewCount: 2,
+					Lines: []DiffLine{
+						{Kind: DiffDel, OldLine: 3, NewLine: 0, Text: "old line"},
+						{Kind: DiffAdd, OldLine: 0, NewLine: 3, Text: "new line"},
+					},
+				}},
 			}},
-		}},
-		Viewed:               make(map[string]bool),
-		Ranges:               map[string][]LineRange{},
+			Viewed: make(map[string]bool),
+			Ranges: map[string][]LineRange{},
+		},
+		DiffFormat:           DiffFormatSplit,
+		SelectedPath:         "b.go",
 		MarkdownRenderByPath: map[string]bool{},
 	}
 
@@ -106,27 +110,29 @@ func TestUpdateDiffViewSplitFormat(t *testing.T) {
 //
 // Scenario: Toggle between unified and side-by-side diff view (selection cleared)
 func TestToggleDiffFormatClearsSelection(t *testing.T) {
-	model := &ReviewModel{
-		Mode:           ModeDiff,
-		DiffFormat:     DiffFormatUnified,
-		SelectedPath:   "c.go",
-		SelectionStart: 5,
-		SelectionEnd:   10,
-		SelectionSide:  "old",
-		DiffFiles: []DiffFile{{
-			Path: "c.go",
-			Hunks: []DiffHunk{{
-				OldStart: 1,
-				OldCount: 1,
-				NewStart: 1,
-				NewCount: 1,
-				Lines: []DiffLine{
-					{Kind: DiffContext, OldLine: 5, NewLine: 5, Text: "ctx"},
-				},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode: ModeDiff,
+			DiffFiles: []DiffFile{{
+				Path: "c.go",
+				Hunks: []DiffHunk{{
+					OldStart: 1,
+					OldCount: 1,
+					NewStart: 1,
+					NewCount: 1,
+					Lines: []DiffLine{
+						{Kind: DiffContext, OldLine: 5, NewLine: 5, Text: "ctx"},
+					},
+				}},
 			}},
-		}},
-		Viewed:               make(map[string]bool),
-		Ranges:               map[string][]LineRange{},
+			Viewed: make(map[string]bool),
+			Ranges: map[string][]LineRange{},
+		},
+		DiffFormat:           DiffFormatUnified,
+		SelectedPath:         "c.go",
+		SelectionStart:       5,
+		SelectionEnd:         10,
+		SelectionSide:        "old",
 		MarkdownRenderByPath: map[string]bool{},
 	}
 
@@ -160,18 +166,20 @@ func TestToggleDiffFormatClearsSelection(t *testing.T) {
 //
 // Scenario: Diff format toggle hidden in file mode
 func TestHTTPRenderDiffFormatToggleHiddenInFileMode(t *testing.T) {
-	model := &ReviewModel{
-		Files: []File{{
-			Path:      "a.go",
-			PathSlash: "a.go",
-			Lines:     []string{"package main"},
-		}},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{
+				Path:      "a.go",
+				PathSlash: "a.go",
+				Lines:     []string{"package main"},
+			}},
+			Mode:   ModeFile,
+			Viewed: make(map[string]bool),
+			Ranges: map[string][]LineRange{},
+		},
 		SelectedPath:         "a.go",
-		Mode:                 ModeFile,
 		RenderFile:           true,
 		RenderComments:       true,
-		Viewed:               make(map[string]bool),
-		Ranges:               map[string][]LineRange{},
 		MarkdownRenderByPath: map[string]bool{},
 	}
 	model.Tree = buildTree(model.Files, model.SelectedPath, nil, nil)
@@ -189,26 +197,28 @@ func TestHTTPRenderDiffFormatToggleHiddenInFileMode(t *testing.T) {
 //
 // Scenario: Diff format toggle hidden in file mode (inverse: visible in diff mode)
 func TestHTTPRenderDiffFormatToggleVisibleInDiffMode(t *testing.T) {
-	model := &ReviewModel{
-		DiffFiles: []DiffFile{{
-			Path: "a.go",
-			Hunks: []DiffHunk{{
-				OldStart: 1,
-				OldCount: 1,
-				NewStart: 1,
-				NewCount: 1,
-				Lines: []DiffLine{
-					{Kind: DiffContext, OldLine: 1, NewLine: 1, Text: "package main"},
-				},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			DiffFiles: []DiffFile{{
+				Path: "a.go",
+				Hunks: []DiffHunk{{
+					OldStart: 1,
+					OldCount: 1,
+					NewStart: 1,
+					NewCount: 1,
+					Lines: []DiffLine{
+						{Kind: DiffContext, OldLine: 1, NewLine: 1, Text: "package main"},
+					},
+				}},
 			}},
-		}},
+			Mode:   ModeDiff,
+			Viewed: make(map[string]bool),
+			Ranges: map[string][]LineRange{},
+		},
 		SelectedPath:         "a.go",
-		Mode:                 ModeDiff,
 		DiffFormat:           DiffFormatUnified,
 		RenderFile:           true,
 		RenderComments:       true,
-		Viewed:               make(map[string]bool),
-		Ranges:               map[string][]LineRange{},
 		MarkdownRenderByPath: map[string]bool{},
 	}
 	model.Tree = buildTree(diffFilesAsFiles(model.DiffFiles), model.SelectedPath, nil, nil)
@@ -226,27 +236,29 @@ func TestHTTPRenderDiffFormatToggleVisibleInDiffMode(t *testing.T) {
 //
 // Scenario: Side-by-side view pairs old and new lines correctly (template output)
 func TestHTTPRenderSplitDiffBlock(t *testing.T) {
-	model := &ReviewModel{
-		DiffFiles: []DiffFile{{
-			Path: "a.go",
-			Hunks: []DiffHunk{{
-				OldStart: 1,
-				OldCount: 1,
-				NewStart: 1,
-				NewCount: 1,
-				Lines: []DiffLine{
-					{Kind: DiffDel, OldLine: 1, NewLine: 0, Text: "old line"},
-					{Kind: DiffAdd, OldLine: 0, NewLine: 1, Text: "new line"},
-				},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			DiffFiles: []DiffFile{{
+				Path: "a.go",
+				Hunks: []DiffHunk{{
+					OldStart: 1,
+					OldCount: 1,
+					NewStart: 1,
+					NewCount: 1,
+					Lines: []DiffLine{
+						{Kind: DiffDel, OldLine: 1, NewLine: 0, Text: "old line"},
+						{Kind: DiffAdd, OldLine: 0, NewLine: 1, Text: "new line"},
+					},
+				}},
 			}},
-		}},
+			Mode:   ModeDiff,
+			Viewed: make(map[string]bool),
+			Ranges: map[string][]LineRange{},
+		},
 		SelectedPath:         "a.go",
-		Mode:                 ModeDiff,
 		DiffFormat:           DiffFormatSplit,
 		RenderFile:           true,
 		RenderComments:       true,
-		Viewed:               make(map[string]bool),
-		Ranges:               map[string][]LineRange{},
 		MarkdownRenderByPath: map[string]bool{},
 	}
 	model.Tree = buildTree(diffFilesAsFiles(model.DiffFiles), model.SelectedPath, nil, nil)
@@ -269,26 +281,28 @@ func TestHTTPRenderSplitDiffBlock(t *testing.T) {
 //
 // Scenario: Toggle between unified and side-by-side diff view (template unified path)
 func TestHTTPRenderUnifiedDiffNotSplit(t *testing.T) {
-	model := &ReviewModel{
-		DiffFiles: []DiffFile{{
-			Path: "a.go",
-			Hunks: []DiffHunk{{
-				OldStart: 1,
-				OldCount: 1,
-				NewStart: 1,
-				NewCount: 1,
-				Lines: []DiffLine{
-					{Kind: DiffContext, OldLine: 1, NewLine: 1, Text: "package main"},
-				},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			DiffFiles: []DiffFile{{
+				Path: "a.go",
+				Hunks: []DiffHunk{{
+					OldStart: 1,
+					OldCount: 1,
+					NewStart: 1,
+					NewCount: 1,
+					Lines: []DiffLine{
+						{Kind: DiffContext, OldLine: 1, NewLine: 1, Text: "package main"},
+					},
+				}},
 			}},
-		}},
+			Mode:   ModeDiff,
+			Viewed: make(map[string]bool),
+			Ranges: map[string][]LineRange{},
+		},
 		SelectedPath:         "a.go",
-		Mode:                 ModeDiff,
 		DiffFormat:           DiffFormatUnified,
 		RenderFile:           true,
 		RenderComments:       true,
-		Viewed:               make(map[string]bool),
-		Ranges:               map[string][]LineRange{},
 		MarkdownRenderByPath: map[string]bool{},
 	}
 	model.Tree = buildTree(diffFilesAsFiles(model.DiffFiles), model.SelectedPath, nil, nil)
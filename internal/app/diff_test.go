@@ -1,6 +1,12 @@
 package app
 
-import "testing"
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
 
 func TestParseUnifiedDiff(t *testing.T) {
 	input := "diff --git a/hello.txt b/hello.txt\n" +
@@ -14,7 +20,7 @@ func TestParseUnifiedDiff(t *testing.T) {
 		" line3\n" +
 		"+line4\n"
 
-	files, err := parseUnifiedDiff(input)
+	files, err := parseUnifiedDiff(input, diffLenient)
 	if err != nil {
 		t.Fatalf("parse error: %v", err)
 	}
@@ -32,8 +38,8 @@ func TestParseUnifiedDiff(t *testing.T) {
 	if h.OldStart != 1 || h.NewStart != 1 {
 		t.Fatalf("unexpected hunk starts: old %d new %d", h.OldStart, h.NewStart)
 	}
-	if len(h.Lines) != 6 {
-		t.Fatalf("expected 6 lines, got %d", len(h.Lines))
+	if len(h.Lines) != 5 {
+		t.Fatalf("expected 5 lines, got %d", len(h.Lines))
 	}
 	if h.Lines[1].Kind != DiffDel || h.Lines[1].OldLine != 2 || h.Lines[1].NewLine != 0 {
 		t.Fatalf("unexpected delete line mapping: %+v", h.Lines[1])
@@ -51,7 +57,7 @@ func TestParseUnifiedDiffFileAdd(t *testing.T) {
 		"+hello\n" +
 		"+world\n"
 
-	files, err := parseUnifiedDiff(input)
+	files, err := parseUnifiedDiff(input, diffLenient)
 	if err != nil {
 		t.Fatalf("parse error: %v", err)
 	}
@@ -62,3 +68,266 @@ func TestParseUnifiedDiffFileAdd(t *testing.T) {
 		t.Fatalf("expected new.txt, got %q", files[0].Path)
 	}
 }
+
+// TestParseUnifiedDiffHeaderLikeBody verifies that body lines resembling
+// file headers are read as hunk content while the hunk is still open.
+func TestParseUnifiedDiffHeaderLikeBody(t *testing.T) {
+	input := "--- a/q.sql\n" +
+		"+++ b/q.sql\n" +
+		"@@ -1,2 +1,2 @@\n" +
+		"--- old comment\n" +
+		"+++ new comment\n" +
+		" select 1;\n"
+
+	files, err := parseUnifiedDiff(input, diffStrict)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	lines := files[0].Hunks[0].Lines
+	if len(lines) != 3 || lines[0].Kind != DiffDel || lines[0].Text != "-- old comment" ||
+		lines[1].Kind != DiffAdd || lines[1].Text != "++ new comment" {
+		t.Fatalf("unexpected hunk lines: %+v", lines)
+	}
+}
+
+// TestParseUnifiedDiffStrictErrors verifies that strict mode reports the
+// offending line number and reason.
+func TestParseUnifiedDiffStrictErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		reason string
+	}{
+		{"bad header", "--- a/x\n+++ b/x\n@@ -1 +1 @ broken\n", 3, "invalid hunk header"},
+		{"bad prefix", "--- a/x\n+++ b/x\n@@ -1,2 +1,2 @@\n a\n?b\n", 5, "unexpected line in hunk"},
+		{"short hunk", "--- a/x\n+++ b/x\n@@ -1,3 +1,3 @@\n a\n", 3, "hunk ends 2 old and 2 new lines short of its header"},
+		{"long hunk", "--- a/x\n+++ b/x\n@@ -1 +1 @@\n a\n+b\n", 5, "hunk is longer than its header"},
+		{"no file", "@@ -1 +1 @@\n a\n", 1, "hunk without file header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseUnifiedDiff(tt.input, diffStrict)
+			perr, ok := err.(*DiffParseError)
+			if !ok {
+				t.Fatalf("expected *DiffParseError, got %v", err)
+			}
+			if perr.Line != tt.line || perr.Reason != tt.reason {
+				t.Fatalf("got line %d reason %q, want line %d reason %q", perr.Line, perr.Reason, tt.line, tt.reason)
+			}
+		})
+	}
+}
+
+// TestParseUnifiedDiffLenientWarnings verifies that lenient mode keeps going
+// and records warnings on the affected file only.
+func TestParseUnifiedDiffLenientWarnings(t *testing.T) {
+	input := "diff --git a/x b/x\n" +
+		"--- a/x\n" +
+		"+++ b/x\n" +
+		"@@ -1,2 +1,2 @@\n" +
+		" a\n" +
+		"?b\n" +
+		"diff --git a/y b/y\n" +
+		"--- a/y\n" +
+		"+++ b/y\n" +
+		"@@ -1 +1 @@\n" +
+		"-c\n" +
+		"+d\n" +
+		"-- \n" +
+		"2.43.0\n"
+
+	files, err := parseUnifiedDiff(input, diffLenient)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	if w := files[0].Warnings; len(w) != 1 || w[0].Line != 6 || w[0].Text != "?b" {
+		t.Fatalf("unexpected warnings for x: %+v", w)
+	}
+	if len(files[0].Hunks[0].Lines) != 2 {
+		t.Fatalf("expected malformed line kept as context, got %+v", files[0].Hunks[0].Lines)
+	}
+	if w := files[1].Warnings; len(w) != 0 {
+		t.Fatalf("expected no warnings for y, got %+v", w)
+	}
+}
+
+// TestParseUnifiedDiffDeletedFile verifies that deletions keep the old path
+// and are marked deleted, including empty files that have no hunks.
+func TestParseUnifiedDiffDeletedFile(t *testing.T) {
+	input := "diff --git a/gone.txt b/gone.txt\n" +
+		"deleted file mode 100644\n" +
+		"index 111..000\n" +
+		"--- a/gone.txt\n" +
+		"+++ /dev/null\n" +
+		"@@ -1,2 +0,0 @@\n" +
+		"-one\n" +
+		"-two\n" +
+		"diff --git a/empty.txt b/empty.txt\n" +
+		"deleted file mode 100644\n" +
+		"index e69de29..0000000\n"
+
+	files, err := parseUnifiedDiff(input, diffStrict)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	gone := files[0]
+	if gone.Path != "gone.txt" || gone.Status != DiffDeleted {
+		t.Fatalf("unexpected deleted file: path %q status %q", gone.Path, gone.Status)
+	}
+	if lines := gone.Hunks[0].Lines; len(lines) != 2 || lines[1].OldLine != 2 || lines[1].Kind != DiffDel {
+		t.Fatalf("unexpected removed lines: %+v", lines)
+	}
+	if files[1].Path != "empty.txt" || files[1].Status != DiffDeleted || len(files[1].Hunks) != 0 {
+		t.Fatalf("unexpected empty deleted file: %+v", files[1])
+	}
+}
+
+func TestParseUnifiedDiffBinary(t *testing.T) {
+	input := "diff --git a/logo.png b/logo.png\n" +
+		"new file mode 100644\n" +
+		"index 0000000..1234567\n" +
+		"Binary files /dev/null and b/logo.png differ\n" +
+		"diff --git a/icon.ico b/icon.ico\n" +
+		"index 1234567..89abcde 100644\n" +
+		"GIT binary patch\n" +
+		"literal 5\n" +
+		"McmZQzWMO0g00Ra5761SM\n" +
+		"\n" +
+		"literal 3\n" +
+		"KcmZQzWB>pF5C8!H\n" +
+		"\n" +
+		"diff --git a/a.go b/a.go\n" +
+		"--- a/a.go\n" +
+		"+++ b/a.go\n" +
+		"@@ -1 +1 @@\n" +
+		"-old\n" +
+		"+new\n"
+	files, err := parseUnifiedDiff(input, diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(files))
+	}
+	if f := files[0]; f.Path != "logo.png" || !f.Binary || f.Status != DiffAdded || len(f.Hunks) != 0 {
+		t.Fatalf("unexpected binary file %+v", f)
+	}
+	if f := files[1]; f.Path != "icon.ico" || !f.Binary || len(f.Hunks) != 0 {
+		t.Fatalf("unexpected binary patch %+v", f)
+	}
+	if files[2].Binary || len(files[2].Hunks) != 1 {
+		t.Fatalf("unexpected text file %+v", files[2])
+	}
+}
+
+func TestParseUnifiedDiffRenameAndMode(t *testing.T) {
+	input := "diff --git a/old/name.go b/new/name.go\n" +
+		"similarity index 100%\n" +
+		"rename from old/name.go\n" +
+		"rename to new/name.go\n" +
+		"diff --git a/run.sh b/run.sh\n" +
+		"old mode 100644\n" +
+		"new mode 100755\n" +
+		"diff --git a/tool.sh b/tool.sh\n" +
+		"new file mode 100755\n" +
+		"--- /dev/null\n" +
+		"+++ b/tool.sh\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+echo hi\n"
+	files, err := parseUnifiedDiff(input, diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(files))
+	}
+	if f := files[0]; f.Status != DiffRenamed || f.OldPath != "old/name.go" || f.Path != "new/name.go" {
+		t.Fatalf("unexpected rename %+v", f)
+	}
+	if f := files[1]; f.Status != DiffModified || f.OldMode != "100644" || f.NewMode != "100755" {
+		t.Fatalf("unexpected mode change %+v", f)
+	}
+	if f := files[2]; f.Status != DiffAdded || f.NewMode != "100755" || f.OldMode != "" {
+		t.Fatalf("unexpected new file %+v", f)
+	}
+}
+
+// TestParseUnifiedDiffFileLoadsHunksLazily verifies that a streamed diff keeps
+// only hunk headers until a file's lines are asked for, and that they then
+// match what parsing the diff in memory gives.
+func TestParseUnifiedDiffFileLoadsHunksLazily(t *testing.T) {
+	input := "diff --git a/a.go b/a.go\r\n" +
+		"--- a/a.go\r\n" +
+		"+++ b/a.go\r\n" +
+		"@@ -1,2 +1,2 @@\r\n" +
+		" package a\r\n" +
+		"-var x = 1\r\n" +
+		"+var x = 2\r\n" +
+		"diff --git a/b.go b/b.go\n" +
+		"--- a/b.go\n" +
+		"+++ b/b.go\n" +
+		"@@ -3 +3,2 @@\n" +
+		" func b() {}\n" +
+		"+func c() {}\n"
+	path := filepath.Join(t.TempDir(), "big.diff")
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want, err := parseUnifiedDiff(input, diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := parseUnifiedDiffFile(path, diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	for i := range files {
+		f := &files[i]
+		if f.section == nil || len(f.Hunks) != 1 || f.Hunks[0].Lines != nil {
+			t.Fatalf("%s: expected a section and a hunk header without lines, got %+v", f.Path, f)
+		}
+		if f.Hunks[0].NewStart != want[i].Hunks[0].NewStart {
+			t.Fatalf("%s: hunk header %+v, want %+v", f.Path, f.Hunks[0], want[i].Hunks[0])
+		}
+	}
+
+	if !hasNewLine(&files[1], 4) {
+		t.Fatal("expected looking up a line to load the file's hunks")
+	}
+	if files[0].section == nil {
+		t.Fatal("expected the other file to stay unloaded")
+	}
+	for i := range files {
+		if err := ensureDiffLoaded(&files[i]); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(files[i].Hunks, want[i].Hunks) {
+			t.Fatalf("%s: loaded hunks %+v, want %+v", files[i].Path, files[i].Hunks, want[i].Hunks)
+		}
+	}
+}
+
+// TestParseUnifiedDiffFileStrict verifies that a streamed diff is still
+// checked in full when it is parsed.
+func TestParseUnifiedDiffFileStrict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.diff")
+	if err := os.WriteFile(path, []byte("--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-a\n+b\n+c\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := parseUnifiedDiffFile(path, diffStrict)
+	var perr *DiffParseError
+	if !errors.As(err, &perr) || perr.Line != 6 {
+		t.Fatalf("expected a parse error on line 6, got %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// errNotDiffSession is returned when a diff is pushed into a session that is
+// reviewing files rather than a diff.
+var errNotDiffSession = errors.New("session is not reviewing a diff")
+
+// errSeriesSession is returned when a diff is pushed into a session that is
+// reviewing a patch series, whose files are grouped by commit.
+var errSeriesSession = errors.New("session is reviewing a patch series; its diff cannot be updated")
+
+// diffUpdate summarises what pushing a new diff into a session changed.
+type diffUpdate struct {
+	UpdatedFiles     []string `json:"updated_files"`
+	OutdatedComments []int    `json:"outdated_comments"`
+}
+
+// applyDiffUpdate parses input and merges it into the session's diff. With
+// replace, the new diff becomes the whole review; otherwise its files replace
+// files with the same path and new files are appended.
+//
+// Files whose hunks changed are flagged as updated and marked unviewed.
+// Comments on them are re-anchored to the nearest line with the same text
+// on the same side; comments that cannot be found again keep their old
+// anchor and are flagged as outdated.
+func applyDiffUpdate(model *ReviewModel, input string, replace bool) (diffUpdate, error) {
+	if model.Mode != ModeDiff {
+		return diffUpdate{}, errNotDiffSession
+	}
+	if len(model.Commits) > 0 {
+		return diffUpdate{}, errSeriesSession
+	}
+	parsed, err := parseUnifiedDiff(input, diffLenient)
+	if err != nil {
+		return diffUpdate{}, err
+	}
+	if len(parsed) == 0 {
+		return diffUpdate{}, errors.New("no files in diff")
+	}
+
+	previous := make(map[string]DiffFile, len(model.DiffFiles))
+	for _, df := range model.DiffFiles {
+		previous[df.Path] = df
+	}
+	files := parsed
+	if !replace {
+		// Files already under review keep their place; new ones go last.
+		byPath := make(map[string]DiffFile, len(parsed))
+		for _, df := range parsed {
+			byPath[df.Path] = df
+		}
+		files = make([]DiffFile, 0, len(model.DiffFiles)+len(parsed))
+		for _, df := range model.DiffFiles {
+			if next, ok := byPath[df.Path]; ok {
+				df = next
+				delete(byPath, df.Path)
+			}
+			files = append(files, df)
+		}
+		for _, df := range parsed {
+			if _, ok := byPath[df.Path]; ok {
+				files = append(files, df)
+			}
+		}
+	}
+
+	var result diffUpdate
+	if model.UpdatedFiles == nil {
+		model.UpdatedFiles = make(map[string]bool)
+	}
+	if model.Viewed == nil {
+		model.Viewed = make(map[string]bool)
+	}
+	unchanged := func(df DiffFile) bool {
+		old, existed := previous[df.Path]
+		if existed && ensureDiffLoaded(&old) != nil {
+			return false
+		}
+		return existed && reflect.DeepEqual(hunksAsParsed(old), df.Hunks) && old.Status == df.Status
+	}
+	for i, df := range files {
+		// Unchanged files keep the context the reviewer expanded.
+		if unchanged(df) {
+			files[i] = previous[df.Path]
+		}
+	}
+	for _, df := range parsed {
+		if unchanged(df) {
+			continue
+		}
+		model.UpdatedFiles[df.Path] = true
+		model.Viewed[df.Path] = false
+		result.UpdatedFiles = append(result.UpdatedFiles, df.Path)
+	}
+
+	model.DiffFiles = files
+	applyLanguages(model)
+	kept := make(map[string]bool, len(files))
+	for _, df := range files {
+		kept[df.Path] = true
+	}
+	for i := range model.Comments {
+		c := &model.Comments[i]
+		old, existed := previous[c.Path]
+		switch {
+		case !kept[c.Path]:
+			// The file left the review, so the comment has nothing to anchor to.
+		case !existed || !model.UpdatedFiles[c.Path] || c.isFileLevel():
+			continue
+		case reanchorComment(c, &old, model.lookupDiffFile(c.Path)):
+			delete(model.OutdatedComments, c.ID)
+			continue
+		}
+		if model.OutdatedComments == nil {
+			model.OutdatedComments = make(map[int]bool)
+		}
+		model.OutdatedComments[c.ID] = true
+		result.OutdatedComments = append(result.OutdatedComments, c.ID)
+	}
+	return result, nil
+}
+
+// outdatedComments returns the IDs of comments that lost their anchor when
+// the diff was updated, in comment order.
+func outdatedComments(model *ReviewModel) []int {
+	var ids []int
+	for _, c := range model.Comments {
+		if model.OutdatedComments[c.ID] {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+// diffUpdateHandler serves PUT /api/diff, which replaces the session's diff,
+// and POST /api/diff, which extends it. The request body is the unified diff.
+func diffUpdateHandler(rs *ReviewServer, notify func(), replace bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 64<<20))
+		if err != nil {
+			http.Error(w, "could not read diff", http.StatusBadRequest)
+			return
+		}
+		rs.mu.Lock()
+		var result diffUpdate
+		status := http.StatusConflict
+		if rs.Model.Completed {
+			err = errors.New("review is already completed")
+		} else if result, err = applyDiffUpdate(rs.Model, string(body), replace); err != nil && err != errNotDiffSession && err != errSeriesSession {
+			status = http.StatusBadRequest
+		}
+		rs.mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if notify != nil {
+			notify()
+		}
+		result.UpdatedFiles = nonNil(result.UpdatedFiles)
+		result.OutdatedComments = nonNil(result.OutdatedComments)
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// reanchorComment moves c from its lines in old to the lines with the same
+// text in next, preferring the match closest to the original position. It
+// reports false if the commented lines are no longer in the diff.
+func reanchorComment(c *Comment, old, next *DiffFile) bool {
+	side := c.Side == "old"
+	want := make([]string, 0, c.EndLine-c.StartLine+1)
+	for n := c.StartLine; n <= c.EndLine; n++ {
+		text, ok := diffLineText(old, side, n)
+		if !ok {
+			return false
+		}
+		want = append(want, text)
+	}
+	best, found := 0, false
+	for _, start := range diffLineNumbers(next, side) {
+		if found && abs(start-c.StartLine) >= abs(best-c.StartLine) {
+			continue
+		}
+		match := true
+		for i, text := range want {
+			if got, ok := diffLineText(next, side, start+i); !ok || got != text {
+				match = false
+				break
+			}
+		}
+		if match {
+			best, found = start, true
+		}
+	}
+	if !found {
+		return false
+	}
+	c.EndLine += best - c.StartLine
+	c.StartLine = best
+	return true
+}
+
+// diffLineText returns the text of line n on the old or new side of file.
+func diffLineText(file *DiffFile, oldSide bool, n int) (string, bool) {
+	if ensureDiffLoaded(file) != nil {
+		return "", false
+	}
+	for _, h := range file.Hunks {
+		for _, dl := range h.Lines {
+			if oldSide && dl.Kind != DiffAdd && dl.OldLine == n {
+				return dl.Text, true
+			}
+			if !oldSide && dl.Kind != DiffDel && dl.NewLine == n {
+				return dl.Text, true
+			}
+		}
+	}
+	return "", false
+}
+
+// diffLineNumbers lists the line numbers present on one side of file.
+func diffLineNumbers(file *DiffFile, oldSide bool) []int {
+	if ensureDiffLoaded(file) != nil {
+		return nil
+	}
+	var out []int
+	for _, h := range file.Hunks {
+		for _, dl := range h.Lines {
+			switch {
+			case oldSide && dl.Kind != DiffAdd:
+				out = append(out, dl.OldLine)
+			case !oldSide && dl.Kind != DiffDel:
+				out = append(out, dl.NewLine)
+			}
+		}
+	}
+	return out
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
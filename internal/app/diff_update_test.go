@@ -0,0 +1,154 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const diffUpdateBefore = `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,3 +1,4 @@
+ package a
++
+ func A() {}
+ func B() {}
+diff --git a/b.go b/b.go
+--- a/b.go
++++ b/b.go
+@@ -1,2 +1,2 @@
+-package b
++package bb
+ func C() {}
+`
+
+// The agent inserted two lines above A and removed B.
+const diffUpdateAfter = `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,3 +1,5 @@
+ package a
++
++// A does nothing.
+ func A() {}
+-func B() {}
++func D() {}
+`
+
+func newDiffUpdateModel(t *testing.T) *ReviewView {
+	t.Helper()
+	files, err := parseUnifiedDiff(diffUpdateBefore, diffLenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode:          ModeDiff,
+			DiffFiles:     files,
+			Viewed:        map[string]bool{"a.go": true, "b.go": true},
+			NextCommentID: 3,
+			Comments: []Comment{
+				{ID: 1, Path: "a.go", StartLine: 3, EndLine: 3, Text: "document A"},
+				{ID: 2, Path: "a.go", StartLine: 4, EndLine: 4, Text: "rename B"},
+			},
+		},
+		SelectedPath: "a.go",
+	}
+	rebuildTree(model)
+	updateView(model)
+	return model
+}
+
+// TestApplyDiffUpdateExtend verifies that extending the diff replaces the
+// matching file, keeps the others, re-anchors comments whose lines moved and
+// flags comments whose lines disappeared.
+func TestApplyDiffUpdateExtend(t *testing.T) {
+	model := newDiffUpdateModel(t)
+	result, err := applyDiffUpdate(model.ReviewModel, diffUpdateAfter, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(model.DiffFiles) != 2 || model.DiffFiles[0].Path != "a.go" || model.DiffFiles[1].Path != "b.go" {
+		t.Fatalf("unexpected files after extend: %+v", model.DiffFiles)
+	}
+	if len(result.UpdatedFiles) != 1 || result.UpdatedFiles[0] != "a.go" || !model.UpdatedFiles["a.go"] {
+		t.Fatalf("expected a.go to be flagged as updated, got %v", result.UpdatedFiles)
+	}
+	if model.Viewed["a.go"] || !model.Viewed["b.go"] {
+		t.Fatalf("expected only the updated file to be unviewed, got %v", model.Viewed)
+	}
+	if c := model.Comments[0]; c.StartLine != 4 || c.EndLine != 4 || model.OutdatedComments[c.ID] {
+		t.Fatalf("expected the comment on A to move to line 4, got %+v", c)
+	}
+	if len(result.OutdatedComments) != 1 || result.OutdatedComments[0] != 2 {
+		t.Fatalf("expected the comment on B to be outdated, got %v", result.OutdatedComments)
+	}
+
+	meta := reviewMetadata(model.ReviewModel)
+	if ids, ok := meta["outdated_comments"].([]int); !ok || len(ids) != 1 || ids[0] != 2 {
+		t.Fatalf("expected outdated comments in the metadata, got %v", meta["outdated_comments"])
+	}
+	html := renderReviewHTML(t, model)
+	if !strings.Contains(html, "Updated by the agent") || !strings.Contains(html, ">outdated<") {
+		t.Fatal("expected the UI to flag the updated file and the outdated comment")
+	}
+}
+
+// TestApplyDiffUpdateReplace verifies that replacing the diff drops files it
+// no longer contains and outdates their comments, and that a view of a
+// dropped file moves to the first one.
+func TestApplyDiffUpdateReplace(t *testing.T) {
+	model := newDiffUpdateModel(t)
+	model.SelectedPath = "b.go"
+	model.Comments = append(model.Comments, Comment{ID: 3, Path: "b.go", StartLine: 1, EndLine: 1, Text: "why rename?"})
+	result, err := applyDiffUpdate(model.ReviewModel, diffUpdateAfter, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshView(model)
+	if len(model.DiffFiles) != 1 || model.SelectedPath != "a.go" {
+		t.Fatalf("expected only a.go to remain selected, got %d files, selected %q", len(model.DiffFiles), model.SelectedPath)
+	}
+	if !model.OutdatedComments[3] || len(result.OutdatedComments) != 2 {
+		t.Fatalf("expected comments on removed lines and files to be outdated, got %v", result.OutdatedComments)
+	}
+
+	if _, err := applyDiffUpdate(model.ReviewModel, "not a diff", true); err == nil {
+		t.Fatal("expected an error for input without files")
+	}
+}
+
+// TestAPIDiffUpdate verifies the HTTP endpoint and its errors.
+func TestAPIDiffUpdate(t *testing.T) {
+	model := newDiffUpdateModel(t)
+	notified := 0
+	srv := httptest.NewServer(apiHandler(&ReviewServer{Model: model.ReviewModel}, func() { notified++ }))
+	defer srv.Close()
+
+	send := func(method, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+"/api/diff", strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		out, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(out)
+	}
+	if code, _ := send("POST", "garbage"); code != http.StatusBadRequest {
+		t.Fatalf("invalid diff: got status %d", code)
+	}
+	code, body := send("POST", diffUpdateAfter)
+	if code != http.StatusOK || !strings.Contains(body, `"updated_files":["a.go"]`) || notified != 1 {
+		t.Fatalf("extend: got status %d body %s (notified %d)", code, body, notified)
+	}
+
+	model.Mode = ModeFile
+	if code, _ := send("PUT", diffUpdateAfter); code != http.StatusConflict {
+		t.Fatalf("file mode: got status %d", code)
+	}
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jfyne/live"
+)
+
+// Disposition is the reviewer's decision on a comment proposed by the agent
+// or another tool. Comments written in the UI have no disposition.
+type Disposition string
+
+const (
+	DispositionNone       Disposition = ""
+	DispositionPending    Disposition = "pending"
+	DispositionAccepted   Disposition = "accepted"
+	DispositionRejected   Disposition = "rejected"
+	DispositionDiscussion Disposition = "needs-discussion"
+)
+
+func validDisposition(d Disposition) bool {
+	switch d {
+	case DispositionPending, DispositionAccepted, DispositionRejected, DispositionDiscussion:
+		return true
+	}
+	return false
+}
+
+// proposeComment adds c as a proposal awaiting the reviewer's decision. The
+// anchor must resolve against the reviewed content.
+func proposeComment(model *ReviewModel, c Comment) (Comment, error) {
+	c.Author = strings.TrimSpace(c.Author)
+	if c.Author == "" {
+		c.Author = defaultReplyAuthor
+	}
+	return addAPIComment(model, c, DispositionPending)
+}
+
+// addAPIComment checks c, a comment posted through the API, and adds it
+// with disposition d.
+func addAPIComment(model *ReviewModel, c Comment, d Disposition) (Comment, error) {
+	c.Text = strings.TrimSpace(c.Text)
+	if c.Text == "" {
+		return Comment{}, fmt.Errorf("comment text is required")
+	}
+	if c.Side != "" && c.Side != "old" && c.Side != "new" {
+		return Comment{}, fmt.Errorf("side must be old or new")
+	}
+	if c.StartLine != 0 && c.EndLine == 0 {
+		c.EndLine = c.StartLine
+	}
+	if reason := commentAnchorProblem(model, c); reason != "" {
+		return Comment{}, fmt.Errorf("%s", reason)
+	}
+	if err := normalizeWeight(&c); err != nil {
+		return Comment{}, err
+	}
+	model.NextCommentID++
+	c.ID = model.NextCommentID
+	c.UID = newCommentUID()
+	c.Disposition = d
+	c.rendered = ""
+	model.Comments = append(model.Comments, c)
+	return c, nil
+}
+
+func registerDispositionHandlers(h *live.Handler, rs *ReviewServer) {
+	h.HandleEvent("set-disposition", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		c := findComment(view.ReviewModel, eventCommentID(view.ReviewModel, p))
+		d := Disposition(p.String("disposition"))
+		if c == nil || c.Disposition == DispositionNone || !validDisposition(d) {
+			return view, nil
+		}
+		// Clicking the current decision again leaves the proposal pending.
+		if c.Disposition == d {
+			d = DispositionPending
+		}
+		c.Disposition = d
+		updateView(view)
+		rs.markChanged()
+		return view, nil
+	}))
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+// TestProposedCommentDisposition verifies that comments proposed through the
+// API await a decision, that the reviewer can accept, reject or flag them for
+// discussion, and that the decision is written to the output.
+func TestProposedCommentDisposition(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	srv := httptest.NewServer(apiHandler(rs, nil))
+	defer srv.Close()
+
+	post := func(body string) int {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/api/comments", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if got := post(`{"path":"test.go","start_line":1,"text":"Consider a doc comment."}`); got != http.StatusCreated {
+		t.Fatalf("propose comment: got status %d", got)
+	}
+	if got := post(`{"path":"test.go","start_line":5,"text":"Past the end."}`); got != http.StatusBadRequest {
+		t.Fatalf("propose comment past the end of the file: got status %d", got)
+	}
+	proposal := model.Comments[1]
+	if proposal.Disposition != DispositionPending || proposal.Author != defaultReplyAuthor || proposal.EndLine != 1 {
+		t.Fatalf("unexpected proposal %+v", proposal)
+	}
+
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	s := live.NewSocket(ctx, engine, "reviewer")
+	s.Assign(model)
+	callEvent(t, engine, s, "set-disposition", map[string]string{"id": "2", "disposition": "rejected"})
+	if got := model.Comments[1].Disposition; got != DispositionRejected {
+		t.Fatalf("expected the proposal to be rejected, got %q", got)
+	}
+	callEvent(t, engine, s, "set-disposition", map[string]string{"id": "2", "disposition": "rejected"})
+	if got := model.Comments[1].Disposition; got != DispositionPending {
+		t.Fatalf("expected a second click to withdraw the decision, got %q", got)
+	}
+	callEvent(t, engine, s, "set-disposition", map[string]string{"id": "2", "disposition": "needs-discussion"})
+	callEvent(t, engine, s, "set-disposition", map[string]string{"id": "1", "disposition": "accepted"})
+	if model.Comments[0].Disposition != DispositionNone {
+		t.Fatal("comments written by the reviewer must not get a disposition")
+	}
+
+	if html := renderReviewHTML(t, model); !strings.Contains(html, `live-click="set-disposition"`) || !strings.Contains(html, "Needs discussion") {
+		t.Fatal("expected decision controls on the proposed comment")
+	}
+	var out bytes.Buffer
+	if err := emitReview(&out, model.ReviewModel); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "disposition") || !strings.Contains(out.String(), "needs-discussion") {
+		t.Fatalf("expected the decision in the output, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,304 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// docRenderer renders a documentation file as blocks mapped to the source
+// lines they came from, so the preview can be commented on like the code.
+type docRenderer func(r *renderers, path, input string) []MarkdownBlock
+
+// docRenderers maps a file extension to the renderer for its preview.
+var docRenderers = map[string]docRenderer{
+	".md":       renderMarkdownBlocks,
+	".markdown": renderMarkdownBlocks,
+	".mdx":      renderMDXBlocks,
+	".rst":      renderRSTBlocks,
+	".adoc":     renderAsciiDocBlocks,
+	".asciidoc": renderAsciiDocBlocks,
+}
+
+func docRendererFor(path string) docRenderer {
+	return docRenderers[strings.ToLower(filepath.Ext(path))]
+}
+
+// renderDocumentBlocks renders path with the renderer for its extension,
+// falling back to markdown.
+func renderDocumentBlocks(r *renderers, path, input string) []MarkdownBlock {
+	if render := docRendererFor(path); render != nil {
+		return render(r, path, input)
+	}
+	return renderMarkdownBlocks(r, path, input)
+}
+
+// joinBlocks concatenates rendered blocks back into one document.
+func joinBlocks(blocks []MarkdownBlock) template.HTML {
+	var b strings.Builder
+	for _, block := range blocks {
+		b.WriteString(string(block.ListOpen))
+		b.WriteString(string(block.HTML))
+		b.WriteString(string(block.ListClose))
+	}
+	return template.HTML(b.String())
+}
+
+// renderMDXBlocks renders MDX as markdown. Top-level import and export
+// statements run to the next blank line and are blanked out, keeping the
+// line numbers; JSX elements pass through as raw HTML.
+func renderMDXBlocks(r *renderers, path, input string) []MarkdownBlock {
+	lines := strings.Split(input, "\n")
+	fence, esm := "", false
+	for i, line := range lines {
+		switch {
+		case esm:
+			if strings.TrimSpace(line) == "" {
+				esm = false
+			} else {
+				lines[i] = ""
+			}
+		case fence != "":
+			trimmed := strings.TrimLeft(line, " ")
+			if marker := fenceMarker(trimmed); strings.HasPrefix(marker, fence) && strings.TrimSpace(trimmed[len(marker):]) == "" {
+				fence = ""
+			}
+		case fenceMarker(strings.TrimLeft(line, " ")) != "":
+			fence = fenceMarker(strings.TrimLeft(line, " "))
+		case strings.HasPrefix(line, "import ") || strings.HasPrefix(line, "export "):
+			lines[i], esm = "", true
+		}
+	}
+	return renderMarkdownBlocks(r, path, strings.Join(lines, "\n"))
+}
+
+// docBuilder collects the blocks of the line based renderers. Line numbers
+// are 1-based and inclusive.
+type docBuilder struct {
+	render  *renderers
+	baseDir string
+	blocks  []MarkdownBlock
+}
+
+func newDocBuilder(r *renderers, path string) *docBuilder {
+	baseDir := filepath.Dir(path)
+	if baseDir == "." {
+		baseDir = ""
+	}
+	return &docBuilder{render: r, baseDir: baseDir}
+}
+
+func (b *docBuilder) add(start, end int, body string) {
+	b.blocks = append(b.blocks, MarkdownBlock{
+		StartLine: start,
+		EndLine:   max(end, start),
+		HTML:      rewriteMarkdownImageSources(body, b.baseDir),
+	})
+}
+
+// docItem is one rendered list item.
+type docItem struct {
+	start, end int
+	body       string
+}
+
+// addList adds a list with a block per item, wrapped the same way as the
+// markdown renderer wraps lists.
+func (b *docBuilder) addList(ordered bool, items []docItem) {
+	for i, item := range items {
+		b.add(item.start, item.end, "<li>"+item.body+"</li>")
+		block := &b.blocks[len(b.blocks)-1]
+		if i == 0 {
+			block.ListOpen = "<ul>"
+			if ordered {
+				block.ListOpen = `<ol style="counter-reset: md-li-counter 0">`
+			}
+		}
+		if i == len(items)-1 {
+			block.ListClose = "</ul>"
+			if ordered {
+				block.ListClose = "</ol>"
+			}
+		}
+	}
+}
+
+// html joins the builder's blocks into one fragment, for nested content.
+func (b *docBuilder) html() string {
+	return string(joinBlocks(b.blocks))
+}
+
+// codeBlockHTML highlights body the same way as a fenced markdown block.
+func (b *docBuilder) codeBlockHTML(lang, body string) string {
+	longest := 0
+	for run := range strings.SplitSeq(body, "\n") {
+		if m := fenceMarker(strings.TrimLeft(run, " ")); m != "" && m[0] == '`' {
+			longest = max(longest, len(m))
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return string(b.render.renderMarkdown(fence + lang + "\n" + body + "\n" + fence + "\n"))
+}
+
+// preHTML shows body as unhighlighted preformatted text.
+func preHTML(body string) string {
+	return "<pre><code>" + html.EscapeString(body) + "</code></pre>"
+}
+
+// admonitionHTML renders a note, warning and the like as a titled quote.
+func admonitionHTML(kind, body string) string {
+	title := strings.ToUpper(kind[:1]) + strings.ToLower(kind[1:])
+	return `<blockquote class="admonition admonition-` + strings.ToLower(kind) + `"><p><strong>` + html.EscapeString(title) + "</strong></p>" + body + "</blockquote>"
+}
+
+func headingHTML(level int, body string) string {
+	level = min(max(level, 1), 6)
+	return fmt.Sprintf("<h%d>%s</h%d>", level, body, level)
+}
+
+// inlineRule turns a match of re into HTML; the text around matches is
+// escaped.
+type inlineRule struct {
+	re     *regexp.Regexp
+	render func(m []string) string
+}
+
+// renderInline applies the first rule to match at the earliest position,
+// repeatedly, so a rule listed earlier shields its match from later ones.
+func renderInline(s string, rules []inlineRule) string {
+	var b strings.Builder
+	for s != "" {
+		best, bestLoc := -1, []int(nil)
+		for i, rule := range rules {
+			if loc := rule.re.FindStringSubmatchIndex(s); loc != nil && (bestLoc == nil || loc[0] < bestLoc[0]) {
+				best, bestLoc = i, loc
+			}
+		}
+		if bestLoc == nil {
+			break
+		}
+		m := make([]string, len(bestLoc)/2)
+		for i := range m {
+			if bestLoc[2*i] >= 0 {
+				m[i] = s[bestLoc[2*i]:bestLoc[2*i+1]]
+			}
+		}
+		b.WriteString(html.EscapeString(s[:bestLoc[0]]))
+		b.WriteString(rules[best].render(m))
+		s = s[bestLoc[1]:]
+	}
+	b.WriteString(html.EscapeString(s))
+	return b.String()
+}
+
+// linkHTML renders a link, or only its text when href has a scheme other
+// than http, https or mailto, so a document cannot run javascript: URLs.
+func linkHTML(href, text string) string {
+	if !safeURL(href) {
+		return html.EscapeString(text)
+	}
+	return `<a href="` + html.EscapeString(href) + `">` + html.EscapeString(text) + "</a>"
+}
+
+// imageHTML renders an image, or only its alt text when src is not safe.
+func imageHTML(src, alt string) string {
+	if !safeURL(src) {
+		return html.EscapeString(alt)
+	}
+	return `<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(alt) + `">`
+}
+
+// safeURL reports whether u is relative or uses the http, https or mailto
+// scheme. Whitespace and control characters are ignored, as browsers drop
+// them from a scheme.
+func safeURL(u string) bool {
+	u = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, u)
+	i := strings.IndexAny(u, ":/?#")
+	if i < 0 || u[i] != ':' {
+		return true
+	}
+	switch strings.ToLower(u[:i]) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// tableHTML renders rows of already rendered cells, the first row as the
+// header when header is set.
+func tableHTML(rows [][]string, header bool) string {
+	var b strings.Builder
+	b.WriteString("<table>\n")
+	for i, row := range rows {
+		if i == 0 && header {
+			b.WriteString("<thead>\n")
+		}
+		if i == 1 && header || i == 0 && !header {
+			b.WriteString("<tbody>\n")
+		}
+		b.WriteString("<tr>")
+		tag := "td"
+		if i == 0 && header {
+			tag = "th"
+		}
+		for _, cell := range row {
+			b.WriteString("<" + tag + ">" + cell + "</" + tag + ">")
+		}
+		b.WriteString("</tr>\n")
+		if i == 0 && header {
+			b.WriteString("</thead>\n")
+		}
+	}
+	if len(rows) > 1 || !header {
+		b.WriteString("</tbody>\n")
+	}
+	b.WriteString("</table>\n")
+	return b.String()
+}
+
+// indentOf is the number of leading spaces and tabs in line.
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// dedent removes the smallest indentation of the non-blank lines.
+func dedent(lines []string) []string {
+	least := -1
+	for _, line := range lines {
+		if !isBlank(line) && (least < 0 || indentOf(line) < least) {
+			least = indentOf(line)
+		}
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= least && least > 0 {
+			out[i] = line[least:]
+		} else {
+			out[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return out
+}
+
+// trimBlankEdges drops leading and trailing blank lines.
+func trimBlankEdges(lines []string) []string {
+	for len(lines) > 0 && isBlank(lines[0]) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && isBlank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+// blockAt returns the HTML of the block starting at line, with its list
+// wrapper.
+func blockAt(t *testing.T, blocks []MarkdownBlock, line int) string {
+	t.Helper()
+	for _, b := range blocks {
+		if b.StartLine == line {
+			return string(b.ListOpen + b.HTML + b.ListClose)
+		}
+	}
+	t.Fatalf("no block starts at line %d", line)
+	return ""
+}
+
+func TestRenderRSTBlocks(t *testing.T) {
+	src := strings.Join([]string{
+		"=====", // 1
+		"Guide",
+		"=====",
+		"",
+		"Intro with ``code`` and `a link <https://example.com>`_.", // 5
+		"",
+		"Section",
+		"-------",
+		"",
+		"- one", // 10
+		"- two",
+		"",
+		"Example::",
+		"",
+		"    x = 1", // 15
+		"",
+		".. code-block:: go",
+		"",
+		"   func main() {}",
+		"", // 20
+		".. warning:: Mind the gap.",
+		"",
+		".. a comment",
+		"",
+		"=====  =====", // 25
+		"Name   Value",
+		"=====  =====",
+		"a      1",
+		"=====  =====",
+	}, "\n")
+	blocks := renderRSTBlocks(defaultRenderers, "docs/guide.rst", src)
+
+	for line, want := range map[int]string{
+		1:  "<h1>Guide</h1>",
+		5:  `<code>code</code> and <a href="https://example.com">a link</a>`,
+		7:  "<h2>Section</h2>",
+		10: "<ul><li>one</li>",
+		13: "<p>Example:</p>",
+		15: "<pre><code>x = 1</code></pre>",
+		17: `<span class="kd">func</span>`,
+		21: `<blockquote class="admonition admonition-warning"><p><strong>Warning</strong></p><p>Mind the gap.</p></blockquote>`,
+		25: "<th>Name</th><th>Value</th>",
+	} {
+		if got := blockAt(t, blocks, line); !strings.Contains(got, want) {
+			t.Errorf("block at line %d = %q, want %q", line, got, want)
+		}
+	}
+	if got := blockAt(t, blocks, 11); got != "<li>two</li></ul>" {
+		t.Errorf("unexpected last list item %q", got)
+	}
+	for _, b := range blocks {
+		if b.StartLine == 23 {
+			t.Error("expected the comment to be hidden")
+		}
+	}
+}
+
+func TestRenderAsciiDocBlocks(t *testing.T) {
+	src := strings.Join([]string{
+		"= Guide", // 1
+		":toc:",
+		"",
+		"Intro *bold* and `code` and https://example.com[a link].",
+		"", // 5
+		"* one",
+		"** nested",
+		"* two",
+		"",
+		". first", // 10
+		"",
+		"[source,go]",
+		"----",
+		"func main() {}",
+		"----", // 15
+		"",
+		"NOTE: Read this.",
+		"",
+		"|===",
+		"|Name |Value", // 20
+		"",
+		"|a |1",
+		"|===",
+		"",
+		"////", // 25
+		"hidden",
+		"////",
+	}, "\n")
+	blocks := renderAsciiDocBlocks(defaultRenderers, "docs/guide.adoc", src)
+
+	for line, want := range map[int]string{
+		1:  "<h1>Guide</h1>",
+		4:  `<strong>bold</strong> and <code>code</code> and <a href="https://example.com">a link</a>`,
+		6:  "<ul><li>one<ul><li>nested</li></ul></li>",
+		8:  "<li>two</li></ul>",
+		10: `<ol style="counter-reset: md-li-counter 0"><li>first</li></ol>`,
+		12: `<span class="kd">func</span>`,
+		17: `<blockquote class="admonition admonition-note"><p><strong>Note</strong></p><p>Read this.</p></blockquote>`,
+		19: "<th>Name</th><th>Value</th></tr>\n</thead>\n<tbody>\n<tr><td>a</td><td>1</td>",
+	} {
+		if got := blockAt(t, blocks, line); !strings.Contains(got, want) {
+			t.Errorf("block at line %d = %q, want %q", line, got, want)
+		}
+	}
+	if got := blockAt(t, blocks, 12); !strings.HasPrefix(got, `<pre class="chroma">`) {
+		t.Errorf("expected the source block to take in its attribute line, got %q", got)
+	}
+	if len(blocks) != 8 {
+		t.Errorf("expected the attribute entry and comment block to be hidden, got %d blocks", len(blocks))
+	}
+}
+
+func TestRenderMDXBlocksDropsESM(t *testing.T) {
+	src := "import Chart from './chart'\nexport const meta = {\n  title: 'x',\n}\n\n# Results\n\n<Chart />\n\n```js\nimport y from 'y'\n```\n"
+	html := string(joinBlocks(renderMDXBlocks(defaultRenderers, "docs/results.mdx", src)))
+	if strings.Contains(html, "Chart from") || strings.Contains(html, "meta") {
+		t.Fatalf("expected import and export statements to be dropped, got %q", html)
+	}
+	for _, want := range []string{"<h1>Results</h1>", "&lt;Chart /&gt;", `<span class="kr">import</span>`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in %q", want, html)
+		}
+	}
+	if blocks := renderMDXBlocks(defaultRenderers, "docs/results.mdx", src); blocks[0].StartLine != 6 {
+		t.Errorf("expected line numbers to be kept, heading at %d", blocks[0].StartLine)
+	}
+}
+
+func TestUpdateFileViewRendersDocFormats(t *testing.T) {
+	m := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{Path: "docs/index.rst", PathSlash: "docs/index.rst", Lines: []string{"Title", "=====", "", "Body"}}},
+		},
+		SelectedPath: "docs/index.rst",
+		RenderFile:   true,
+	}
+	updateFileView(m)
+	if !m.ViewFile.MarkdownRendered || len(m.ViewFile.MarkdownBlocks) != 2 || m.ViewFile.MarkdownBlocks[0].HTML != "<h1>Title</h1>" {
+		t.Fatalf("expected the rst preview, got %+v", m.ViewFile.MarkdownBlocks)
+	}
+}
+
+func TestLinkHTMLRejectsUnsafeSchemes(t *testing.T) {
+	for _, href := range []string{"javascript:alert(1)", "JavaScript:x", "java\tscript:x", "data:text/html,x", "vbscript:x"} {
+		if got := linkHTML(href, "click"); got != "click" {
+			t.Errorf("linkHTML(%q) = %q, want the text alone", href, got)
+		}
+		if got := imageHTML(href, "alt"); got != "alt" {
+			t.Errorf("imageHTML(%q) = %q, want the alt text alone", href, got)
+		}
+	}
+	for _, href := range []string{"https://example.com", "http://x/y?a:b", "mailto:a@b.c", "docs/guide.html", "#top", "/abs:path"} {
+		if got := linkHTML(href, "x"); !strings.Contains(got, "<a href=") {
+			t.Errorf("linkHTML(%q) = %q, want a link", href, got)
+		}
+	}
+}
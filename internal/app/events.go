@@ -0,0 +1,82 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Lifecycle events written by --events, one line each:
+//
+//	event=ready url=http://127.0.0.1:8080/
+//	event=reviewer_connected reviewer=alice role=reviewer
+//	event=reviewer_disconnected reviewer=alice role=reviewer
+//	event=comment_added id=3 path=main.go count=3
+//	event=finished comments=3 verdict=approve
+//	event=timed_out
+//	event=aborted signal=interrupt
+//
+// Fields are logfmt: values containing spaces, quotes or '=' are quoted.
+const (
+	eventReady                = "ready"
+	eventReviewerConnected    = "reviewer_connected"
+	eventReviewerDisconnected = "reviewer_disconnected"
+	eventCommentAdded         = "comment_added"
+	eventFinished             = "finished"
+	eventDeadlineExpired      = "deadline_expired"
+	eventTimedOut             = "timed_out"
+	eventAborted              = "aborted"
+)
+
+// eventLog writes lifecycle events for wrappers that drive timeouts,
+// notifications or progress displays. A nil *eventLog discards events.
+type eventLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newEventLog(w io.Writer) *eventLog {
+	if w == nil {
+		return nil
+	}
+	return &eventLog{w: w}
+}
+
+// emit writes one event line. kv alternates field names and values; fields
+// with empty values are left out.
+func (l *eventLog) emit(name string, kv ...any) {
+	if l == nil {
+		return
+	}
+	var b strings.Builder
+	b.WriteString("event=")
+	b.WriteString(name)
+	for i := 0; i+1 < len(kv); i += 2 {
+		value := fmt.Sprint(kv[i+1])
+		if value == "" {
+			continue
+		}
+		fmt.Fprintf(&b, " %s=%s", kv[i], logfmtValue(value))
+	}
+	b.WriteByte('\n')
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = io.WriteString(l.w, b.String())
+}
+
+func logfmtValue(v string) string {
+	if strings.ContainsAny(v, " \t\r\n\"=\\") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// viewerRole names the role of a connection in events.
+func viewerRole(observer bool) string {
+	if observer {
+		return roleObserver
+	}
+	return "reviewer"
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+func TestEventLogFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l := newEventLog(&buf)
+	l.emit(eventReady, "url", "http://127.0.0.1:8080/", "api", "")
+	l.emit(eventReviewerConnected, "reviewer", "Ada Lovelace", "role", "reviewer")
+	l.emit(eventCommentAdded, "id", 3, "path", `a "b".go`, "count", 3)
+
+	want := "event=ready url=http://127.0.0.1:8080/\n" +
+		"event=reviewer_connected reviewer=\"Ada Lovelace\" role=reviewer\n" +
+		"event=comment_added id=3 path=\"a \\\"b\\\".go\" count=3\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// A nil log, as used when --events is not set, discards events.
+	var none *eventLog
+	none.emit(eventFinished)
+	if newEventLog(nil) != nil {
+		t.Fatal("expected a nil log for a nil writer")
+	}
+}
+
+// TestLifecycleEvents verifies that adding a comment and finishing the
+// review are reported on the event log.
+func TestLifecycleEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files:  []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"package a", "func A() {}"}}},
+			Mode:   ModeFile,
+			Viewed: map[string]bool{},
+		},
+		SelectedPath:         "a.go",
+		MarkdownRenderByPath: map[string]bool{},
+	}
+	rebuildTree(model)
+	updateView(model)
+	var buf bytes.Buffer
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{}), events: newEventLog(&buf)}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	s := live.NewSocket(ctx, engine, "alice")
+	s.Assign(model)
+
+	callEvent(t, engine, s, "select-line", map[string]string{"line": "2"})
+	callEvent(t, engine, s, "add-comment", map[string]string{"comment": "rename this"})
+	callEvent(t, engine, s, "finish", nil)
+
+	want := "event=comment_added id=1 path=a.go count=1\n" +
+		"event=finished comments=1\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
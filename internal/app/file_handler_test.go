@@ -10,13 +10,13 @@ import (
 
 func TestLocalFileHandlerServesFile(t *testing.T) {
 	tmp := t.TempDir()
-	path := filepath.Join(tmp, "a.txt")
+	path := filepath.Join(tmp, "a.svg")
 	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
 		t.Fatalf("write file: %v", err)
 	}
 
 	h := localFileHandler(tmp)
-	req := httptest.NewRequest(http.MethodGet, "/file?path=a.txt", nil)
+	req := httptest.NewRequest(http.MethodGet, "/file?path=a.svg", nil)
 	rr := httptest.NewRecorder()
 	h.ServeHTTP(rr, req)
 
@@ -39,3 +39,54 @@ func TestLocalFileHandlerBlocksTraversal(t *testing.T) {
 		t.Fatalf("expected 400, got %d", rr.Code)
 	}
 }
+
+// TestLocalFileHandlerBlocksSymlinkEscape verifies that a symlink inside the
+// root cannot be used to read files outside it.
+func TestLocalFileHandlerBlocksSymlinkEscape(t *testing.T) {
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.png")
+	if err := os.WriteFile(secret, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	if err := os.Symlink(secret, filepath.Join(root, "link.png")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "dir")); err != nil {
+		t.Fatal(err)
+	}
+
+	h := localFileHandler(root)
+	for _, path := range []string{"link.png", "dir/secret.png"} {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/file?path="+path, nil))
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", path, rr.Code)
+		}
+	}
+}
+
+// TestLocalFileHandlerRestrictsFileTypes verifies that only image types
+// referenced by rendered markdown are served.
+func TestLocalFileHandlerRestrictsFileTypes(t *testing.T) {
+	tmp := t.TempDir()
+	for _, name := range []string{"notes.txt", "pic.PNG"} {
+		if err := os.WriteFile(filepath.Join(tmp, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := localFileHandler(tmp)
+	tests := map[string]int{
+		"notes.txt": http.StatusForbidden,
+		"pic.PNG":   http.StatusOK,
+		"gone.png":  http.StatusNotFound,
+	}
+	for path, want := range tests {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/file?path="+path, nil))
+		if rr.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// forgeTimeout bounds the requests made to publish a review to a code host
+// with --github-pr or --gitlab-mr.
+const forgeTimeout = time.Minute
+
+// forgeClient calls the JSON API of a code host.
+type forgeClient struct {
+	api    string
+	header http.Header
+	http   *http.Client
+}
+
+// newForgeClient returns a client for the API rooted at the URL in env, or
+// at def when env is unset, sending header with every request.
+func newForgeClient(env, def string, header http.Header) *forgeClient {
+	api := strings.TrimSuffix(os.Getenv(env), "/")
+	if api == "" {
+		api = def
+	}
+	return &forgeClient{api: api, header: header, http: http.DefaultClient}
+}
+
+// do sends in, if not nil, as JSON to path and decodes the response into
+// out, if not nil. Error responses are reported with the API's message.
+func (c *forgeClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	data, err := c.send(ctx, method, path, body, "")
+	if err != nil {
+		return err
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// raw fetches path as the media type accept instead of JSON.
+func (c *forgeClient) raw(ctx context.Context, path, accept string) ([]byte, error) {
+	return c.send(ctx, http.MethodGet, path, nil, accept)
+}
+
+func (c *forgeClient) send(ctx context.Context, method, path string, body io.Reader, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.api+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Message any `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != nil {
+			return nil, fmt.Errorf("%s %s: %s: %v", method, path, resp.Status, apiErr.Message)
+		}
+		return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return data, nil
+}
+
+// forgeComments returns the comments to publish, with paths relative to
+// the repository root. Rejected proposals and resolved threads are left out
+// as they need no action. Comments on a patch series keep their commit
+// unless they are on the new side of the last commit to change their file,
+// the only lines that are the same on the code host; the rest cannot be
+// anchored to its diff.
+func forgeComments(model *ReviewModel) []Comment {
+	root := ""
+	if model.Git != nil && model.Mode == ModeFile {
+		root = model.Git.RepoRoot
+	}
+	comments, _ := validateComments(model)
+	last := make(map[string]int)
+	for _, f := range model.DiffFiles {
+		last[pickDiffPath(f.OldPath, f.NewPath)] = f.Commit
+	}
+	out := make([]Comment, 0, len(comments))
+	for i, c := range exportComments(model, comments) {
+		if c.Disposition == DispositionRejected || c.Status == ThreadResolved {
+			continue
+		}
+		if commit := model.commitOf(comments[i].Path); commit != nil && c.Side != "old" && last[c.Path] == commit.Number {
+			c.Commit = ""
+		}
+		c.Path = repoPath(c.Path, root)
+		out = append(out, c)
+	}
+	return out
+}
+
+// repoPath turns a reviewed path into one relative to the repository root,
+// as code hosts name files. Paths from a diff already are; files named on
+// the command line are resolved against root when it is known.
+func repoPath(path, root string) string {
+	if root != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			if rel, err := filepath.Rel(root, abs); err == nil && filepath.IsLocal(rel) {
+				path = rel
+			}
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(path), "./")
+}
+
+// parsePatch parses the hunks a code host sends for one file, which come
+// without file headers. It returns nil for an empty or unreadable patch.
+func parsePatch(oldPath, newPath, patch string) *DiffFile {
+	if patch == "" {
+		return nil
+	}
+	diff := fmt.Sprintf("--- a/%s\n+++ b/%s\n%s\n", oldPath, newPath, strings.TrimSuffix(patch, "\n"))
+	parsed, err := parseUnifiedDiff(diff, diffLenient)
+	if err != nil || len(parsed) != 1 {
+		return nil
+	}
+	return &parsed[0]
+}
+
+// findDiffLine returns the line numbered n on the old or new side of file,
+// and the index of the hunk it is in.
+func findDiffLine(file *DiffFile, oldSide bool, n int) (DiffLine, int, bool) {
+	if ensureDiffLoaded(file) != nil {
+		return DiffLine{}, 0, false
+	}
+	for i, h := range file.Hunks {
+		for _, dl := range h.Lines {
+			if oldSide && dl.Kind != DiffAdd && dl.OldLine == n {
+				return dl, i, true
+			}
+			if !oldSide && dl.Kind != DiffDel && dl.NewLine == n {
+				return dl, i, true
+			}
+		}
+	}
+	return DiffLine{}, 0, false
+}
+
+// unanchoredList writes the comments that could not be placed on the
+// diff as a Markdown list under heading.
+func unanchoredList(b *strings.Builder, heading string, comments []Comment) {
+	if len(comments) == 0 {
+		return
+	}
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString(heading)
+	b.WriteString("\n\n")
+	for _, c := range comments {
+		fmt.Fprintf(b, "- `%s` %s", c.Path, markdownLocation(c))
+		if c.Commit != "" {
+			fmt.Fprintf(b, " in %s", c.Commit)
+		}
+		fmt.Fprintf(b, ": %s\n", indentMarkdown(c.Text, "  "))
+	}
+}
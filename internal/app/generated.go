@@ -0,0 +1,226 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Why a file is collapsed, as shown next to it. Each is a translatable
+// message.
+const (
+	collapsedGenerated = "This file is generated."
+	collapsedVendored  = "This file is vendored."
+	collapsedLockfile  = "This is a dependency lockfile."
+	collapsedMinified  = "This file is minified."
+	collapsedGlob      = "This file matches --collapse-glob."
+)
+
+// minifiedLineLength is the line length from which a file is taken to be
+// minified.
+const minifiedLineLength = 1000
+
+// collapseHeadSize is how much of each file under review is read to look
+// for a generated-code header or minified lines.
+const collapseHeadSize = 8 << 10
+
+// generatedHeaderRE matches the Go convention for marking generated code,
+// which other generators follow too.
+var generatedHeaderRE = regexp.MustCompile(`^\s*(//|#|--|/\*)\s*Code generated .* DO NOT EDIT\.`)
+
+// lockfiles are the names of dependency lockfiles, which tools rewrite.
+var lockfiles = map[string]bool{
+	"go.sum":              true,
+	"go.work.sum":         true,
+	"package-lock.json":   true,
+	"npm-shrinkwrap.json": true,
+	"yarn.lock":           true,
+	"pnpm-lock.yaml":      true,
+	"bun.lock":            true,
+	"Cargo.lock":          true,
+	"Gemfile.lock":        true,
+	"composer.lock":       true,
+	"poetry.lock":         true,
+	"Pipfile.lock":        true,
+	"uv.lock":             true,
+	"flake.lock":          true,
+	"mix.lock":            true,
+	"Podfile.lock":        true,
+	"pubspec.lock":        true,
+	"packages.lock.json":  true,
+}
+
+// linguistAttrs are the linguist-generated and linguist-vendored values
+// .gitattributes gives a path: "set", "unset", a value, or "unspecified".
+type linguistAttrs struct {
+	generated string
+	vendored  string
+}
+
+// collapsedFiles decides which of the files under review are collapsed by
+// default, keyed by path with the reason. Generated and vendored files
+// are found from .gitattributes as GitHub's linguist reads it, and by
+// path and content; globs are the --collapse-glob patterns.
+func collapsedFiles(model *ReviewModel, globs []string) map[string]string {
+	type candidate struct {
+		key, path string
+		head      func() []string
+	}
+	var files []candidate
+	if model.Mode == ModeDiff {
+		for i := range model.DiffFiles {
+			df := &model.DiffFiles[i]
+			files = append(files, candidate{df.Path, pickDiffPath(df.OldPath, df.NewPath), func() []string { return diffHead(df) }})
+		}
+	} else {
+		for i := range model.Files {
+			f := &model.Files[i]
+			if f.Binary {
+				continue
+			}
+			files = append(files, candidate{f.Path, f.PathSlash, func() []string { return fileHead(f.Path) }})
+		}
+	}
+	var attrs map[string]linguistAttrs
+	if model.Git != nil {
+		paths := make([]string, len(files))
+		for i, f := range files {
+			paths[i] = f.path
+		}
+		attrs = checkLinguistAttrs(paths)
+	}
+	collapsed := make(map[string]string)
+	for _, f := range files {
+		if reason := collapseReason(f.path, attrs[f.path], f.head, globs); reason != "" {
+			collapsed[f.key] = reason
+		}
+	}
+	return collapsed
+}
+
+// collapseReason says why the file at p is collapsed, or "" if it is not.
+// head returns the first lines of the file, and is only called when the
+// path alone does not decide.
+func collapseReason(p string, attrs linguistAttrs, head func() []string, globs []string) string {
+	switch {
+	case excluded(globs, p):
+		return collapsedGlob
+	case attrSet(attrs.generated):
+		return collapsedGenerated
+	case attrSet(attrs.vendored):
+		return collapsedVendored
+	}
+	if attrs.vendored != "unset" {
+		for _, dir := range strings.Split(path.Dir(p), "/") {
+			if dir == "vendor" || dir == "node_modules" {
+				return collapsedVendored
+			}
+		}
+	}
+	if attrs.generated == "unset" {
+		return ""
+	}
+	base := path.Base(p)
+	if lockfiles[base] {
+		return collapsedLockfile
+	}
+	if strings.Contains(base, ".min.") {
+		return collapsedMinified
+	}
+	lines := head()
+	for i, line := range lines {
+		if i < 5 && generatedHeaderRE.MatchString(line) {
+			return collapsedGenerated
+		}
+		if len(line) >= minifiedLineLength {
+			return collapsedMinified
+		}
+	}
+	return ""
+}
+
+func attrSet(value string) bool {
+	return value != "" && value != "unset" && value != "unspecified" && value != "false"
+}
+
+// checkLinguistAttrs asks git for the linguist attributes of paths. It
+// returns nil if git cannot tell, e.g. outside a repository.
+func checkLinguistAttrs(paths []string) map[string]linguistAttrs {
+	if len(paths) == 0 {
+		return nil
+	}
+	cmd := exec.Command("git", "check-attr", "-z", "--stdin", "linguist-generated", "linguist-vendored")
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\x00") + "\x00")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	// The output is path, attribute and value, each NUL-terminated.
+	fields := strings.Split(string(out), "\x00")
+	attrs := make(map[string]linguistAttrs)
+	for i := 0; i+2 < len(fields); i += 3 {
+		p, attr, value := fields[i], fields[i+1], fields[i+2]
+		a := attrs[p]
+		switch attr {
+		case "linguist-generated":
+			a.generated = value
+		case "linguist-vendored":
+			a.vendored = value
+		}
+		attrs[p] = a
+	}
+	return attrs
+}
+
+// fileHead returns the lines in the first collapseHeadSize bytes of the
+// file at p, or nil if it cannot be read.
+func fileHead(p string) []string {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, collapseHeadSize))
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(data), "\n")
+}
+
+// diffHead returns the lines a diff adds or keeps, for collapseReason to
+// look at. The lines of a diff read on demand are not loaded for it.
+func diffHead(df *DiffFile) []string {
+	var lines []string
+	for _, h := range df.Hunks {
+		for _, dl := range h.Lines {
+			if dl.Kind != DiffDel {
+				lines = append(lines, dl.Text)
+			}
+		}
+	}
+	return lines
+}
+
+// collapsedReason says why path is collapsed, or "" if it is not or the
+// reviewer chose to load it anyway.
+func (v *ReviewView) collapsedReason(path string) string {
+	if v.Expanded[path] {
+		return ""
+	}
+	return v.Collapsed[path]
+}
+
+// validateCollapseGlobs checks that every --collapse-glob pattern is a
+// valid glob.
+func validateCollapseGlobs(patterns []string) error {
+	for _, pattern := range patterns {
+		if err := validateGlob(pattern); err != nil {
+			return fmt.Errorf("invalid --collapse-glob pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+func TestCollapseReason(t *testing.T) {
+	long := strings.Repeat("x", minifiedLineLength)
+	tests := []struct {
+		name  string
+		path  string
+		attrs linguistAttrs
+		head  []string
+		globs []string
+		want  string
+	}{
+		{name: "plain", path: "main.go", head: []string{"package main"}},
+		{name: "generated header", path: "api.pb.go", head: []string{"// Code generated by protoc-gen-go. DO NOT EDIT.", "", "package api"}, want: collapsedGenerated},
+		{name: "generated attribute", path: "schema.go", attrs: linguistAttrs{generated: "set"}, want: collapsedGenerated},
+		{name: "generated attribute unset", path: "go.sum", attrs: linguistAttrs{generated: "unset"}},
+		{name: "vendor dir", path: "vendor/github.com/x/y.go", want: collapsedVendored},
+		{name: "node_modules", path: "web/node_modules/a/index.js", want: collapsedVendored},
+		{name: "vendored attribute unset", path: "vendor/ours.go", attrs: linguistAttrs{vendored: "unset"}},
+		{name: "lockfile", path: "web/package-lock.json", want: collapsedLockfile},
+		{name: "go.sum", path: "go.sum", want: collapsedLockfile},
+		{name: "minified name", path: "static/app.min.js", want: collapsedMinified},
+		{name: "minified content", path: "static/app.js", head: []string{long}, want: collapsedMinified},
+		{name: "glob", path: "docs/api.md", globs: []string{"docs/*.md"}, want: collapsedGlob},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head := func() []string { return tt.head }
+			if got := collapseReason(tt.path, tt.attrs, head, tt.globs); got != tt.want {
+				t.Fatalf("collapseReason(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadCollapsed verifies that a collapsed file shows no content until a
+// reviewer loads it anyway.
+func TestLoadCollapsed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	files, err := parseUnifiedDiff("--- a/go.sum\n+++ b/go.sum\n@@ -1 +1 @@\n-a v1 h1:x\n+a v2 h1:y\n", diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{ReviewModel: &ReviewModel{DiffFiles: files, Mode: ModeDiff}, SelectedPath: "go.sum"}
+	model.Collapsed = collapsedFiles(model.ReviewModel, nil)
+	rebuildTree(model)
+	updateView(model)
+	if model.ViewDiff.Collapsed != collapsedLockfile || len(model.ViewDiff.Hunks) != 0 {
+		t.Fatalf("expected a collapsed lockfile without hunks, got %+v", model.ViewDiff)
+	}
+	if model.Tree[0].Collapsed != collapsedLockfile {
+		t.Fatalf("expected the tree to mark the file collapsed, got %+v", model.Tree[0])
+	}
+
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	s := live.NewSocket(ctx, engine, "alice")
+	s.Assign(model)
+	callEvent(t, engine, s, "load-collapsed", nil)
+
+	if model.ViewDiff.Collapsed != "" || len(model.ViewDiff.Hunks) != 1 {
+		t.Fatalf("expected the hunk after loading anyway, got %+v", model.ViewDiff)
+	}
+	if model.Tree[0].Collapsed != "" {
+		t.Fatal("expected the tree to stop marking the file collapsed")
+	}
+}
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -10,14 +12,86 @@ import (
 // gitRun executes a git command in the current working directory and returns
 // the trimmed output. Returns an error if the command fails.
 func gitRun(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
-	out, err := cmd.Output()
+	out, err := exec.Command("git", args...).Output()
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(string(out)), nil
 }
 
+// gitOutput runs git like gitRun but keeps the output as is and puts git's
+// own message in the error. Exit codes listed in ok are not failures.
+func gitOutput(ok []int, args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		for _, code := range ok {
+			if exitErr.ExitCode() == code {
+				return string(out), nil
+			}
+		}
+		if msg := strings.TrimSpace(string(exitErr.Stderr)); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+// gitDiff returns the unified diff for --git: the changes since rev, or
+// between the revisions of a range such as HEAD~1..HEAD, or in the index
+// with staged. Paths are pathspecs limiting the diff, and untracked adds
+// files git does not know about yet as new files. Paths in the diff are
+// relative to the working directory, like the ones given on the command
+// line.
+func gitDiff(rev string, staged, untracked bool, paths []string) (string, error) {
+	if untracked && (staged || strings.Contains(rev, "..")) {
+		return "", errors.New("--untracked only applies to a diff against the working tree")
+	}
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--relative"}
+	if staged {
+		args = append(args, "--staged")
+	}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	args = append(append(args, "--"), paths...)
+	diff, err := gitOutput(nil, args...)
+	if err != nil || !untracked {
+		return diff, err
+	}
+
+	names, err := gitOutput(nil, append([]string{"ls-files", "-z", "--others", "--exclude-standard", "--"}, paths...)...)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString(diff)
+	for _, name := range strings.Split(names, "\x00") {
+		if name == "" {
+			continue
+		}
+		// --no-index exits 1 when the files differ, which a new file always
+		// does.
+		d, err := gitOutput([]int{1}, "diff", "--no-color", "--no-ext-diff", "--no-index", "--", os.DevNull, name)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(d)
+	}
+	return b.String(), nil
+}
+
+// gitSeries returns the commits of the range rev as git format-patch
+// output, for stepping through them one at a time. Merge commits are left
+// out, as format-patch does. Paths limit the patches as they do the diff.
+func gitSeries(rev string, paths []string) (string, error) {
+	args := []string{"format-patch", "--stdout", "--no-color", "--no-signature", "--relative", rev, "--"}
+	return gitOutput(nil, append(args, paths...)...)
+}
+
 // detectGitContext detects whether the current working directory is inside a
 // git repository and returns a populated *GitContext if so. Returns nil when:
 //   - os.Getwd() fails
@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -163,3 +164,89 @@ func TestDetectGitContextDetachedHead(t *testing.T) {
 		t.Errorf("GitContext.Branch = %q; want %q in detached HEAD state", ctx.Branch, "HEAD")
 	}
 }
+
+// TestGitDiff verifies that gitDiff produces a diff parseUnifiedDiff reads
+// for a revision, a range, the index and untracked files.
+func TestGitDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+
+	repo := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test",
+			"GIT_AUTHOR_EMAIL=test@test.com",
+			"GIT_COMMITTER_NAME=test",
+			"GIT_COMMITTER_EMAIL=test@test.com",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init")
+	write("a.go", "package a\n")
+	run("add", "a.go")
+	run("commit", "-m", "initial")
+	write("a.go", "package a\n\nvar x = 1\n")
+	run("commit", "-am", "second")
+	write("a.go", "package a\n\nvar x = 2\n")
+	write("b.go", "package b\n")
+	write("c.go", "package c\n")
+	run("add", "b.go")
+	t.Chdir(repo)
+
+	paths := func(diff string) []string {
+		t.Helper()
+		files, err := parseUnifiedDiff(diff, diffStrict)
+		if err != nil {
+			t.Fatalf("parse %q: %v", diff, err)
+		}
+		var out []string
+		for _, f := range files {
+			out = append(out, f.Path)
+		}
+		return out
+	}
+
+	for _, tc := range []struct {
+		name      string
+		rev       string
+		staged    bool
+		untracked bool
+		paths     []string
+		want      string
+	}{
+		{name: "range", rev: "HEAD~1..HEAD", want: "a.go"},
+		{name: "revision", rev: "HEAD", want: "a.go b.go"},
+		{name: "staged", staged: true, want: "b.go"},
+		{name: "untracked", rev: "HEAD", untracked: true, want: "a.go b.go c.go"},
+		{name: "pathspec", rev: "HEAD", untracked: true, paths: []string{"c.go"}, want: "c.go"},
+	} {
+		diff, err := gitDiff(tc.rev, tc.staged, tc.untracked, tc.paths)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := strings.Join(paths(diff), " "); got != tc.want {
+			t.Errorf("%s: files = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	if _, err := gitDiff("no-such-rev", false, false, nil); err == nil || !strings.Contains(err.Error(), "no-such-rev") {
+		t.Errorf("expected git's error for an unknown revision, got %v", err)
+	}
+	if _, err := gitDiff("", true, true, nil); err == nil {
+		t.Error("expected --untracked to be refused with --staged")
+	}
+}
@@ -0,0 +1,239 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// githubTokenEnv names the variable --github-pr reads its token from.
+const githubTokenEnv = "GITHUB_TOKEN"
+
+// githubAPIEnv overrides the GitHub API root, as GitHub Actions sets it for
+// GitHub Enterprise Server.
+const githubAPIEnv = "GITHUB_API_URL"
+
+const defaultGitHubAPI = "https://api.github.com"
+
+// GitHubPR identifies the pull request --github-pr posts the review to.
+type GitHubPR struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+func (pr GitHubPR) String() string {
+	return fmt.Sprintf("%s/%s#%d", pr.Owner, pr.Repo, pr.Number)
+}
+
+var githubPRPattern = regexp.MustCompile(`^([A-Za-z0-9_.-]+)/([A-Za-z0-9_.-]+)#([0-9]+)$`)
+
+// ParseGitHubPR parses a --github-pr or --fetch-pr value of the form
+// owner/repo#123.
+func ParseGitHubPR(s string) (*GitHubPR, error) {
+	m := githubPRPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil, fmt.Errorf("invalid pull request %q: want owner/repo#123", s)
+	}
+	n, err := strconv.Atoi(m[3])
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid pull request %q: bad number", s)
+	}
+	return &GitHubPR{Owner: m[1], Repo: m[2], Number: n}, nil
+}
+
+// githubReviewComment is a comment anchored to lines of the pull request's
+// diff. Side is LEFT for removed lines and RIGHT for the rest; StartLine is
+// set for comments on more than one line.
+type githubReviewComment struct {
+	Path      string `json:"path"`
+	Body      string `json:"body"`
+	Line      int    `json:"line"`
+	Side      string `json:"side"`
+	StartLine int    `json:"start_line,omitempty"`
+	StartSide string `json:"start_side,omitempty"`
+}
+
+// githubReview is a review without an event, which GitHub keeps pending
+// until its author submits it.
+type githubReview struct {
+	Body     string                `json:"body,omitempty"`
+	Comments []githubReviewComment `json:"comments"`
+}
+
+type githubPRFile struct {
+	Filename string `json:"filename"`
+	Patch    string `json:"patch"`
+}
+
+// newGitHubClient returns a client authenticated with token, or an
+// anonymous one when it is empty, which can still read public repositories.
+func newGitHubClient(token string) *forgeClient {
+	header := http.Header{
+		"Accept":               {"application/vnd.github+json"},
+		"X-Github-Api-Version": {"2022-11-28"},
+	}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return newForgeClient(githubAPIEnv, defaultGitHubAPI, header)
+}
+
+// githubPRFiles lists the files of the pull request with their patches.
+func githubPRFiles(ctx context.Context, c *forgeClient, pr GitHubPR) ([]githubPRFile, error) {
+	const perPage = 100
+	var files []githubPRFile
+	for page := 1; ; page++ {
+		var batch []githubPRFile
+		path := fmt.Sprintf("/repos/%s/%s/pulls/%d/files?per_page=%d&page=%d", pr.Owner, pr.Repo, pr.Number, perPage, page)
+		if err := c.do(ctx, http.MethodGet, path, nil, &batch); err != nil {
+			return nil, err
+		}
+		files = append(files, batch...)
+		if len(batch) < perPage {
+			return files, nil
+		}
+	}
+}
+
+// githubPatches parses the patch of each pull request file, keyed by path.
+// Files GitHub sends no patch for, such as binary or very large ones, are
+// left out, so comments on them go in the review body.
+func githubPatches(files []githubPRFile) map[string]*DiffFile {
+	out := make(map[string]*DiffFile)
+	for _, f := range files {
+		if file := parsePatch(f.Filename, f.Filename, f.Patch); file != nil {
+			out[f.Filename] = file
+		}
+	}
+	return out
+}
+
+// githubAnchor maps c onto the pull request's diff. It reports false when
+// the comment is file-level, on an earlier commit of a patch series or its
+// last line is not in the diff; a range whose first line is in another hunk
+// is shortened to its last line.
+func githubAnchor(patches map[string]*DiffFile, c Comment) (githubReviewComment, bool) {
+	file := patches[c.Path]
+	if file == nil || c.isFileLevel() || c.Commit != "" {
+		return githubReviewComment{}, false
+	}
+	oldSide := c.Side == "old"
+	_, endHunk, ok := findDiffLine(file, oldSide, c.EndLine)
+	if !ok {
+		return githubReviewComment{}, false
+	}
+	side := "RIGHT"
+	if oldSide {
+		side = "LEFT"
+	}
+	gc := githubReviewComment{Path: c.Path, Body: c.Text, Line: c.EndLine, Side: side}
+	if _, startHunk, ok := findDiffLine(file, oldSide, c.StartLine); ok && startHunk == endHunk && c.StartLine < c.EndLine {
+		gc.StartLine, gc.StartSide = c.StartLine, side
+	}
+	return gc, true
+}
+
+// buildGitHubReview anchors each comment to the pull request's diff and
+// collects the rest, with the review summary, in the review body.
+func buildGitHubReview(model *ReviewModel, patches map[string]*DiffFile) githubReview {
+	review := githubReview{Comments: []githubReviewComment{}}
+	var body strings.Builder
+	body.WriteString(strings.TrimSpace(model.Summary.Text))
+	var unanchored []Comment
+	for _, c := range forgeComments(model) {
+		if gc, ok := githubAnchor(patches, c); ok {
+			review.Comments = append(review.Comments, gc)
+		} else {
+			unanchored = append(unanchored, c)
+		}
+	}
+	unanchoredList(&body, "Comments outside this pull request's diff:", unanchored)
+	review.Body = strings.TrimSpace(body.String())
+	return review
+}
+
+// postGitHubReview creates a pending review on pr with the finished
+// review's comments, for the token's owner to check and submit on GitHub.
+func postGitHubReview(ctx context.Context, client *forgeClient, pr GitHubPR, model *ReviewModel) (string, error) {
+	files, err := githubPRFiles(ctx, client, pr)
+	if err != nil {
+		return "", fmt.Errorf("github: list files of %s: %w", pr, err)
+	}
+	review := buildGitHubReview(model, githubPatches(files))
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d/reviews", pr.Owner, pr.Repo, pr.Number)
+	if err := client.do(ctx, http.MethodPost, path, review, &created); err != nil {
+		return "", fmt.Errorf("github: post review to %s: %w", pr, err)
+	}
+	return created.HTMLURL, nil
+}
+
+// githubPull is the part of a pull request --fetch-pr needs.
+type githubPull struct {
+	Head struct {
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+type githubContent struct {
+	Encoding string `json:"encoding"`
+	Content  string `json:"content"`
+}
+
+// fetchGitHubDiff downloads the unified diff of pr and returns it with the
+// commit the pull request's head is at.
+func fetchGitHubDiff(ctx context.Context, c *forgeClient, pr GitHubPR) (string, string, error) {
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d", pr.Owner, pr.Repo, pr.Number)
+	var pull githubPull
+	if err := c.do(ctx, http.MethodGet, path, nil, &pull); err != nil {
+		return "", "", fmt.Errorf("github: get %s: %w", pr, err)
+	}
+	diff, err := c.raw(ctx, path, "application/vnd.github.diff")
+	if err != nil {
+		return "", "", fmt.Errorf("github: get diff of %s: %w", pr, err)
+	}
+	return string(diff), pull.Head.SHA, nil
+}
+
+// fetchGitHubSources downloads each file of the pull request as of head, so
+// the unchanged code around its hunks can be expanded as it is for local
+// diffs. Files that are deleted, binary, too large for the API or that do
+// not match the diff are left without. Local files are never read, as they
+// need not be the pull request's.
+func fetchGitHubSources(ctx context.Context, c *forgeClient, pr GitHubPR, head string, files []DiffFile) error {
+	for i := range files {
+		file := &files[i]
+		file.sourceRead = true
+		if file.Status == DiffDeleted || file.Binary {
+			continue
+		}
+		segments := strings.Split(file.Path, "/")
+		for j, s := range segments {
+			segments[j] = url.PathEscape(s)
+		}
+		path := fmt.Sprintf("/repos/%s/%s/contents/%s?ref=%s", pr.Owner, pr.Repo, strings.Join(segments, "/"), url.QueryEscape(head))
+		var content githubContent
+		if err := c.do(ctx, http.MethodGet, path, nil, &content); err != nil {
+			return fmt.Errorf("github: get %s of %s: %w", file.Path, pr, err)
+		}
+		if content.Encoding != "base64" {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(content.Content, "\n", ""))
+		if err != nil || len(data) > largeFileThreshold {
+			continue
+		}
+		if content := decodeFileContent(data); matchesNewSide(file, content.lines) {
+			file.source = content
+		}
+	}
+	return nil
+}
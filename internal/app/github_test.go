@@ -0,0 +1,148 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseGitHubPR(t *testing.T) {
+	pr, err := ParseGitHubPR("jfyne/meatcheck#42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *pr != (GitHubPR{Owner: "jfyne", Repo: "meatcheck", Number: 42}) || pr.String() != "jfyne/meatcheck#42" {
+		t.Fatalf("unexpected pull request: %+v", pr)
+	}
+	for _, bad := range []string{"meatcheck#42", "jfyne/meatcheck", "jfyne/meatcheck#0", "jfyne/meat check#1"} {
+		if _, err := ParseGitHubPR(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+// TestPostGitHubReview verifies that comments on lines in the pull
+// request's diff are anchored to them and the rest go in the review body.
+func TestPostGitHubReview(t *testing.T) {
+	var posted githubReview
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/o/r/pulls/7/files":
+			_ = json.NewEncoder(w).Encode([]githubPRFile{{
+				Filename: "c.go",
+				Patch:    "@@ -9,4 +9,4 @@\n line 9\n-old 10\n+line 10\n line 11\n line 12",
+			}})
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/o/r/pulls/7/reviews":
+			if err := json.NewDecoder(r.Body).Decode(&posted); err != nil {
+				t.Errorf("decode review: %v", err)
+			}
+			_, _ = w.Write([]byte(`{"html_url":"https://github.com/o/r/pull/7#pullrequestreview-1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv(githubAPIEnv, srv.URL)
+
+	lines := make([]string, 40)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	model := &ReviewModel{Mode: ModeFile, Files: []File{{Path: "c.go", PathSlash: "c.go", Lines: lines}}, Summary: ReviewSummary{Text: "Nearly there."}, Comments: []Comment{
+		{ID: 1, Path: "c.go", StartLine: 10, EndLine: 11, Text: "range"},
+		{ID: 2, Path: "c.go", StartLine: 10, EndLine: 10, Side: "old", Text: "removed"},
+		{ID: 3, Path: "c.go", StartLine: 30, EndLine: 30, Text: "outside"},
+		{ID: 4, Path: "c.go", Text: "whole file"},
+		{ID: 5, Path: "c.go", StartLine: 9, EndLine: 9, Text: "rejected", Disposition: DispositionRejected},
+	}}
+	link, err := postGitHubReview(context.Background(), newGitHubClient("secret"), GitHubPR{Owner: "o", Repo: "r", Number: 7}, model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(link, "pullrequestreview-1") {
+		t.Fatalf("unexpected link %q", link)
+	}
+	want := []githubReviewComment{
+		{Path: "c.go", Body: "range", Line: 11, Side: "RIGHT", StartLine: 10, StartSide: "RIGHT"},
+		{Path: "c.go", Body: "removed", Line: 10, Side: "LEFT"},
+	}
+	if len(posted.Comments) != len(want) {
+		t.Fatalf("expected %d anchored comments, got %+v", len(want), posted.Comments)
+	}
+	for i := range want {
+		if posted.Comments[i] != want[i] {
+			t.Errorf("comment %d: expected %+v, got %+v", i, want[i], posted.Comments[i])
+		}
+	}
+	for _, s := range []string{"Nearly there.", "- `c.go` Line 30: outside", "- `c.go` File: whole file"} {
+		if !strings.Contains(posted.Body, s) {
+			t.Errorf("review body missing %q:\n%s", s, posted.Body)
+		}
+	}
+	if strings.Contains(posted.Body, "rejected") {
+		t.Error("expected rejected proposals to be left out")
+	}
+
+	if _, err := postGitHubReview(context.Background(), newGitHubClient("wrong"), GitHubPR{Owner: "o", Repo: "r", Number: 7}, model); err == nil || !strings.Contains(err.Error(), "Bad credentials") {
+		t.Fatalf("expected the API error to be reported, got %v", err)
+	}
+}
+
+// TestFetchGitHubPR verifies that --fetch-pr downloads the diff and, for
+// the files that match it, their contents at the pull request's head.
+func TestFetchGitHubPR(t *testing.T) {
+	const diff = "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -2,1 +2,1 @@\n-old\n+two\n" +
+		"diff --git a/dir/b c.go b/dir/b c.go\n--- a/dir/b c.go\n+++ b/dir/b c.go\n@@ -1,1 +1,1 @@\n-old\n+new\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected an anonymous request, got %q", r.Header.Get("Authorization"))
+		}
+		switch {
+		case r.URL.Path == "/repos/o/r/pulls/7" && r.Header.Get("Accept") == "application/vnd.github.diff":
+			_, _ = w.Write([]byte(diff))
+		case r.URL.Path == "/repos/o/r/pulls/7":
+			_, _ = w.Write([]byte(`{"head":{"sha":"abc"}}`))
+		case r.URL.Query().Get("ref") != "abc":
+			http.NotFound(w, r)
+		case r.URL.EscapedPath() == "/repos/o/r/contents/a.go":
+			_, _ = w.Write([]byte(`{"encoding":"base64","content":"b25lCnR3bwp0aHJl\nZQo=\n"}`))
+		case r.URL.EscapedPath() == "/repos/o/r/contents/dir/b%20c.go":
+			// Does not match the diff, so it must not be used.
+			_, _ = w.Write([]byte(`{"encoding":"base64","content":"b3RoZXIK"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv(githubAPIEnv, srv.URL)
+
+	client := newGitHubClient("")
+	pr := GitHubPR{Owner: "o", Repo: "r", Number: 7}
+	got, head, err := fetchGitHubDiff(context.Background(), client, pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != diff || head != "abc" {
+		t.Fatalf("unexpected diff %q at %q", got, head)
+	}
+	files, err := parseUnifiedDiff(got, diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fetchGitHubSources(context.Background(), client, pr, head, files); err != nil {
+		t.Fatal(err)
+	}
+	if src := diffSource(&files[0]); strings.Join(src, ",") != "one,two,three" {
+		t.Fatalf("unexpected source for a.go: %q", src)
+	}
+	if src := diffSource(&files[1]); src != nil {
+		t.Fatalf("expected no source for a file that does not match the diff, got %q", src)
+	}
+}
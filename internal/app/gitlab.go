@@ -0,0 +1,182 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// gitlabTokenEnv names the variable --gitlab-mr reads its token from.
+const gitlabTokenEnv = "GITLAB_TOKEN"
+
+// gitlabAPIEnv overrides the GitLab API root, as GitLab CI sets it for
+// self-managed instances.
+const gitlabAPIEnv = "CI_API_V4_URL"
+
+const defaultGitLabAPI = "https://gitlab.com/api/v4"
+
+// GitLabMR identifies the merge request --gitlab-mr posts the review to.
+// Project is the project's full path, including any subgroups.
+type GitLabMR struct {
+	Project string
+	IID     int
+}
+
+func (mr GitLabMR) String() string {
+	return fmt.Sprintf("%s!%d", mr.Project, mr.IID)
+}
+
+var gitlabMRPattern = regexp.MustCompile(`^([A-Za-z0-9_.-]+(?:/[A-Za-z0-9_.-]+)+)!([0-9]+)$`)
+
+// ParseGitLabMR parses a --gitlab-mr value of the form group/project!123.
+func ParseGitLabMR(s string) (*GitLabMR, error) {
+	m := gitlabMRPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil, fmt.Errorf("invalid --gitlab-mr %q: want group/project!123", s)
+	}
+	iid, err := strconv.Atoi(m[2])
+	if err != nil || iid <= 0 {
+		return nil, fmt.Errorf("invalid --gitlab-mr %q: bad merge request number", s)
+	}
+	return &GitLabMR{Project: m[1], IID: iid}, nil
+}
+
+type gitlabDiffRefs struct {
+	BaseSHA  string `json:"base_sha"`
+	StartSHA string `json:"start_sha"`
+	HeadSHA  string `json:"head_sha"`
+}
+
+type gitlabMergeRequest struct {
+	WebURL   string         `json:"web_url"`
+	DiffRefs gitlabDiffRefs `json:"diff_refs"`
+}
+
+type gitlabDiff struct {
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+	Diff    string `json:"diff"`
+}
+
+// gitlabPosition anchors a discussion to a line of the merge request's
+// diff. Removed lines have only OldLine, added lines only NewLine, and
+// unchanged lines both.
+type gitlabPosition struct {
+	PositionType string `json:"position_type"`
+	BaseSHA      string `json:"base_sha"`
+	StartSHA     string `json:"start_sha"`
+	HeadSHA      string `json:"head_sha"`
+	OldPath      string `json:"old_path"`
+	NewPath      string `json:"new_path"`
+	OldLine      int    `json:"old_line,omitempty"`
+	NewLine      int    `json:"new_line,omitempty"`
+}
+
+type gitlabDiscussion struct {
+	Body     string          `json:"body"`
+	Position *gitlabPosition `json:"position,omitempty"`
+}
+
+func newGitLabClient(token string) *forgeClient {
+	return newForgeClient(gitlabAPIEnv, defaultGitLabAPI, http.Header{
+		"Private-Token": {token},
+	})
+}
+
+func (mr GitLabMR) path() string {
+	return fmt.Sprintf("/projects/%s/merge_requests/%d", url.PathEscape(mr.Project), mr.IID)
+}
+
+// gitlabDiffs lists the files of the merge request with their hunks.
+func gitlabDiffs(ctx context.Context, c *forgeClient, mr GitLabMR) ([]gitlabDiff, error) {
+	const perPage = 100
+	var diffs []gitlabDiff
+	for page := 1; ; page++ {
+		var batch []gitlabDiff
+		path := fmt.Sprintf("%s/diffs?per_page=%d&page=%d", mr.path(), perPage, page)
+		if err := c.do(ctx, http.MethodGet, path, nil, &batch); err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, batch...)
+		if len(batch) < perPage {
+			return diffs, nil
+		}
+	}
+}
+
+// gitlabAnchor places c on the line of the merge request's diff it ends
+// on. It reports false when the comment is file-level, on an earlier commit
+// of a patch series or the line is not in the diff.
+func gitlabAnchor(diffs map[string]gitlabDiff, refs gitlabDiffRefs, c Comment) (*gitlabPosition, bool) {
+	d, ok := diffs[c.Path]
+	if !ok || c.isFileLevel() || c.Commit != "" {
+		return nil, false
+	}
+	file := parsePatch(d.OldPath, d.NewPath, d.Diff)
+	if file == nil {
+		return nil, false
+	}
+	dl, _, ok := findDiffLine(file, c.Side == "old", c.EndLine)
+	if !ok {
+		return nil, false
+	}
+	pos := &gitlabPosition{
+		PositionType: "text",
+		BaseSHA:      refs.BaseSHA,
+		StartSHA:     refs.StartSHA,
+		HeadSHA:      refs.HeadSHA,
+		OldPath:      d.OldPath,
+		NewPath:      d.NewPath,
+	}
+	if dl.Kind != DiffAdd {
+		pos.OldLine = dl.OldLine
+	}
+	if dl.Kind != DiffDel {
+		pos.NewLine = dl.NewLine
+	}
+	return pos, true
+}
+
+// postGitLabDiscussions starts a discussion on mr for each comment that
+// can be placed on the diff, then one general discussion with the review
+// summary and the comments that could not. It returns the merge request's
+// URL.
+func postGitLabDiscussions(ctx context.Context, client *forgeClient, mr GitLabMR, model *ReviewModel) (string, error) {
+	var req gitlabMergeRequest
+	if err := client.do(ctx, http.MethodGet, mr.path(), nil, &req); err != nil {
+		return "", fmt.Errorf("gitlab: get %s: %w", mr, err)
+	}
+	list, err := gitlabDiffs(ctx, client, mr)
+	if err != nil {
+		return "", fmt.Errorf("gitlab: list diffs of %s: %w", mr, err)
+	}
+	diffs := make(map[string]gitlabDiff, len(list))
+	for _, d := range list {
+		diffs[d.NewPath] = d
+	}
+
+	var body strings.Builder
+	body.WriteString(strings.TrimSpace(model.Summary.Text))
+	var unanchored []Comment
+	for _, c := range forgeComments(model) {
+		pos, ok := gitlabAnchor(diffs, req.DiffRefs, c)
+		if !ok {
+			unanchored = append(unanchored, c)
+			continue
+		}
+		if err := client.do(ctx, http.MethodPost, mr.path()+"/discussions", gitlabDiscussion{Body: c.Text, Position: pos}, nil); err != nil {
+			return "", fmt.Errorf("gitlab: post comment on %s %s to %s: %w", c.Path, markdownLocation(c), mr, err)
+		}
+	}
+	unanchoredList(&body, "Comments outside this merge request's diff:", unanchored)
+	if text := strings.TrimSpace(body.String()); text != "" {
+		if err := client.do(ctx, http.MethodPost, mr.path()+"/discussions", gitlabDiscussion{Body: text}, nil); err != nil {
+			return "", fmt.Errorf("gitlab: post summary to %s: %w", mr, err)
+		}
+	}
+	return req.WebURL, nil
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseGitLabMR(t *testing.T) {
+	mr, err := ParseGitLabMR("group/sub/project!12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *mr != (GitLabMR{Project: "group/sub/project", IID: 12}) || mr.String() != "group/sub/project!12" {
+		t.Fatalf("unexpected merge request: %+v", mr)
+	}
+	for _, bad := range []string{"project!12", "group/project", "group/project!0", "group/project#12"} {
+		if _, err := ParseGitLabMR(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+// TestPostGitLabDiscussions verifies that comments on the diff become
+// positioned discussions, with both line numbers on unchanged lines, and
+// that the rest go in one general discussion with the summary.
+func TestPostGitLabDiscussions(t *testing.T) {
+	var posted []gitlabDiscussion
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Private-Token") != "secret" {
+			http.Error(w, `{"message":"401 Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		switch path := r.URL.EscapedPath(); {
+		case r.Method == http.MethodGet && path == "/projects/g%2Fp/merge_requests/3":
+			_, _ = w.Write([]byte(`{"web_url":"https://gitlab.example/g/p/-/merge_requests/3","diff_refs":{"base_sha":"b","start_sha":"s","head_sha":"h"}}`))
+		case r.Method == http.MethodGet && path == "/projects/g%2Fp/merge_requests/3/diffs":
+			_ = json.NewEncoder(w).Encode([]gitlabDiff{{
+				OldPath: "old.go",
+				NewPath: "c.go",
+				Diff:    "@@ -9,3 +9,3 @@\n line 9\n-old 10\n+line 10\n line 11\n",
+			}})
+		case r.Method == http.MethodPost && path == "/projects/g%2Fp/merge_requests/3/discussions":
+			var d gitlabDiscussion
+			if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+				t.Errorf("decode discussion: %v", err)
+			}
+			posted = append(posted, d)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv(gitlabAPIEnv, srv.URL)
+
+	lines := make([]string, 40)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	model := &ReviewModel{Mode: ModeFile, Files: []File{{Path: "c.go", PathSlash: "c.go", Lines: lines}}, Summary: ReviewSummary{Text: "Nearly there."}, Comments: []Comment{
+		{ID: 1, Path: "c.go", StartLine: 11, EndLine: 11, Text: "context"},
+		{ID: 2, Path: "c.go", StartLine: 10, EndLine: 10, Side: "old", Text: "removed"},
+		{ID: 3, Path: "c.go", StartLine: 10, EndLine: 10, Text: "added"},
+		{ID: 4, Path: "c.go", StartLine: 30, EndLine: 30, Text: "outside"},
+	}}
+	mr := GitLabMR{Project: "g/p", IID: 3}
+	link, err := postGitLabDiscussions(context.Background(), newGitLabClient("secret"), mr, model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != "https://gitlab.example/g/p/-/merge_requests/3" {
+		t.Fatalf("unexpected link %q", link)
+	}
+	if len(posted) != 4 {
+		t.Fatalf("expected 4 discussions, got %+v", posted)
+	}
+	base := gitlabPosition{PositionType: "text", BaseSHA: "b", StartSHA: "s", HeadSHA: "h", OldPath: "old.go", NewPath: "c.go"}
+	for i, want := range []struct{ old, new int }{{11, 11}, {10, 0}, {0, 10}} {
+		pos := base
+		pos.OldLine, pos.NewLine = want.old, want.new
+		if posted[i].Position == nil || *posted[i].Position != pos {
+			t.Errorf("discussion %d: expected %+v, got %+v", i, pos, posted[i].Position)
+		}
+	}
+	general := posted[3]
+	if general.Position != nil || !strings.Contains(general.Body, "Nearly there.") || !strings.Contains(general.Body, "- `c.go` Line 30: outside") {
+		t.Fatalf("unexpected general discussion: %+v", general)
+	}
+
+	if _, err := postGitLabDiscussions(context.Background(), newGitLabClient("wrong"), mr, model); err == nil || !strings.Contains(err.Error(), "401 Unauthorized") {
+		t.Fatalf("expected the API error to be reported, got %v", err)
+	}
+}
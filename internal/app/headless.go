@@ -0,0 +1,158 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// headlessReviewer is the reviewer a --headless run records its verdict and
+// comments under.
+const headlessReviewer = "auto"
+
+// AutoReview is the synthetic result of a --headless run: the verdict and
+// comments a reviewer would have left before pressing Finish.
+type AutoReview struct {
+	Verdict  Verdict
+	Comments []Comment
+}
+
+// ParseAutoFlag parses --auto, a comma-separated list of key=value pairs:
+//
+//	verdict=approve|comment|request-changes
+//	comments-file=path   JSON array of comments, as in the review output
+func ParseAutoFlag(spec string) (*AutoReview, error) {
+	review := &AutoReview{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --auto option %q: expected key=value", part)
+		}
+		switch strings.TrimSpace(key) {
+		case "verdict":
+			v := Verdict(strings.TrimSpace(value))
+			if !validVerdict(v) {
+				return nil, fmt.Errorf("invalid --auto verdict %q", value)
+			}
+			review.Verdict = v
+		case "comments-file":
+			comments, err := readAutoComments(strings.TrimSpace(value))
+			if err != nil {
+				return nil, err
+			}
+			review.Comments = comments
+		default:
+			return nil, fmt.Errorf("unknown --auto option %q", key)
+		}
+	}
+	return review, nil
+}
+
+func readAutoComments(path string) ([]Comment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read comments file: %w", err)
+	}
+	var comments []Comment
+	if err := json.Unmarshal(data, &comments); err != nil {
+		return nil, fmt.Errorf("parse comments file: %w", err)
+	}
+	for i, c := range comments {
+		if strings.TrimSpace(c.Text) == "" {
+			return nil, fmt.Errorf("comment at index %d has no text", i)
+		}
+		if strings.TrimSpace(c.Path) == "" {
+			return nil, fmt.Errorf("comment at index %d has no path", i)
+		}
+		if err := normalizeWeight(&comments[i]); err != nil {
+			return nil, fmt.Errorf("comment at index %d: %w", i, err)
+		}
+	}
+	return comments, nil
+}
+
+// applyAutoReview completes the review with review's verdict and comments,
+// as if a reviewer named headlessReviewer had written them and finished.
+// Comments keep whatever anchors they were given; ones that do not fit the
+// files under review are reported like any other invalid comment.
+func applyAutoReview(model *ReviewModel, review *AutoReview) {
+	if review != nil {
+		for _, c := range review.Comments {
+			importComment(model, &c)
+			model.NextCommentID++
+			c.ID = model.NextCommentID
+			c.UID = newCommentUID()
+			c.Text = strings.TrimSpace(c.Text)
+			if c.StartLine != 0 && c.EndLine == 0 {
+				c.EndLine = c.StartLine
+			}
+			if c.Author == "" {
+				c.Author = headlessReviewer
+			}
+			c.Disposition = DispositionNone
+			c.Status = ThreadNone
+			model.Comments = append(model.Comments, c)
+		}
+		if review.Verdict != VerdictNone {
+			addReviewer(model, headlessReviewer)
+			setVerdict(model, headlessReviewer, review.Verdict)
+		}
+	}
+	model.Completed = true
+	model.CompletedBy = headlessReviewer
+}
+
+// reviewState is the review as GET /api/state reports it: the progress
+// /api/partial returns, plus what is under review.
+type reviewState struct {
+	partialReview
+	Mode   ViewMode `json:"mode"`
+	Files  []string `json:"files"`
+	Prompt string   `json:"prompt,omitempty"`
+}
+
+func stateOf(model *ReviewModel) reviewState {
+	return reviewState{
+		partialReview: partialResult(model),
+		Mode:          model.Mode,
+		Files:         reviewPaths(model),
+		Prompt:        model.Prompt,
+	}
+}
+
+// addReviewerComment adds c as written by reviewer, as if they had left it
+// in the browser, for scripts that review without the UI.
+func addReviewerComment(model *ReviewModel, c Comment, reviewer string) (Comment, error) {
+	c.Author = reviewer
+	c, err := addAPIComment(model, c, DispositionNone)
+	if err == nil {
+		addReviewer(model, reviewer)
+	}
+	return c, err
+}
+
+// finishFromAPI completes the review for reviewer, first recording verdict
+// v unless it is VerdictNone. Like a second press of Finish, it does not
+// stop for stale files. It reports false when the review was already
+// finished.
+func (rs *ReviewServer) finishFromAPI(reviewer string, v Verdict) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	model := rs.Model
+	if model.Completed {
+		return false
+	}
+	if v != VerdictNone {
+		addReviewer(model, reviewer)
+		setVerdict(model, reviewer, v)
+	}
+	model.Completed = true
+	model.CompletedBy = reviewer
+	rs.events.emit(eventFinished, "comments", len(model.Comments), "verdict", model.Verdict, "by", model.CompletedBy)
+	return true
+}
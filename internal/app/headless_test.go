@@ -0,0 +1,206 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseAutoFlag(t *testing.T) {
+	path := writeTempFile(t, "comments.json", `[
+		{"path": "a.go", "start_line": 2, "text": "rename this"},
+		{"path": "a.go", "text": "file-level note", "author": "ci"}
+	]`)
+	review, err := ParseAutoFlag("verdict=approve, comments-file=" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if review.Verdict != VerdictApprove || len(review.Comments) != 2 {
+		t.Fatalf("unexpected review: %+v", review)
+	}
+
+	empty := writeTempFile(t, "empty.json", `[{"path": "a.go", "text": " "}]`)
+	for _, spec := range []string{
+		"verdict=lgtm",
+		"verdict",
+		"mood=happy",
+		"comments-file=" + empty,
+		"comments-file=/does/not/exist.json",
+	} {
+		if _, err := ParseAutoFlag(spec); err == nil {
+			t.Errorf("ParseAutoFlag(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+// TestApplyAutoReview verifies that a headless run produces the same
+// document a reviewer finishing the session would.
+func TestApplyAutoReview(t *testing.T) {
+	model := &ReviewModel{
+		Files: []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"package a", "func A() {}"}}},
+		Mode:  ModeFile,
+	}
+	applyAutoReview(model, &AutoReview{
+		Verdict: VerdictRequestChanges,
+		Comments: []Comment{
+			{Path: "a.go", StartLine: 2, Text: " rename this ", Disposition: DispositionAccepted, Priority: PriorityP1, Confidence: "high"},
+			{Path: "a.go", Text: "file-level note", Author: "ci"},
+		},
+	})
+	if !model.Completed || model.CompletedBy != headlessReviewer {
+		t.Fatalf("expected the review to be completed by %s", headlessReviewer)
+	}
+	if model.Verdict != VerdictRequestChanges {
+		t.Fatalf("expected request-changes, got %q", model.Verdict)
+	}
+
+	var buf bytes.Buffer
+	if err := emitReview(&buf, model); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`auto,"",high,"",2,1,a.go,P1,"",2,"","",rename this`,
+		`ci,"","","",0,2,a.go,"","",0,"","",file-level note`,
+		"verdict: request-changes",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if err := validateDocument(reviewDocument(model)); err != nil {
+		t.Fatalf("headless result does not match the schema: %v", err)
+	}
+}
+
+// TestRunWritesOutputFile verifies that --output writes the result to a
+// file instead of stdout, and that a missing directory fails up front.
+func TestRunWritesOutputFile(t *testing.T) {
+	isolatePreferences(t)
+	src := writeTempFile(t, "a.go", "package a\n")
+	out := filepath.Join(t.TempDir(), "review.json")
+	cfg := Config{
+		Paths:        []string{src},
+		Headless:     true,
+		Auto:         &AutoReview{Verdict: VerdictApprove, Comments: []Comment{{Path: src, StartLine: 1, Text: "fine"}}},
+		Output:       out,
+		OutputFormat: outputJSON,
+	}
+	if err := Run(t.Context(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, data)
+	}
+	if comments := doc["comments"].([]any); len(comments) != 1 {
+		t.Fatalf("expected one comment, got %v", comments)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(out)); len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left behind, got %v", entries)
+	}
+
+	cfg.Output = filepath.Join(t.TempDir(), "missing", "review.json")
+	if err := Run(t.Context(), cfg); err == nil || !strings.Contains(err.Error(), "--output") {
+		t.Errorf("expected a missing output directory to be refused, got %v", err)
+	}
+}
+
+// TestHeadlessAPI verifies that a script can read the review state, comment
+// as a reviewer and finish the review through the API alone.
+func TestHeadlessAPI(t *testing.T) {
+	model := buildCommentModel()
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	srv := httptest.NewServer(apiHandler(rs, nil))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state map[string]any
+	err = json.NewDecoder(resp.Body).Decode(&state)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state["mode"] != string(ModeFile) || len(state["files"].([]any)) != 1 || state["completed"] != false || state["file_count"] != float64(1) {
+		t.Fatalf("unexpected state %v", state)
+	}
+
+	post := func(path, body string) int {
+		t.Helper()
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if got := post("/api/comments", `{"path": "test.go", "start_line": 1, "text": "Needs a test.", "reviewer": "ci"}`); got != http.StatusCreated {
+		t.Fatalf("post comment: got status %d", got)
+	}
+	c := model.Comments[len(model.Comments)-1]
+	if c.Author != "ci" || c.Disposition != DispositionNone || !slices.Contains(model.Reviewers, "ci") {
+		t.Fatalf("expected a reviewer comment by ci, got %+v", c)
+	}
+
+	if got := post("/api/finish", `{"verdict": "maybe"}`); got != http.StatusBadRequest {
+		t.Fatalf("invalid verdict: got status %d", got)
+	}
+	if got := post("/api/finish", `{"reviewer": "ci", "verdict": "request-changes"}`); got != http.StatusOK {
+		t.Fatalf("finish: got status %d", got)
+	}
+	select {
+	case <-rs.DoneCh:
+	default:
+		t.Fatal("expected the session to end")
+	}
+	if !model.Completed || model.CompletedBy != "ci" || model.Verdict != VerdictRequestChanges {
+		t.Fatalf("unexpected result: completed %v by %q, verdict %q", model.Completed, model.CompletedBy, model.Verdict)
+	}
+	if got := post("/api/finish", ``); got != http.StatusConflict {
+		t.Fatalf("second finish: got status %d", got)
+	}
+}
+
+// TestReviewResult verifies that Review returns the finished review instead
+// of printing it, setting aside comments whose lines are not under review.
+func TestReviewResult(t *testing.T) {
+	isolatePreferences(t)
+	path := writeTempFile(t, "a.go", "package a\nfunc A() {}\n")
+	result, err := Review(context.Background(), Config{
+		Paths:    []string{path},
+		Headless: true,
+		Auto: &AutoReview{
+			Verdict: VerdictApprove,
+			Comments: []Comment{
+				{Path: path, StartLine: 2, Text: "rename this"},
+				{Path: path, StartLine: 9, Text: "past the end"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Comments) != 1 || result.Comments[0].Text != "rename this" {
+		t.Fatalf("unexpected comments %+v", result.Comments)
+	}
+	if len(result.Dropped) != 1 || result.Dropped[0].StartLine != 9 {
+		t.Fatalf("unexpected dropped comments %+v", result.Dropped)
+	}
+	if result.Verdict != VerdictApprove || result.Verdicts[headlessReviewer] != VerdictApprove || result.CompletedBy != headlessReviewer {
+		t.Fatalf("unexpected verdict %+v", result)
+	}
+}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"html/template"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/jfyne/meatcheck/internal/highlight"
+)
+
+// highlightCacheBytes bounds the highlighted HTML each code renderer of a
+// review keeps. It holds the files and hunks of a large review, so switching
+// between them does not tokenise the same content again, without keeping
+// every file of a huge one.
+const highlightCacheBytes = 64 << 20
+
+// highlighter highlights code with one of the review's renderers, which
+// cache what they render by path, language and content. Views are built
+// with a nil highlighter when code is shown plain.
+type highlighter struct {
+	renderer *highlight.Renderer
+}
+
+// highlighter returns the highlighter for the view's whitespace setting.
+func (v *ReviewView) highlighter() *highlighter {
+	return &highlighter{renderer: v.renderers().codeFor(v.ShowWhitespace)}
+}
+
+// fileLines returns lines highlighted as language ("" to detect it) for a
+// whole file.
+func (hl *highlighter) fileLines(path, language string, lines []string) []template.HTML {
+	return hl.renderer.RenderLinesAs(path, language, lines)
+}
+
+// hunkLines returns the lines of hunk index of a diff file highlighted.
+func (hl *highlighter) hunkLines(file *DiffFile, index int) []template.HTML {
+	return hl.renderer.RenderLinesAs(file.Path, file.Language, hunkTexts(file.Hunks[index]))
+}
+
+func hunkTexts(h DiffHunk) []string {
+	texts := make([]string, 0, len(h.Lines))
+	for _, dl := range h.Lines {
+		texts = append(texts, dl.Text)
+	}
+	return texts
+}
+
+// windowLines returns lines of an indexed file, read for one window of it,
+// highlighted.
+func (hl *highlighter) windowLines(file *File, lines []string) []template.HTML {
+	return hl.renderer.RenderLinesAs(file.Path, file.Language, lines)
+}
+
+// warmupInterval is how often the header's highlighting progress is updated.
+const warmupInterval = 250 * time.Millisecond
+
+// highlightProgress counts the highlighting started in the background at
+// startup and how much of it has finished, so the header can show progress
+// while the cache warms.
+type highlightProgress struct {
+	total int
+	done  atomic.Int64
+}
+
+func (p *highlightProgress) counts() (done, total int) {
+	return int(p.done.Load()), p.total
+}
+
+// prehighlight highlights files, and the hunks of diff files, in the
+// background with a pool bounded by the number of CPUs, so the first visit
+// to each is served from the cache. Large files are skipped since they are
+// rendered window by window, as are the files of a diff too large to keep
+// in memory, and binary files since they are not rendered at all. So are
+// collapsed files, which are rarely opened.
+func prehighlight(hl *highlighter, files []File, diffFiles []DiffFile, collapsed map[string]string) *highlightProgress {
+	var jobs []func()
+	for _, f := range files {
+		if f.Size > largeFileThreshold || f.Binary || collapsed[f.Path] != "" {
+			continue
+		}
+		jobs = append(jobs, func() {
+			if lines, err := readFileLines(f.Path); err == nil {
+				hl.fileLines(f.Path, f.Language, lines)
+			}
+		})
+	}
+	for _, df := range diffFiles {
+		if df.Binary || df.section != nil || collapsed[df.Path] != "" {
+			continue
+		}
+		for _, h := range df.Hunks {
+			// The texts are taken now; handlers may change the hunks once
+			// the review is served.
+			texts := hunkTexts(h)
+			jobs = append(jobs, func() {
+				hl.renderer.RenderLinesAs(df.Path, df.Language, texts)
+			})
+		}
+	}
+	progress := &highlightProgress{total: len(jobs)}
+	work := make(chan func())
+	for range min(runtime.NumCPU(), len(jobs)) {
+		go func() {
+			for job := range work {
+				job()
+				progress.done.Add(1)
+			}
+		}()
+	}
+	go func() {
+		for _, job := range jobs {
+			work <- job
+		}
+		close(work)
+	}()
+	return progress
+}
+
+// watchWarmup copies the background highlighting progress into the model
+// every warmupInterval, calling notify so connected views show it, until all
+// of it is done or stop is called.
+func (rs *ReviewServer) watchWarmup(notify func()) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(warmupInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			rs.mu.Lock()
+			warmed := rs.Model.WarmDone
+			rs.Model.WarmDone, rs.Model.WarmTotal = rs.Model.warmup.counts()
+			rs.Model.Warming = rs.Model.WarmDone < rs.Model.WarmTotal
+			changed, finished := rs.Model.WarmDone != warmed, !rs.Model.Warming
+			rs.mu.Unlock()
+			if changed {
+				notify()
+			}
+			if finished {
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestHighlighterUsesCache verifies that highlighted lines are reused for
+// identical content and re-rendered when the content or renderer changes.
+func TestHighlighterUsesCache(t *testing.T) {
+	view := &ReviewView{ReviewModel: &ReviewModel{render: newRenderers(defaultTabWidth, false)}}
+	lines := []string{"package cache"}
+	first := view.highlighter().fileLines("cache_test_a.go", "", lines)
+	if len(first) != 1 {
+		t.Fatalf("expected 1 rendered line, got %d", len(first))
+	}
+	if again := view.highlighter().fileLines("cache_test_a.go", "", []string{"package cache"}); &again[0] != &first[0] {
+		t.Fatal("expected the highlighted lines to be reused")
+	}
+	if other := view.highlighter().fileLines("cache_test_a.go", "", []string{"package other"}); &other[0] == &first[0] {
+		t.Fatal("expected different content to be highlighted again")
+	}
+	view.ShowWhitespace = true
+	if marked := view.highlighter().fileLines("cache_test_a.go", "", lines); &marked[0] == &first[0] {
+		t.Fatal("expected the whitespace renderer to highlight the lines itself")
+	}
+}
+
+// TestRenderHunkLinesUsesCache verifies that each hunk of a diff file is
+// highlighted once and reused across view rebuilds until its lines change.
+func TestRenderHunkLinesUsesCache(t *testing.T) {
+	files, err := parseUnifiedDiff("diff --git a/cache_test_hunk.go b/cache_test_hunk.go\n--- a/cache_test_hunk.go\n+++ b/cache_test_hunk.go\n"+
+		"@@ -1,1 +1,1 @@\n-package old\n+package hunk\n"+
+		"@@ -10,1 +10,1 @@\n-var a = 1\n+var b = 2\n", diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{ReviewModel: &ReviewModel{Mode: ModeDiff, DiffFiles: files, render: newRenderers(defaultTabWidth, false)}, SelectedPath: "cache_test_hunk.go", RenderFile: true}
+	updateView(model)
+	hl := model.highlighter()
+	first := hl.renderer.RenderLinesAs("cache_test_hunk.go", "", []string{"package old", "package hunk"})
+	second := hl.renderer.RenderLinesAs("cache_test_hunk.go", "", []string{"var a = 1", "var b = 2"})
+	if &first[0] == &second[0] {
+		t.Fatal("expected each hunk to be highlighted on its own")
+	}
+
+	updateView(model)
+	if got := hl.hunkLines(&files[0], 0); &got[0] != &first[0] {
+		t.Fatal("expected the cached highlights of the first hunk to be reused")
+	}
+	if got := hl.hunkLines(&files[0], 1); &got[0] != &second[0] {
+		t.Fatal("expected the cached highlights of the second hunk to be reused")
+	}
+
+	files[0].Hunks[1].Lines[1].Text = "var c = 3"
+	if got := hl.hunkLines(&files[0], 1); &got[0] == &second[0] {
+		t.Fatal("expected changed hunk lines to be highlighted again")
+	}
+}
+
+// TestPrehighlightFillsCache verifies that background highlighting populates
+// the cache for loaded files and diff hunks, and counts its progress.
+func TestPrehighlightFillsCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pre.go")
+	if err := os.WriteFile(path, []byte("package pre\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := loadFiles([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffFiles := []DiffFile{{Path: "pre_hunk.go", Hunks: []DiffHunk{{
+		NewStart: 1, NewCount: 1,
+		Lines: []DiffLine{{Kind: DiffAdd, NewLine: 1, Text: "package hunk"}},
+	}}}}
+
+	hl := (&ReviewView{ReviewModel: &ReviewModel{render: newRenderers(defaultTabWidth, false)}}).highlighter()
+	progress := prehighlight(hl, files, diffFiles, nil)
+	if _, total := progress.counts(); total != 2 {
+		t.Fatalf("expected 2 highlighting jobs, got %d", total)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if done, total := progress.counts(); done == total {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for background highlighting")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// The cache hands back the very fragments prehighlight rendered.
+	file, again := hl.fileLines(path, "", []string{"package pre"}), hl.fileLines(path, "", []string{"package pre"})
+	if &file[0] != &again[0] {
+		t.Fatal("expected the file to be cached")
+	}
+	hunk, again := hl.hunkLines(&diffFiles[0], 0), hl.hunkLines(&diffFiles[0], 0)
+	if &hunk[0] != &again[0] {
+		t.Fatal("expected the hunk to be cached")
+	}
+}
+
+// TestWatchWarmup verifies that highlighting progress is copied into the
+// model and Warming cleared once it is done.
+func TestWatchWarmup(t *testing.T) {
+	progress := &highlightProgress{total: 2}
+	model := &ReviewModel{Warming: true, WarmTotal: 2, warmup: progress}
+	rs := &ReviewServer{Model: model}
+	notified := make(chan struct{}, 4)
+	stop := rs.watchWarmup(func() { notified <- struct{}{} })
+	defer stop()
+
+	progress.done.Add(2)
+	select {
+	case <-notified:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a progress update")
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if model.Warming || model.WarmDone != 2 {
+		t.Fatalf("expected warming done, got warming=%v done=%d", model.Warming, model.WarmDone)
+	}
+}
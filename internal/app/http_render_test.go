@@ -3,6 +3,8 @@ package app
 import (
 	"context"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -10,14 +12,16 @@ import (
 )
 
 func TestHTTPRenderIncludesThemeClass(t *testing.T) {
-	model := &ReviewModel{
-		Files:                []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"package main"}}},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files:  []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"package main"}}},
+			Mode:   ModeFile,
+			Viewed: make(map[string]bool),
+			Ranges: map[string][]LineRange{},
+		},
 		SelectedPath:         "a.go",
-		Mode:                 ModeFile,
 		RenderFile:           true,
 		RenderComments:       true,
-		Viewed:               make(map[string]bool),
-		Ranges:               map[string][]LineRange{},
 		MarkdownRenderByPath: map[string]bool{},
 	}
 	model.Tree = buildTree(model.Files, model.SelectedPath, nil, nil)
@@ -29,20 +33,22 @@ func TestHTTPRenderIncludesThemeClass(t *testing.T) {
 }
 
 func TestHTTPRenderFileModeCommentFormAutofocus(t *testing.T) {
-	model := &ReviewModel{
-		Files: []File{{
-			Path:      "a.go",
-			PathSlash: "a.go",
-			Lines:     []string{"package main", "func main() {}"},
-		}},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{
+				Path:      "a.go",
+				PathSlash: "a.go",
+				Lines:     []string{"package main", "func main() {}"},
+			}},
+			Mode:   ModeFile,
+			Viewed: make(map[string]bool),
+			Ranges: map[string][]LineRange{},
+		},
 		SelectedPath:         "a.go",
 		SelectionStart:       2,
 		SelectionEnd:         2,
-		Mode:                 ModeFile,
 		RenderFile:           true,
 		RenderComments:       true,
-		Viewed:               make(map[string]bool),
-		Ranges:               map[string][]LineRange{},
 		MarkdownRenderByPath: map[string]bool{},
 	}
 	model.Tree = buildTree(model.Files, model.SelectedPath, nil, nil)
@@ -54,27 +60,29 @@ func TestHTTPRenderFileModeCommentFormAutofocus(t *testing.T) {
 }
 
 func TestHTTPRenderDiffModeCommentFormAutofocus(t *testing.T) {
-	model := &ReviewModel{
-		DiffFiles: []DiffFile{{
-			Path: "a.go",
-			Hunks: []DiffHunk{{
-				OldStart: 1,
-				OldCount: 1,
-				NewStart: 1,
-				NewCount: 1,
-				Lines: []DiffLine{
-					{Kind: DiffContext, OldLine: 1, NewLine: 1, Text: "package main"},
-				},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			DiffFiles: []DiffFile{{
+				Path: "a.go",
+				Hunks: []DiffHunk{{
+					OldStart: 1,
+					OldCount: 1,
+					NewStart: 1,
+					NewCount: 1,
+					Lines: []DiffLine{
+						{Kind: DiffContext, OldLine: 1, NewLine: 1, Text: "package main"},
+					},
+				}},
 			}},
-		}},
+			Mode:   ModeDiff,
+			Viewed: make(map[string]bool),
+			Ranges: map[string][]LineRange{},
+		},
 		SelectedPath:         "a.go",
 		SelectionStart:       1,
 		SelectionEnd:         1,
-		Mode:                 ModeDiff,
 		RenderFile:           true,
 		RenderComments:       true,
-		Viewed:               make(map[string]bool),
-		Ranges:               map[string][]LineRange{},
 		MarkdownRenderByPath: map[string]bool{},
 	}
 	model.Tree = buildTree(diffFilesAsFiles(model.DiffFiles), model.SelectedPath, nil, nil)
@@ -88,30 +96,32 @@ func TestHTTPRenderDiffModeCommentFormAutofocus(t *testing.T) {
 // buildCommentModel returns a ReviewModel in file mode with a single comment
 // (ID 1, path "test.go", line 1, text "hello") ready for rendering.
 // Callers may mutate the returned model before calling renderReviewHTML.
-func buildCommentModel() *ReviewModel {
-	return &ReviewModel{
-		Files: []File{{
-			Path:      "test.go",
-			PathSlash: "test.go",
-			Lines:     []string{"package main"},
-		}},
-		SelectedPath:   "test.go",
-		Mode:           ModeFile,
-		RenderFile:     true,
-		RenderComments: true,
-		Viewed:         make(map[string]bool),
-		NextCommentID:  1,
-		Comments: []Comment{
-			{ID: 1, Path: "test.go", StartLine: 1, EndLine: 1, Text: "hello"},
+func buildCommentModel() *ReviewView {
+	return &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{
+				Path:      "test.go",
+				PathSlash: "test.go",
+				Lines:     []string{"package main"},
+			}},
+			Mode:          ModeFile,
+			Viewed:        make(map[string]bool),
+			NextCommentID: 1,
+			Comments: []Comment{
+				{ID: 1, UID: "c0ffee00-0000-4000-8000-000000000001", Path: "test.go", StartLine: 1, EndLine: 1, Text: "hello"},
+			},
+			Ranges: map[string][]LineRange{},
 		},
-		Ranges:               map[string][]LineRange{},
+		SelectedPath:         "test.go",
+		RenderFile:           true,
+		RenderComments:       true,
 		MarkdownRenderByPath: map[string]bool{},
 	}
 }
 
 // TestRenderCommentEditDeleteButtons verifies that each rendered comment
 // displays an edit button (data-action="start-edit-comment") and a delete
-// button (data-action="delete-comment"), both carrying the comment's ID.
+// button (data-action="delete-comment"), both carrying the comment's UID.
 //
 // Scenario: Edit and delete buttons visible on comments
 func TestRenderCommentEditDeleteButtons(t *testing.T) {
@@ -126,13 +136,17 @@ func TestRenderCommentEditDeleteButtons(t *testing.T) {
 	if !strings.Contains(html, `data-action="delete-comment"`) {
 		t.Errorf("expected delete-comment button in rendered HTML, got: %q", html)
 	}
-	if !strings.Contains(html, `data-comment-id="1"`) {
-		t.Errorf("expected data-comment-id=\"1\" on comment action buttons, got: %q", html)
+	if !strings.Contains(html, `data-comment-uid="c0ffee00-0000-4000-8000-000000000001"`) {
+		t.Errorf("expected data-comment-uid on comment action buttons, got: %q", html)
+	}
+	if !strings.Contains(html, `<code class="comment-uid" title="Comment ID c0ffee00-0000-4000-8000-000000000001; stable across review rounds">c0ffee00</code>`) {
+		t.Errorf("expected the comment's short UID in its header, got: %q", html)
 	}
 }
 
 // TestRenderCommentEditForm verifies that when EditingCommentID matches a
-// comment's ID the rendered HTML shows an edit form pre-filled with the
+// comment's ID the rendered HTML shows an edit form, identifying the comment
+// by its UID and pre-filled with the
 // comment text, and hides the edit/delete action buttons for that comment.
 //
 // Scenario: Edit form appears with pre-filled text
@@ -153,11 +167,8 @@ func TestRenderCommentEditForm(t *testing.T) {
 	if !strings.Contains(html, `hello`) {
 		t.Errorf("expected comment text \"hello\" pre-filled in textarea, got: %q", html)
 	}
-	if !strings.Contains(html, `name="id"`) {
-		t.Errorf("expected hidden id input (name=\"id\") in edit form, got: %q", html)
-	}
-	if !strings.Contains(html, `value="1"`) {
-		t.Errorf("expected value=\"1\" on hidden id input, got: %q", html)
+	if !strings.Contains(html, `<input type="hidden" name="uid" value="c0ffee00-0000-4000-8000-000000000001" />`) {
+		t.Errorf("expected hidden uid input in edit form, got: %q", html)
 	}
 	// Edit and delete action buttons should be hidden while editing.
 	if strings.Contains(html, `data-action="start-edit-comment"`) {
@@ -193,15 +204,17 @@ func TestRenderCommentNotEditing(t *testing.T) {
 
 // buildMinimalModel returns the smallest valid ReviewModel that produces a
 // full HTML render (including inline CSS). It is used by CSS presence tests.
-func buildMinimalModel() *ReviewModel {
-	m := &ReviewModel{
-		Files:                []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"x"}}},
+func buildMinimalModel() *ReviewView {
+	m := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files:  []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"x"}}},
+			Mode:   ModeFile,
+			Viewed: make(map[string]bool),
+			Ranges: map[string][]LineRange{},
+		},
 		SelectedPath:         "a.go",
-		Mode:                 ModeFile,
 		RenderFile:           true,
 		RenderComments:       true,
-		Viewed:               make(map[string]bool),
-		Ranges:               map[string][]LineRange{},
 		MarkdownRenderByPath: map[string]bool{},
 	}
 	m.Tree = buildTree(m.Files, m.SelectedPath, nil, nil)
@@ -380,17 +393,19 @@ func TestMarkdownLinkTextDecoration(t *testing.T) {
 
 // buildGitModel returns a ReviewModel with a GitContext set, suitable for
 // testing git-context-aware rendering. Callers may mutate before rendering.
-func buildGitModel(git *GitContext) *ReviewModel {
-	m := &ReviewModel{
-		Files:                []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"x"}}},
+func buildGitModel(git *GitContext) *ReviewView {
+	m := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files:  []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"x"}}},
+			Mode:   ModeFile,
+			Viewed: make(map[string]bool),
+			Ranges: map[string][]LineRange{},
+			Git:    git,
+		},
 		SelectedPath:         "a.go",
-		Mode:                 ModeFile,
 		RenderFile:           true,
 		RenderComments:       true,
-		Viewed:               make(map[string]bool),
-		Ranges:               map[string][]LineRange{},
 		MarkdownRenderByPath: map[string]bool{},
-		Git:                  git,
 	}
 	m.Tree = buildTree(m.Files, m.SelectedPath, nil, nil)
 	return m
@@ -482,12 +497,12 @@ func TestRenderHeaderContextCSS(t *testing.T) {
 	}
 }
 
-func renderReviewHTML(t *testing.T, model *ReviewModel) string {
+func renderReviewHTML(t *testing.T, model *ReviewView) string {
 	t.Helper()
 
 	updateView(model)
 
-	rs := &ReviewServer{Model: model, DoneCh: make(chan struct{})}
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
 	h := buildLiveHandler(rs)
 	out, err := h.RenderHandler(context.Background(), &live.RenderContext{Assigns: model})
 	if err != nil {
@@ -499,3 +514,252 @@ func renderReviewHTML(t *testing.T, model *ReviewModel) string {
 	}
 	return string(b)
 }
+
+func TestHTTPRenderSetsTabWidth(t *testing.T) {
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files:    []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"\tpackage main"}}},
+			Mode:     ModeFile,
+			TabWidth: 8,
+			Viewed:   make(map[string]bool),
+		},
+		SelectedPath:         "a.go",
+		MarkdownRenderByPath: map[string]bool{},
+	}
+	model.Tree = buildTree(model.Files, model.SelectedPath, nil, nil)
+
+	html := renderReviewHTML(t, model)
+	if !strings.Contains(html, `style="--tab-width: 8"`) {
+		t.Fatalf("expected tab width custom property in rendered html, got: %q", html)
+	}
+}
+
+// TestHTTPRenderDiffParseWarnings verifies that lenient parse warnings for
+// the selected diff file are shown above its hunks.
+func TestHTTPRenderDiffParseWarnings(t *testing.T) {
+	files, err := parseUnifiedDiff("--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,2 @@\n package main\n", diffLenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			DiffFiles: files,
+			Mode:      ModeDiff,
+			Viewed:    make(map[string]bool),
+			Ranges:    map[string][]LineRange{},
+		},
+		SelectedPath:         "a.go",
+		MarkdownRenderByPath: map[string]bool{},
+	}
+	model.Tree = buildTree(diffFilesAsFiles(model.DiffFiles), model.SelectedPath, nil, nil)
+
+	html := renderReviewHTML(t, model)
+	if !strings.Contains(html, `class="diff-warnings"`) || !strings.Contains(html, "line 3") {
+		t.Fatalf("expected diff parse warnings in HTML, got: %q", html)
+	}
+}
+
+// TestHTTPRenderSkippedFiles verifies that paths skipped by --skip-missing are
+// listed in the header.
+func TestHTTPRenderSkippedFiles(t *testing.T) {
+	model := buildCommentModel()
+	model.SkippedFiles = []SkippedFile{{Path: "gone.go", Reason: "read gone.go: no such file or directory"}}
+
+	html := renderReviewHTML(t, model)
+	if !strings.Contains(html, "Skipped unreadable paths") || !strings.Contains(html, "gone.go") {
+		t.Fatalf("expected skipped paths in HTML, got: %q", html)
+	}
+}
+
+// TestHTTPRenderEmptyFile verifies that a zero-byte file shows an explicit
+// empty state with no selectable lines, and that file-level comments on it
+// are rendered.
+func TestHTTPRenderEmptyFile(t *testing.T) {
+	path := writeTempFile(t, "empty.go", "")
+	files, err := loadFiles([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files:    files,
+			Mode:     ModeFile,
+			Viewed:   make(map[string]bool),
+			Comments: []Comment{{ID: 1, Path: path, Text: "why is this empty?"}},
+		},
+		SelectedPath:         path,
+		RenderComments:       true,
+		MarkdownRenderByPath: map[string]bool{},
+	}
+
+	html := renderReviewHTML(t, model)
+	if !strings.Contains(html, "This file is empty.") {
+		t.Fatalf("expected empty file state, got: %q", html)
+	}
+	if strings.Contains(html, `class="line-block"`) {
+		t.Fatalf("expected no selectable lines, got: %q", html)
+	}
+	if !strings.Contains(html, "why is this empty?") || !strings.Contains(html, "(file)") {
+		t.Fatalf("expected file-level comment, got: %q", html)
+	}
+}
+
+// TestHTTPRenderBinaryFile verifies that a binary file shows a placeholder
+// instead of its bytes.
+func TestHTTPRenderBinaryFile(t *testing.T) {
+	path := writeTempFile(t, "logo.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	files, err := loadFiles([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !files[0].Binary {
+		t.Fatal("expected the file to be detected as binary")
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files:  files,
+			Mode:   ModeFile,
+			Viewed: make(map[string]bool),
+		},
+		SelectedPath:         path,
+		MarkdownRenderByPath: map[string]bool{},
+	}
+
+	html := renderReviewHTML(t, model)
+	if !strings.Contains(html, "Binary file not rendered.") || strings.Contains(html, "This file is empty.") {
+		t.Fatalf("expected the binary placeholder, got: %q", html)
+	}
+	if strings.Contains(html, "IHDR") || strings.Contains(html, `class="line-block"`) {
+		t.Fatalf("expected no file content, got: %q", html)
+	}
+}
+
+// TestHTTPRenderImageFile verifies that image files are previewed through
+// the /file handler, and that SVG source stays selectable below the image.
+func TestHTTPRenderImageFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("logo.png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("icon.svg", []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"/>\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := loadFiles([]string{"logo.png", "icon.svg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files:  files,
+			Mode:   ModeFile,
+			Viewed: make(map[string]bool),
+		},
+		SelectedPath:         "logo.png",
+		MarkdownRenderByPath: map[string]bool{},
+	}
+
+	html := renderReviewHTML(t, model)
+	if !strings.Contains(html, `<img src="/file?path=logo.png" alt="logo.png">`) {
+		t.Fatalf("expected an image preview, got: %q", html)
+	}
+	if strings.Contains(html, "Binary file not rendered.") {
+		t.Fatalf("expected the preview to replace the binary placeholder, got: %q", html)
+	}
+
+	model.SelectedPath = "icon.svg"
+	html = renderReviewHTML(t, model)
+	if !strings.Contains(html, `/file?path=icon.svg`) || !strings.Contains(html, `class="line-block"`) {
+		t.Fatalf("expected an SVG preview above its source, got: %q", html)
+	}
+
+	if got := imagePreviewURL(filepath.Join(os.TempDir(), "..", "elsewhere.png")); got != "" {
+		t.Errorf("expected no preview outside the working directory, got %q", got)
+	}
+	if got := imagePreviewURL("main.go"); got != "" {
+		t.Errorf("expected no preview for a non-image, got %q", got)
+	}
+}
+
+// TestHTTPRenderDiffStatusBadges verifies that diff mode marks added,
+// deleted and renamed files in the tree and names the selected file's
+// status and mode change in the header.
+func TestHTTPRenderDiffStatusBadges(t *testing.T) {
+	files, err := parseUnifiedDiff("diff --git a/old.go b/new.go\nsimilarity index 100%\nrename from old.go\nrename to new.go\n"+
+		"diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100755\n"+
+		"diff --git a/gone.go b/gone.go\ndeleted file mode 100644\n--- a/gone.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-package gone\n", diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode:      ModeDiff,
+			DiffFiles: files,
+			Viewed:    make(map[string]bool),
+		},
+		SelectedPath:         "new.go",
+		MarkdownRenderByPath: map[string]bool{},
+	}
+	rebuildTree(model)
+	html := renderReviewHTML(t, model)
+	for _, want := range []string{
+		`<span class="status-badge renamed" title="Renamed">R</span>`,
+		`<span class="status-badge deleted" title="Deleted">D</span>`,
+		`title="Renamed from old.go">Renamed</span>`,
+		"Renamed from old.go without changes.",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in HTML", want)
+		}
+	}
+
+	model.SelectedPath = "run.sh"
+	refreshTree(model)
+	html = renderReviewHTML(t, model)
+	if !strings.Contains(html, "100644 → 100755") || !strings.Contains(html, "Only the file mode changed.") {
+		t.Fatalf("expected the mode change in the header, got: %q", html)
+	}
+}
+
+// TestHTTPRenderRangePastEOF verifies that ranges entirely past the end of a
+// file explain themselves instead of rendering nothing.
+func TestHTTPRenderRangePastEOF(t *testing.T) {
+	model := buildCommentModel()
+	model.Ranges = map[string][]LineRange{"test.go": {{Start: 10, End: 20}}}
+
+	html := renderReviewHTML(t, model)
+	if !strings.Contains(html, "past the end of the file, which has 1 line.") {
+		t.Fatalf("expected out-of-range note, got: %q", html)
+	}
+}
+
+// TestHTTPRenderDeletedDiffFile verifies that a deleted file shows its removed
+// lines as old-side, selectable lines along with old-side comments.
+func TestHTTPRenderDeletedDiffFile(t *testing.T) {
+	files, err := parseUnifiedDiff("--- a/gone.go\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-package gone\n-func F() {}\n", diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			DiffFiles: files,
+			Mode:      ModeDiff,
+			Viewed:    make(map[string]bool),
+			Ranges:    map[string][]LineRange{},
+			Comments:  []Comment{{ID: 1, Path: "gone.go", StartLine: 2, EndLine: 2, Side: "old", Text: "still used elsewhere"}},
+		},
+		SelectedPath:         "gone.go",
+		RenderComments:       true,
+		MarkdownRenderByPath: map[string]bool{},
+	}
+	model.Tree = buildTree(diffFilesAsFiles(model.DiffFiles), model.SelectedPath, nil, nil)
+
+	if valid, _ := validateComments(model.ReviewModel); len(valid) != 1 {
+		t.Fatal("expected old-side comment on a deleted file to be valid")
+	}
+	html := renderReviewHTML(t, model)
+	for _, want := range []string{"This file was deleted.", `data-old-line="2"`, "still used elsewhere"} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("expected %q in HTML, got: %q", want, html)
+		}
+	}
+}
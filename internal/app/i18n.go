@@ -0,0 +1,133 @@
+package app
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultLang is the language the UI is written in. Template strings are
+// English and double as the keys of the other catalogs.
+const defaultLang = "en"
+
+// localeFS holds one JSON catalog per language, mapping each English UI
+// string to its translation.
+//
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// catalogs maps a language code to its catalog. The default language has
+// an empty catalog.
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]map[string]string {
+	out := map[string]map[string]string{defaultLang: {}}
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, e := range entries {
+		data, err := localeFS.ReadFile(path.Join("locales", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var cat map[string]string
+		if err := json.Unmarshal(data, &cat); err != nil {
+			panic(fmt.Sprintf("locale %s: %v", e.Name(), err))
+		}
+		out[strings.TrimSuffix(e.Name(), ".json")] = cat
+	}
+	return out
+}
+
+// Languages lists the UI languages, sorted.
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// CheckLang reports whether lang is a UI language, for validating --lang.
+func CheckLang(lang string) error {
+	if _, ok := catalogs[lang]; !ok {
+		return fmt.Errorf("unsupported language %q (available: %s)", lang, strings.Join(Languages(), ", "))
+	}
+	return nil
+}
+
+// translator returns the "t" template function for lang. It looks msg up
+// in the catalog, falling back to the English text, and formats it with
+// args when there are any.
+func translator(lang string) func(msg string, args ...any) string {
+	cat := catalogs[lang]
+	return func(msg string, args ...any) string {
+		if tr := cat[msg]; tr != "" {
+			msg = tr
+		}
+		if len(args) > 0 {
+			return fmt.Sprintf(msg, args...)
+		}
+		return msg
+	}
+}
+
+// negotiateLang picks the UI language best matching an Accept-Language
+// header, or returns "" if none of the listed languages is available.
+func negotiateLang(header string) string {
+	type pref struct {
+		tag string
+		q   float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag == "" || tag == "*" || q <= 0 {
+			continue
+		}
+		prefs = append(prefs, pref{strings.ToLower(tag), q})
+	}
+	slices.SortStableFunc(prefs, func(a, b pref) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		}
+		return 0
+	})
+	for _, p := range prefs {
+		primary, _, _ := strings.Cut(p.tag, "-")
+		if _, ok := catalogs[primary]; ok {
+			return primary
+		}
+	}
+	return ""
+}
+
+// uiLang returns the language to render for a browser preferring lang: the
+// --lang override, then the browser's preference, then English.
+func (rs *ReviewServer) uiLang(lang string) string {
+	switch {
+	case rs.lang != "":
+		return rs.lang
+	case lang != "":
+		return lang
+	}
+	return defaultLang
+}
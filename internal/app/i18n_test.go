@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+// TestCatalogsCoverTemplate verifies that every string the templates
+// translate has an entry in every catalog.
+func TestCatalogsCoverTemplate(t *testing.T) {
+	keys := regexp.MustCompile(`\{\{t "([^"]*)"`).FindAllStringSubmatch(templateHTML+printHTML+reportHTML, -1)
+	if len(keys) == 0 {
+		t.Fatal("expected translated strings in the template")
+	}
+	for lang, cat := range catalogs {
+		if lang == defaultLang {
+			continue
+		}
+		for _, k := range keys {
+			if cat[k[1]] == "" {
+				t.Errorf("%s: missing translation for %q", lang, k[1])
+			}
+		}
+	}
+}
+
+func TestNegotiateLang(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                          "",
+		"de-DE,de;q=0.9,en;q=0.8":   "de",
+		"fr-FR, es;q=0.5, de;q=0.7": "de",
+		"fr, *;q=0.5":               "",
+		"ES":                        "es",
+		"de;q=0, es;q=0.1":          "es",
+		"en-GB,de;q=0.9":            "en",
+	} {
+		if got := negotiateLang(header); got != want {
+			t.Errorf("negotiateLang(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestTranslator(t *testing.T) {
+	de := translator("de")
+	if got := de("Show %d lines", 12); got != "12 Zeilen anzeigen" {
+		t.Errorf("unexpected translation %q", got)
+	}
+	if got := de("not in the catalog"); got != "not in the catalog" {
+		t.Errorf("expected an unknown string to fall back to English, got %q", got)
+	}
+	if err := CheckLang("fr"); err == nil {
+		t.Error("expected an unsupported language to be rejected")
+	}
+}
+
+func TestRenderLocalized(t *testing.T) {
+	model := buildCommentModel()
+	model.SelectionStart, model.SelectionEnd = 1, 1
+	model.Error = "comment text is required"
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{}), lang: "de"}
+	h := buildLiveHandler(rs)
+	out, err := h.RenderHandler(context.Background(), &live.RenderContext{Assigns: model})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(b)
+	for _, want := range []string{`<html lang="de">`, "Kommentar hinzufügen", "Ausgewählt: 1–1", "Kommentartext ist erforderlich"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in the German UI", want)
+		}
+	}
+
+	if html := renderReviewHTML(t, model); !strings.Contains(html, `<html lang="en">`) || !strings.Contains(html, "Add Comment") {
+		t.Error("expected the English UI by default")
+	}
+}
+
+func TestViewerLang(t *testing.T) {
+	model := buildCommentModel()
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	view := rs.newView()
+	view.Lang = negotiateLang("es-MX,es;q=0.9")
+	if got := rs.uiLang(view.Lang); got != "es" {
+		t.Fatalf("expected es, got %q", got)
+	}
+	rs.lang = "de"
+	if got := rs.uiLang(view.Lang); got != "de" {
+		t.Fatalf("expected --lang to win, got %q", got)
+	}
+}
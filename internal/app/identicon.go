@@ -0,0 +1,42 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"strings"
+	"sync"
+)
+
+var identicons sync.Map // name -> template.URL
+
+// identicon returns a data URL for a 5x5 mirrored SVG identicon derived from
+// name, so each comment author gets a stable, recognisable avatar.
+func identicon(name string) template.URL {
+	if cached, ok := identicons.Load(name); ok {
+		return cached.(template.URL)
+	}
+	sum := sha256.Sum256([]byte(name))
+	hue := (int(sum[0])<<8 | int(sum[1])) % 360
+	fill := fmt.Sprintf("hsl(%d,55%%,55%%)", hue)
+
+	var b strings.Builder
+	b.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 5 5" shape-rendering="crispEdges">`)
+	b.WriteString(`<rect width="5" height="5" fill="#1f1a1c"/>`)
+	for row := 0; row < 5; row++ {
+		for col := 0; col < 3; col++ {
+			if sum[2+row*3+col]&1 == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="1" height="1" fill="%s"/>`, col, row, fill)
+			if col < 2 {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="1" height="1" fill="%s"/>`, 4-col, row, fill)
+			}
+		}
+	}
+	b.WriteString(`</svg>`)
+	url := template.URL("data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(b.String())))
+	identicons.Store(name, url)
+	return url
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// imagePreviewURL returns the /file URL that serves the image at path for
+// the viewer to show, or "" when path is not an image or lies outside the
+// working directory localFileHandler serves from.
+func imagePreviewURL(path string) string {
+	if !markdownAssetExts[strings.ToLower(filepath.Ext(path))] {
+		return ""
+	}
+	rel := filepath.Clean(path)
+	if filepath.IsAbs(rel) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return ""
+		}
+		if rel, err = filepath.Rel(wd, rel); err != nil {
+			return ""
+		}
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return "/file?path=" + url.QueryEscape(filepath.ToSlash(rel))
+}
@@ -0,0 +1,41 @@
+package app
+
+// pathIndex maps paths to elements of a slice owned by the model. The index
+// is rebuilt whenever the slice is replaced or resized, so callers can keep
+// assigning to ReviewModel.Files / DiffFiles directly.
+type pathIndex[T any] struct {
+	base   *T
+	n      int
+	byPath map[string]*T
+}
+
+func (ix *pathIndex[T]) lookup(items []T, key func(*T) string, path string) *T {
+	if len(items) == 0 {
+		return nil
+	}
+	if ix.byPath == nil || ix.base != &items[0] || ix.n != len(items) {
+		ix.byPath = make(map[string]*T, len(items))
+		for i := range items {
+			k := key(&items[i])
+			if _, dup := ix.byPath[k]; !dup {
+				ix.byPath[k] = &items[i]
+			}
+		}
+		ix.base = &items[0]
+		ix.n = len(items)
+	}
+	return ix.byPath[path]
+}
+
+func filePath(f *File) string         { return f.Path }
+func diffFilePath(f *DiffFile) string { return f.Path }
+
+// lookupFile returns the file-mode file with the given path, or nil.
+func (m *ReviewModel) lookupFile(path string) *File {
+	return m.fileIndex.lookup(m.Files, filePath, path)
+}
+
+// lookupDiffFile returns the diff-mode file with the given path, or nil.
+func (m *ReviewModel) lookupDiffFile(path string) *DiffFile {
+	return m.diffIndex.lookup(m.DiffFiles, diffFilePath, path)
+}
@@ -0,0 +1,34 @@
+package app
+
+import "testing"
+
+// TestLookupFileReindexesOnReplace verifies that the path index follows the
+// model's file slice when it is replaced.
+func TestLookupFileReindexesOnReplace(t *testing.T) {
+	model := &ReviewModel{Files: []File{{Path: "a.go"}, {Path: "b.go"}}}
+	if f := model.lookupFile("b.go"); f != &model.Files[1] {
+		t.Fatalf("expected pointer to b.go, got %v", f)
+	}
+	if f := model.lookupFile("c.go"); f != nil {
+		t.Fatalf("expected nil for unknown path, got %v", f)
+	}
+
+	model.Files = []File{{Path: "c.go"}, {Path: "d.go"}}
+	if f := model.lookupFile("c.go"); f != &model.Files[0] {
+		t.Fatalf("expected index rebuild after replace, got %v", f)
+	}
+	if f := model.lookupFile("a.go"); f != nil {
+		t.Fatalf("expected stale path to be gone, got %v", f)
+	}
+}
+
+// TestLookupDiffFile verifies diff-mode path lookups through the index.
+func TestLookupDiffFile(t *testing.T) {
+	model := &ReviewModel{DiffFiles: []DiffFile{{Path: "x.go"}, {Path: "y.go"}}}
+	if f := model.lookupDiffFile("y.go"); f != &model.DiffFiles[1] {
+		t.Fatalf("expected pointer to y.go, got %v", f)
+	}
+	if f := (&ReviewModel{}).lookupDiffFile("y.go"); f != nil {
+		t.Fatalf("expected nil on empty model, got %v", f)
+	}
+}
@@ -13,23 +13,58 @@ import (
 	"github.com/alpkeskin/gotoon"
 )
 
+// loadFiles registers the given paths without reading their content. Each
+// path must exist and be a regular file; lines are read lazily on selection.
 func loadFiles(paths []string) ([]File, error) {
 	files := make([]File, 0, len(paths))
 	for _, path := range paths {
-		data, err := os.ReadFile(path)
+		file, err := loadFile(path)
 		if err != nil {
-			return nil, fmt.Errorf("read %s: %w", path, err)
+			return nil, err
 		}
-		lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
-		files = append(files, File{
-			Path:      path,
-			PathSlash: filepath.ToSlash(path),
-			Lines:     lines,
-		})
+		files = append(files, file)
 	}
 	return files, nil
 }
 
+// loadFilesSkipping is loadFiles for --skip-missing: paths that cannot be
+// loaded are returned as skipped instead of failing the run.
+func loadFilesSkipping(paths []string) ([]File, []SkippedFile) {
+	files := make([]File, 0, len(paths))
+	var skipped []SkippedFile
+	for _, path := range paths {
+		file, err := loadFile(path)
+		if err != nil {
+			skipped = append(skipped, SkippedFile{Path: filepath.ToSlash(path), Reason: err.Error()})
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, skipped
+}
+
+func loadFile(path string) (File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return File{}, fmt.Errorf("read %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return File{}, fmt.Errorf("read %s: is a directory", path)
+	}
+	bom, binary, err := sniffFile(path)
+	if err != nil {
+		return File{}, err
+	}
+	return File{
+		Path:      path,
+		PathSlash: filepath.ToSlash(path),
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+		BOM:       bom,
+		Binary:    binary,
+	}, nil
+}
+
 func ReadStdDiff() (string, error) {
 	stat, err := os.Stdin.Stat()
 	if err != nil {
@@ -53,6 +88,37 @@ func ReadStdDiff() (string, error) {
 	return b.String(), nil
 }
 
+// checkOutputDir fails early when path cannot be written because its
+// directory does not exist, rather than after the reviewer has finished.
+func checkOutputDir(flag, path string) error {
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s: no directory %s", flag, filepath.Dir(path))
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to path through a temporary file in the same
+// directory, so anything watching path never sees it half written.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp makes the file 0600; give it the mode os.WriteFile would.
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
 func ParseRangeFlag(values []string) (map[string][]LineRange, error) {
 	if len(values) == 0 {
 		return nil, nil
@@ -87,10 +153,125 @@ func ParseRangeFlag(values []string) (map[string][]LineRange, error) {
 }
 
 func emitToon(w io.Writer, comments []Comment) error {
-	doc := map[string]any{
+	return emitDocument(w, map[string]any{
 		"comments": comments,
+	}, outputTOON)
+}
+
+// commentRecord is a comment as written to the review document. Unlike
+// Comment, every field is always present, so the comments encode as one
+// table.
+type commentRecord struct {
+	ID          int          `json:"id"`
+	UID         string       `json:"uid"`
+	Path        string       `json:"path"`
+	StartLine   int          `json:"start_line"`
+	EndLine     int          `json:"end_line"`
+	Side        string       `json:"side"`
+	Text        string       `json:"text"`
+	Author      string       `json:"author"`
+	Disposition Disposition  `json:"disposition"`
+	Status      ThreadStatus `json:"status"`
+	Priority    Priority     `json:"priority"`
+	Confidence  string       `json:"confidence"`
+	Tags        string       `json:"tags"`
+	Commit      string       `json:"commit"`
+}
+
+// emitReview writes the session result built by reviewDocument.
+func emitReview(w io.Writer, model *ReviewModel) error {
+	return emitDocument(w, reviewDocument(model), outputTOON)
+}
+
+// reviewDocument builds the session result: the comments, the review
+// summary, patches for suggested changes, replies posted through the API,
+// the chat transcript if anyone used the chat, rubric scores when a rubric
+// was given, and metadata about the reviewed files and the document's own
+// size in tokens.
+// review.schema.json describes it.
+//
+// Comments with impossible anchors are left out of the comment list and
+// reported under metadata.invalid_comments instead.
+func reviewDocument(model *ReviewModel) map[string]any {
+	comments, invalid := validateComments(model)
+	records := make([]commentRecord, 0, len(comments))
+	for _, c := range exportComments(model, comments) {
+		records = append(records, commentRecord{
+			ID:          c.ID,
+			UID:         c.UID,
+			Path:        c.Path,
+			StartLine:   c.StartLine,
+			EndLine:     c.EndLine,
+			Side:        c.Side,
+			Text:        c.Text,
+			Author:      c.Author,
+			Disposition: c.Disposition,
+			Status:      c.Status,
+			Priority:    c.Priority,
+			Confidence:  c.Confidence,
+			Tags:        c.Tags.String(),
+			Commit:      c.Commit,
+		})
+	}
+	doc := map[string]any{
+		"comments": records,
+		"summary":  summaryDocument(model.Summary),
+	}
+	if suggestions := suggestionRecords(model, comments); len(suggestions) > 0 {
+		doc["suggestions"] = suggestions
 	}
-	encoded, err := gotoon.Encode(doc)
+	if len(model.Replies) > 0 {
+		doc["replies"] = model.Replies
+	}
+	if len(model.Chat) > 0 {
+		doc["chat"] = chatTranscript(model.Chat)
+	}
+	if len(model.Rubric) > 0 {
+		doc["rubric"] = rubricScores(model)
+	}
+	meta := reviewMetadata(model)
+	if len(invalid) > 0 {
+		meta["invalid_comments"] = invalid
+	}
+	if len(comments) > 0 {
+		meta["comment_tokens"] = commentTokenCounts(model, comments)
+	}
+	doc["metadata"] = meta
+	setTokenEstimate(doc, outputTOON)
+	return doc
+}
+
+// setTokenEstimate records in the metadata of doc roughly how many tokens
+// it takes encoded as format. It is counted without the estimate itself;
+// the one extra line is within the margin of the estimate.
+func setTokenEstimate(doc map[string]any, format string) {
+	meta := doc["metadata"].(map[string]any)
+	delete(meta, "token_estimate")
+	if encoded, err := encodeDocument(doc, format); err == nil {
+		meta["token_estimate"] = estimateTokens(encoded)
+	}
+}
+
+// Output formats of the review document, chosen with --output-format.
+const (
+	outputTOON = "toon"
+	outputJSON = "json"
+)
+
+// encodeDocument encodes doc as TOON, or as indented JSON for outputJSON.
+func encodeDocument(doc map[string]any, format string) (string, error) {
+	switch format {
+	case "", outputTOON:
+		return gotoon.Encode(doc)
+	case outputJSON:
+		data, err := json.MarshalIndent(doc, "", "  ")
+		return string(data), err
+	}
+	return "", fmt.Errorf("unsupported output format: %s", format)
+}
+
+func emitDocument(w io.Writer, doc map[string]any, format string) error {
+	encoded, err := encodeDocument(doc, format)
 	if err != nil {
 		return err
 	}
@@ -98,6 +279,89 @@ func emitToon(w io.Writer, comments []Comment) error {
 	return err
 }
 
+// reviewMetadata describes the reviewed files. Line endings and byte order
+// marks are recorded so that tools applying suggestions can write files back
+// with their original line breaks and encoding; viewed marks, assignments and
+// verdicts record who reviewed what and what they concluded.
+func reviewMetadata(model *ReviewModel) map[string]any {
+	meta := make(map[string]any)
+	endings := make(map[string]any)
+	boms := make(map[string]any)
+	for i := range model.Files {
+		if eol := lineEndingOf(&model.Files[i]); eol != LineEndingNone {
+			endings[model.Files[i].PathSlash] = string(eol)
+		}
+		if bom := model.Files[i].BOM; bom != BOMNone {
+			boms[model.Files[i].PathSlash] = string(bom)
+		}
+	}
+	if len(endings) > 0 {
+		meta["line_endings"] = endings
+	}
+	if len(boms) > 0 {
+		meta["byte_order_marks"] = boms
+	}
+	if viewed := viewedMetadata(model); len(viewed) > 0 {
+		meta["viewed"] = viewed
+	}
+	if len(model.Assignments) > 0 {
+		meta["assignments"] = assignmentMetadata(model)
+	}
+	if len(model.Commits) > 0 {
+		meta["commits"] = commitsMetadata(model.Commits)
+	}
+	if len(model.Verdicts) > 0 {
+		verdicts := make(map[string]any, len(model.Verdicts))
+		for reviewer, v := range model.Verdicts {
+			verdicts[reviewer] = string(v)
+		}
+		meta["verdicts"] = verdicts
+		meta["verdict"] = string(aggregateVerdict(model.Verdicts))
+	}
+	if model.DeadlineExpired {
+		meta["deadline_expired"] = true
+	}
+	if model.TimedOut {
+		meta["timed_out"] = true
+	}
+	if model.Aborted {
+		meta["aborted"] = true
+	}
+	if len(model.UpdatedFiles) > 0 {
+		meta["updated_files"] = sortedKeys(model.UpdatedFiles)
+	}
+	if ids := outdatedComments(model); len(ids) > 0 {
+		meta["outdated_comments"] = ids
+	}
+	if len(model.StaleFiles) > 0 {
+		meta["changed_files"] = sortedKeys(model.StaleFiles)
+		if ids := staleComments(model); len(ids) > 0 {
+			meta["stale_comments"] = ids
+		}
+	}
+	return meta
+}
+
+// lineEndingOf returns the line ending of f, reading it from disk when the
+// file was never opened during the session.
+func lineEndingOf(f *File) LineEnding {
+	if f.Lines != nil || f.index != nil {
+		return f.LineEnding
+	}
+	if f.Size > largeFileThreshold {
+		if idx, err := buildLineIndex(f.Path); err == nil {
+			return idx.lineEnding
+		}
+		return LineEndingNone
+	}
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return LineEndingNone
+	}
+	text, _ := decodeText(data)
+	return detectLineEnding(text)
+}
+
 func ParseGroupsFile(path string) ([]Group, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
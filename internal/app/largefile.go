@@ -0,0 +1,156 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	// largeFileThreshold is the size above which a file is indexed by line
+	// offset instead of being held in memory.
+	largeFileThreshold = 8 << 20
+	// largeFileWindow is the number of lines shown at once for a windowed
+	// file when no --range is given, and how many more are shown each time
+	// the reviewer scrolls to the end of the window.
+	largeFileWindow = 2000
+)
+
+// lineIndex records the byte offset of each line start in a file so that
+// arbitrary line windows can be read from disk without loading the file.
+type lineIndex struct {
+	path           string
+	offsets        []int64
+	size           int64
+	lineEnding     LineEnding
+	noFinalNewline bool
+	// stat is the file's size and modification time when it was indexed.
+	stat fileStat
+}
+
+func buildLineIndex(path string) (*lineIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	defer f.Close()
+	stat, err := statOpenFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReaderSize(f, 64<<10)
+	// A UTF-8 byte order mark is skipped so line 1 starts at real content.
+	var pos int64
+	if head, _ := r.Peek(3); detectBOM(head) == BOMUTF8 {
+		n, _ := r.Discard(3)
+		pos = int64(n)
+	}
+	idx := &lineIndex{path: path, offsets: []int64{pos}, stat: stat}
+	var crlf, lf int
+	prevCR := false
+	for {
+		chunk, err := r.ReadSlice('\n')
+		pos += int64(len(chunk))
+		if err == nil {
+			idx.offsets = append(idx.offsets, pos)
+			if len(chunk) >= 2 && chunk[len(chunk)-2] == '\r' || len(chunk) == 1 && prevCR {
+				crlf++
+			} else {
+				lf++
+			}
+			prevCR = false
+			continue
+		}
+		if err == bufio.ErrBufferFull {
+			prevCR = len(chunk) > 0 && chunk[len(chunk)-1] == '\r'
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	idx.size = pos
+	idx.lineEnding = classifyLineEnding(crlf, lf)
+	idx.noFinalNewline = pos > idx.offsets[0] && idx.offsets[len(idx.offsets)-1] != pos
+	// A final newline terminates the last line rather than starting a new
+	// one, so drop the offset recorded after it.
+	if len(idx.offsets) > 1 && idx.offsets[len(idx.offsets)-1] == pos {
+		idx.offsets = idx.offsets[:len(idx.offsets)-1]
+	}
+	return idx, nil
+}
+
+// lineCount returns the number of lines, matching splitFileLines.
+func (idx *lineIndex) lineCount() int {
+	if idx.size == idx.offsets[0] {
+		return 0
+	}
+	return len(idx.offsets)
+}
+
+// readLines returns lines start..end (1-based, inclusive), clamped to the
+// file bounds.
+func (idx *lineIndex) readLines(start, end int) ([]string, error) {
+	if start < 1 {
+		start = 1
+	}
+	if end > idx.lineCount() {
+		end = idx.lineCount()
+	}
+	if end < start {
+		return nil, nil
+	}
+	from := idx.offsets[start-1]
+	to := idx.size
+	if end < idx.lineCount() {
+		to = idx.offsets[end]
+	}
+
+	f, err := os.Open(idx.path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", idx.path, err)
+	}
+	defer f.Close()
+	buf := make([]byte, to-from)
+	if _, err := f.ReadAt(buf, from); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read %s: %w", idx.path, err)
+	}
+	return splitFileLines(buf), nil
+}
+
+// windowed reports whether file is shown a window of lines at a time: it is
+// indexed, or held in memory with more lines than one window. Rendering
+// every line of such a file would make the page too large to use.
+func windowed(file *File) bool {
+	return file.index != nil || len(file.Lines) > largeFileWindow
+}
+
+// readWindow returns lines start to end (1-based, inclusive) of a windowed
+// file, reading them from disk when the file is indexed.
+func readWindow(file *File, start, end int) ([]string, error) {
+	if file.index != nil {
+		return file.index.readLines(start, end)
+	}
+	start, end = max(start, 1), min(end, len(file.Lines))
+	if start > end {
+		return nil, nil
+	}
+	return file.Lines[start-1 : end], nil
+}
+
+// windowRanges returns the line ranges to display for a windowed file of
+// total lines: the requested --range sections if any, otherwise a single
+// window of windowLines lines (largeFileWindow if fewer) from windowStart.
+func windowRanges(total int, ranges []LineRange, windowStart, windowLines int) []LineRange {
+	if norm := normalizeRanges(ranges); len(norm) > 0 {
+		return norm
+	}
+	if windowStart < 1 {
+		windowStart = 1
+	}
+	end := min(windowStart+max(windowLines, largeFileWindow)-1, total)
+	return []LineRange{{Start: windowStart, End: end}}
+}
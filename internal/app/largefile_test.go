@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestLineIndexReadLines verifies that windows read through the index match
+// the lines a full in-memory split would produce.
+func TestLineIndexReadLines(t *testing.T) {
+	content := "one\r\ntwo\nthree\nfour\n"
+	path := writeTempFile(t, "log.txt", content)
+
+	idx, err := buildLineIndex(path)
+	if err != nil {
+		t.Fatalf("buildLineIndex: %v", err)
+	}
+	if idx.lineEnding != LineEndingMixed || idx.noFinalNewline {
+		t.Fatalf("got lineEnding %q (noFinalNewline=%v), want %q", idx.lineEnding, idx.noFinalNewline, LineEndingMixed)
+	}
+	full := splitFileLines([]byte(content))
+	if idx.lineCount() != len(full) {
+		t.Fatalf("lineCount: got %d, want %d", idx.lineCount(), len(full))
+	}
+
+	tests := []struct {
+		start, end int
+		want       []string
+	}{
+		{1, 1, []string{"one"}},
+		{2, 3, []string{"two", "three"}},
+		{4, 5, []string{"four"}},
+		{0, 99, full},
+	}
+	for _, tt := range tests {
+		got, err := idx.readLines(tt.start, tt.end)
+		if err != nil {
+			t.Fatalf("readLines(%d, %d): %v", tt.start, tt.end, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readLines(%d, %d): got %q, want %q", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+// TestWindowedViewFile verifies that an indexed file is rendered as a window
+// with correct line numbers and navigation flags.
+func TestWindowedViewFile(t *testing.T) {
+	path := writeTempFile(t, "big.log", "a\nb\nc")
+	idx, err := buildLineIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{Path: path, PathSlash: path, index: idx}},
+			Mode:  ModeFile,
+		},
+		SelectedPath:         path,
+		WindowStart:          2,
+		MarkdownRenderByPath: map[string]bool{},
+	}
+
+	updateView(model)
+
+	vf := model.ViewFile
+	if !vf.Windowed || vf.TotalLines != 3 {
+		t.Fatalf("expected windowed view of 3 lines, got %+v", vf)
+	}
+	if len(vf.Lines) != 2 || vf.Lines[0].Number != 2 || vf.Lines[1].Text != "c" {
+		t.Fatalf("unexpected window lines: %+v", vf.Lines)
+	}
+	if !vf.HasPrevWindow || vf.HasNextWindow {
+		t.Fatalf("unexpected window navigation: prev=%v next=%v", vf.HasPrevWindow, vf.HasNextWindow)
+	}
+}
+
+// TestWindowedInMemoryFile verifies that a file held in memory with more
+// lines than one window is rendered a window at a time, that the window
+// grows when its end is reached, and that stale extend requests are ignored.
+func TestWindowedInMemoryFile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lines := make([]string, 2*largeFileWindow+500)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{Path: "big.txt", PathSlash: "big.txt", Lines: lines}},
+			Mode:  ModeFile,
+		},
+		SelectedPath:         "big.txt",
+		MarkdownRenderByPath: map[string]bool{},
+	}
+	updateView(model)
+	if vf := model.ViewFile; !vf.Windowed || len(vf.Lines) != largeFileWindow || !vf.HasNextWindow {
+		t.Fatalf("expected first window of %d lines, got windowed=%v lines=%d", largeFileWindow, vf.Windowed, len(vf.Lines))
+	}
+	key := codeViewKey(model)
+
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	s := live.NewSocket(ctx, engine, "alice")
+	s.Assign(model)
+
+	end := strconv.Itoa(largeFileWindow)
+	callEvent(t, engine, s, "extend-window", map[string]string{"end": end})
+	if vf := model.ViewFile; vf.WindowEnd != 2*largeFileWindow || vf.Lines[len(vf.Lines)-1].Text != lines[2*largeFileWindow-1] {
+		t.Fatalf("expected window to end at %d, got %d", 2*largeFileWindow, vf.WindowEnd)
+	}
+	if codeViewKey(model) != key {
+		t.Fatal("extending the window should keep the code view key")
+	}
+	callEvent(t, engine, s, "extend-window", map[string]string{"end": end})
+	if got := model.ViewFile.WindowEnd; got != 2*largeFileWindow {
+		t.Fatalf("stale extend moved the window end to %d", got)
+	}
+
+	callEvent(t, engine, s, "shift-window", map[string]string{"dir": "next"})
+	vf := model.ViewFile
+	if vf.WindowStart != 2*largeFileWindow+1 || vf.WindowEnd != len(lines) || vf.HasNextWindow {
+		t.Fatalf("unexpected window after next: %d-%d next=%v", vf.WindowStart, vf.WindowEnd, vf.HasNextWindow)
+	}
+	if vf.Lines[0].Text != lines[2*largeFileWindow] {
+		t.Fatalf("unexpected first line %q", vf.Lines[0].Text)
+	}
+
+	model.Ranges = map[string][]LineRange{"big.txt": {{Start: 10, End: 12}}}
+	updateView(model)
+	if model.ViewFile.Windowed || len(model.ViewFile.Lines) != 3 {
+		t.Fatalf("expected --range to show only its lines, got windowed=%v lines=%d", model.ViewFile.Windowed, len(model.ViewFile.Lines))
+	}
+}
@@ -0,0 +1,47 @@
+package app
+
+import "context"
+
+// Result is a finished review as the public review package returns it.
+type Result struct {
+	// Comments are the review's comments whose anchors resolve against the
+	// reviewed content; Dropped lists the others.
+	Comments []Comment
+	Dropped  []Comment
+	Replies  []Reply
+	// Verdict combines Verdicts, each reviewer's own.
+	Verdict     Verdict
+	Verdicts    map[string]Verdict
+	Summary     string
+	Scores      map[string]int
+	CompletedBy string
+}
+
+// Review holds the review cfg describes, without writing anything, and
+// returns its result once a reviewer finishes it. Cancelling ctx stops the
+// server and returns ctx's error. Comments on a patch series name their
+// repository path and commit, as in the review output.
+func Review(ctx context.Context, cfg Config) (*Result, error) {
+	model, err := serve(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	valid, invalid := validateComments(model)
+	result := &Result{
+		Comments:    exportComments(model, valid),
+		Replies:     model.Replies,
+		Verdict:     model.Verdict,
+		Verdicts:    model.Verdicts,
+		Summary:     model.Summary.Text,
+		Scores:      model.Scores,
+		CompletedBy: model.CompletedBy,
+	}
+	var dropped []Comment
+	for _, ic := range invalid {
+		if c := findComment(model, ic.ID); c != nil {
+			dropped = append(dropped, *c)
+		}
+	}
+	result.Dropped = exportComments(model, dropped)
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultLintMinLength is the shortest comment --lint-comments accepts
+// without a warning, in characters.
+const DefaultLintMinLength = 15
+
+// lintIssue is a problem found in a comment's text. Message is English
+// and is translated for display; N fills its %d verb, if it has one.
+type lintIssue struct {
+	Message string
+	N       int
+}
+
+// lintComment checks comment text for problems that make it hard for an
+// agent to act on: too short to say what should change, a suggestion block
+// with nothing in it, or a code fence that is never closed.
+func lintComment(text string, minLength int) []lintIssue {
+	var issues []lintIssue
+	if minLength > 0 && utf8.RuneCountInString(strings.TrimSpace(text)) < minLength {
+		issues = append(issues, lintIssue{Message: "Shorter than %d characters; say what should change and why.", N: minLength})
+	}
+	var (
+		open       bool
+		fence      string
+		suggestion bool
+		body       strings.Builder
+	)
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if len(line)-len(trimmed) > 3 {
+			if open {
+				body.WriteString(line)
+			}
+			continue
+		}
+		marker := fenceMarker(trimmed)
+		switch {
+		case !open && marker != "":
+			open, fence = true, marker
+			suggestion = strings.TrimSpace(trimmed[len(marker):]) == "suggestion"
+			body.Reset()
+		case open && marker != "" && strings.HasPrefix(marker, fence) && strings.TrimSpace(trimmed[len(marker):]) == "":
+			open = false
+			if suggestion && strings.TrimSpace(body.String()) == "" {
+				issues = append(issues, lintIssue{Message: "The suggestion block is empty."})
+			}
+		case open:
+			body.WriteString(line)
+		}
+	}
+	if open {
+		issues = append(issues, lintIssue{Message: "A code block is not closed with a matching fence."})
+	}
+	return issues
+}
+
+// fenceMarker returns the run of three or more backticks or tildes that
+// opens line, or "".
+func fenceMarker(line string) string {
+	if line == "" || (line[0] != '`' && line[0] != '~') {
+		return ""
+	}
+	n := len(line) - len(strings.TrimLeft(line, line[:1]))
+	if n < 3 {
+		return ""
+	}
+	return line[:n]
+}
+
+// lintBeforeSaving reports whether text should be held back for the
+// reviewer to look at the lint warnings first. Sending the same text again
+// saves it anyway.
+func lintBeforeSaving(view *ReviewView, text string) bool {
+	if !view.Lint || text == view.LintedText {
+		clearLint(view)
+		return false
+	}
+	issues := lintComment(text, view.LintMinLength)
+	if len(issues) == 0 {
+		clearLint(view)
+		return false
+	}
+	view.LintIssues, view.LintedText = issues, text
+	return true
+}
+
+// clearLint drops the warnings shown on a comment form.
+func clearLint(view *ReviewView) {
+	view.LintIssues, view.LintedText = nil, ""
+}
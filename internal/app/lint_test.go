@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+func TestLintComment(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want []string
+	}{
+		{"Extract this into a helper so both callers share it.", nil},
+		{"fix this", []string{"Shorter than %d characters; say what should change and why."}},
+		{"Use the shared helper:\n```suggestion\n```", []string{"The suggestion block is empty."}},
+		{"Use the shared helper:\n```suggestion\nreturn helper(x)\n```", nil},
+		{"This should read:\n```go\nreturn nil", []string{"A code block is not closed with a matching fence."}},
+		{"Nested fences are fine:\n````md\n```go\nx\n```\n````", nil},
+		{"Tilde fences close with tildes:\n~~~\nx\n```", []string{"A code block is not closed with a matching fence."}},
+	} {
+		var got []string
+		for _, issue := range lintComment(tc.text, DefaultLintMinLength) {
+			got = append(got, issue.Message)
+		}
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("lintComment(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+// TestLintHoldsComment verifies that a comment with lint warnings is held
+// back with the warnings shown, and that submitting it again saves it.
+func TestLintHoldsComment(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	model.Comments = nil
+	model.Lint, model.LintMinLength = true, DefaultLintMinLength
+	rebuildTree(model)
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	s := live.NewSocket(ctx, engine, "alice")
+	s.Assign(model)
+
+	callEvent(t, engine, s, "select-line", map[string]string{"line": "1"})
+	callEvent(t, engine, s, "add-comment", map[string]string{"comment": "fix this"})
+	if len(model.Comments) != 0 || len(model.LintIssues) != 1 {
+		t.Fatalf("expected the comment to be held with a warning, got %+v", model.LintIssues)
+	}
+	html := renderReviewHTML(t, model)
+	for _, want := range []string{"Shorter than 15 characters", `<textarea name="comment"`, ">fix this</textarea>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in the form", want)
+		}
+	}
+
+	callEvent(t, engine, s, "add-comment", map[string]string{"comment": "fix this"})
+	if len(model.Comments) != 1 || model.LintIssues != nil {
+		t.Fatalf("expected the second submit to save the comment, got %d comments", len(model.Comments))
+	}
+}
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// emitLLMContext is the --emit value for writing comments as compact blocks
+// for a model's context window instead of the review document.
+const emitLLMContext = "llm-context"
+
+// llmContextLines is how many lines around each comment an excerpt shows
+// when the token budget allows it.
+const llmContextLines = 3
+
+type excerptLine struct {
+	Number int
+	Text   string
+}
+
+// commentExcerpt returns the lines c refers to plus up to context lines
+// either side. In diff mode only lines shown on the comment's side of the
+// diff are included. File-level comments have no excerpt.
+func commentExcerpt(model *ReviewModel, c Comment, context int) []excerptLine {
+	if c.isFileLevel() {
+		return nil
+	}
+	from, to := max(1, c.StartLine-context), c.EndLine+context
+	var out []excerptLine
+	if model.Mode == ModeDiff {
+		file := model.lookupDiffFile(c.Path)
+		if file == nil {
+			return nil
+		}
+		oldSide := c.Side == "old"
+		for _, n := range diffLineNumbers(file, oldSide) {
+			if n >= from && n <= to {
+				text, _ := diffLineText(file, oldSide, n)
+				out = append(out, excerptLine{Number: n, Text: text})
+			}
+		}
+		return out
+	}
+	file := model.lookupFile(c.Path)
+	if file == nil || ensureFileLoaded(model.loader, file) != nil {
+		return nil
+	}
+	to = min(to, fileLineCount(file))
+	if to < from {
+		return nil
+	}
+	var lines []string
+	if file.index != nil {
+		read, err := file.index.readLines(from, to)
+		if err != nil {
+			return nil
+		}
+		lines = read
+	} else {
+		lines = file.Lines[from-1 : to]
+	}
+	for i, text := range lines {
+		out = append(out, excerptLine{Number: from + i, Text: text})
+	}
+	return out
+}
+
+// writeLLMContext writes one block per comment: its location, a fenced
+// excerpt and the comment text. With a positive budget, in estimated tokens,
+// excerpts lose their surrounding context first and are then cut short until
+// the output fits; comment text is never trimmed.
+func writeLLMContext(w io.Writer, model *ReviewModel, budget int) error {
+	comments, _ := validateComments(model)
+	excerpts := make([][]excerptLine, len(comments))
+	for i, c := range comments {
+		excerpts[i] = commentExcerpt(model, c, llmContextLines)
+	}
+	comments = exportComments(model, comments)
+	render := func(context, maxLines int) string {
+		var b strings.Builder
+		for i, c := range comments {
+			if i > 0 {
+				b.WriteByte('\n')
+			}
+			lines := excerpts[i]
+			if context < llmContextLines {
+				lines = trimContext(lines, c, context)
+			}
+			writeLLMBlock(&b, c, lines, maxLines)
+		}
+		return b.String()
+	}
+
+	// Each step trims more: first the surrounding context, then the
+	// commented lines themselves, down to no excerpt at all.
+	type trim struct{ context, maxLines int }
+	steps := []trim{{llmContextLines, -1}, {1, -1}, {0, -1}}
+	longest := 0
+	for _, e := range excerpts {
+		longest = max(longest, len(e))
+	}
+	for n := longest / 2; n > 0; n /= 2 {
+		steps = append(steps, trim{0, n})
+	}
+	steps = append(steps, trim{0, 0})
+
+	var out string
+	for _, step := range steps {
+		out = render(step.context, step.maxLines)
+		if budget <= 0 || estimateTokens(out) <= budget {
+			break
+		}
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
+
+// trimContext drops all but context lines around the commented range.
+func trimContext(lines []excerptLine, c Comment, context int) []excerptLine {
+	var out []excerptLine
+	for _, l := range lines {
+		if l.Number >= c.StartLine-context && l.Number <= c.EndLine+context {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func writeLLMBlock(b *strings.Builder, c Comment, lines []excerptLine, maxLines int) {
+	fmt.Fprintf(b, "### %s", c.Path)
+	switch {
+	case c.isFileLevel():
+		b.WriteString(" (file)")
+	case c.StartLine == c.EndLine:
+		fmt.Fprintf(b, ":%d", c.StartLine)
+	default:
+		fmt.Fprintf(b, ":%d-%d", c.StartLine, c.EndLine)
+	}
+	if c.Side == "old" {
+		b.WriteString(" (deleted lines)")
+	}
+	if c.Commit != "" {
+		fmt.Fprintf(b, " (commit %s)", c.Commit)
+	}
+	if weight := weightLabel(c); weight != "" {
+		fmt.Fprintf(b, " (%s)", weight)
+	}
+	if c.UID != "" {
+		fmt.Fprintf(b, " [%s]", c.UID)
+	}
+	b.WriteByte('\n')
+	if len(lines) > 0 && maxLines != 0 {
+		fence := "```"
+		for _, l := range lines {
+			for strings.Contains(l.Text, fence) {
+				fence += "`"
+			}
+		}
+		fmt.Fprintf(b, "%s%s\n", fence, strings.TrimPrefix(filepath.Ext(c.Path), "."))
+		shown := lines
+		if maxLines > 0 && len(lines) > maxLines {
+			shown = lines[:maxLines]
+		}
+		width := len(fmt.Sprint(shown[len(shown)-1].Number))
+		for _, l := range shown {
+			fmt.Fprintf(b, "%*d  %s\n", width, l.Number, l.Text)
+		}
+		if n := len(lines) - len(shown); n > 0 {
+			fmt.Fprintf(b, "... %d more lines\n", n)
+		}
+		fmt.Fprintf(b, "%s\n", fence)
+	}
+	b.WriteString(strings.TrimSpace(c.Text))
+	b.WriteByte('\n')
+}
+
+// commentTokens is the estimated size of one comment's llm-context block.
+type commentTokens struct {
+	ID     int `json:"id"`
+	Tokens int `json:"tokens"`
+}
+
+// commentTokenCounts estimates, for each comment, the tokens of its block
+// as --emit llm-context writes it without a budget.
+func commentTokenCounts(model *ReviewModel, comments []Comment) []commentTokens {
+	out := make([]commentTokens, 0, len(comments))
+	for _, c := range comments {
+		var b strings.Builder
+		writeLLMBlock(&b, exportComments(model, []Comment{c})[0], commentExcerpt(model, c, llmContextLines), -1)
+		out = append(out, commentTokens{ID: c.ID, Tokens: estimateTokens(b.String())})
+	}
+	return out
+}
+
+// estimateTokens approximates the token count of s at four bytes a token.
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func llmContextModel(t *testing.T) *ReviewModel {
+	t.Helper()
+	var content strings.Builder
+	for i := 1; i <= 40; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	path := writeTempFile(t, "main.go", content.String())
+	files, err := loadFiles([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ReviewModel{
+		Mode:  ModeFile,
+		Files: files,
+		Comments: []Comment{
+			{ID: 1, UID: "uid-1", Path: path, StartLine: 10, EndLine: 30, Text: "Split this function."},
+			{ID: 2, UID: "uid-2", Path: path, Text: "Missing tests."},
+		},
+	}
+}
+
+// TestLLMContext verifies the per-comment blocks: location, fenced excerpt
+// with line numbers and context, and the comment text.
+func TestLLMContext(t *testing.T) {
+	model := llmContextModel(t)
+	var buf bytes.Buffer
+	if err := writeLLMContext(&buf, model, 0); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{":10-30 [uid-1]\n```go\n", "7  line 7\n", "33  line 33\n```\nSplit this function.\n", " (file) [uid-2]\nMissing tests.\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+// TestLLMContextTokenBudget verifies that a tight budget drops context and
+// then cuts excerpts short, but keeps every comment's text.
+func TestLLMContextTokenBudget(t *testing.T) {
+	model := llmContextModel(t)
+	var buf bytes.Buffer
+	if err := writeLLMContext(&buf, model, 60); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if estimateTokens(out) > 60 {
+		t.Fatalf("output exceeds the budget (%d tokens):\n%s", estimateTokens(out), out)
+	}
+	if strings.Contains(out, "line 9\n") || !strings.Contains(out, "more lines") {
+		t.Fatalf("expected context dropped and the excerpt cut short:\n%s", out)
+	}
+	if !strings.Contains(out, "Split this function.") || !strings.Contains(out, "Missing tests.") {
+		t.Fatalf("expected comment text to survive trimming:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := writeLLMContext(&buf, model, 1); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "```") || !strings.Contains(buf.String(), "Split this function.") {
+		t.Fatalf("expected excerpts to be dropped entirely:\n%s", buf.String())
+	}
+}
+
+// TestLLMContextDiff verifies that diff excerpts come from the comment's
+// side of the diff.
+func TestLLMContextDiff(t *testing.T) {
+	files, err := parseUnifiedDiff(diffUpdateBefore, diffLenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewModel{
+		Mode:      ModeDiff,
+		DiffFiles: files,
+		Comments:  []Comment{{ID: 1, Path: "b.go", StartLine: 1, EndLine: 1, Side: "old", Text: "Keep the old name."}},
+	}
+	var buf bytes.Buffer
+	if err := writeLLMContext(&buf, model, 0); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "b.go:1 (deleted lines)") || !strings.Contains(out, "1  package b\n") {
+		t.Fatalf("unexpected diff excerpt:\n%s", out)
+	}
+}
+
+// TestTokenEstimateMetadata verifies that the review document reports its
+// own size and each comment's llm-context block size in tokens.
+func TestTokenEstimateMetadata(t *testing.T) {
+	model := llmContextModel(t)
+	doc := reviewDocument(model)
+	meta := doc["metadata"].(map[string]any)
+
+	counts := meta["comment_tokens"].([]commentTokens)
+	if len(counts) != 2 || counts[0].ID != 1 || counts[1].ID != 2 {
+		t.Fatalf("unexpected comment token counts: %+v", counts)
+	}
+	var block bytes.Buffer
+	if err := writeLLMContext(&block, &ReviewModel{Mode: ModeFile, Files: model.Files, Comments: model.Comments[:1]}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if want := estimateTokens(block.String()); counts[0].Tokens != want {
+		t.Fatalf("comment 1: got %d tokens, want %d", counts[0].Tokens, want)
+	}
+	if counts[1].Tokens >= counts[0].Tokens {
+		t.Fatalf("file-level comment should be smaller than the excerpted one: %+v", counts)
+	}
+
+	var out bytes.Buffer
+	if err := emitDocument(&out, doc, outputTOON); err != nil {
+		t.Fatal(err)
+	}
+	estimate := meta["token_estimate"].(int)
+	if actual := estimateTokens(out.String()); estimate > actual || actual-estimate > 10 {
+		t.Fatalf("token estimate %d is far from the emitted size %d", estimate, actual)
+	}
+	if err := validateDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+}
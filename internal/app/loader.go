@@ -0,0 +1,271 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileLoader reads file contents on demand. Files are registered with
+// metadata only at startup; content is read the first time a file is
+// selected, and the next file in the tree is prefetched in the background so
+// stepping through files does not wait on disk.
+type fileLoader struct {
+	mu       sync.Mutex
+	pending  map[string]chan struct{}
+	prefetch map[string]fileContent
+}
+
+// fileContent is the decoded content of a file.
+type fileContent struct {
+	lines          []string
+	lineEnding     LineEnding
+	noFinalNewline bool
+	bom            ByteOrderMark
+	// stat is the file's size and modification time just before it was
+	// read, or zero for content that did not come from disk.
+	stat fileStat
+}
+
+// fileStat is the size and modification time of a file, as the staleness
+// check compares them.
+type fileStat struct {
+	size    int64
+	modTime time.Time
+}
+
+// statOpenFile stats f before it is read, so a change made while reading
+// shows up as a change rather than being recorded as read.
+func statOpenFile(f *os.File) (fileStat, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return fileStat{}, fmt.Errorf("read %s: %w", f.Name(), err)
+	}
+	return fileStat{size: info.Size(), modTime: info.ModTime()}, nil
+}
+
+// record sets the size and modification time of file to those its content
+// was read with.
+func (s fileStat) record(file *File) {
+	if !s.modTime.IsZero() {
+		file.Size, file.ModTime = s.size, s.modTime
+	}
+}
+
+func newFileLoader() *fileLoader {
+	return &fileLoader{
+		pending:  make(map[string]chan struct{}),
+		prefetch: make(map[string]fileContent),
+	}
+}
+
+// splitFileLines splits raw file content into lines, normalising CRLF. A
+// trailing newline ends the last line instead of adding an empty one, and
+// empty content has no lines. The result is never nil.
+func splitFileLines(data []byte) []string {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// hasNoFinalNewline reports whether non-empty data lacks a trailing newline.
+func hasNoFinalNewline(data []byte) bool {
+	return len(data) > 0 && data[len(data)-1] != '\n'
+}
+
+func readFileContent(path string) (fileContent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return fileContent{}, fmt.Errorf("read %s: %w", path, err)
+	}
+	defer f.Close()
+	stat, err := statOpenFile(f)
+	if err != nil {
+		return fileContent{}, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return fileContent{}, fmt.Errorf("read %s: %w", path, err)
+	}
+	content := decodeFileContent(data)
+	content.stat = stat
+	return content, nil
+}
+
+// decodeFileContent decodes raw file data into lines, noting how they were
+// laid out on disk.
+func decodeFileContent(data []byte) fileContent {
+	data, bom := decodeText(data)
+	return fileContent{
+		lines:          splitFileLines(data),
+		lineEnding:     detectLineEnding(data),
+		noFinalNewline: hasNoFinalNewline(data),
+		bom:            bom,
+	}
+}
+
+func readFileLines(path string) ([]string, error) {
+	content, err := readFileContent(path)
+	return content.lines, err
+}
+
+// detectLineEnding classifies the line breaks in data. Lone CRs are not
+// treated as line breaks, matching splitFileLines.
+func detectLineEnding(data []byte) LineEnding {
+	var crlf, lf int
+	for i, b := range data {
+		if b != '\n' {
+			continue
+		}
+		if i > 0 && data[i-1] == '\r' {
+			crlf++
+		} else {
+			lf++
+		}
+	}
+	return classifyLineEnding(crlf, lf)
+}
+
+func classifyLineEnding(crlf, lf int) LineEnding {
+	switch {
+	case crlf > 0 && lf > 0:
+		return LineEndingMixed
+	case crlf > 0:
+		return LineEndingCRLF
+	case lf > 0:
+		return LineEndingLF
+	}
+	return LineEndingNone
+}
+
+// ensureFileLoaded populates file.Lines if it has not been read yet. A
+// completed background prefetch by l, the review's loader, is used when
+// available; l may be nil. Files above largeFileThreshold get a line index
+// instead of their content.
+func ensureFileLoaded(l *fileLoader, file *File) error {
+	if file == nil || file.Lines != nil || file.index != nil {
+		return nil
+	}
+	if file.Binary {
+		file.Lines = []string{}
+		return nil
+	}
+	if file.Size > largeFileThreshold {
+		idx, err := buildLineIndex(file.Path)
+		if err != nil {
+			return err
+		}
+		file.index = idx
+		idx.stat.record(file)
+		// Indexed files are too large to hash; their size and modification
+		// time stand in for the content.
+		file.sum = sumLines([]string{fmt.Sprint(file.Size), fmt.Sprint(file.ModTime.UnixNano())})
+		file.LineEnding = idx.lineEnding
+		file.NoFinalNewline = idx.noFinalNewline
+		return nil
+	}
+	content, ok := l.take(file.Path)
+	if !ok {
+		var err error
+		if content, err = readFileContent(file.Path); err != nil {
+			return err
+		}
+	}
+	file.Lines = content.lines
+	content.stat.record(file)
+	file.sum = sumLines(content.lines)
+	file.LineEnding = content.lineEnding
+	file.NoFinalNewline = content.noFinalNewline
+	return nil
+}
+
+// take waits for any in-flight prefetch of path and returns its result.
+func (l *fileLoader) take(path string) (fileContent, bool) {
+	if l == nil {
+		return fileContent{}, false
+	}
+	l.mu.Lock()
+	done, inflight := l.pending[path]
+	l.mu.Unlock()
+	if inflight {
+		<-done
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	content, ok := l.prefetch[path]
+	delete(l.prefetch, path)
+	return content, ok
+}
+
+// drop discards any prefetched content of path, waiting for an in-flight
+// read, so the next load reads the file from disk again.
+func (l *fileLoader) drop(path string) {
+	l.take(path)
+}
+
+// start reads path in the background unless a read is already in flight.
+func (l *fileLoader) start(path string) {
+	l.mu.Lock()
+	if _, inflight := l.pending[path]; inflight {
+		l.mu.Unlock()
+		return
+	}
+	if _, ok := l.prefetch[path]; ok {
+		l.mu.Unlock()
+		return
+	}
+	done := make(chan struct{})
+	l.pending[path] = done
+	l.mu.Unlock()
+
+	go func() {
+		content, err := readFileContent(path)
+		l.mu.Lock()
+		if err == nil {
+			l.prefetch[path] = content
+		}
+		delete(l.pending, path)
+		l.mu.Unlock()
+		close(done)
+	}()
+}
+
+// prefetchNextFile starts a background read of the file following the
+// selected one in the tree, if it has not been loaded yet.
+func prefetchNextFile(view *ReviewView) {
+	if view.Mode != ModeFile {
+		return
+	}
+	next := nextTreeFile(view.Tree, view.SelectedPath)
+	if next == "" {
+		return
+	}
+	file := view.lookupFile(next)
+	if view.loader == nil || file == nil || file.Lines != nil || file.Binary || file.Size > largeFileThreshold {
+		return
+	}
+	view.loader.start(file.Path)
+}
+
+// nextTreeFile returns the path of the file item after path in tree order,
+// or "" if path is the last file.
+func nextTreeFile(tree []TreeItem, path string) string {
+	found := false
+	for _, item := range tree {
+		if item.IsDir || item.IsGroup || item.Path == "" {
+			continue
+		}
+		if found {
+			return item.Path
+		}
+		if item.Path == path {
+			found = true
+		}
+	}
+	return ""
+}
@@ -0,0 +1,249 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestLoadFilesDefersContent verifies that loadFiles records metadata only and
+// leaves Lines nil until the file is selected.
+func TestLoadFilesDefersContent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(path, []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := loadFiles([]string{path})
+	if err != nil {
+		t.Fatalf("loadFiles: %v", err)
+	}
+	if files[0].Lines != nil {
+		t.Fatalf("expected lines to be deferred, got %v", files[0].Lines)
+	}
+	if files[0].Size != int64(len("package a\n")) {
+		t.Fatalf("expected size to be recorded, got %d", files[0].Size)
+	}
+
+	if err := ensureFileLoaded(nil, &files[0]); err != nil {
+		t.Fatalf("ensureFileLoaded: %v", err)
+	}
+	if len(files[0].Lines) != 1 || files[0].Lines[0] != "package a" {
+		t.Fatalf("unexpected lines after load: %v", files[0].Lines)
+	}
+}
+
+// TestLoadFilesMissingPath verifies that missing files are still reported at
+// startup even though content is read lazily.
+func TestLoadFilesMissingPath(t *testing.T) {
+	_, err := loadFiles([]string{filepath.Join(t.TempDir(), "missing.go")})
+	if err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+// TestSelectFilePrefetchesNext verifies that selecting a file loads it and
+// makes the next tree file available from the prefetch cache.
+func TestSelectFilePrefetchesNext(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, []byte("package x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := loadFiles([]string{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files:  files,
+			Mode:   ModeFile,
+			Viewed: map[string]bool{},
+			loader: newFileLoader(),
+		},
+		MarkdownRenderByPath: map[string]bool{},
+	}
+
+	selectFile(model, a)
+	if model.Files[0].Lines == nil {
+		t.Fatal("expected selected file to be loaded")
+	}
+	content, ok := model.loader.take(b)
+	if !ok || len(content.lines) != 1 || content.lines[0] != "package x" {
+		t.Fatalf("expected next file to be prefetched, got %v (ok=%v)", content.lines, ok)
+	}
+}
+
+func TestDetectLineEnding(t *testing.T) {
+	tests := []struct {
+		in   string
+		want LineEnding
+	}{
+		{"", LineEndingNone},
+		{"single", LineEndingNone},
+		{"a\nb\n", LineEndingLF},
+		{"a\r\nb\r\n", LineEndingCRLF},
+		{"a\r\nb\n", LineEndingMixed},
+		{"a\rb", LineEndingNone},
+	}
+	for _, tt := range tests {
+		if got := detectLineEnding([]byte(tt.in)); got != tt.want {
+			t.Errorf("detectLineEnding(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestEmitReviewRecordsLineEndings verifies that the original line endings of
+// reviewed files, loaded or not, are recorded in the output metadata.
+func TestEmitReviewRecordsLineEndings(t *testing.T) {
+	dir := t.TempDir()
+	crlf := filepath.Join(dir, "crlf.txt")
+	lf := filepath.Join(dir, "lf.txt")
+	if err := os.WriteFile(crlf, []byte("a\r\nb\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lf, []byte("a\nb\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := loadFiles([]string{crlf, lf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ensureFileLoaded(nil, &files[0]); err != nil {
+		t.Fatal(err)
+	}
+	if files[0].LineEnding != LineEndingCRLF {
+		t.Fatalf("expected crlf after load, got %q", files[0].LineEnding)
+	}
+
+	var buf bytes.Buffer
+	if err := emitReview(&buf, &ReviewModel{Files: files}); err != nil {
+		t.Fatalf("emitReview: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "line_endings") || !strings.Contains(out, ": crlf") || !strings.Contains(out, ": lf") {
+		t.Fatalf("expected line endings in metadata, got:\n%s", out)
+	}
+}
+
+// TestSplitFileLinesTrailingNewline verifies that a trailing newline does not
+// produce a phantom empty line and that its absence is tracked separately.
+func TestSplitFileLinesTrailingNewline(t *testing.T) {
+	tests := []struct {
+		in             string
+		want           []string
+		noFinalNewline bool
+	}{
+		{"", []string{}, false},
+		{"a\nb\n", []string{"a", "b"}, false},
+		{"a\r\nb\r\n", []string{"a", "b"}, false},
+		{"a\nb", []string{"a", "b"}, true},
+		{"a\n\n", []string{"a", ""}, false},
+		{"\n", []string{""}, false},
+	}
+	for _, tt := range tests {
+		if got := splitFileLines([]byte(tt.in)); !slices.Equal(got, tt.want) {
+			t.Errorf("splitFileLines(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if got := hasNoFinalNewline([]byte(tt.in)); got != tt.noFinalNewline {
+			t.Errorf("hasNoFinalNewline(%q) = %v, want %v", tt.in, got, tt.noFinalNewline)
+		}
+	}
+}
+
+// TestNoFinalNewlineMarker verifies that the end-of-file marker is shown only
+// for files that lack a trailing newline.
+func TestNoFinalNewlineMarker(t *testing.T) {
+	with := writeTempFile(t, "with.txt", "a\nb\n")
+	without := writeTempFile(t, "without.txt", "a\nb")
+	files, err := loadFiles([]string{with, without})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: files,
+			Mode:  ModeFile,
+		},
+		MarkdownRenderByPath: map[string]bool{},
+	}
+
+	model.SelectedPath = with
+	updateView(model)
+	if model.ViewFile.NoFinalNewline || len(model.ViewFile.Lines) != 2 {
+		t.Fatalf("expected 2 lines without marker, got %d lines (marker=%v)", len(model.ViewFile.Lines), model.ViewFile.NoFinalNewline)
+	}
+
+	model.SelectedPath = without
+	updateView(model)
+	if !model.ViewFile.NoFinalNewline || len(model.ViewFile.Lines) != 2 {
+		t.Fatalf("expected 2 lines with marker, got %d lines (marker=%v)", len(model.ViewFile.Lines), model.ViewFile.NoFinalNewline)
+	}
+	if html := renderReviewHTML(t, model); !strings.Contains(html, "No newline at end of file") {
+		t.Fatal("expected end-of-file marker in rendered HTML")
+	}
+}
+
+// TestLoadFilesStripsBOM verifies that byte order marks are recorded at
+// startup and never show up in line 1, including UTF-16 content.
+func TestLoadFilesStripsBOM(t *testing.T) {
+	utf8Path := writeTempFile(t, "utf8.txt", "\xEF\xBB\xBFfirst\nsecond\n")
+	utf16Path := writeTempFile(t, "utf16.txt", "\xFF\xFEh\x00i\x00\r\x00\n\x00")
+	files, err := loadFiles([]string{utf8Path, utf16Path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files[0].BOM != BOMUTF8 || files[1].BOM != BOMUTF16LE {
+		t.Fatalf("unexpected BOMs: %q, %q", files[0].BOM, files[1].BOM)
+	}
+	for i := range files {
+		if err := ensureFileLoaded(nil, &files[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !slices.Equal(files[0].Lines, []string{"first", "second"}) {
+		t.Fatalf("unexpected utf-8 lines: %q", files[0].Lines)
+	}
+	if !slices.Equal(files[1].Lines, []string{"hi"}) || files[1].LineEnding != LineEndingCRLF {
+		t.Fatalf("unexpected utf-16 content: %q (%q)", files[1].Lines, files[1].LineEnding)
+	}
+
+	idx, err := buildLineIndex(utf8Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines, _ := idx.readLines(1, 1); !slices.Equal(lines, []string{"first"}) {
+		t.Fatalf("expected index to skip the BOM, got %q", lines)
+	}
+
+	var buf bytes.Buffer
+	if err := emitReview(&buf, &ReviewModel{Files: files}); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "byte_order_marks") || !strings.Contains(out, "utf-16le") {
+		t.Fatalf("expected byte order marks in metadata, got:\n%s", out)
+	}
+}
+
+// TestLoadFilesSkipping verifies that unreadable paths are reported as
+// skipped while the remaining files still load.
+func TestLoadFilesSkipping(t *testing.T) {
+	dir := t.TempDir()
+	ok := writeTempFile(t, "ok.go", "package ok\n")
+	missing := filepath.Join(dir, "missing.go")
+
+	files, skipped := loadFilesSkipping([]string{missing, ok, dir})
+	if len(files) != 1 || files[0].Path != ok {
+		t.Fatalf("expected only %s to load, got %+v", ok, files)
+	}
+	if len(skipped) != 2 || skipped[0].Path != filepath.ToSlash(missing) || !strings.Contains(skipped[1].Reason, "is a directory") {
+		t.Fatalf("unexpected skipped files: %+v", skipped)
+	}
+}
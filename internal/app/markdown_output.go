@@ -0,0 +1,94 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// outputMarkdown is the --output-format value for a readable report to
+// paste into a pull request description or chat, instead of the review
+// document.
+const outputMarkdown = "markdown"
+
+// verdictLabels name the verdicts in the Markdown report.
+var verdictLabels = map[Verdict]string{
+	VerdictApprove:        "Approved",
+	VerdictComment:        "Commented",
+	VerdictRequestChanges: "Changes requested",
+}
+
+// writeMarkdownReport writes the verdict and summary, then the comments
+// grouped under a heading per file in review order, each with its replies.
+func writeMarkdownReport(w io.Writer, model *ReviewModel) error {
+	var b strings.Builder
+	b.WriteString("## Review")
+	if label := verdictLabels[model.Verdict]; label != "" {
+		fmt.Fprintf(&b, ": %s", label)
+	}
+	b.WriteString("\n")
+	if text := strings.TrimSpace(model.Summary.Text); text != "" {
+		fmt.Fprintf(&b, "\n%s\n", text)
+	}
+	files := printFiles(model)
+	if len(files) == 0 {
+		b.WriteString("\nNo comments.\n")
+	}
+	for _, f := range files {
+		fmt.Fprintf(&b, "\n### %s\n\n", f.Path)
+		for _, c := range f.Comments {
+			fmt.Fprintf(&b, "- **%s:** %s", markdownLocation(c.Comment), indentMarkdown(c.Text, "  "))
+			if meta := markdownMeta(c.Comment); meta != "" {
+				fmt.Fprintf(&b, " _(%s)_", meta)
+			}
+			b.WriteByte('\n')
+			for _, r := range c.Replies {
+				fmt.Fprintf(&b, "  - **%s:** %s\n", r.Author, indentMarkdown(r.Text, "    "))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownLocation describes the lines a comment is on, e.g. "Line 10–12".
+func markdownLocation(c Comment) string {
+	var loc string
+	switch {
+	case c.isFileLevel():
+		return "File"
+	case c.StartLine == c.EndLine:
+		loc = fmt.Sprintf("Line %d", c.StartLine)
+	default:
+		loc = fmt.Sprintf("Line %d–%d", c.StartLine, c.EndLine)
+	}
+	if c.Side == "old" {
+		loc += " (removed)"
+	}
+	return loc
+}
+
+// markdownMeta lists who wrote a comment and how to weigh it, or returns ""
+// when nothing is known.
+func markdownMeta(c Comment) string {
+	var parts []string
+	if c.Author != "" {
+		parts = append(parts, c.Author)
+	}
+	if weight := weightLabel(c); weight != "" {
+		parts = append(parts, weight)
+	}
+	if c.Disposition != "" {
+		parts = append(parts, string(c.Disposition))
+	}
+	if c.Status != ThreadNone {
+		parts = append(parts, string(c.Status))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// indentMarkdown indents every line of text after the first so it stays
+// inside the list item it starts.
+func indentMarkdown(text, indent string) string {
+	return strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n"+indent)
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteMarkdownReport(t *testing.T) {
+	model := printModel()
+	model.Summary.Text = "Looks good."
+	model.Comments = append(model.Comments, Comment{ID: 3, UID: "uid-3", Path: "test.go", StartLine: 1, EndLine: 3, Text: "first line\nsecond line", Tags: Tags{"style"}})
+
+	var b strings.Builder
+	if err := writeMarkdownReport(&b, model); err != nil {
+		t.Fatal(err)
+	}
+	want := "## Review: Approved\n" +
+		"\n" +
+		"Looks good.\n" +
+		"\n" +
+		"### test.go\n" +
+		"\n" +
+		"- **Line 1:** hello\n" +
+		"- **Line 1–3:** first line\n  second line _(tags: style)_\n" +
+		"- **Line 3:** add a <doc> comment _(alice; P2)_\n" +
+		"  - **agent:** done\n"
+	if got := b.String(); got != want {
+		t.Fatalf("unexpected report:\n%s\nwant:\n%s", got, want)
+	}
+
+	b.Reset()
+	if err := writeMarkdownReport(&b, &ReviewModel{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != "## Review\n\nNo comments.\n" {
+		t.Fatalf("unexpected empty report: %q", got)
+	}
+}
@@ -13,7 +13,9 @@ func TestIsMarkdownPath(t *testing.T) {
 		{path: "README.md", want: true},
 		{path: "docs/guide.markdown", want: true},
 		{path: "docs/Guide.MD", want: true},
-		{path: "notes.mdx", want: false},
+		{path: "notes.mdx", want: true},
+		{path: "docs/index.rst", want: true},
+		{path: "docs/guide.adoc", want: true},
 		{path: "main.go", want: false},
 		{path: "README", want: false},
 	}
@@ -27,8 +29,10 @@ func TestIsMarkdownPath(t *testing.T) {
 }
 
 func TestUpdateFileViewMarkdownDefaultsToRendered(t *testing.T) {
-	m := &ReviewModel{
-		Files:        []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"# Heading", "", "Hello"}}},
+	m := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"# Heading", "", "Hello"}}},
+		},
 		SelectedPath: "README.md",
 		RenderFile:   true,
 	}
@@ -61,7 +65,7 @@ func TestUpdateFileViewMarkdownDefaultsToRendered(t *testing.T) {
 
 func TestRenderMarkdownDocumentRewritesRelativeImagePaths(t *testing.T) {
 	md := "![logo](internal/ui/logo.png)"
-	html := string(renderMarkdownDocument("README.md", md))
+	html := string(defaultRenderers.renderMarkdownDocument("README.md", md))
 	if !strings.Contains(html, `/file?path=internal%2Fui%2Flogo.png`) {
 		t.Fatalf("expected rewritten local file URL, got %q", html)
 	}
@@ -69,15 +73,40 @@ func TestRenderMarkdownDocumentRewritesRelativeImagePaths(t *testing.T) {
 
 func TestRenderMarkdownDocumentKeepsExternalImagePaths(t *testing.T) {
 	md := "![logo](https://example.com/logo.png)"
-	html := string(renderMarkdownDocument("README.md", md))
+	html := string(defaultRenderers.renderMarkdownDocument("README.md", md))
 	if !strings.Contains(html, `https://example.com/logo.png`) {
 		t.Fatalf("expected external URL unchanged, got %q", html)
 	}
 }
 
+func TestRenderMarkdownHighlightsCodeFences(t *testing.T) {
+	html := string(defaultRenderers.renderMarkdown("Use this:\n\n```go\nfunc main() {}\n```\n"))
+	if !strings.Contains(html, `<pre class="chroma">`) || !strings.Contains(html, `<span class="kd">func</span>`) {
+		t.Fatalf("expected a chroma highlighted code block, got %q", html)
+	}
+	if strings.Contains(html, "style=") {
+		t.Fatalf("expected classes rather than inline styles, got %q", html)
+	}
+}
+
+func TestRenderMarkdownEmoji(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{":shipit: :warning:", "<p>&#x1f43f;&#xfe0f; &#x26a0;&#xfe0f;</p>"},
+		{"at 12:30:45 see main.go:12: here", "<p>at 12:30:45 see main.go:12: here</p>"},
+		{":nosuchemoji:", "<p>:nosuchemoji:</p>"},
+		{"`:warning:`", "<p><code>:warning:</code></p>"},
+	} {
+		if html := strings.TrimSpace(string(defaultRenderers.renderMarkdown(tc.in))); html != tc.want {
+			t.Errorf("defaultRenderers.renderMarkdown(%q) = %q, want %q", tc.in, html, tc.want)
+		}
+	}
+}
+
 func TestUpdateFileViewMarkdownCodeMode(t *testing.T) {
-	m := &ReviewModel{
-		Files:                []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"# Heading", "Hello"}}},
+	m := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"# Heading", "Hello"}}},
+		},
 		SelectedPath:         "README.md",
 		RenderFile:           true,
 		MarkdownRenderByPath: map[string]bool{"README.md": false},
@@ -98,7 +127,7 @@ func TestUpdateFileViewMarkdownCodeMode(t *testing.T) {
 
 func TestRenderMarkdownBlocksLineNumbers(t *testing.T) {
 	input := "# Heading\n\nParagraph text\nwith two lines.\n\n- item 1\n- item 2\n"
-	blocks := renderMarkdownBlocks("test.md", input)
+	blocks := renderMarkdownBlocks(defaultRenderers, "test.md", input)
 
 	if len(blocks) != 4 {
 		t.Fatalf("expected 4 blocks (heading, paragraph, list-item-1, list-item-2), got %d", len(blocks))
@@ -147,7 +176,7 @@ func TestRenderMarkdownBlocksOrderedListWrapper(t *testing.T) {
 	// Ordered list starting at 5 should use CSS counter-reset so that list
 	// items rendered inside .md-block wrappers still number sequentially.
 	input := "5. fifth\n6. sixth\n"
-	blocks := renderMarkdownBlocks("test.md", input)
+	blocks := renderMarkdownBlocks(defaultRenderers, "test.md", input)
 
 	if len(blocks) != 2 {
 		t.Fatalf("expected 2 blocks, got %d", len(blocks))
@@ -165,7 +194,7 @@ func TestRenderMarkdownBlocksOrderedListWrapper(t *testing.T) {
 
 	// Ordered list starting at 1 should use counter-reset value 0.
 	input2 := "1. first\n2. second\n"
-	blocks2 := renderMarkdownBlocks("test.md", input2)
+	blocks2 := renderMarkdownBlocks(defaultRenderers, "test.md", input2)
 
 	if len(blocks2) < 1 {
 		t.Fatalf("expected at least 1 block for 1-indexed ordered list, got %d", len(blocks2))
@@ -178,7 +207,7 @@ func TestRenderMarkdownBlocksOrderedListWrapper(t *testing.T) {
 func TestRenderMarkdownBlocksNestedList(t *testing.T) {
 	// Nested list items belong to their parent item block; only top-level items are split.
 	input := "- parent\n  - child1\n  - child2\n- sibling\n"
-	blocks := renderMarkdownBlocks("test.md", input)
+	blocks := renderMarkdownBlocks(defaultRenderers, "test.md", input)
 
 	if len(blocks) != 2 {
 		t.Fatalf("expected 2 blocks (parent item and sibling item), got %d", len(blocks))
@@ -194,7 +223,7 @@ func TestRenderMarkdownBlocksNestedList(t *testing.T) {
 func TestRenderMarkdownBlocksTaskList(t *testing.T) {
 	// GFM task list checkboxes should render as <input> elements.
 	input := "- [ ] todo\n- [x] done\n"
-	blocks := renderMarkdownBlocks("test.md", input)
+	blocks := renderMarkdownBlocks(defaultRenderers, "test.md", input)
 
 	if len(blocks) != 2 {
 		t.Fatalf("expected 2 blocks, got %d", len(blocks))
@@ -210,7 +239,7 @@ func TestRenderMarkdownBlocksTaskList(t *testing.T) {
 func TestRenderMarkdownBlocksNonListUnchanged(t *testing.T) {
 	// Non-list blocks should have empty ListOpen and ListClose.
 	input := "# Heading\n\nParagraph\n\n> Blockquote\n"
-	blocks := renderMarkdownBlocks("test.md", input)
+	blocks := renderMarkdownBlocks(defaultRenderers, "test.md", input)
 
 	if len(blocks) < 3 {
 		t.Fatalf("expected at least 3 blocks, got %d", len(blocks))
@@ -226,14 +255,16 @@ func TestRenderMarkdownBlocksNonListUnchanged(t *testing.T) {
 }
 
 func TestMarkdownBlocksListItemCommentProjection(t *testing.T) {
-	m := &ReviewModel{
-		Files:        []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"- item 1", "- item 2", "- item 3"}}},
+	m := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"- item 1", "- item 2", "- item 3"}}},
+			Comments: []Comment{
+				{ID: 1, Path: "README.md", StartLine: 2, EndLine: 2, Text: "Note on item 2"},
+			},
+			NextCommentID: 1,
+		},
 		SelectedPath: "README.md",
 		RenderFile:   true,
-		Comments: []Comment{
-			{ID: 1, Path: "README.md", StartLine: 2, EndLine: 2, Text: "Note on item 2"},
-		},
-		NextCommentID: 1,
 	}
 
 	updateFileView(m)
@@ -266,8 +297,10 @@ func TestMarkdownBlocksListItemCommentProjection(t *testing.T) {
 }
 
 func TestMarkdownBlocksListItemSelection(t *testing.T) {
-	m := &ReviewModel{
-		Files:          []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"- item 1", "- item 2", "- item 3"}}},
+	m := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"- item 1", "- item 2", "- item 3"}}},
+		},
 		SelectedPath:   "README.md",
 		RenderFile:     true,
 		SelectionStart: 2,
@@ -299,7 +332,7 @@ func TestMarkdownBlocksListItemSelection(t *testing.T) {
 
 func TestRenderMarkdownBlocksFrontmatterOffset(t *testing.T) {
 	input := "---\ntitle: Test\n---\n# Heading\n\nBody\n"
-	blocks := renderMarkdownBlocks("test.md", input)
+	blocks := renderMarkdownBlocks(defaultRenderers, "test.md", input)
 
 	if len(blocks) < 2 {
 		t.Fatalf("expected at least 2 blocks (frontmatter + heading), got %d", len(blocks))
@@ -323,14 +356,16 @@ func TestRenderMarkdownBlocksFrontmatterOffset(t *testing.T) {
 }
 
 func TestMarkdownBlocksCommentsProjection(t *testing.T) {
-	m := &ReviewModel{
-		Files:        []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"# Heading", "", "Paragraph"}}},
+	m := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"# Heading", "", "Paragraph"}}},
+			Comments: []Comment{
+				{ID: 1, Path: "README.md", StartLine: 3, EndLine: 3, Text: "Note on paragraph"},
+			},
+			NextCommentID: 1,
+		},
 		SelectedPath: "README.md",
 		RenderFile:   true,
-		Comments: []Comment{
-			{ID: 1, Path: "README.md", StartLine: 3, EndLine: 3, Text: "Note on paragraph"},
-		},
-		NextCommentID: 1,
 	}
 
 	updateFileView(m)
@@ -353,8 +388,10 @@ func TestMarkdownBlocksCommentsProjection(t *testing.T) {
 }
 
 func TestMarkdownBlocksSelection(t *testing.T) {
-	m := &ReviewModel{
-		Files:          []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"# Heading", "", "Paragraph"}}},
+	m := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"# Heading", "", "Paragraph"}}},
+		},
 		SelectedPath:   "README.md",
 		RenderFile:     true,
 		SelectionStart: 3,
@@ -379,8 +416,10 @@ func TestMarkdownBlocksSelection(t *testing.T) {
 }
 
 func TestUpdateFileViewMarkdownResetsToRenderedOnFileSwitch(t *testing.T) {
-	m := &ReviewModel{
-		Files:        []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"# Heading"}}},
+	m := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{Path: "README.md", PathSlash: "README.md", Lines: []string{"# Heading"}}},
+		},
 		SelectedPath: "README.md",
 		RenderFile:   true,
 		ViewFile: ViewFile{
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// katexCDN is the KaTeX build --math loads unless --katex-dir names a local
+// copy.
+const katexCDN = "https://cdn.jsdelivr.net/npm/katex@0.16.11/dist/"
+
+// katexPath is where a local KaTeX build from --katex-dir is served.
+const katexPath = "/katex/"
+
+// mathAssets returns the base URL the page loads katex.min.js and
+// katex.min.css from, or "" when math is off.
+func mathAssets(cfg Config) (string, error) {
+	if cfg.KaTeXDir != "" {
+		if _, err := os.Stat(filepath.Join(cfg.KaTeXDir, "katex.min.js")); err != nil {
+			return "", fmt.Errorf("katex: %w", err)
+		}
+		return katexPath, nil
+	}
+	if cfg.Math {
+		return katexCDN, nil
+	}
+	return "", nil
+}
+
+// katexHandler serves the local KaTeX build, fonts included.
+func katexHandler(dir string) http.Handler {
+	return http.StripPrefix(katexPath, http.FileServer(http.Dir(dir)))
+}
+
+var kindMath = ast.NewNodeKind("Math")
+
+// mathNode is TeX between dollar signs: $inline$ or $$display$$.
+type mathNode struct {
+	ast.BaseInline
+	TeX     []byte
+	Display bool
+}
+
+func (n *mathNode) Kind() ast.NodeKind { return kindMath }
+
+func (n *mathNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"TeX": string(n.TeX)}, nil)
+}
+
+// mathExtension parses math between dollar signs and renders it as a
+// .math span holding the source, which the page typesets with KaTeX.
+type mathExtension struct{}
+
+func (mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(mathParser{}, 500)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(mathRenderer{}, 500)))
+}
+
+type mathParser struct{}
+
+func (mathParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Parse follows the pandoc rules so prices are left alone: an inline
+// opening $ is followed by a non-space, and its closing $ comes after a
+// non-space and before a non-digit. Math may run over several lines of a
+// paragraph, and \$ does not close it.
+func (mathParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	startLine, startPos := block.Position()
+	line, _ := block.PeekLine()
+	opener := 1
+	if len(line) > 1 && line[1] == '$' {
+		opener = 2
+	}
+	if len(line) <= opener || (opener == 1 && util.IsSpace(line[1])) {
+		return nil
+	}
+	block.Advance(opener)
+	var tex bytes.Buffer
+	for {
+		line, _ := block.PeekLine()
+		if line == nil {
+			block.SetPosition(startLine, startPos)
+			return nil
+		}
+		for i := 0; i < len(line); i++ {
+			switch {
+			case line[i] == '\\':
+				i++
+			case opener == 2 && line[i] == '$' && i+1 < len(line) && line[i+1] == '$':
+				tex.Write(line[:i])
+				block.Advance(i + 2)
+				return &mathNode{TeX: bytes.TrimSpace(tex.Bytes()), Display: true}
+			case opener == 1 && line[i] == '$':
+				if util.IsSpace(prevByte(line, i, tex.Bytes())) {
+					continue
+				}
+				if i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9' {
+					continue
+				}
+				tex.Write(line[:i])
+				block.Advance(i + 1)
+				return &mathNode{TeX: tex.Bytes()}
+			}
+		}
+		tex.Write(line)
+		block.AdvanceLine()
+	}
+}
+
+// prevByte returns the byte before line[i], looking back into the lines
+// already read.
+func prevByte(line []byte, i int, read []byte) byte {
+	if i > 0 {
+		return line[i-1]
+	}
+	if len(read) > 0 {
+		return read[len(read)-1]
+	}
+	return ' '
+}
+
+type mathRenderer struct{}
+
+func (mathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindMath, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		n := node.(*mathNode)
+		class, delim := "math inline", "$"
+		if n.Display {
+			class, delim = "math display", "$$"
+		}
+		_, _ = w.WriteString(`<span class="` + class + `">` + html.EscapeString(delim+string(n.TeX)+delim) + "</span>")
+		return ast.WalkSkipChildren, nil
+	})
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+func TestRenderMarkdownMath(t *testing.T) {
+	if html := string(defaultRenderers.renderMarkdown("Euler $e^{i\\pi}+1=0$")); strings.Contains(html, "math") {
+		t.Fatalf("expected math to be left alone without --math, got %q", html)
+	}
+
+	math := newRenderers(defaultTabWidth, true)
+	for _, tc := range []struct{ in, want string }{
+		{"Euler $e^{i\\pi}+1=0$ here", `Euler <span class="math inline">$e^{i\pi}+1=0$</span> here`},
+		{"$$\nE = mc^2\n$$", `<span class="math display">$$E = mc^2$$</span>`},
+		{"$x<y$", `<span class="math inline">$x&lt;y$</span>`},
+		{"$a_1 * b_2$ and *em*", `<span class="math inline">$a_1 * b_2$</span> and <em>em</em>`},
+		{"costs $5 and $10 now", "costs $5 and $10 now"},
+		{"a \\$x$ b", "a $x$ b"},
+		{"`$x$`", "<code>$x$</code>"},
+	} {
+		if html := string(math.renderMarkdown(tc.in)); !strings.Contains(html, tc.want) {
+			t.Errorf("renderMarkdown(%q) = %q, want %q", tc.in, html, tc.want)
+		}
+	}
+}
+
+func TestMathAssets(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "katex.min.js"), []byte("window.katex = {};"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{}, ""},
+		{Config{Math: true}, katexCDN},
+		{Config{KaTeXDir: dir}, katexPath},
+	} {
+		if got, err := mathAssets(tc.cfg); err != nil || got != tc.want {
+			t.Errorf("mathAssets(%+v) = %q, %v; want %q", tc.cfg, got, err, tc.want)
+		}
+	}
+	if _, err := mathAssets(Config{KaTeXDir: t.TempDir()}); err == nil {
+		t.Error("expected an error for a --katex-dir without katex.min.js")
+	}
+
+	rec := httptest.NewRecorder()
+	katexHandler(dir).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, katexPath+"katex.min.js", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "window.katex = {};" {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRenderKaTeXAssets(t *testing.T) {
+	model := buildCommentModel()
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{}), katex: katexPath}
+	out, err := buildLiveHandler(rs).RenderHandler(t.Context(), &live.RenderContext{Assigns: model})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(b)
+	for _, want := range []string{`<link id="katex-css" rel="stylesheet" href="/katex/katex.min.css">`, `<script defer src="/katex/katex.min.js"></script>`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in the page", want)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PatchCommit is one commit of a patch series given as git format-patch or
+// mbox input. Its files are reviewed under their own group, with the commit
+// message shown above them.
+type PatchCommit struct {
+	// Number is the commit's 1-based position in the series.
+	Number int
+	// SHA is the commit from the mbox "From" line, or "" when it is not
+	// known, as with format-patch --zero-commit.
+	SHA     string
+	Author  string
+	Date    string
+	Subject string
+	// Message is the commit message after the subject.
+	Message string
+	// Total is the number of commits in the series.
+	Total int
+}
+
+// ID identifies the commit in the review output: its SHA, or its number in
+// the series when the SHA is not known.
+func (c PatchCommit) ID() string {
+	if c.SHA != "" {
+		return c.SHA
+	}
+	return strconv.Itoa(c.Number)
+}
+
+// Label names the commit as format-patch numbers it, e.g. "[2/3] Fix it".
+func (c PatchCommit) Label() string {
+	return fmt.Sprintf("[%d/%d] %s", c.Number, c.Total, c.Subject)
+}
+
+// mboxFromRE matches the line starting each message of an mbox; git
+// format-patch puts the commit there.
+var mboxFromRE = regexp.MustCompile(`^From (\S+) `)
+
+var (
+	zeroSHARE       = regexp.MustCompile(`^0+$`)
+	hexSHARE        = regexp.MustCompile(`^[0-9a-f]{7,64}$`)
+	subjectPrefixRE = regexp.MustCompile(`^(\[[^\]]*\]\s*)+`)
+)
+
+// isPatchSeries reports whether input is git format-patch or mbox output
+// rather than a plain diff.
+func isPatchSeries(input string) bool {
+	first, _, _ := strings.Cut(strings.TrimLeft(input, "\r\n"), "\n")
+	return mboxFromRE.MatchString(first)
+}
+
+// seriesKey is the path a file of commit n is reviewed under. A series can
+// change a file in more than one commit, so the path alone is not unique.
+func seriesKey(n int, path string) string {
+	return fmt.Sprintf("%04d/%s", n, path)
+}
+
+// parsePatchSeries splits git format-patch or mbox input into its commits
+// and parses the diff of each. Files are keyed by seriesKey and tagged with
+// their commit; messages without a diff, such as a cover letter, are left
+// out. Line numbers in parse errors refer to the whole input.
+func parsePatchSeries(input string, mode diffParseMode) ([]DiffFile, []PatchCommit, error) {
+	var files []DiffFile
+	var commits []PatchCommit
+	for _, m := range splitMbox(input) {
+		commit, diff, diffLine, err := parsePatchMessage(m.text)
+		if err != nil {
+			return nil, nil, fmt.Errorf("patch at line %d: %w", m.line, err)
+		}
+		if diff == "" {
+			continue
+		}
+		parsed, err := parseUnifiedDiff(diff, mode)
+		offset := m.line + diffLine - 2
+		if err != nil {
+			var perr *DiffParseError
+			if errors.As(err, &perr) {
+				perr.Line += offset
+			}
+			return nil, nil, err
+		}
+		if len(parsed) == 0 {
+			continue
+		}
+		commit.Number = len(commits) + 1
+		commit.SHA = m.sha
+		for i := range parsed {
+			f := &parsed[i]
+			for j := range f.Warnings {
+				f.Warnings[j].Line += offset
+			}
+			f.Commit = commit.Number
+			f.Path = seriesKey(commit.Number, f.Path)
+		}
+		files = append(files, parsed...)
+		commits = append(commits, commit)
+	}
+	for i := range commits {
+		commits[i].Total = len(commits)
+	}
+	return files, commits, nil
+}
+
+// mboxMessage is one message of an mbox without its "From" line. Its text
+// starts on line of the input.
+type mboxMessage struct {
+	sha  string
+	text string
+	line int
+}
+
+// splitMbox splits input at each "From" line that starts the input or
+// follows a blank line, as mbox separates messages.
+func splitMbox(input string) []mboxMessage {
+	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+	var out []mboxMessage
+	var cur *mboxMessage
+	var body []string
+	flush := func() {
+		if cur != nil {
+			cur.text = strings.Join(body, "\n")
+			out = append(out, *cur)
+		}
+	}
+	for i, line := range lines {
+		m := mboxFromRE.FindStringSubmatch(line)
+		if m != nil && (i == 0 || strings.TrimSpace(lines[i-1]) == "" || cur == nil) {
+			flush()
+			sha := m[1]
+			if !hexSHARE.MatchString(sha) || zeroSHARE.MatchString(sha) {
+				sha = ""
+			}
+			cur = &mboxMessage{sha: sha, line: i + 2}
+			body = body[:0]
+			continue
+		}
+		if cur != nil {
+			body = append(body, line)
+		}
+	}
+	flush()
+	return out
+}
+
+// parsePatchMessage reads the commit from the mail headers and body of a
+// format-patch message and returns it with the diff that follows the
+// message, and the line of the text the diff starts on.
+func parsePatchMessage(text string) (PatchCommit, string, int, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(text))
+	if err != nil {
+		return PatchCommit{}, "", 0, err
+	}
+	data, err := io.ReadAll(msg.Body)
+	if err != nil {
+		return PatchCommit{}, "", 0, err
+	}
+	dec := new(mime.WordDecoder)
+	decode := func(s string) string {
+		if d, err := dec.DecodeHeader(s); err == nil {
+			return d
+		}
+		return s
+	}
+	commit := PatchCommit{
+		Author:  decode(msg.Header.Get("From")),
+		Date:    msg.Header.Get("Date"),
+		Subject: subjectPrefixRE.ReplaceAllString(decode(msg.Header.Get("Subject")), ""),
+	}
+
+	body := strings.Split(string(data), "\n")
+	headerLines := strings.Count(text, "\n") - len(body) + 1
+	message, diffStart := len(body), len(body)
+	for i, line := range body {
+		if line == "---" && message == len(body) {
+			message = i
+		}
+		if strings.HasPrefix(line, "diff --git ") || strings.HasPrefix(line, "--- ") && i+1 < len(body) && strings.HasPrefix(body[i+1], "+++ ") {
+			diffStart = i
+			break
+		}
+	}
+	commit.Message = strings.TrimSpace(strings.Join(body[:min(message, diffStart)], "\n"))
+	if diffStart == len(body) {
+		return commit, "", 0, nil
+	}
+	return commit, strings.Join(body[diffStart:], "\n"), headerLines + diffStart + 1, nil
+}
+
+// seriesGroups puts the files of each commit in a group of its own, in the
+// order of the series.
+func seriesGroups(commits []PatchCommit, files []DiffFile) []Group {
+	groups := make([]Group, len(commits))
+	for i, c := range commits {
+		groups[i].Name = c.Label()
+	}
+	for _, f := range files {
+		g := &groups[f.Commit-1]
+		g.Files = append(g.Files, f.Path)
+	}
+	return groups
+}
+
+// commitOf returns the commit the file at path is changed in, or nil when
+// the review is not of a patch series.
+func (m *ReviewModel) commitOf(path string) *PatchCommit {
+	if len(m.Commits) == 0 {
+		return nil
+	}
+	if f := m.lookupDiffFile(path); f != nil && f.Commit > 0 {
+		return &m.Commits[f.Commit-1]
+	}
+	return nil
+}
+
+// seriesPath returns the path in the repository of the file reviewed at
+// path, which differs from it for the files of a patch series.
+func (m *ReviewModel) seriesPath(path string) string {
+	if m.commitOf(path) == nil {
+		return path
+	}
+	f := m.lookupDiffFile(path)
+	return pickDiffPath(f.OldPath, f.NewPath)
+}
+
+// exportComments rewrites the paths of comments on a patch series to the
+// ones in the repository, with the commit they are on, as the review output
+// names them. Other comments are returned as they are.
+func exportComments(model *ReviewModel, comments []Comment) []Comment {
+	if len(model.Commits) == 0 {
+		return comments
+	}
+	out := make([]Comment, len(comments))
+	for i, c := range comments {
+		if commit := model.commitOf(c.Path); commit != nil {
+			c.Path, c.Commit = model.seriesPath(c.Path), commit.ID()
+		}
+		out[i] = c
+	}
+	return out
+}
+
+// importComment turns the repository path and commit of a comment read
+// from review output back into the path it is reviewed at. Comments that
+// name no commit of the series are left as they are.
+func importComment(model *ReviewModel, c *Comment) {
+	if c.Commit == "" {
+		return
+	}
+	for _, commit := range model.Commits {
+		if commit.ID() == c.Commit {
+			c.Path = seriesKey(commit.Number, c.Path)
+			break
+		}
+	}
+	c.Commit = ""
+}
+
+// pathLabel names the file reviewed at path in reports: its path, followed
+// by its commit for the files of a patch series.
+func (m *ReviewModel) pathLabel(path string) string {
+	if commit := m.commitOf(path); commit != nil {
+		return fmt.Sprintf("%s (%s)", m.seriesPath(path), commit.Label())
+	}
+	return path
+}
+
+// commitsMetadata lists the commits of a patch series for the review
+// output, so the commit column of its comments can be told apart.
+func commitsMetadata(commits []PatchCommit) []map[string]any {
+	out := make([]map[string]any, len(commits))
+	for i, c := range commits {
+		out[i] = map[string]any{"id": c.ID(), "number": c.Number, "subject": c.Subject}
+	}
+	return out
+}
+
+// commitMessagePath is the path the commit message is reviewed and written
+// out under, so that it can be commented on like the files of the commit.
+const commitMessagePath = "commit_message"
+
+// messageFile returns the commit message as an added file at path, the
+// subject on its first line.
+func (c PatchCommit) messageFile(path string) DiffFile {
+	text := []string{c.Subject}
+	if c.Message != "" {
+		text = append(text, "")
+		text = append(text, strings.Split(c.Message, "\n")...)
+	}
+	hunk := DiffHunk{NewStart: 1, NewCount: len(text)}
+	for i, line := range text {
+		hunk.Lines = append(hunk.Lines, DiffLine{Kind: DiffAdd, NewLine: i + 1, Text: line})
+	}
+	// There is nothing on disk to read context from; the hunk is all of it.
+	return DiffFile{
+		NewPath:    commitMessagePath,
+		Path:       path,
+		Status:     DiffAdded,
+		Hunks:      []DiffHunk{hunk},
+		source:     fileContent{lines: text, lineEnding: LineEndingLF},
+		sourceRead: true,
+	}
+}
+
+// withCommitMessages puts the message of each commit before its files.
+func withCommitMessages(files []DiffFile, commits []PatchCommit) []DiffFile {
+	out := make([]DiffFile, 0, len(files)+len(commits))
+	next := 0
+	for _, f := range files {
+		for ; next < f.Commit; next++ {
+			msg := commits[next].messageFile(seriesKey(next+1, commitMessagePath))
+			msg.Commit = next + 1
+			out = append(out, msg)
+		}
+		out = append(out, f)
+	}
+	return out
+}
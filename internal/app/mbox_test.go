@@ -0,0 +1,163 @@
+package app
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testSeries = "From 1111111111111111111111111111111111111111 Mon Sep 17 00:00:00 2001\n" +
+	"From: =?UTF-8?q?J=C3=B6rg?= <j@example.com>\n" +
+	"Date: Tue, 1 Oct 2024 10:00:00 +0200\n" +
+	"Subject: [PATCH 1/2] Add the greeting\n" +
+	"\n" +
+	"Say hello first.\n" +
+	"--- not a diff\n" +
+	"---\n" +
+	" a.go | 2 +-\n" +
+	" 1 file changed, 1 insertion(+), 1 deletion(-)\n" +
+	"\n" +
+	"diff --git a/a.go b/a.go\n" +
+	"--- a/a.go\n" +
+	"+++ b/a.go\n" +
+	"@@ -1,2 +1,2 @@\n" +
+	" package a\n" +
+	"-// TODO\n" +
+	"+// hello\n" +
+	"-- \n" +
+	"2.43.0\n" +
+	"\n" +
+	"From 2222222222222222222222222222222222222222 Mon Sep 17 00:00:00 2001\n" +
+	"From: Sam <sam@example.com>\n" +
+	"Subject: [PATCH 2/2] Say goodbye\n" +
+	"\n" +
+	"---\n" +
+	"diff --git a/a.go b/a.go\n" +
+	"--- a/a.go\n" +
+	"+++ b/a.go\n" +
+	"@@ -2,1 +2,2 @@\n" +
+	" // hello\n" +
+	"+// goodbye\n" +
+	"diff --git a/b.go b/b.go\n" +
+	"new file mode 100644\n" +
+	"--- /dev/null\n" +
+	"+++ b/b.go\n" +
+	"@@ -0,0 +1 @@\n" +
+	"+package a\n" +
+	"-- \n" +
+	"2.43.0\n"
+
+func TestParsePatchSeries(t *testing.T) {
+	if !isPatchSeries(testSeries) || isPatchSeries("diff --git a/a.go b/a.go\n") {
+		t.Fatal("expected only format-patch output to be taken for a series")
+	}
+	files, commits, err := parsePatchSeries(testSeries, diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits, got %+v", commits)
+	}
+	first := commits[0]
+	if first.Subject != "Add the greeting" || first.Author != "Jörg <j@example.com>" || first.Message != "Say hello first.\n--- not a diff" || first.Label() != "[1/2] Add the greeting" || first.ID() != strings.Repeat("1", 40) {
+		t.Fatalf("unexpected first commit: %+v", first)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	if strings.Join(paths, " ") != "0001/a.go 0002/a.go 0002/b.go" || files[1].Commit != 2 || files[1].NewPath != "a.go" {
+		t.Fatalf("unexpected files: %v", paths)
+	}
+	if hunk := files[0].Hunks[0]; len(hunk.Lines) != 3 {
+		t.Fatalf("expected the signature to be left out of the hunk, got %+v", hunk.Lines)
+	}
+	groups := seriesGroups(commits, files)
+	if len(groups) != 2 || groups[1].Name != "[2/2] Say goodbye" || strings.Join(groups[1].Files, " ") != "0002/a.go 0002/b.go" {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+
+	// Errors point at the line of the whole input.
+	broken := strings.Replace(testSeries, "@@ -2,1 +2,2 @@", "@@ -2,1 +2,x @@", 1)
+	_, _, err = parsePatchSeries(broken, diffStrict)
+	var perr *DiffParseError
+	if !errors.As(err, &perr) || perr.Line != 30 {
+		t.Fatalf("expected an error on line 30, got %v", err)
+	}
+}
+
+// TestPatchSeriesComments verifies that comments on a series are written
+// with their repository path and commit, and read back onto the same file.
+func TestPatchSeriesComments(t *testing.T) {
+	files, commits, err := parsePatchSeries(testSeries, diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{ReviewModel: &ReviewModel{Mode: ModeDiff, DiffFiles: files, Commits: commits, Viewed: map[string]bool{}}}
+	model.SelectedPath = "0002/a.go"
+	updateView(model)
+	if model.ViewDiff.Commit == nil || model.ViewDiff.Commit.Number != 2 || model.SelectedLabel != "a.go" {
+		t.Fatalf("expected the second commit to be shown, got %+v labelled %q", model.ViewDiff.Commit, model.SelectedLabel)
+	}
+	model.Comments = []Comment{{ID: 1, UID: "u1", Path: "0002/a.go", StartLine: 3, EndLine: 3, Text: "bye?"}}
+
+	doc := reviewDocument(model.ReviewModel)
+	if err := validateDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+	rec := doc["comments"].([]commentRecord)[0]
+	if rec.Path != "a.go" || rec.Commit != strings.Repeat("2", 40) {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+	if got := doc["metadata"].(map[string]any)["commits"].([]map[string]any); len(got) != 2 || got[1]["subject"] != "Say goodbye" {
+		t.Fatalf("unexpected commits metadata: %v", got)
+	}
+
+	next := &ReviewModel{Mode: ModeDiff, DiffFiles: files, Commits: commits}
+	carryOverComments(next, &PreviousReview{Comments: []Comment{{ID: 1, UID: "u1", Path: rec.Path, Commit: rec.Commit, StartLine: 3, EndLine: 3, Text: "bye?"}}})
+	if c := next.Comments[0]; c.Path != "0002/a.go" || c.Commit != "" || next.OutdatedComments[c.ID] {
+		t.Fatalf("expected the comment back on the second commit, got %+v", c)
+	}
+
+	// Only comments on the last commit to change a file match the code
+	// host's diff.
+	model.Comments = append(model.Comments, Comment{ID: 2, UID: "u2", Path: "0001/a.go", StartLine: 2, EndLine: 2, Text: "hi"})
+	published := forgeComments(model.ReviewModel)
+	if published[0].Commit != "" || published[1].Commit != strings.Repeat("1", 40) || published[1].Path != "a.go" {
+		t.Fatalf("unexpected published comments: %+v", published)
+	}
+}
+
+// TestCommitMessageFiles verifies that each commit's message is reviewed as
+// a file before the commit's own, and written out as commit_message.
+func TestCommitMessageFiles(t *testing.T) {
+	files, commits, err := parsePatchSeries(testSeries, diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = withCommitMessages(files, commits)
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	if strings.Join(paths, " ") != "0001/commit_message 0001/a.go 0002/commit_message 0002/a.go 0002/b.go" {
+		t.Fatalf("unexpected files: %v", paths)
+	}
+	var text []string
+	for _, l := range files[0].Hunks[0].Lines {
+		text = append(text, l.Text)
+	}
+	if files[0].Commit != 1 || strings.Join(text, "|") != "Add the greeting||Say hello first.|--- not a diff" {
+		t.Fatalf("unexpected message file: %+v", files[0])
+	}
+	if len(files[2].Hunks[0].Lines) != 1 {
+		t.Fatalf("expected only the subject of a commit without a message, got %+v", files[2].Hunks[0].Lines)
+	}
+
+	model := &ReviewModel{Mode: ModeDiff, DiffFiles: files, Commits: commits, Viewed: map[string]bool{}}
+	model.Comments = []Comment{{ID: 1, UID: "u1", Path: "0001/commit_message", StartLine: 1, EndLine: 1, Text: "Subject too vague"}}
+	rec := reviewDocument(model)["comments"].([]commentRecord)[0]
+	if rec.Path != commitMessagePath || rec.Commit != strings.Repeat("1", 40) {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+}
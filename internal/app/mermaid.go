@@ -0,0 +1,69 @@
+package app
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/util"
+)
+
+// mermaidCDN is the Mermaid build --mermaid loads unless --mermaid-js names
+// a local copy.
+const mermaidCDN = "https://cdn.jsdelivr.net/npm/mermaid@11.4.1/dist/mermaid.min.js"
+
+// mermaidPath is where a local Mermaid build from --mermaid-js is served.
+const mermaidPath = "/mermaid.js"
+
+// mermaidScript returns the URL the page loads Mermaid from, or "" when
+// diagrams are off.
+func mermaidScript(cfg Config) (string, error) {
+	if cfg.MermaidJS != "" {
+		if _, err := os.Stat(cfg.MermaidJS); err != nil {
+			return "", fmt.Errorf("mermaid: %w", err)
+		}
+		return mermaidPath, nil
+	}
+	if cfg.Mermaid {
+		return mermaidCDN, nil
+	}
+	return "", nil
+}
+
+// mermaidHandler serves the local Mermaid build.
+func mermaidHandler(path string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		http.ServeFile(w, r, path)
+	})
+}
+
+// codeBlockWrapper writes the markup around fenced code blocks that chroma
+// does not highlight. Mermaid blocks are wrapped in a .mermaid-diagram the
+// page draws the diagram over when --mermaid is on; otherwise they show as
+// their source.
+func codeBlockWrapper(w util.BufWriter, c highlighting.CodeBlockContext, entering bool) {
+	if c.Highlighted() {
+		return
+	}
+	lang, ok := c.Language()
+	mermaid := ok && string(lang) == "mermaid"
+	if !entering {
+		_, _ = w.WriteString("</code></pre>")
+		if mermaid {
+			_, _ = w.WriteString("</div>")
+		}
+		_ = w.WriteByte('\n')
+		return
+	}
+	if mermaid {
+		_, _ = w.WriteString(`<div class="mermaid-diagram">`)
+	}
+	_, _ = w.WriteString("<pre><code")
+	if ok {
+		_, _ = w.WriteString(` class="language-` + html.EscapeString(string(lang)) + `"`)
+	}
+	_ = w.WriteByte('>')
+}
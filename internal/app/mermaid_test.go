@@ -0,0 +1,74 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+func TestRenderMarkdownMermaid(t *testing.T) {
+	html := string(defaultRenderers.renderMarkdown("```mermaid\ngraph TD\n  A-->B\n```\n\n```\nplain\n```\n\n```nosuchlang\nx\n```\n"))
+	for _, want := range []string{
+		"<div class=\"mermaid-diagram\"><pre><code class=\"language-mermaid\">graph TD\n  A--&gt;B\n</code></pre></div>",
+		"<pre><code>plain\n</code></pre>",
+		`<pre><code class="language-nosuchlang">x`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in %q", want, html)
+		}
+	}
+}
+
+func TestMermaidScript(t *testing.T) {
+	local := writeTempFile(t, "mermaid.min.js", "window.mermaid = {};")
+	for _, tc := range []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{}, ""},
+		{Config{Mermaid: true}, mermaidCDN},
+		{Config{MermaidJS: local}, mermaidPath},
+	} {
+		if got, err := mermaidScript(tc.cfg); err != nil || got != tc.want {
+			t.Errorf("mermaidScript(%+v) = %q, %v; want %q", tc.cfg, got, err, tc.want)
+		}
+	}
+	if _, err := mermaidScript(Config{MermaidJS: filepath.Join(t.TempDir(), "missing.js")}); err == nil {
+		t.Error("expected an error for a missing --mermaid-js file")
+	}
+
+	rec := httptest.NewRecorder()
+	mermaidHandler(local).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, mermaidPath, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "window.mermaid = {};" || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/javascript") {
+		t.Fatalf("unexpected response %d %q %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
+
+func TestRenderMermaidScriptTag(t *testing.T) {
+	render := func(rs *ReviewServer) string {
+		out, err := buildLiveHandler(rs).RenderHandler(t.Context(), &live.RenderContext{Assigns: rs.start})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	model := buildCommentModel()
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	if html := render(rs); strings.Contains(html, "drawMermaid") {
+		t.Error("expected no mermaid script without --mermaid")
+	}
+	rs.mermaid = mermaidPath
+	if html := render(rs); !strings.Contains(html, `<script defer src="/mermaid.js"></script>`) || !strings.Contains(html, "drawMermaid") {
+		t.Error("expected the mermaid script with --mermaid")
+	}
+}
@@ -2,16 +2,69 @@ package app
 
 import (
 	"html/template"
+	"io"
 	"sync"
+	"time"
 )
 
 type Comment struct {
-	ID        int    `json:"id"`
+	ID int `json:"id"`
+	// UID identifies the comment across review rounds; see newCommentUID.
+	UID       string `json:"uid"`
 	Path      string `json:"path"`
 	StartLine int    `json:"start_line"`
 	EndLine   int    `json:"end_line"`
 	Side      string `json:"side,omitempty"`
 	Text      string `json:"text"`
+	// Author is the name of the reviewer who wrote the comment, or "" for
+	// connections that did not identify themselves.
+	Author string `json:"author"`
+	// Disposition is the reviewer's decision on a proposed comment.
+	Disposition Disposition `json:"disposition"`
+	// Status is set on threads carried over from a previous round.
+	Status ThreadStatus `json:"status,omitempty"`
+	// Priority and Confidence tell the agent how much weight to give the
+	// comment; both are optional.
+	Priority   Priority `json:"priority,omitempty"`
+	Confidence string   `json:"confidence,omitempty"`
+	// Tags label the comment for filtering and triage.
+	Tags Tags `json:"tags,omitempty"`
+	// Commit is the ID of the PatchCommit the comment is on when the diff
+	// is a patch series. It is only set on comments read from or written to
+	// the review output; in the review, Path tells the commit apart.
+	Commit string `json:"commit,omitempty"`
+
+	// rendered caches the markdown rendering of Text. It is filled lazily
+	// by renderedHTML and cleared whenever Text changes.
+	rendered template.HTML
+}
+
+// renderComments renders the markdown of the comments whose text changed
+// since it was last rendered, with the review's renderers, before a view is
+// built from them.
+func renderComments(model *ReviewModel) {
+	r := model.renderers()
+	for i := range model.Comments {
+		if c := &model.Comments[i]; c.rendered == "" {
+			c.rendered = r.renderMarkdown(c.Text)
+		}
+	}
+}
+
+// renderedHTML returns the markdown rendering of the comment text, as
+// renderComments left it, rendering it with the default renderers for a
+// comment outside any review.
+func (c *Comment) renderedHTML() template.HTML {
+	if c.rendered == "" {
+		c.rendered = defaultRenderers.renderMarkdown(c.Text)
+	}
+	return c.rendered
+}
+
+// isFileLevel reports whether the comment applies to its whole file rather
+// than a line range. File-level comments have StartLine and EndLine 0.
+func (c *Comment) isFileLevel() bool {
+	return c.StartLine == 0 && c.EndLine == 0
 }
 
 type Group struct {
@@ -19,12 +72,43 @@ type Group struct {
 	Files []string `json:"files"`
 }
 
+// File is a file under review. Lines is nil until the file is first
+// selected; see ensureFileLoaded. Files larger than largeFileThreshold are
+// never held in memory and are read through index instead.
 type File struct {
-	Path      string
-	PathSlash string
-	Lines     []string
+	Path       string
+	PathSlash  string
+	Size       int64
+	ModTime    time.Time
+	Lines      []string
+	LineEnding LineEnding
+	// NoFinalNewline is set when the file's last line is not terminated.
+	NoFinalNewline bool
+	// BOM is the byte order mark the file starts with on disk. It is never
+	// part of Lines.
+	BOM ByteOrderMark
+	// Binary is set for files that are not text; they are listed but their
+	// content is never read.
+	Binary bool
+	// Language is the lexer the file is highlighted with, or "" to detect
+	// it; see applyLanguages.
+	Language string
+	index    *lineIndex
+	// sum hashes the content when it is loaded; see contentSum.
+	sum uint64
 }
 
+// LineEnding describes the line breaks a file used on disk before they were
+// normalised to LF for display.
+type LineEnding string
+
+const (
+	LineEndingNone  LineEnding = ""
+	LineEndingLF    LineEnding = "lf"
+	LineEndingCRLF  LineEnding = "crlf"
+	LineEndingMixed LineEnding = "mixed"
+)
+
 type TreeItem struct {
 	Name        string
 	Path        string
@@ -34,8 +118,22 @@ type TreeItem struct {
 	IsGroup     bool
 	Viewed      bool
 	HasComments bool
+	Assignee    string
+	Updated     bool
+	// Status is what the diff does to the file; always modified in file
+	// mode.
+	Status      DiffFileStatus
 	GroupName   string
 	GroupActive bool
+	// Commit is the number of the commit the item is listed under when the
+	// review has a commit picker, or 0 for the combined diff.
+	Commit int
+	// Collapsed says why the file is collapsed, or is "".
+	Collapsed string
+	// Comments counts the file's comments; Added and Removed count the
+	// lines its diff adds and removes.
+	Comments       int
+	Added, Removed int
 }
 
 type ViewLine struct {
@@ -60,12 +158,37 @@ type MarkdownBlock struct {
 
 type ViewFile struct {
 	Path             string
+	Language         string
 	Lines            []ViewLine
 	MarkdownFile     bool
 	MarkdownRendered bool
 	MarkdownBlocks   []MarkdownBlock
+	Windowed         bool
+	WindowStart      int
+	WindowEnd        int
+	TotalLines       int
+	HasPrevWindow    bool
+	HasNextWindow    bool
+	LineEnding       LineEnding
+	NoFinalNewline   bool
+	// Empty is set for files with no lines at all.
+	Empty bool
+	// Binary is set for binary files, whose content is not shown.
+	Binary bool
+	// ImageURL serves the file for an image preview; "" for files that are
+	// not images.
+	ImageURL string
+	// OutOfRange is set when --range sections were requested but none of
+	// them overlap the file.
+	OutOfRange bool
+	// Collapsed says why the file is collapsed; its content is not shown
+	// until loaded anyway.
+	Collapsed string
 }
 
+// diffHunkBudget is the number of hunks per file built eagerly in diff mode.
+const diffHunkBudget = 20
+
 type ViewMode string
 
 const (
@@ -91,14 +214,38 @@ type ViewDiffLine struct {
 	Comments  []ViewComment
 }
 
+// ViewDiffHunk is one rendered hunk. Deferred hunks carry only their header
+// and line count; their lines are built once the client asks for them.
 type ViewDiffHunk struct {
-	Header string
-	Lines  []ViewDiffLine
+	Header    string
+	Index     int
+	Deferred  bool
+	LineCount int
+	// ExpandUp and ExpandDown offer to show unchanged lines above the hunk
+	// or below the last one.
+	ExpandUp   bool
+	ExpandDown bool
+	Lines      []ViewDiffLine
 }
 
 type ViewDiffFile struct {
-	Path  string
-	Hunks []ViewDiffHunk
+	Path     string
+	Language string
+	Status   DiffFileStatus
+	OldPath  string
+	OldMode  string
+	NewMode  string
+	Binary   bool
+	Hunks    []ViewDiffHunk
+	Warnings []DiffParseError
+	// Commit is the commit of a patch series the file is changed in.
+	Commit *PatchCommit
+	// WhitespaceHunks counts the hunks left out because HideWhitespace is on
+	// and they only change whitespace.
+	WhitespaceHunks int
+	// Collapsed says why the file is collapsed; its hunks are not shown
+	// until loaded anyway.
+	Collapsed string
 }
 
 type ViewDiffSide struct {
@@ -118,8 +265,15 @@ type ViewDiffRow struct {
 }
 
 type ViewDiffSplitHunk struct {
-	Header string
-	Rows   []ViewDiffRow
+	Header    string
+	Index     int
+	Deferred  bool
+	LineCount int
+	// ExpandUp and ExpandDown offer to show unchanged lines above the hunk
+	// or below the last one.
+	ExpandUp   bool
+	ExpandDown bool
+	Rows       []ViewDiffRow
 }
 
 type ViewComment struct {
@@ -133,38 +287,155 @@ type LineRange struct {
 	End   int
 }
 
+// ReviewModel is the review every connection shares: the files and diff
+// under review, the comments, replies, verdicts and assignments, and the
+// session settings. What a connection has open is its ReviewView.
 type ReviewModel struct {
-	Files                []File
-	DiffFiles            []DiffFile
-	Viewed               map[string]bool
-	Groups               []Group
-	HasGroups            bool
-	Tree                 []TreeItem
-	SelectedPath         string
-	SelectedLabel        string
-	CodeViewKey          string
-	Mode                 ViewMode
-	RenderFile           bool
-	RenderComments       bool
-	SidebarCollapsed     bool
-	Prompt               string
-	PromptHTML           template.HTML
-	SelectionStart       int
-	SelectionEnd         int
-	SelectionSide        string
-	CommentDraft         string
-	Comments             []Comment
-	NextCommentID        int
-	EditingCommentID     int
-	Ranges               map[string][]LineRange
+	Files     []File
+	DiffFiles []DiffFile
+	Commits   []PatchCommit
+	// CommitPicker is set when DiffFiles holds the combined diff of a git
+	// range followed by the files of each of its Commits, so reviewers can
+	// step through the commits or see the whole range.
+	CommitPicker bool
+	Viewed       map[string]bool
+	// Collapsed maps generated, vendored, lockfile and minified files, and
+	// those matching --collapse-glob, to why they are collapsed.
+	Collapsed        map[string]string
+	Groups           []Group
+	HasGroups        bool
+	Mode             ViewMode
+	Prompt           string
+	PromptHTML       template.HTML
+	Comments         []Comment
+	NextCommentID    int
+	Replies          []Reply
+	NextReplyID      int
+	Ranges           map[string][]LineRange
+	TabWidth         int
+	StaleFiles       map[string]bool
+	UpdatedFiles     map[string]bool
+	OutdatedComments map[int]bool
+	SkippedFiles     []SkippedFile
+	SyntaxRules      []SyntaxRule
+	SyntaxOverrides  map[string]string
+	Assignments      map[string]string
+	Reviewers        []string
+	Verdicts         map[string]Verdict
+	Verdict          Verdict
+	Rubric           []Criterion
+	Scores           map[string]int
+	Summary          ReviewSummary
+	Chat             []ChatMessage
+	Share            *ShareInfo
+	Completed        bool
+	CompletedBy      string
+	// Lint checks comment text before saving it.
+	Lint          bool
+	LintMinLength int
+	// Deadline is when the reviewer's time runs out; zero for none.
+	Deadline        time.Time
+	DeadlineAction  DeadlineAction
+	DeadlineExpired bool
+	// TimedOut is set when --timeout finished the review, and Aborted when
+	// SIGINT or SIGTERM did.
+	TimedOut bool
+	Aborted  bool
+	// Watching is set with --watch, when files that change on disk are
+	// reloaded into the review instead of being flagged as stale.
+	Watching bool
+	// Warming is set while files are highlighted in the background after
+	// startup; WarmDone of WarmTotal files and hunks are done.
+	Warming   bool
+	WarmDone  int
+	WarmTotal int
+	Git       *GitContext
+
+	fileIndex pathIndex[File]
+	warmup    *highlightProgress
+	diffIndex pathIndex[DiffFile]
+	// render highlights and renders markdown for the review, and loader
+	// prefetches its files; see renderers and ensureFileLoaded.
+	render *renderers
+	loader *fileLoader
+}
+
+// renderers returns the review's renderers, or the defaults for a model
+// built without a review.
+func (m *ReviewModel) renderers() *renderers {
+	if m.render == nil {
+		return defaultRenderers
+	}
+	return m.render
+}
+
+// ReviewView is what one connection has open on the shared review: who is
+// connected and in what role, the selected file and lines, the comment being
+// written or edited, the window into a large file and the display settings.
+// Each socket is assigned its own, guarded by the model lock, and the
+// template renders it.
+type ReviewView struct {
+	*ReviewModel
+
+	// Expanded holds the collapsed files the reviewer loaded anyway.
+	Expanded         map[string]bool
+	Tree             []TreeItem
+	SelectedPath     string
+	SelectedLabel    string
+	CodeViewKey      string
+	RenderFile       bool
+	RenderComments   bool
+	SidebarCollapsed bool
+	SelectionStart   int
+	SelectionEnd     int
+	SelectionSide    string
+	CommentDraft     string
+	EditingCommentID int
+	FileCommentOpen  bool
+	FileComments     []ViewComment
+	WindowStart      int
+	// WindowLines is how many lines of a windowed file are shown from
+	// WindowStart; it grows as the reviewer scrolls to the end of the
+	// window. Zero shows largeFileWindow lines.
+	WindowLines          int
+	RenderedHunks        map[string]map[int]bool
 	MarkdownRenderByPath map[string]bool
 	ViewFile             ViewFile
 	ViewDiff             ViewDiffFile
 	ViewDiffSplit        []ViewDiffSplitHunk
 	DiffFormat           DiffFormat
+	HideWhitespace       bool
+	ShowWhitespace       bool
+	TreeSort             TreeSort
 	SidebarWidth         string
-	Git                  *GitContext
-	Error                string
+	// LintIssues holds the warnings for LintedText, which is saved as is if
+	// sent again.
+	LintIssues []lintIssue
+	LintedText string
+	Error      string
+
+	// Reviewer is the name the connection identified itself with, or "".
+	Reviewer string
+	// MyFilesOnly hides tree files not assigned to Reviewer.
+	MyFilesOnly bool
+	// TagFilter shows only the comments carrying one of these tags, or
+	// every comment when empty.
+	TagFilter []string
+	// ChatOpen shows the session chat panel.
+	ChatOpen bool
+	// ShareOpen shows the link and QR code for joining a shared session.
+	ShareOpen bool
+	// Observer marks a read-only connection that watches the review but
+	// cannot change it.
+	Observer bool
+	// Announced is set once a reviewer_connected event has been written
+	// for the connection.
+	Announced bool
+	// Lang is the UI language negotiated from the browser's
+	// Accept-Language, or "" for the default.
+	Lang string
+	// LightTheme shows the light theme instead of the dark one.
+	LightTheme bool
 }
 
 // GitContext holds git repository information detected at startup.
@@ -181,8 +452,12 @@ type GitContext struct {
 // the diff hunks for the specified file path. Add lines are excluded because
 // they have no old-side representation.
 func diffOldLineExists(files []DiffFile, path string, oldLine int) bool {
-	file := findDiffFile(files, path)
-	if file == nil {
+	return hasOldLine(findDiffFile(files, path), oldLine)
+}
+
+// hasOldLine reports whether oldLine is a del or context line in file.
+func hasOldLine(file *DiffFile, oldLine int) bool {
+	if file == nil || ensureDiffLoaded(file) != nil {
 		return false
 	}
 	for _, h := range file.Hunks {
@@ -199,15 +474,177 @@ type ReviewServer struct {
 	Model    *ReviewModel
 	DoneCh   chan struct{}
 	DoneOnce sync.Once
+
+	// mu guards Model; see locked.
+	mu      sync.Mutex
+	changed bool
+
+	// start is the view a new connection opens with; see newView. It is
+	// guarded by mu.
+	start *ReviewView
+
+	// events receives lifecycle events; nil discards them.
+	events *eventLog
+
+	// lang forces the UI language for every viewer; "" negotiates it from
+	// each browser's Accept-Language.
+	lang string
+
+	// brand is the logo, avatar and accent colour; the zero value uses the
+	// built-in assets.
+	brand branding
+
+	// mermaid is the URL of the Mermaid script that draws diagrams in
+	// rendered markdown, or "" to show them as source.
+	mermaid string
+
+	// katex is the base URL of the KaTeX script and stylesheet that
+	// typeset math in rendered markdown, or "" when math is off.
+	katex string
 }
 
 type Config struct {
-	Host    string
-	Port    int
-	Paths   []string
-	Prompt  string
-	Diff    string
-	Ranges  map[string][]LineRange
-	StdDiff string
-	Groups  []Group
+	Host     string
+	Port     int
+	Paths    []string
+	Prompt   string
+	Diff     string
+	Ranges   map[string][]LineRange
+	StdDiff  string
+	Groups   []Group
+	TabWidth int
+	// StrictDiff fails on malformed diff input instead of showing warnings.
+	StrictDiff bool
+	// SkipMissing drops unreadable paths with a warning instead of failing.
+	SkipMissing bool
+	// Exclude lists glob patterns for files to leave out of the review;
+	// directories in Paths also skip what .gitignore ignores.
+	Exclude []string
+	// CollapseGlobs lists glob patterns for files to collapse by default,
+	// as generated files are.
+	CollapseGlobs []string
+	// Syntax forces the highlighting language of matching files; later
+	// rules win.
+	Syntax []SyntaxRule
+	// API serves the agent API for posting replies during the session.
+	API bool
+	// Share serves the session on the LAN behind a generated token.
+	Share bool
+	// ReviewerName is the reviewer the browser opened by meatcheck
+	// identifies as, so their comments carry it as the author.
+	ReviewerName string
+	// TLS serves the session over HTTPS. Without TLSCert and TLSKey a
+	// self-signed certificate is generated for the session.
+	TLS bool
+	// TLSCert and TLSKey are the PEM certificate and key files to serve
+	// HTTPS with; setting them implies TLS.
+	TLSCert string
+	TLSKey  string
+	// Rubric lists criteria for the reviewer to score.
+	Rubric []Criterion
+	// Vars are the --var values available to the prompt template.
+	Vars map[string]string
+	// Summary is a drafted review summary for the reviewer to edit.
+	Summary string
+	// Validate checks the emitted review against the output schema.
+	Validate bool
+	// Previous is the earlier round's result to carry comments over from.
+	Previous *PreviousReview
+	// Comments seeds the review with comments, usually the agent's own,
+	// shown as open threads like those carried over with Previous.
+	Comments *PreviousReview
+	// Output is the file the review is written to; "" prints it to stdout.
+	Output string
+	// OutputFormat encodes the review document as "toon" (the default, also
+	// for "") or "json".
+	OutputFormat string
+	// Emit selects the stdout output: "" for the review document or
+	// "llm-context" for per-comment blocks.
+	Emit string
+	// TokenBudget caps the llm-context output, in estimated tokens.
+	TokenBudget int
+	// Headless skips the server and browser and completes the review at
+	// once with Auto. With API the server runs without opening a browser
+	// and the review ends with POST /api/finish.
+	Headless bool
+	// Auto is the synthetic result of a headless run; nil finishes with no
+	// comments or verdict.
+	Auto *AutoReview
+	// Watch reloads reviewed files as they change on disk while the review
+	// is open.
+	Watch bool
+	// NoBrowser serves the review without opening a browser on it.
+	NoBrowser bool
+	// NoOpen is NoBrowser for the command line: the URL is printed to open
+	// elsewhere instead, such as through an SSH tunnel, with a QR code of
+	// it when QR is set.
+	NoOpen bool
+	QR     bool
+	// Ready is called with the review's URL once it is being served.
+	Ready func(url string)
+	// Events receives lifecycle events, one logfmt line each; nil disables
+	// them.
+	Events io.Writer
+	// Lang forces the UI language; "" follows the browser.
+	Lang string
+	// Deadline is how long the reviewer has; 0 for no limit.
+	Deadline time.Duration
+	// DeadlineAction is what happens when the deadline passes.
+	DeadlineAction DeadlineAction
+	// Timeout finishes the review as it stands once it has been open this
+	// long; 0 for no limit. Unlike Deadline it is not shown to reviewers.
+	Timeout time.Duration
+	// Logo and Avatar are paths to images replacing the built-in ones, and
+	// AccentColor a #rrggbb colour replacing the theme accent.
+	Logo        string
+	Avatar      string
+	AccentColor string
+	// LintComments warns about unactionable comment text before saving it;
+	// LintMinLength is the shortest comment that passes.
+	LintComments  bool
+	LintMinLength int
+	// Mermaid draws mermaid code blocks in rendered markdown as diagrams,
+	// with the build at MermaidJS if set or else one from a CDN.
+	Mermaid   bool
+	MermaidJS string
+	// Math typesets $...$ and $$...$$ in rendered markdown with KaTeX, from
+	// KaTeXDir if set or else from a CDN.
+	Math     bool
+	KaTeXDir string
+	// Git reviews the output of git diff instead of a diff file: the changes
+	// since the revision or within the range Git, or in the index with
+	// GitStaged. Paths then limit the diff, and GitUntracked adds untracked
+	// files as new ones.
+	Git          string
+	GitStaged    bool
+	GitUntracked bool
+	// FetchPR reviews the diff of a GitHub pull request, downloaded with
+	// GitHubToken if set. FetchPRContents also downloads the changed files
+	// so the code around the hunks can be expanded.
+	FetchPR         *GitHubPR
+	FetchPRContents bool
+	// ExportPDF is where to archive the print view of the finished review:
+	// a PDF, or HTML when the path ends in .html.
+	ExportPDF string
+	// ExportHTML is where to write a standalone HTML report of the finished
+	// review with the full files or diff and the comments inline.
+	ExportHTML string
+	// GitHubPR is the pull request to post the finished review to as a
+	// pending review, authenticated with GitHubToken.
+	GitHubPR    *GitHubPR
+	GitHubToken string
+	// GitLabMR is the merge request to post the finished review to as
+	// discussions, authenticated with GitLabToken.
+	GitLabMR    *GitLabMR
+	GitLabToken string
+
+	// handleSignals finishes the review on SIGINT or SIGTERM with the
+	// comments so far; Run sets it, leaving signals to library callers.
+	handleSignals bool
+}
+
+// SkippedFile is a path left out of the review by --skip-missing.
+type SkippedFile struct {
+	Path   string
+	Reason string
 }
@@ -11,6 +11,17 @@ import (
 type Preferences struct {
 	DiffFormat   DiffFormat `json:"diff_format,omitempty"`
 	SidebarWidth string     `json:"sidebar_width,omitempty"`
+	// HideWhitespace and ShowWhitespace are the whitespace toggles of the
+	// code view.
+	HideWhitespace bool `json:"hide_whitespace,omitempty"`
+	ShowWhitespace bool `json:"show_whitespace,omitempty"`
+	// TreeSort orders the file tree.
+	TreeSort TreeSort `json:"tree_sort,omitempty"`
+	// Logo, Avatar and AccentColor brand the UI when the matching flags are
+	// not given; they are only ever set by hand.
+	Logo        string `json:"logo,omitempty"`
+	Avatar      string `json:"avatar,omitempty"`
+	AccentColor string `json:"accent_color,omitempty"`
 }
 
 func preferencesPath() string {
@@ -0,0 +1,213 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jfyne/live"
+)
+
+// ThreadStatus tracks a comment carried over from a previous review round
+// with --previous. Comments from the current round have no status.
+type ThreadStatus string
+
+const (
+	ThreadNone     ThreadStatus = ""
+	ThreadOpen     ThreadStatus = "open"
+	ThreadResolved ThreadStatus = "resolved"
+)
+
+// PreviousReview is the comments and replies of an earlier round's result.
+type PreviousReview struct {
+	Comments []Comment `json:"comments"`
+	Replies  []Reply   `json:"replies"`
+}
+
+// ParsePreviousFile reads a result document written by an earlier session,
+// either as TOON or as JSON.
+func ParsePreviousFile(path string) (*PreviousReview, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read previous review: %w", err)
+	}
+	prev, err := parseReviewDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse previous review: %w", err)
+	}
+	return prev, nil
+}
+
+// ParseCommentsFile reads the comments --comments seeds a review with: a
+// review document as TOON or JSON, or a JSON array of comments as in the
+// document. Comments without an author are attributed to the agent.
+func ParseCommentsFile(path string) (*PreviousReview, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read comments file: %w", err)
+	}
+	var seed *PreviousReview
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		seed = &PreviousReview{}
+		err = json.Unmarshal(data, &seed.Comments)
+	} else {
+		seed, err = parseReviewDocument(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse comments file: %w", err)
+	}
+	for i := range seed.Comments {
+		c := &seed.Comments[i]
+		if strings.TrimSpace(c.Path) == "" || strings.TrimSpace(c.Text) == "" {
+			return nil, fmt.Errorf("parse comments file: comment at index %d needs a path and text", i)
+		}
+		if c.Author = strings.TrimSpace(c.Author); c.Author == "" {
+			c.Author = defaultReplyAuthor
+		}
+	}
+	return seed, nil
+}
+
+// parseReviewDocument reads the comments and replies of a review document,
+// either as TOON or as JSON.
+func parseReviewDocument(data []byte) (*PreviousReview, error) {
+	var prev PreviousReview
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		if err := json.Unmarshal(data, &prev); err != nil {
+			return nil, err
+		}
+		return &prev, nil
+	}
+	tables, err := readToonTables(string(data))
+	if err != nil {
+		return nil, err
+	}
+	// Decode the rows through JSON so they pick up the same field mapping
+	// as the JSON form.
+	encoded, err := json.Marshal(map[string]any{"comments": tables["comments"], "replies": tables["replies"]})
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(encoded, &prev); err != nil {
+		return nil, err
+	}
+	return &prev, nil
+}
+
+// carryOverComments adds the previous round's comments and replies to model
+// as threads. A thread keeps the status it had; threads without one are
+// resolved if someone replied to them, which is how the agent reports a
+// fix, and open otherwise. Comments keep their UIDs, so they can be tracked
+// from round to round; comments from output that predates UIDs get new ones.
+// Line comments whose lines are no longer under
+// review become file-level comments and are flagged as outdated. Comments
+// whose IDs are already taken are renumbered, and their replies follow them.
+func carryOverComments(model *ReviewModel, prev *PreviousReview) {
+	replied := make(map[int]bool)
+	for _, r := range prev.Replies {
+		replied[r.CommentID] = true
+	}
+	// ids maps the IDs comments had in prev to the ones they have now,
+	// which differ when they clash with comments already in the review.
+	ids := make(map[int]int)
+	uids := make(map[int]string)
+	for _, c := range prev.Comments {
+		importComment(model, &c)
+		oldID := c.ID
+		if c.ID == 0 || findComment(model, c.ID) != nil {
+			model.NextCommentID++
+			for findComment(model, model.NextCommentID) != nil {
+				model.NextCommentID++
+			}
+			c.ID = model.NextCommentID
+		}
+		if c.UID == "" || findCommentByUID(model, c.UID) != nil {
+			c.UID = newCommentUID()
+		}
+		if normalizeWeight(&c) != nil {
+			c.Priority, c.Confidence, c.Tags = PriorityNone, "", nil
+		}
+		c.rendered = ""
+		if c.Status != ThreadOpen && c.Status != ThreadResolved {
+			c.Status = ThreadOpen
+			if replied[oldID] {
+				c.Status = ThreadResolved
+			}
+		}
+		if !c.isFileLevel() && commentAnchorProblem(model, c) != "" {
+			file := c
+			file.StartLine, file.EndLine, file.Side = 0, 0, ""
+			if commentAnchorProblem(model, file) == "" {
+				c = file
+				if model.OutdatedComments == nil {
+					model.OutdatedComments = make(map[int]bool)
+				}
+				model.OutdatedComments[c.ID] = true
+			}
+		}
+		if _, ok := ids[oldID]; !ok && oldID != 0 {
+			ids[oldID] = c.ID
+		}
+		uids[c.ID] = c.UID
+		model.NextCommentID = max(model.NextCommentID, c.ID)
+		model.Comments = append(model.Comments, c)
+	}
+	replyIDs := make(map[int]bool, len(model.Replies))
+	for _, r := range model.Replies {
+		replyIDs[r.ID] = true
+	}
+	for _, r := range prev.Replies {
+		id, ok := ids[r.CommentID]
+		if !ok {
+			continue
+		}
+		r.rendered = ""
+		r.CommentID, r.CommentUID = id, uids[id]
+		if r.ID == 0 || replyIDs[r.ID] {
+			r.ID = model.NextReplyID + 1
+		}
+		replyIDs[r.ID] = true
+		model.NextReplyID = max(model.NextReplyID, r.ID)
+		model.Replies = append(model.Replies, r)
+	}
+}
+
+// defaultReviewerReplyAuthor names replies written in the UI by a reviewer
+// who did not join with a name.
+const defaultReviewerReplyAuthor = "reviewer"
+
+func registerPreviousHandlers(h *live.Handler, rs *ReviewServer) {
+	h.HandleEvent("reply-comment", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		c := findComment(view.ReviewModel, eventCommentID(view.ReviewModel, p))
+		if c == nil {
+			return view, nil
+		}
+		author := cmp.Or(view.Reviewer, defaultReviewerReplyAuthor)
+		if _, err := addReply(view.ReviewModel, c.ID, author, p.String("reply")); err != nil {
+			return view, nil
+		}
+		updateView(view)
+		rs.markChanged()
+		return view, nil
+	}))
+
+	h.HandleEvent("toggle-resolved", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		c := findComment(view.ReviewModel, eventCommentID(view.ReviewModel, p))
+		if c == nil || c.Status == ThreadNone {
+			return view, nil
+		}
+		if c.Status == ThreadResolved {
+			c.Status = ThreadOpen
+		} else {
+			c.Status = ThreadResolved
+		}
+		updateView(view)
+		rs.markChanged()
+		return view, nil
+	}))
+}
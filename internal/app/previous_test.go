@@ -0,0 +1,182 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+// TestParsePreviousFileRoundTrip verifies that a result written by meatcheck
+// reads back with the same comments and replies, including text that TOON
+// has to quote.
+func TestParsePreviousFileRoundTrip(t *testing.T) {
+	model := buildCommentModel()
+	model.Comments = []Comment{
+		{ID: 1, Path: "test.go", StartLine: 1, EndLine: 1, Text: `needs "quotes", commas: and` + "\nnewlines", Author: "alice"},
+		{ID: 2, Path: "test.go", Text: "42"},
+		{ID: 3, Path: "test.go", StartLine: 1, EndLine: 1, Text: "- list-like", Disposition: DispositionAccepted},
+	}
+	model.Replies = []Reply{{ID: 1, CommentID: 1, Author: "agent", Text: "Fixed, see a.go", Time: "2026-01-02T03:04:05Z"}}
+	var buf bytes.Buffer
+	if err := emitReview(&buf, model.ReviewModel); err != nil {
+		t.Fatal(err)
+	}
+
+	prev, err := ParsePreviousFile(writeTempFile(t, "review.toon", buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prev.Comments) != 3 || len(prev.Replies) != 1 {
+		t.Fatalf("unexpected previous review %+v", prev)
+	}
+	for i, c := range prev.Comments {
+		want := model.Comments[i]
+		if c.ID != want.ID || c.Text != want.Text || c.StartLine != want.StartLine || c.Author != want.Author || c.Disposition != want.Disposition {
+			t.Errorf("comment %d: got %+v, want %+v", i, c, want)
+		}
+	}
+	if r := prev.Replies[0]; r.CommentID != 1 || r.Text != "Fixed, see a.go" {
+		t.Errorf("unexpected reply %+v", r)
+	}
+
+	// Results from before the id column existed still load.
+	legacy := "comments[1]{author,end_line,path,\"side,omitempty\",start_line,text}:\n  \"\",3,a.go,old,2,tidy up\n"
+	prev, err = ParsePreviousFile(writeTempFile(t, "old.toon", legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := prev.Comments[0]; c.Side != "old" || c.StartLine != 2 || c.EndLine != 3 || c.Text != "tidy up" {
+		t.Fatalf("unexpected legacy comment %+v", c)
+	}
+
+	prev, err = ParsePreviousFile(writeTempFile(t, "review.json", `{"comments":[{"id":4,"path":"a.go","start_line":1,"end_line":1,"text":"json"}]}`))
+	if err != nil || len(prev.Comments) != 1 || prev.Comments[0].ID != 4 {
+		t.Fatalf("unexpected JSON result %+v (%v)", prev, err)
+	}
+}
+
+// TestCarryOverComments verifies that previous comments become open or
+// resolved threads, that comments whose lines are gone fall back to the file
+// and that the reviewer can reopen a resolved thread.
+func TestCarryOverComments(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	model.Comments = nil
+	model.NextCommentID = 0
+	carryOverComments(model.ReviewModel, &PreviousReview{
+		Comments: []Comment{
+			{ID: 3, Path: "test.go", StartLine: 1, EndLine: 1, Text: "still wrong"},
+			{ID: 5, Path: "test.go", StartLine: 1, EndLine: 1, Text: "rename this"},
+			{ID: 7, Path: "test.go", StartLine: 10, EndLine: 12, Text: "this block is gone"},
+		},
+		Replies: []Reply{{ID: 2, CommentID: 5, Author: "agent", Text: "Renamed."}, {ID: 9, CommentID: 99, Text: "orphan"}},
+	})
+
+	if got := []ThreadStatus{model.Comments[0].Status, model.Comments[1].Status}; got[0] != ThreadOpen || got[1] != ThreadResolved {
+		t.Fatalf("expected open and resolved threads, got %v", got)
+	}
+	if c := model.Comments[2]; !c.isFileLevel() || !model.OutdatedComments[7] {
+		t.Fatalf("expected the comment on removed lines to move to the file, got %+v", c)
+	}
+	if model.NextCommentID != 7 || model.NextReplyID != 2 || len(model.Replies) != 1 {
+		t.Fatalf("unexpected counters %d/%d or replies %+v", model.NextCommentID, model.NextReplyID, model.Replies)
+	}
+
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	s := live.NewSocket(ctx, engine, "reviewer")
+	s.Assign(model)
+	callEvent(t, engine, s, "toggle-resolved", map[string]string{"id": "5"})
+	if model.Comments[1].Status != ThreadOpen {
+		t.Fatal("expected the resolved thread to be reopened")
+	}
+	model.SelectionStart, model.SelectionEnd = 1, 1
+	updateView(model)
+	callEvent(t, engine, s, "add-comment", map[string]string{"comment": "new this round"})
+	if c := model.Comments[len(model.Comments)-1]; c.ID != 8 || c.Status != ThreadNone {
+		t.Fatalf("expected new comments to follow the carried-over IDs, got %+v", c)
+	}
+
+	if html := renderReviewHTML(t, model); !strings.Contains(html, "still open") || !strings.Contains(html, `live-click="toggle-resolved"`) {
+		t.Fatal("expected carried-over threads to show their status")
+	}
+	if err := validateDocument(reviewDocument(model.ReviewModel)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestParseCommentsFile verifies that --comments takes a JSON array or a
+// review document, and that comments without an author are the agent's.
+func TestParseCommentsFile(t *testing.T) {
+	seed, err := ParseCommentsFile(writeTempFile(t, "comments.json", `[
+		{"path": "test.go", "start_line": 1, "end_line": 1, "text": "check this"},
+		{"path": "test.go", "text": "file note", "author": "lint-bot"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seed.Comments) != 2 || seed.Comments[0].Author != defaultReplyAuthor || seed.Comments[1].Author != "lint-bot" {
+		t.Fatalf("unexpected seed %+v", seed.Comments)
+	}
+
+	model := buildCommentModel()
+	var buf bytes.Buffer
+	if err := emitReview(&buf, model.ReviewModel); err != nil {
+		t.Fatal(err)
+	}
+	seed, err = ParseCommentsFile(writeTempFile(t, "review.toon", buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seed.Comments) != 1 || seed.Comments[0].Text != "hello" || seed.Comments[0].Author != defaultReplyAuthor {
+		t.Fatalf("unexpected seed from a review document %+v", seed.Comments)
+	}
+
+	if _, err := ParseCommentsFile(writeTempFile(t, "bad.json", `[{"path": "test.go"}]`)); err == nil {
+		t.Error("expected a comment without text to be refused")
+	}
+}
+
+// TestSeedCommentsAfterPrevious verifies that seeded comments whose IDs
+// clash with carried-over ones are renumbered, keep their replies, and can
+// be replied to from the UI.
+func TestSeedCommentsAfterPrevious(t *testing.T) {
+	model := buildCommentModel()
+	model.Comments = nil
+	model.NextCommentID = 0
+	carryOverComments(model.ReviewModel, &PreviousReview{
+		Comments: []Comment{{ID: 1, Path: "test.go", StartLine: 1, EndLine: 1, Text: "from round one"}},
+		Replies:  []Reply{{ID: 1, CommentID: 1, Author: "agent", Text: "Done."}},
+	})
+	carryOverComments(model.ReviewModel, &PreviousReview{
+		Comments: []Comment{{ID: 1, Path: "test.go", StartLine: 1, EndLine: 1, Text: "seeded", Author: "agent"}},
+		Replies:  []Reply{{ID: 1, CommentID: 1, Author: "agent", Text: "Context for the reviewer."}},
+	})
+	if len(model.Comments) != 2 || model.Comments[1].ID != 2 {
+		t.Fatalf("expected the seeded comment to be renumbered, got %+v", model.Comments)
+	}
+	if r := model.Replies[1]; r.ID != 2 || r.CommentID != 2 || r.CommentUID != model.Comments[1].UID {
+		t.Fatalf("expected the seeded reply to follow its comment, got %+v", r)
+	}
+
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(t.Context(), buildLiveHandler(rs))
+	s := live.NewSocket(t.Context(), engine, "reviewer")
+	s.Assign(model)
+	if html := renderReviewHTML(t, model); !strings.Contains(html, `live-submit="reply-comment"`) {
+		t.Fatal("expected a reply form on seeded threads")
+	}
+	callEvent(t, engine, s, "reply-comment", map[string]string{"uid": model.Comments[1].UID, "reply": "  Agreed, fixing.  "})
+	if r := model.Replies[len(model.Replies)-1]; r.ID != 3 || r.CommentID != 2 || r.Author != defaultReviewerReplyAuthor || r.Text != "Agreed, fixing." {
+		t.Fatalf("unexpected reply %+v", r)
+	}
+	callEvent(t, engine, s, "reply-comment", map[string]string{"uid": model.Comments[1].UID, "reply": " "})
+	if len(model.Replies) != 3 {
+		t.Fatal("expected an empty reply to be ignored")
+	}
+}
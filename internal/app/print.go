@@ -0,0 +1,223 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// chromeEnv names the browser binary --export-pdf prints with, overriding
+// the search for an installed Chrome or Chromium.
+const chromeEnv = "MEATCHECK_CHROME"
+
+// pdfTimeout bounds how long the headless browser may take to print.
+const pdfTimeout = time.Minute
+
+// printTemplates holds the print view template for each UI language.
+var printTemplates = buildPrintTemplates()
+
+func buildPrintTemplates() map[string]*template.Template {
+	base := template.Must(template.New("print").Funcs(template.FuncMap{
+		"t":        translator(defaultLang),
+		"shortUID": shortUID,
+	}).Parse(printHTML))
+	out := map[string]*template.Template{defaultLang: base}
+	for _, lang := range Languages() {
+		if lang != defaultLang {
+			out[lang] = template.Must(base.Clone()).Funcs(template.FuncMap{"t": translator(lang)})
+		}
+	}
+	return out
+}
+
+type printLine struct {
+	Number    int
+	Text      string
+	Commented bool
+}
+
+type printComment struct {
+	Comment
+	Excerpt []printLine
+	Replies []ViewReply
+}
+
+type printFile struct {
+	Path     string
+	Comments []printComment
+}
+
+// printFiles groups the valid comments by file, in the order the files
+// are reviewed, with each comment's excerpt and replies.
+func printFiles(model *ReviewModel) []printFile {
+	comments, _ := validateComments(model)
+	order := map[string]int{}
+	if model.Mode == ModeDiff {
+		for i, f := range model.DiffFiles {
+			order[f.Path] = i
+		}
+	} else {
+		for i, f := range model.Files {
+			order[f.Path] = i
+		}
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		a, b := comments[i], comments[j]
+		if a.Path != b.Path {
+			return order[a.Path] < order[b.Path]
+		}
+		return a.StartLine < b.StartLine
+	})
+	var out []printFile
+	for i, c := range comments {
+		if i == 0 || comments[i-1].Path != c.Path {
+			out = append(out, printFile{Path: model.pathLabel(c.Path)})
+		}
+		pc := printComment{Comment: c, Replies: repliesTo(model, c.ID)}
+		for _, l := range commentExcerpt(model, c, llmContextLines) {
+			pc.Excerpt = append(pc.Excerpt, printLine{
+				Number:    l.Number,
+				Text:      l.Text,
+				Commented: l.Number >= c.StartLine && l.Number <= c.EndLine,
+			})
+		}
+		f := &out[len(out)-1]
+		f.Comments = append(f.Comments, pc)
+	}
+	return out
+}
+
+// writePrintView writes a self-contained, print-friendly page of the review
+// in lang: verdicts, summary, scores, every comment with its excerpt and
+// replies, and the chat transcript.
+func writePrintView(w io.Writer, model *ReviewModel, lang string, brand branding) error {
+	tmpl := printTemplates[lang]
+	if tmpl == nil {
+		tmpl = printTemplates[defaultLang]
+	}
+	data := struct {
+		Lang  string
+		CSS   template.CSS
+		Logo  template.URL
+		Model *ReviewModel
+		Files []printFile
+	}{
+		Lang:  lang,
+		CSS:   template.CSS(printCSS),
+		Logo:  brand.Logo,
+		Model: model,
+		Files: printFiles(model),
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// printHandler serves the print view of the live review at /print.
+func printHandler(rs *ReviewServer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := rs.uiLang(negotiateLang(r.Header.Get("Accept-Language")))
+		var buf bytes.Buffer
+		rs.mu.Lock()
+		err := writePrintView(&buf, rs.Model, lang, rs.branding())
+		rs.mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = buf.WriteTo(w)
+	})
+}
+
+// exportReview writes the print view of the finished review to path: as
+// HTML when path ends in .html, otherwise as a PDF printed by a headless
+// Chrome or Chromium.
+func exportReview(model *ReviewModel, path, lang string, brand branding) error {
+	if strings.EqualFold(filepath.Ext(path), ".html") {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := writePrintView(f, model, lang, brand); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	chrome, err := findChrome()
+	if err != nil {
+		return err
+	}
+	out, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp("", "meatcheck-print-*.html")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := writePrintView(tmp, model, lang, brand); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pdfTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, chrome,
+		"--headless",
+		"--disable-gpu",
+		"--no-pdf-header-footer",
+		"--print-to-pdf="+out,
+		"file://"+filepath.ToSlash(tmp.Name()),
+	)
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("export pdf with %s: %v: %s", chrome, err, strings.TrimSpace(string(msg)))
+	}
+	if _, err := os.Stat(out); err != nil {
+		return fmt.Errorf("export pdf with %s: no file written", chrome)
+	}
+	return nil
+}
+
+// findChrome locates a browser that can print to PDF headlessly.
+func findChrome() (string, error) {
+	if path := os.Getenv(chromeEnv); path != "" {
+		return path, nil
+	}
+	names := []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome", "msedge"}
+	for _, name := range names {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	if runtime.GOOS == "darwin" {
+		for _, path := range []string{
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+		} {
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("--export-pdf needs Chrome or Chromium; install one, set %s, or export to a .html file and print it from a browser", chromeEnv)
+}
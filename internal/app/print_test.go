@@ -0,0 +1,100 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func printModel() *ReviewModel {
+	model := buildCommentModel()
+	model.Files[0].Lines = []string{"package main", "", "func main() {}"}
+	model.Comments = append(model.Comments, Comment{ID: 2, UID: "uid-2", Path: "test.go", StartLine: 3, EndLine: 3, Text: "add a <doc> comment", Author: "alice", Priority: PriorityP2})
+	model.Replies = []Reply{{ID: 1, CommentID: 2, Author: "agent", Text: "done"}}
+	model.Verdict = VerdictApprove
+	model.Verdicts = map[string]Verdict{"alice": VerdictApprove}
+	model.Completed, model.CompletedBy = true, "alice"
+	return model.ReviewModel
+}
+
+func TestWritePrintView(t *testing.T) {
+	var b strings.Builder
+	if err := writePrintView(&b, printModel(), defaultLang, defaultBranding); err != nil {
+		t.Fatal(err)
+	}
+	html := b.String()
+	for _, want := range []string{
+		"Review completed by alice",
+		`<div class="print-verdict verdict-approve">Approved</div>`,
+		"<h3>test.go</h3>",
+		"add a &lt;doc&gt; comment",
+		`<span class="hl"><span class="ln">3</span>func main() {}</span>`,
+		"<strong>agent</strong> replied",
+		"&middot; P2",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("print view missing %q", want)
+		}
+	}
+	if strings.Index(html, "hello") > strings.Index(html, "add a &lt;doc&gt;") {
+		t.Error("expected comments in line order")
+	}
+
+	b.Reset()
+	if err := writePrintView(&b, printModel(), "de", defaultBranding); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "Review abgeschlossen von alice") {
+		t.Error("expected a German print view")
+	}
+}
+
+func TestPrintHandler(t *testing.T) {
+	rs := &ReviewServer{Model: printModel()}
+	req := httptest.NewRequest(http.MethodGet, "/print", nil)
+	req.Header.Set("Accept-Language", "es")
+	rec := httptest.NewRecorder()
+	printHandler(rs).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `<html lang="es">`) {
+		t.Fatalf("unexpected response %d:\n%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestExportReview(t *testing.T) {
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "review.html")
+	if err := exportReview(printModel(), htmlPath, defaultLang, defaultBranding); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(htmlPath); err != nil || !strings.Contains(string(data), "print-comment") {
+		t.Fatalf("expected the print view in %s: %v", htmlPath, err)
+	}
+
+	t.Setenv(chromeEnv, "")
+	t.Setenv("PATH", "")
+	if runtime.GOOS != "darwin" {
+		if err := exportReview(printModel(), filepath.Join(dir, "review.pdf"), defaultLang, defaultBranding); err == nil || !strings.Contains(err.Error(), chromeEnv) {
+			t.Fatalf("expected a missing browser to be reported, got %v", err)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("fake browser is a shell script")
+	}
+	fake := writeTempFile(t, "chrome", "#!/bin/sh\nfor a; do case $a in --print-to-pdf=*) echo '%PDF-1.4' > \"${a#--print-to-pdf=}\";; esac; done\n")
+	if err := os.Chmod(fake, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(chromeEnv, fake)
+	pdfPath := filepath.Join(dir, "review.pdf")
+	if err := exportReview(printModel(), pdfPath, defaultLang, defaultBranding); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(pdfPath); err != nil || !strings.HasPrefix(string(data), "%PDF") {
+		t.Fatalf("expected a PDF at %s: %v", pdfPath, err)
+	}
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jfyne/live"
+)
+
+// Priority is how urgently a comment should be acted on, from P0 (must fix)
+// to P3 (nice to have).
+type Priority string
+
+const (
+	PriorityNone Priority = ""
+	PriorityP0   Priority = "P0"
+	PriorityP1   Priority = "P1"
+	PriorityP2   Priority = "P2"
+	PriorityP3   Priority = "P3"
+)
+
+// maxConfidenceLen bounds the free-form confidence note on a comment.
+const maxConfidenceLen = 80
+
+func validPriority(p Priority) bool {
+	switch p {
+	case PriorityNone, PriorityP0, PriorityP1, PriorityP2, PriorityP3:
+		return true
+	}
+	return false
+}
+
+// normalizeWeight validates the priority, confidence and tags of c, trimming
+// the confidence note and normalizing the tags.
+func normalizeWeight(c *Comment) error {
+	c.Priority = Priority(strings.ToUpper(strings.TrimSpace(string(c.Priority))))
+	if !validPriority(c.Priority) {
+		return fmt.Errorf("priority must be one of P0, P1, P2 or P3")
+	}
+	c.Confidence = strings.TrimSpace(c.Confidence)
+	if len(c.Confidence) > maxConfidenceLen {
+		return fmt.Errorf("confidence must be at most %d characters", maxConfidenceLen)
+	}
+	tags, err := normalizeTags(c.Tags)
+	if err != nil {
+		return err
+	}
+	c.Tags = tags
+	return nil
+}
+
+// weightFromParams reads the priority, confidence and tags fields of a
+// comment form into c.
+func weightFromParams(c *Comment, p live.Params) error {
+	c.Priority = Priority(p.String("priority"))
+	c.Confidence = p.String("confidence")
+	c.Tags = parseTags(p.String("tags"))
+	return normalizeWeight(c)
+}
+
+// weightLabel describes a comment's priority, confidence and tags for
+// plain-text output, or returns "" when none is set.
+func weightLabel(c Comment) string {
+	var parts []string
+	if c.Priority != PriorityNone {
+		parts = append(parts, string(c.Priority))
+	}
+	if c.Confidence != "" {
+		parts = append(parts, "confidence: "+c.Confidence)
+	}
+	if len(c.Tags) > 0 {
+		parts = append(parts, "tags: "+strings.Join(c.Tags, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+// TestCommentPriorityAndConfidence verifies that the comment form sets a
+// priority and confidence, that the edit form changes them and that they
+// are shown on the comment and written to the output.
+func TestCommentPriorityAndConfidence(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	model.Comments = nil
+	rebuildTree(model)
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	s := live.NewSocket(ctx, engine, "alice")
+	s.Assign(model)
+
+	callEvent(t, engine, s, "select-line", map[string]string{"line": "1"})
+	if html := renderReviewHTML(t, model); !strings.Contains(html, `<select name="priority">`) || !strings.Contains(html, `name="confidence"`) {
+		t.Fatal("expected priority and confidence fields in the comment form")
+	}
+	callEvent(t, engine, s, "add-comment", map[string]string{"comment": "leaks", "priority": "P9"})
+	if len(model.Comments) != 0 || model.Error == "" {
+		t.Fatalf("expected an unknown priority to be rejected, got %+v", model.Comments)
+	}
+	callEvent(t, engine, s, "add-comment", map[string]string{"comment": "leaks", "priority": "p1", "confidence": " fairly sure "})
+	c := model.Comments[0]
+	if c.Priority != PriorityP1 || c.Confidence != "fairly sure" {
+		t.Fatalf("unexpected weight %q/%q", c.Priority, c.Confidence)
+	}
+
+	html := renderReviewHTML(t, model)
+	for _, want := range []string{`<span class="priority-tag priority-P1" title="Priority">P1</span>`, "confidence: fairly sure"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in the rendered comment", want)
+		}
+	}
+
+	model.EditingCommentID = c.ID
+	if html := renderReviewHTML(t, model); !strings.Contains(html, `<option value="P1" selected>`) || !strings.Contains(html, `value="fairly sure"`) {
+		t.Fatal("expected the edit form to be pre-filled with the comment's weight")
+	}
+	callEvent(t, engine, s, "edit-comment", map[string]string{"uid": c.UID, "comment": "leaks memory", "priority": "P0"})
+	if c := model.Comments[0]; c.Priority != PriorityP0 || c.Confidence != "" || c.Text != "leaks memory" {
+		t.Fatalf("unexpected comment after edit: %+v", c)
+	}
+
+	var out strings.Builder
+	if err := emitReview(&out, model.ReviewModel); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `,P0,`) {
+		t.Fatalf("expected the priority in the output:\n%s", out.String())
+	}
+	if err := validateDocument(reviewDocument(model.ReviewModel)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProposedCommentWeight(t *testing.T) {
+	model := buildCommentModel()
+	if _, err := proposeComment(model.ReviewModel, Comment{Path: "test.go", Text: "x", Priority: "urgent"}); err == nil {
+		t.Fatal("expected an unknown priority to be rejected")
+	}
+	c, err := proposeComment(model.ReviewModel, Comment{Path: "test.go", StartLine: 1, Text: "x", Priority: "P2", Confidence: "low"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := weightLabel(c); got != "P2, confidence: low" {
+		t.Fatalf("unexpected label %q", got)
+	}
+	var b strings.Builder
+	writeLLMBlock(&b, c, nil, -1)
+	if !strings.HasPrefix(b.String(), "### test.go:1 (P2, confidence: low) [") {
+		t.Fatalf("unexpected llm-context heading %q", b.String())
+	}
+}
@@ -0,0 +1,91 @@
+package app
+
+// partialReview is the review's progress while the session is open, served
+// at /api/partial for orchestrators waiting on the process.
+type partialReview struct {
+	Comments      []Comment `json:"comments"`
+	Replies       []Reply   `json:"replies"`
+	ViewedFiles   []string  `json:"viewed_files"`
+	FileCount     int       `json:"file_count"`
+	ViewedCount   int       `json:"viewed_count"`
+	PercentViewed int       `json:"percent_viewed"`
+	Reviewers     []string  `json:"reviewers"`
+	Verdict       Verdict   `json:"verdict"`
+	Completed     bool      `json:"completed"`
+	CompletedBy   string    `json:"completed_by"`
+}
+
+// partialResult snapshots model's progress. Files are counted once each, in
+// review order, whether or not they are in a group.
+func partialResult(model *ReviewModel) partialReview {
+	paths := reviewPaths(model)
+	p := partialReview{
+		Comments:    append([]Comment{}, model.Comments...),
+		Replies:     append([]Reply{}, model.Replies...),
+		ViewedFiles: []string{},
+		FileCount:   len(paths),
+		Reviewers:   append([]string{}, model.Reviewers...),
+		Verdict:     model.Verdict,
+		Completed:   model.Completed,
+		CompletedBy: model.CompletedBy,
+	}
+	for _, path := range paths {
+		if model.Viewed[path] {
+			p.ViewedFiles = append(p.ViewedFiles, path)
+		}
+	}
+	progress := viewedProgress(model)
+	p.ViewedCount, p.PercentViewed = progress.Viewed, progress.Percent
+	return p
+}
+
+// reviewProgress counts the files marked viewed, for the header and
+// /api/partial.
+type reviewProgress struct {
+	Viewed  int
+	Total   int
+	Percent int
+}
+
+func viewedProgress(model *ReviewModel) reviewProgress {
+	paths := reviewPaths(model)
+	p := reviewProgress{Total: len(paths)}
+	for _, path := range paths {
+		if model.Viewed[path] {
+			p.Viewed++
+		}
+	}
+	if p.Total > 0 {
+		p.Percent = p.Viewed * 100 / p.Total
+	}
+	return p
+}
+
+// viewedMetadata records for every file under review whether it was marked
+// viewed, keyed by slash path.
+func viewedMetadata(model *ReviewModel) map[string]any {
+	out := make(map[string]any)
+	for _, path := range reviewPaths(model) {
+		key := path
+		if f := model.lookupFile(path); f != nil {
+			key = f.PathSlash
+		}
+		out[key] = model.Viewed[path]
+	}
+	return out
+}
+
+// reviewPaths lists the paths under review in the order they were given.
+func reviewPaths(model *ReviewModel) []string {
+	paths := []string{}
+	if model.Mode == ModeDiff {
+		for _, df := range model.DiffFiles {
+			paths = append(paths, df.Path)
+		}
+	} else {
+		for _, f := range model.Files {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestAPIPartial verifies that orchestrators can poll the review's progress
+// while the session is open.
+func TestAPIPartial(t *testing.T) {
+	model := buildCommentModel()
+	model.Files = append(model.Files, File{Path: "b.go", PathSlash: "b.go"}, File{Path: "c.go", PathSlash: "c.go"}, File{Path: "d.go", PathSlash: "d.go"})
+	model.Viewed["test.go"] = true
+	model.Viewed["c.go"] = true
+	srv := httptest.NewServer(apiHandler(&ReviewServer{Model: model.ReviewModel}, nil))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/partial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got partialReview
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.FileCount != 4 || got.ViewedCount != 2 || got.PercentViewed != 50 || len(got.Comments) != 1 || got.Completed {
+		t.Fatalf("unexpected progress %+v", got)
+	}
+	if got.ViewedFiles[0] != "test.go" || got.ViewedFiles[1] != "c.go" {
+		t.Fatalf("expected viewed files in review order, got %v", got.ViewedFiles)
+	}
+}
+
+// TestViewedProgress verifies the header's viewed count and the per-file
+// viewed marks written to the review document.
+func TestViewedProgress(t *testing.T) {
+	model := buildCommentModel()
+	model.Files = append(model.Files, File{Path: "b.go", PathSlash: "b.go"}, File{Path: "c.go", PathSlash: "c.go"})
+	model.Viewed["b.go"] = true
+
+	html := renderReviewHTML(t, model)
+	if !strings.Contains(html, `<span class="review-progress-count">1/3</span>`) || !strings.Contains(html, "width: 33%") {
+		t.Fatal("expected the header to show 1/3 files viewed")
+	}
+
+	doc := reviewDocument(model.ReviewModel)
+	viewed := doc["metadata"].(map[string]any)["viewed"].(map[string]any)
+	if viewed["test.go"] != false || viewed["b.go"] != true || viewed["c.go"] != false || len(viewed) != 3 {
+		t.Fatalf("unexpected viewed metadata %v", viewed)
+	}
+	if err := validateDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+}
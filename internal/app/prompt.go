@@ -0,0 +1,68 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// promptData is what --prompt templates can refer to, e.g. {{.FileCount}}
+// or {{.Vars.ticket}} for a value given with --var ticket=...
+type promptData struct {
+	FileCount int
+	Files     []string
+	Mode      ViewMode
+	Branch    string
+	WorkDir   string
+	RepoRoot  string
+	Vars      map[string]string
+}
+
+// ParseVarFlag parses repeated --var key=value flags.
+func ParseVarFlag(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	vars := make(map[string]string, len(values))
+	for _, val := range values {
+		key, value, ok := strings.Cut(val, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid var: %s (want key=value)", val)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// expandPrompt executes prompt as a text/template over the session's files
+// and git context. Unknown fields and vars are errors rather than silently
+// rendering as "<no value>".
+func expandPrompt(prompt string, model *ReviewModel, vars map[string]string) (string, error) {
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(prompt)
+	if err != nil {
+		return prompt, fmt.Errorf("parse prompt template: %w", err)
+	}
+	data := promptData{Mode: model.Mode, Vars: vars}
+	if model.Mode == ModeDiff {
+		for _, df := range model.DiffFiles {
+			data.Files = append(data.Files, df.Path)
+		}
+	} else {
+		for _, f := range model.Files {
+			data.Files = append(data.Files, f.PathSlash)
+		}
+	}
+	data.FileCount = len(data.Files)
+	if git := model.Git; git != nil {
+		data.Branch, data.WorkDir, data.RepoRoot = git.Branch, git.WorkDir, git.RepoRoot
+	}
+	if data.Vars == nil {
+		data.Vars = map[string]string{}
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return prompt, fmt.Errorf("expand prompt template: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseVarFlag verifies key=value parsing; values may contain '='.
+func TestParseVarFlag(t *testing.T) {
+	vars, err := ParseVarFlag([]string{"ticket=ABC-12", "query=a=b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["ticket"] != "ABC-12" || vars["query"] != "a=b" {
+		t.Fatalf("unexpected vars %v", vars)
+	}
+	for _, bad := range []string{"novalue", "=x"} {
+		if _, err := ParseVarFlag([]string{bad}); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+// TestExpandPrompt verifies the built-in fields and --var values available to
+// prompt templates, and that unknown vars fail instead of rendering blank.
+func TestExpandPrompt(t *testing.T) {
+	model := &ReviewModel{
+		Mode:  ModeFile,
+		Files: []File{{Path: "a.go", PathSlash: "a.go"}, {Path: "b.go", PathSlash: "b.go"}},
+		Git:   &GitContext{Branch: "feature/x"},
+	}
+	got, err := expandPrompt("Review {{.FileCount}} files on {{.Branch}} for {{.Vars.ticket}}: {{range .Files}}{{.}} {{end}}", model, map[string]string{"ticket": "ABC-12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Review 2 files on feature/x for ABC-12: a.go b.go "; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if got, err := expandPrompt("{{.Vars.missing}}", model, nil); err == nil || !strings.Contains(err.Error(), "missing") || got != "{{.Vars.missing}}" {
+		t.Fatalf("expected the literal prompt and an error naming the missing var, got %q, %v", got, err)
+	}
+	if got, _ := expandPrompt("Plain **markdown** prompt", model, nil); got != "Plain **markdown** prompt" {
+		t.Fatalf("expected prompts without placeholders to be unchanged, got %q", got)
+	}
+}
+
+// TestExpandPromptKeepsLiteralBraces verifies that a prompt quoting another
+// template language is shown as written instead of failing the review.
+func TestExpandPromptKeepsLiteralBraces(t *testing.T) {
+	model := &ReviewModel{Mode: ModeFile}
+	for _, prompt := range []string{
+		"Check the chart sets {{ .Values.x }} in values.yaml",
+		"Unbalanced {{ in a prompt",
+	} {
+		got, err := expandPrompt(prompt, model, nil)
+		if err == nil {
+			t.Errorf("%q: expected an error to warn about", prompt)
+		}
+		if got != prompt {
+			t.Errorf("got %q, want the prompt unchanged", got)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package app
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultReplyAuthor names replies posted through the API without an author.
+const defaultReplyAuthor = "agent"
+
+// Reply is a response posted to a comment thread while the session is open,
+// typically by the agent whose work is under review.
+type Reply struct {
+	ID         int    `json:"id"`
+	CommentID  int    `json:"comment_id"`
+	CommentUID string `json:"comment_uid"`
+	Author     string `json:"author"`
+	Text       string `json:"text"`
+	Time       string `json:"time"`
+
+	rendered template.HTML
+}
+
+// ViewReply is a reply prepared for rendering.
+type ViewReply struct {
+	Reply
+	Rendered template.HTML
+}
+
+// repliesTo returns the replies to comment id in the order they were posted.
+// Reply markdown is rendered with raw HTML shown as text, as replies come
+// from the agent and other reviewers.
+func repliesTo(model *ReviewModel, id int) []ViewReply {
+	var out []ViewReply
+	for i := range model.Replies {
+		r := &model.Replies[i]
+		if r.CommentID != id {
+			continue
+		}
+		if r.rendered == "" {
+			r.rendered = model.renderers().renderMarkdown(r.Text)
+		}
+		out = append(out, ViewReply{Reply: *r, Rendered: r.rendered})
+	}
+	return out
+}
+
+// addReply appends a reply to comment id.
+func addReply(model *ReviewModel, id int, author, text string) (Reply, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Reply{}, fmt.Errorf("reply text is required")
+	}
+	c := findComment(model, id)
+	if c == nil {
+		return Reply{}, fmt.Errorf("comment not found")
+	}
+	author = strings.TrimSpace(author)
+	if author == "" {
+		author = defaultReplyAuthor
+	}
+	model.NextReplyID++
+	reply := Reply{
+		ID:         model.NextReplyID,
+		CommentID:  id,
+		CommentUID: c.UID,
+		Author:     author,
+		Text:       text,
+		Time:       time.Now().UTC().Format(time.RFC3339),
+	}
+	model.Replies = append(model.Replies, reply)
+	return reply, nil
+}
+
+// dropReplies removes the replies to a deleted comment.
+func dropReplies(model *ReviewModel, id int) {
+	kept := model.Replies[:0]
+	for _, r := range model.Replies {
+		if r.CommentID != id {
+			kept = append(kept, r)
+		}
+	}
+	model.Replies = kept
+}
+
+func findComment(model *ReviewModel, id int) *Comment {
+	for i := range model.Comments {
+		if model.Comments[i].ID == id {
+			return &model.Comments[i]
+		}
+	}
+	return nil
+}
+
+// apiHandler serves the agent API under /api/:
+//
+//	GET  /api/state                 the files under review plus /api/partial
+//	GET  /api/comments              current comments and replies as JSON
+//	GET  /api/partial               comments plus viewed-file progress
+//	POST /api/comments              propose a comment for the reviewer to decide
+//	                                on, or with "reviewer" add it as theirs
+//	POST /api/comments/{id}/replies post {"text": ..., "author": ...}; {id} is
+//	                                the comment's numeric ID or its UID
+//	PUT  /api/diff                  replace the diff under review
+//	POST /api/diff                  replace or add the files in a diff
+//	POST /api/finish                finish the review, optionally with
+//	                                {"reviewer": ..., "verdict": ...}
+//
+// notify is called after the review changes, with the model unlocked, so
+// connected clients re-render.
+func apiHandler(rs *ReviewServer, notify func()) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/comments", func(w http.ResponseWriter, r *http.Request) {
+		rs.mu.Lock()
+		body := map[string]any{
+			"comments": slices.Clone(nonNil(rs.Model.Comments)),
+			"replies":  slices.Clone(nonNil(rs.Model.Replies)),
+		}
+		rs.mu.Unlock()
+		writeJSON(w, http.StatusOK, body)
+	})
+	mux.HandleFunc("GET /api/state", func(w http.ResponseWriter, r *http.Request) {
+		rs.mu.Lock()
+		body := stateOf(rs.Model)
+		rs.mu.Unlock()
+		writeJSON(w, http.StatusOK, body)
+	})
+	mux.HandleFunc("GET /api/partial", func(w http.ResponseWriter, r *http.Request) {
+		rs.mu.Lock()
+		body := partialResult(rs.Model)
+		rs.mu.Unlock()
+		writeJSON(w, http.StatusOK, body)
+	})
+	mux.HandleFunc("POST /api/comments", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Comment
+			Reviewer string `json:"reviewer"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		rs.mu.Lock()
+		importComment(rs.Model, &body.Comment)
+		var c Comment
+		var err error
+		if reviewer := strings.TrimSpace(body.Reviewer); reviewer != "" {
+			c, err = addReviewerComment(rs.Model, body.Comment, reviewer)
+		} else {
+			c, err = proposeComment(rs.Model, body.Comment)
+		}
+		if err == nil {
+			rs.events.emit(eventCommentAdded, "id", c.ID, "path", c.Path, "count", len(rs.Model.Comments), "author", c.Author)
+		}
+		rs.mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if notify != nil {
+			notify()
+		}
+		writeJSON(w, http.StatusCreated, c)
+	})
+	mux.HandleFunc("POST /api/comments/{id}/replies", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text   string `json:"text"`
+			Author string `json:"author"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		rs.mu.Lock()
+		c := lookupComment(rs.Model, r.PathValue("id"))
+		if c == nil {
+			rs.mu.Unlock()
+			http.Error(w, "comment not found", http.StatusNotFound)
+			return
+		}
+		reply, err := addReply(rs.Model, c.ID, body.Author, body.Text)
+		rs.mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if notify != nil {
+			notify()
+		}
+		writeJSON(w, http.StatusCreated, reply)
+	})
+	mux.HandleFunc("POST /api/finish", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Reviewer string  `json:"reviewer"`
+			Verdict  Verdict `json:"verdict"`
+		}
+		// The body is optional: an empty one finishes without a verdict.
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if body.Verdict != VerdictNone && !validVerdict(body.Verdict) {
+			http.Error(w, "verdict must be approve, comment or request-changes", http.StatusBadRequest)
+			return
+		}
+		reviewer := cmp.Or(strings.TrimSpace(body.Reviewer), defaultReviewerReplyAuthor)
+		if !rs.finishFromAPI(reviewer, body.Verdict) {
+			http.Error(w, "review already finished", http.StatusConflict)
+			return
+		}
+		rs.mu.Lock()
+		state := stateOf(rs.Model)
+		rs.mu.Unlock()
+		if notify != nil {
+			notify()
+		}
+		rs.DoneOnce.Do(func() {
+			close(rs.DoneCh)
+		})
+		writeJSON(w, http.StatusOK, state)
+	})
+	mux.HandleFunc("PUT /api/diff", diffUpdateHandler(rs, notify, true))
+	mux.HandleFunc("POST /api/diff", diffUpdateHandler(rs, notify, false))
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestAPIPostReply verifies that the agent can reply to a comment thread
+// through the API, that clients are notified and that the reply renders
+// under the comment.
+func TestAPIPostReply(t *testing.T) {
+	model := buildCommentModel()
+	id := model.Comments[0].ID
+	notified := 0
+	srv := httptest.NewServer(apiHandler(&ReviewServer{Model: model.ReviewModel}, func() { notified++ }))
+	defer srv.Close()
+
+	post := func(path, body string) int {
+		t.Helper()
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if got := post("/api/comments/"+strconv.Itoa(id)+"/replies", `{"text":"Fixed in the next revision."}`); got != http.StatusCreated {
+		t.Fatalf("post reply: got status %d", got)
+	}
+	if got := post("/api/comments/999/replies", `{"text":"hello"}`); got != http.StatusNotFound {
+		t.Fatalf("reply to missing comment: got status %d", got)
+	}
+	if got := post("/api/comments/"+strconv.Itoa(id)+"/replies", `{"text":"  "}`); got != http.StatusBadRequest {
+		t.Fatalf("empty reply: got status %d", got)
+	}
+	if notified != 1 || len(model.Replies) != 1 || model.Replies[0].Author != defaultReplyAuthor {
+		t.Fatalf("unexpected replies %+v (notified %d)", model.Replies, notified)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/comments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var listing struct {
+		Comments []Comment `json:"comments"`
+		Replies  []Reply   `json:"replies"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		t.Fatal(err)
+	}
+	if len(listing.Comments) != len(model.Comments) || len(listing.Replies) != 1 || listing.Replies[0].CommentID != id {
+		t.Fatalf("unexpected listing: %+v", listing)
+	}
+
+	if html := renderReviewHTML(t, model); !strings.Contains(html, "Fixed in the next revision.") {
+		t.Fatal("expected reply to render under its comment")
+	}
+
+	deleteComment(model.ReviewModel, id)
+	if len(model.Replies) != 0 {
+		t.Fatalf("expected replies to be dropped with their comment, got %+v", model.Replies)
+	}
+}
+
+// TestReplyEscapesScript verifies that a <script> in a reply body shows as
+// text on every reviewer's page instead of running.
+func TestReplyEscapesScript(t *testing.T) {
+	model := buildCommentModel()
+	id := model.Comments[0].ID
+	if _, err := addReply(model.ReviewModel, id, "agent", "<script>alert(document.cookie)</script>"); err != nil {
+		t.Fatal(err)
+	}
+	html := renderReviewHTML(t, model)
+	if strings.Contains(html, "<script>alert(document.cookie)") {
+		t.Fatal("expected the reply's script tag to be escaped")
+	}
+	if !strings.Contains(html, "&lt;script&gt;alert(document.cookie)&lt;/script&gt;") {
+		t.Fatal("expected the reply's script tag to show as text")
+	}
+}
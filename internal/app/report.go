@@ -0,0 +1,213 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+)
+
+// reportTemplates holds the --export-html report template for each UI
+// language.
+var reportTemplates = buildReportTemplates()
+
+func buildReportTemplates() map[string]*template.Template {
+	base := template.Must(template.New("report").Funcs(template.FuncMap{
+		"t":        translator(defaultLang),
+		"shortUID": shortUID,
+	}).Parse(reportHTML))
+	out := map[string]*template.Template{defaultLang: base}
+	for _, lang := range Languages() {
+		if lang != defaultLang {
+			out[lang] = template.Must(base.Clone()).Funcs(template.FuncMap{"t": translator(lang)})
+		}
+	}
+	return out
+}
+
+// reportLine is one line of a reported file or diff hunk, with the comments
+// that end on it. Old is 0 outside diff mode and on added lines, New is 0 on
+// removed lines.
+type reportLine struct {
+	Kind     DiffLineKind
+	Old      int
+	New      int
+	HTML     template.HTML
+	Comments []printComment
+}
+
+// reportHunk is a run of consecutive lines: a diff hunk, a whole file, or
+// for files too large to include, the lines around a comment.
+type reportHunk struct {
+	Header string
+	Lines  []reportLine
+}
+
+type reportFile struct {
+	Path    string
+	OldPath string
+	Status  DiffFileStatus
+	// Note explains why the content is missing or incomplete.
+	Note         string
+	FileComments []printComment
+	Hunks        []reportHunk
+}
+
+// reportFiles lays out every reviewed file, or every file in the diff, with
+// the valid comments placed under the line they end on. File-level comments
+// and comments whose line is not shown head their file.
+func reportFiles(model *ReviewModel) []reportFile {
+	comments, _ := validateComments(model)
+	byPath := make(map[string][]Comment)
+	for _, c := range comments {
+		byPath[c.Path] = append(byPath[c.Path], c)
+	}
+	var out []reportFile
+	if model.Mode == ModeDiff {
+		for i := range model.DiffFiles {
+			out = append(out, reportDiffFile(model, &model.DiffFiles[i], byPath[model.DiffFiles[i].Path]))
+		}
+		return out
+	}
+	for i := range model.Files {
+		out = append(out, reportFileContent(model, &model.Files[i], byPath[model.Files[i].Path]))
+	}
+	return out
+}
+
+// placeComments attaches each comment to the line of rf it ends on, on its
+// side of the diff, and the rest to the file.
+func placeComments(model *ReviewModel, rf *reportFile, comments []Comment) {
+	for _, c := range comments {
+		pc := printComment{Comment: c, Replies: repliesTo(model, c.ID)}
+		if line := rf.commentLine(c); line != nil {
+			line.Comments = append(line.Comments, pc)
+		} else {
+			rf.FileComments = append(rf.FileComments, pc)
+		}
+	}
+}
+
+func (rf *reportFile) commentLine(c Comment) *reportLine {
+	if c.isFileLevel() {
+		return nil
+	}
+	for i := range rf.Hunks {
+		for j := range rf.Hunks[i].Lines {
+			l := &rf.Hunks[i].Lines[j]
+			if c.Side == "old" {
+				if l.Kind != DiffAdd && l.Old == c.EndLine {
+					return l
+				}
+			} else if l.Kind != DiffDel && l.New == c.EndLine {
+				return l
+			}
+		}
+	}
+	return nil
+}
+
+func reportFileContent(model *ReviewModel, file *File, comments []Comment) reportFile {
+	rf := reportFile{Path: file.Path}
+	switch {
+	case file.Binary:
+		rf.Note = "Binary file not rendered."
+	case ensureFileLoaded(model.loader, file) != nil:
+		rf.Note = "The file could not be read."
+	case file.index != nil:
+		rf.Note = "File too large to include in full; only the commented lines are shown."
+		for _, c := range comments {
+			excerpt := commentExcerpt(model, c, llmContextLines)
+			if len(excerpt) == 0 {
+				continue
+			}
+			texts := make([]string, len(excerpt))
+			for i, l := range excerpt {
+				texts[i] = l.Text
+			}
+			rendered := model.renderers().code.RenderLinesAs(file.Path, file.Language, texts)
+			h := reportHunk{Header: fmt.Sprintf("@@ %d-%d @@", excerpt[0].Number, excerpt[len(excerpt)-1].Number)}
+			for i, l := range excerpt {
+				h.Lines = append(h.Lines, reportLine{New: l.Number, HTML: rendered[i]})
+			}
+			rf.Hunks = append(rf.Hunks, h)
+		}
+	default:
+		rendered := model.renderers().code.RenderLinesAs(file.Path, file.Language, file.Lines)
+		var h reportHunk
+		for i := range file.Lines {
+			h.Lines = append(h.Lines, reportLine{New: i + 1, HTML: rendered[i]})
+		}
+		rf.Hunks = []reportHunk{h}
+	}
+	placeComments(model, &rf, comments)
+	return rf
+}
+
+func reportDiffFile(model *ReviewModel, file *DiffFile, comments []Comment) reportFile {
+	rf := reportFile{Path: model.pathLabel(file.Path), Status: file.Status}
+	if file.Status == DiffRenamed {
+		rf.OldPath = file.OldPath
+	}
+	switch {
+	case file.Binary:
+		rf.Note = "Binary file not rendered."
+	case ensureDiffLoaded(file) != nil:
+		rf.Note = "The file could not be read."
+	}
+	for _, h := range file.Hunks {
+		texts := make([]string, len(h.Lines))
+		for i, dl := range h.Lines {
+			texts[i] = dl.Text
+		}
+		rendered := model.renderers().code.RenderLinesAs(file.Path, file.Language, texts)
+		rh := reportHunk{Header: hunkHeader(h)}
+		for i, dl := range h.Lines {
+			rh.Lines = append(rh.Lines, reportLine{Kind: dl.Kind, Old: dl.OldLine, New: dl.NewLine, HTML: rendered[i]})
+		}
+		rf.Hunks = append(rf.Hunks, rh)
+	}
+	placeComments(model, &rf, comments)
+	return rf
+}
+
+// writeHTMLReport writes a self-contained page of the finished review in
+// lang: the summary and verdicts, then every file or diff in full with its
+// comments and replies inline, for readers who were not in the session.
+func writeHTMLReport(w io.Writer, model *ReviewModel, lang string, brand branding) error {
+	tmpl := reportTemplates[lang]
+	if tmpl == nil {
+		tmpl = reportTemplates[defaultLang]
+	}
+	data := struct {
+		Lang  string
+		CSS   template.CSS
+		Logo  template.URL
+		Model *ReviewModel
+		Files []reportFile
+	}{
+		Lang:  lang,
+		CSS:   template.CSS(printCSS + "\n" + reportCSS + "\n" + model.renderers().code.BuildCSS()),
+		Logo:  brand.Logo,
+		Model: model,
+		Files: reportFiles(model),
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// exportHTMLReport writes the report of the finished review to path.
+func exportHTMLReport(model *ReviewModel, path, lang string, brand branding) error {
+	var buf bytes.Buffer
+	if err := writeHTMLReport(&buf, model, lang, brand); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("export html: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteHTMLReport(t *testing.T) {
+	var b strings.Builder
+	if err := writeHTMLReport(&b, printModel(), defaultLang, defaultBranding); err != nil {
+		t.Fatal(err)
+	}
+	html := b.String()
+	for _, want := range []string{
+		"Review completed by alice",
+		"<h3>test.go</h3>",
+		`<td class="ln">2</td>`,
+		"add a &lt;doc&gt; comment",
+		"<strong>agent</strong> replied",
+		"body.theme-light .chroma",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report missing %q", want)
+		}
+	}
+	// Comments follow the line they end on.
+	if strings.Index(html, "hello") > strings.Index(html, `<td class="ln">2</td>`) ||
+		strings.Index(html, "add a &lt;doc&gt;") < strings.Index(html, `<td class="ln">3</td>`) {
+		t.Error("expected comments inline after their lines")
+	}
+}
+
+func TestHTMLReportDiff(t *testing.T) {
+	diff := "diff --git a/c.go b/c.go\n--- a/c.go\n+++ b/c.go\n" +
+		"@@ -9,3 +9,3 @@\n line 9\n-old 10\n+line 10\n line 11\n"
+	files, err := parseUnifiedDiff(diff, diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewModel{Mode: ModeDiff, DiffFiles: files, Comments: []Comment{
+		{ID: 1, Path: "c.go", StartLine: 10, EndLine: 10, Side: "old", Text: "why remove this"},
+		{ID: 2, Path: "c.go", StartLine: 10, EndLine: 10, Side: "new", Text: "nice"},
+	}}
+	report := reportFiles(model)
+	if len(report) != 1 || len(report[0].Hunks) != 1 {
+		t.Fatalf("expected one file with one hunk, got %+v", report)
+	}
+	lines := report[0].Hunks[0].Lines
+	if len(lines[1].Comments) != 1 || lines[1].Comments[0].Text != "why remove this" {
+		t.Fatalf("expected the old-side comment on the removed line, got %+v", lines[1].Comments)
+	}
+	if len(lines[2].Comments) != 1 || lines[2].Comments[0].Text != "nice" {
+		t.Fatalf("expected the new-side comment on the added line, got %+v", lines[2].Comments)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := exportHTMLReport(model, path, "de", defaultBranding); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<html lang="de">`, "@@ -9,3 &#43;9,3 @@", `class="report-line report-del"`, "why remove this"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("exported report missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package app
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/jfyne/live"
+)
+
+// reviewerParam is the query parameter naming the reviewer behind a
+// connection, e.g. http://127.0.0.1:8080/?reviewer=alice.
+const reviewerParam = "reviewer"
+
+// roleObserver names the role of a connection joined with the observer
+// token, which watches the review read-only; see requireToken.
+const roleObserver = "observer"
+
+// newView returns the view a new connection opens with: where the session
+// started, showing the review as it is now.
+func (rs *ReviewServer) newView() *ReviewView {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	view := *rs.start
+	view.Expanded = maps.Clone(view.Expanded)
+	view.MarkdownRenderByPath = maps.Clone(view.MarkdownRenderByPath)
+	view.RenderedHunks = nil
+	view.Tree = nil
+	rebuildTree(&view)
+	updateView(&view)
+	return &view
+}
+
+// mountView returns a new view for the connection that made request r,
+// taking its role from the token it was let in with and its language and
+// theme from the browser. r is nil outside a live connection.
+func (rs *ReviewServer) mountView(r *http.Request) *ReviewView {
+	view := rs.newView()
+	if r != nil {
+		view.Observer = isObserver(r)
+		view.Lang = negotiateLang(r.Header.Get("Accept-Language"))
+		view.LightTheme = prefersLightTheme(r)
+	}
+	return view
+}
+
+// view returns the view socket s has open. A nil socket, as used when
+// rendering outside a live connection, gets the start view.
+func (rs *ReviewServer) view(s *live.Socket) *ReviewView {
+	if s != nil {
+		if view, ok := s.Assigns().(*ReviewView); ok {
+			return view
+		}
+	}
+	return rs.start
+}
+
+// refreshView brings view up to date after another connection or the agent
+// changed the review, selecting the first file if its own was dropped from
+// it. It must be called with rs.mu held.
+func refreshView(view *ReviewView) {
+	if view.EditingCommentID != 0 && findComment(view.ReviewModel, view.EditingCommentID) == nil {
+		view.EditingCommentID = 0
+	}
+	if view.Mode == ModeDiff && view.lookupDiffFile(view.SelectedPath) == nil && len(view.DiffFiles) > 0 {
+		selectFile(view, view.DiffFiles[0].Path)
+		return
+	}
+	rebuildTree(view)
+	updateView(view)
+}
+
+// withView applies fn to the view socket s has open, with the model
+// locked. It is for handlers that only change per-connection state, which
+// observers and a completed review still allow.
+func (rs *ReviewServer) withView(s *live.Socket, fn func(view *ReviewView)) *ReviewView {
+	view := rs.view(s)
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	fn(view)
+	return view
+}
+
+// addReviewer records name as a known reviewer, keeping the list sorted.
+func addReviewer(model *ReviewModel, name string) {
+	if name == "" {
+		return
+	}
+	if i, found := slices.BinarySearch(model.Reviewers, name); !found {
+		model.Reviewers = slices.Insert(model.Reviewers, i, name)
+	}
+}
+
+// assignFile assigns path to reviewer, or clears the assignment when reviewer
+// is empty.
+func assignFile(model *ReviewModel, path, reviewer string) {
+	if reviewer == "" {
+		delete(model.Assignments, path)
+		return
+	}
+	if model.Assignments == nil {
+		model.Assignments = make(map[string]string)
+	}
+	model.Assignments[path] = reviewer
+	addReviewer(model, reviewer)
+}
+
+// assignmentMetadata returns file assignments keyed by slash path.
+func assignmentMetadata(model *ReviewModel) map[string]any {
+	out := make(map[string]any, len(model.Assignments))
+	for path, reviewer := range model.Assignments {
+		key := path
+		if f := model.lookupFile(path); f != nil {
+			key = f.PathSlash
+		}
+		out[key] = reviewer
+	}
+	return out
+}
+
+func registerReviewerHandlers(h *live.Handler, rs *ReviewServer) {
+	// Identity is per connection, so this runs for observers too and takes
+	// the model lock itself rather than going through locked. The role is
+	// not a parameter: it comes from the token the connection was let in
+	// with, set when the view was mounted.
+	h.HandleParams(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		name := strings.TrimSpace(p.String(reviewerParam))
+		announce, observer := false, false
+		view := rs.withView(s, func(view *ReviewView) {
+			view.Reviewer = name
+			observer = view.Observer
+			if name == "" {
+				view.MyFilesOnly = false
+			}
+			// Params also run for the initial HTTP render and on every URL
+			// patch; only the first live connection counts.
+			if s.Connected() && !view.Announced {
+				view.Announced = true
+				announce = true
+			}
+			if !observer {
+				addReviewer(view.ReviewModel, name)
+			}
+		})
+		if announce {
+			rs.events.emit(eventReviewerConnected, "reviewer", name, "role", viewerRole(observer))
+		}
+		return view, nil
+	})
+
+	h.HandleEvent("set-reviewer", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		name := strings.TrimSpace(p.String(reviewerParam))
+		if name == "" {
+			return view, nil
+		}
+		view.Reviewer = name
+		addReviewer(view.ReviewModel, name)
+		if s != nil {
+			// Keep the name in the URL so a reload or shared link keeps it.
+			s.PatchURL(url.Values{reviewerParam: {name}})
+		}
+		rs.markChanged()
+		return view, nil
+	}))
+
+	h.HandleEvent("assign-file", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		if view.SelectedPath == "" {
+			return view, nil
+		}
+		assignFile(view.ReviewModel, view.SelectedPath, strings.TrimSpace(p.String(reviewerParam)))
+		refreshTree(view)
+		rs.markChanged()
+		return view, nil
+	}))
+
+	h.HandleEvent("toggle-my-files", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		return rs.withView(s, func(view *ReviewView) {
+			view.MyFilesOnly = view.Reviewer != "" && !view.MyFilesOnly
+		}), nil
+	})
+
+	h.UnmountHandler = func(s *live.Socket) error {
+		view := rs.view(s)
+		rs.mu.Lock()
+		announced, reviewer, observer := view.Announced, view.Reviewer, view.Observer
+		rs.mu.Unlock()
+		if announced {
+			rs.events.emit(eventReviewerDisconnected, "reviewer", reviewer, "role", viewerRole(observer))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,292 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+func callEvent(t *testing.T, engine *live.Engine, s *live.Socket, event string, params map[string]string) {
+	t.Helper()
+	data, _ := json.Marshal(params)
+	var err error
+	if event == live.EventParams {
+		err = engine.CallParams(context.Background(), s, live.Event{T: event, Data: data})
+	} else {
+		err = engine.CallEvent(context.Background(), event, s, live.Event{T: event, Data: data})
+	}
+	if err != nil {
+		t.Fatalf("%s: %v", event, err)
+	}
+}
+
+// TestAssignFileToReviewer verifies that reviewers identified by the URL can
+// assign the selected file, that the assignment shows in the tree and that
+// the "my files" filter is per connection.
+func TestAssignFileToReviewer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{
+				{Path: "a.go", PathSlash: "a.go", Lines: []string{"package a"}},
+				{Path: "b.go", PathSlash: "b.go", Lines: []string{"package b"}},
+			},
+			Mode:   ModeFile,
+			Viewed: map[string]bool{},
+		},
+		SelectedPath:         "a.go",
+		MarkdownRenderByPath: map[string]bool{},
+	}
+	rebuildTree(model)
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+
+	alice := live.NewSocket(ctx, engine, "alice")
+	bob := live.NewSocket(ctx, engine, "bob")
+	for _, s := range []*live.Socket{alice, bob} {
+		s.Assign(rs.newView())
+	}
+	callEvent(t, engine, alice, live.EventParams, map[string]string{"reviewer": "alice"})
+	callEvent(t, engine, bob, live.EventParams, map[string]string{"reviewer": "bob"})
+	callEvent(t, engine, alice, "assign-file", map[string]string{"reviewer": "alice"})
+	callEvent(t, engine, alice, "toggle-my-files", nil)
+
+	if model.Assignments["a.go"] != "alice" {
+		t.Fatalf("expected a.go assigned to alice, got %v", model.Assignments)
+	}
+	if got := strings.Join(model.Reviewers, ","); got != "alice,bob" {
+		t.Fatalf("unexpected known reviewers: %s", got)
+	}
+	if tree := rs.view(alice).Tree; tree[0].Assignee != "alice" || tree[1].Assignee != "" {
+		t.Fatalf("unexpected tree assignees: %+v", tree)
+	}
+	if !rs.view(alice).MyFilesOnly || rs.view(bob).MyFilesOnly {
+		t.Fatalf("expected only alice to filter: alice=%v bob=%v", rs.view(alice).MyFilesOnly, rs.view(bob).MyFilesOnly)
+	}
+
+	render := func(s *live.Socket) string {
+		out, err := engine.Handler.RenderHandler(ctx, &live.RenderContext{Socket: s, Assigns: rs.view(s)})
+		if err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(out)
+		return buf.String()
+	}
+	aliceHTML := render(alice)
+	if !strings.Contains(aliceHTML, "sidebar mine-only") || !strings.Contains(aliceHTML, " mine\"") {
+		t.Fatal("expected alice's sidebar to filter to her files")
+	}
+	bobHTML := render(bob)
+	if strings.Contains(bobHTML, "sidebar mine-only") || !strings.Contains(bobHTML, `class="assignee-badge"`) || !strings.Contains(bobHTML, "Assign to me") {
+		t.Fatal("expected bob to see alice's assignment without filtering")
+	}
+
+	callEvent(t, engine, bob, "assign-file", map[string]string{"reviewer": ""})
+	if _, ok := model.Assignments["a.go"]; ok || rs.view(bob).Tree[0].Assignee != "" {
+		t.Fatalf("expected a.go to be unassigned, got %v", model.Assignments)
+	}
+}
+
+// TestEmitReviewRecordsAssignments verifies that file assignments are
+// written to the output metadata by slash path.
+func TestEmitReviewRecordsAssignments(t *testing.T) {
+	model := &ReviewModel{
+		Files: []File{{Path: `dir\a.go`, PathSlash: "dir/a.go", Lines: []string{"package a"}}},
+	}
+	assignFile(model, `dir\a.go`, "alice")
+
+	var buf bytes.Buffer
+	if err := emitReview(&buf, model); err != nil {
+		t.Fatalf("emitReview: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "assignments") || !strings.Contains(out, `"dir/a.go": alice`) {
+		t.Fatalf("expected assignments in metadata, got:\n%s", out)
+	}
+}
+
+// TestCommentAuthorAttribution verifies that comments record the reviewer who
+// wrote them and render with that reviewer's identicon and name.
+func TestCommentAuthorAttribution(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	model.SelectionStart, model.SelectionEnd = 1, 1
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	alice := live.NewSocket(ctx, engine, "alice")
+	alice.Assign(model)
+	callEvent(t, engine, alice, live.EventParams, map[string]string{"reviewer": "alice"})
+	callEvent(t, engine, alice, "add-comment", map[string]string{"comment": "needs a test"})
+
+	c := model.Comments[len(model.Comments)-1]
+	if c.Author != "alice" {
+		t.Fatalf("expected comment by alice, got %q", c.Author)
+	}
+	html := renderReviewHTML(t, model)
+	if !strings.Contains(html, `alt="alice" title="alice" class="line-comment-avatar"`) || !strings.Contains(html, `<span class="comment-author">alice</span>`) {
+		t.Fatal("expected the comment to render with alice's identicon and name")
+	}
+	if identicon("alice") == identicon("bob") {
+		t.Fatal("expected different reviewers to get different identicons")
+	}
+
+	var buf bytes.Buffer
+	if err := emitReview(&buf, model.ReviewModel); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "author") || !strings.Contains(out, "alice") {
+		t.Fatalf("expected author in output, got:\n%s", out)
+	}
+}
+
+// TestReviewersBrowseIndependently verifies that each connection keeps its
+// own selected file and lines while comments are shared, and that comments
+// carry the name of the reviewer who wrote them.
+func TestReviewersBrowseIndependently(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{
+				{Path: "a.go", PathSlash: "a.go", Lines: []string{"package a", "", "func A() {}"}},
+				{Path: "b.go", PathSlash: "b.go", Lines: []string{"package b", "", "func B() {}"}},
+			},
+			Mode:   ModeFile,
+			Viewed: map[string]bool{},
+		},
+		SelectedPath:         "a.go",
+		MarkdownRenderByPath: map[string]bool{},
+	}
+	rebuildTree(model)
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+
+	alice := live.NewSocket(ctx, engine, "alice")
+	bob := live.NewSocket(ctx, engine, "bob")
+	for _, s := range []*live.Socket{alice, bob} {
+		s.Assign(rs.newView())
+	}
+	callEvent(t, engine, alice, live.EventParams, map[string]string{"reviewer": "alice"})
+	callEvent(t, engine, bob, live.EventParams, map[string]string{"reviewer": "bob"})
+	callEvent(t, engine, alice, "select-line", map[string]string{"line": "3"})
+	callEvent(t, engine, bob, "select-file", map[string]string{"path": "b.go"})
+	callEvent(t, engine, bob, "select-line", map[string]string{"line": "1"})
+	callEvent(t, engine, alice, "add-comment", map[string]string{"comment": "export this?"})
+	callEvent(t, engine, bob, "add-comment", map[string]string{"comment": "package doc missing"})
+
+	if len(model.Comments) != 2 {
+		t.Fatalf("expected both comments, got %+v", model.Comments)
+	}
+	for _, want := range []Comment{
+		{Path: "a.go", StartLine: 3, Author: "alice"},
+		{Path: "b.go", StartLine: 1, Author: "bob"},
+	} {
+		found := false
+		for _, c := range model.Comments {
+			found = found || (c.Path == want.Path && c.StartLine == want.StartLine && c.Author == want.Author)
+		}
+		if !found {
+			t.Fatalf("expected a comment by %s on %s:%d, got %+v", want.Author, want.Path, want.StartLine, model.Comments)
+		}
+	}
+
+	render := func(s *live.Socket) string {
+		out, err := engine.Handler.RenderHandler(ctx, &live.RenderContext{Socket: s, Assigns: rs.view(s)})
+		if err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(out)
+		return buf.String()
+	}
+	if html := render(alice); !strings.Contains(html, "func A() {}") || strings.Contains(html, "func B() {}") || !strings.Contains(html, "export this?") {
+		t.Fatal("expected alice to still see a.go with her comment")
+	}
+	if html := render(bob); !strings.Contains(html, "func B() {}") || strings.Contains(html, "func A() {}") {
+		t.Fatal("expected bob to still see b.go")
+	}
+
+	// A new connection starts where the session started, not where the
+	// last reviewer was.
+	carol := live.NewSocket(ctx, engine, "carol")
+	carol.Assign(rs.newView())
+	callEvent(t, engine, carol, live.EventParams, map[string]string{"reviewer": "carol"})
+	if html := render(carol); !strings.Contains(html, "func A() {}") {
+		t.Fatal("expected a new reviewer to start on a.go")
+	}
+	if start := rs.view(carol).SelectionStart; start != 0 {
+		t.Fatalf("expected no selection for a new reviewer, got %d", start)
+	}
+}
+
+// TestReviewersKeepOwnDisplay verifies that display settings such as the
+// collapsed sidebar and the split diff belong to the connection that chose
+// them.
+func TestReviewersKeepOwnDisplay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	files, err := parseUnifiedDiff("diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,2 @@\n package a\n-var x = 1\n+var x = 2\n", diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			DiffFiles: files,
+			Mode:      ModeDiff,
+			Viewed:    map[string]bool{},
+		},
+		SelectedPath:         "a.go",
+		DiffFormat:           DiffFormatUnified,
+		RenderFile:           true,
+		MarkdownRenderByPath: map[string]bool{},
+	}
+	rebuildTree(model)
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+
+	alice := live.NewSocket(ctx, engine, "alice")
+	bob := live.NewSocket(ctx, engine, "bob")
+	for _, s := range []*live.Socket{alice, bob} {
+		s.Assign(rs.newView())
+	}
+	callEvent(t, engine, alice, "toggle-sidebar", nil)
+	callEvent(t, engine, alice, "toggle-diff-format", nil)
+
+	if a := rs.view(alice); !a.SidebarCollapsed || a.DiffFormat != DiffFormatSplit || len(a.ViewDiffSplit) == 0 {
+		t.Fatalf("expected alice to get a collapsed sidebar and the split diff, got %+v", a)
+	}
+	if b := rs.view(bob); b.SidebarCollapsed || b.DiffFormat != DiffFormatUnified {
+		t.Fatalf("expected bob's display to be unchanged, got sidebar=%v format=%s", b.SidebarCollapsed, b.DiffFormat)
+	}
+
+	render := func(s *live.Socket) string {
+		out, err := engine.Handler.RenderHandler(ctx, &live.RenderContext{Socket: s, Assigns: rs.view(s)})
+		if err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(out)
+		return buf.String()
+	}
+	if html := render(alice); !strings.Contains(html, `class="workspace sidebar-collapsed`) || !strings.Contains(html, `class="diff diff-split"`) {
+		t.Fatal("expected alice to render a collapsed sidebar and the split diff")
+	}
+	if html := render(bob); strings.Contains(html, `class="workspace sidebar-collapsed`) || strings.Contains(html, `class="diff diff-split"`) {
+		t.Fatal("expected bob to render his sidebar and the unified diff")
+	}
+}
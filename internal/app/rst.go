@@ -0,0 +1,327 @@
+package app
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	rstBullet      = regexp.MustCompile(`^[-*+•]( +|$)`)
+	rstEnumerated  = regexp.MustCompile(`^(?:(?:\d+|#)[.)]|\((?:\d+|#)\))( +|$)`)
+	rstDirective   = regexp.MustCompile(`^\.\.\s+([\w-]+)::\s*(.*)$`)
+	rstOption      = regexp.MustCompile(`^:([\w -]+):(?:\s+(.*))?$`)
+	rstTableBorder = regexp.MustCompile(`^=+( +=+)+ *$`)
+
+	rstInlineRules = []inlineRule{
+		{regexp.MustCompile("``(.+?)``"), func(m []string) string { return "<code>" + html.EscapeString(m[1]) + "</code>" }},
+		{regexp.MustCompile("`([^`<]+?)\\s*<([^>`]+)>`__?"), func(m []string) string { return linkHTML(m[2], m[1]) }},
+		{regexp.MustCompile(":[\\w-]+:`([^`]+)`"), func(m []string) string { return "<code>" + html.EscapeString(m[1]) + "</code>" }},
+		{regexp.MustCompile("`([^`]+)`_{0,2}"), func(m []string) string { return "<em>" + html.EscapeString(m[1]) + "</em>" }},
+		{regexp.MustCompile(`\*\*([^*]+)\*\*`), func(m []string) string { return "<strong>" + html.EscapeString(m[1]) + "</strong>" }},
+		{regexp.MustCompile(`\*([^*\s][^*]*)\*`), func(m []string) string { return "<em>" + html.EscapeString(m[1]) + "</em>" }},
+		{regexp.MustCompile(`https?://[^\s<>]*[^\s<>.,;:!?)]`), func(m []string) string { return linkHTML(m[0], m[0]) }},
+	}
+)
+
+// admonitionDirectives are the reStructuredText directives shown as a
+// titled note.
+var admonitionDirectives = []string{"attention", "caution", "danger", "error", "hint", "important", "note", "tip", "warning"}
+
+// renderRSTBlocks renders the common parts of reStructuredText: section
+// titles, paragraphs, lists, literal and code blocks, admonitions, images,
+// field lists and simple tables. Constructs it does not know are shown as
+// their source.
+func renderRSTBlocks(r *renderers, path, input string) []MarkdownBlock {
+	p := &rstParser{b: newDocBuilder(r, path)}
+	p.parse(strings.Split(input, "\n"), 1)
+	return p.b.blocks
+}
+
+type rstParser struct {
+	b *docBuilder
+	// styles are the title adornments in the order they are first used,
+	// which is what sets the heading levels.
+	styles []string
+}
+
+// nested renders lines into a fragment, such as the body of a list item.
+func (p *rstParser) nested(lines []string) string {
+	outer := p.b
+	p.b = &docBuilder{render: outer.render, baseDir: outer.baseDir}
+	p.parse(lines, 1)
+	out := p.b.html()
+	p.b = outer
+	return out
+}
+
+// parse renders lines, the first of which is line number first.
+func (p *rstParser) parse(lines []string, first int) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		n := first + i
+		switch {
+		case isBlank(line):
+			i++
+		case indentOf(line) > 0:
+			j := indentedEnd(lines, i)
+			p.b.add(n, first+j-1, "<blockquote>"+p.nested(dedent(lines[i:j]))+"</blockquote>")
+			i = j
+		case line == ".." || strings.HasPrefix(line, ".. "):
+			i = p.directive(lines, i, first)
+		case i+2 < len(lines) && isRSTAdornment(line) && !isBlank(lines[i+1]) && strings.TrimRight(lines[i+2], " ") == strings.TrimRight(line, " "):
+			p.title("over"+line[:1], lines[i+1], n, n+2)
+			i += 3
+		case i+1 < len(lines) && !isRSTAdornment(line) && isRSTAdornment(lines[i+1]) &&
+			len(strings.TrimRight(lines[i+1], " ")) >= utf8.RuneCountInString(strings.TrimSpace(line)):
+			p.title(lines[i+1][:1], line, n, n+1)
+			i += 2
+		case isRSTAdornment(line) && len(strings.TrimRight(line, " ")) >= 4:
+			p.b.add(n, n, "<hr>")
+			i++
+		case rstTableBorder.MatchString(line):
+			i = p.simpleTable(lines, i, first)
+		case strings.HasPrefix(line, "+-"):
+			j := i
+			for j < len(lines) && !isBlank(lines[j]) {
+				j++
+			}
+			p.b.add(n, first+j-1, preHTML(strings.Join(lines[i:j], "\n")))
+			i = j
+		case rstBullet.MatchString(line):
+			i = p.list(lines, i, first, rstBullet)
+		case rstEnumerated.MatchString(line):
+			i = p.list(lines, i, first, rstEnumerated)
+		case rstOption.MatchString(line):
+			i = p.fields(lines, i, first)
+		default:
+			i = p.paragraph(lines, i, first)
+		}
+	}
+}
+
+// isRSTAdornment reports whether line is a run of one punctuation
+// character, as used to underline titles and for transitions.
+func isRSTAdornment(line string) bool {
+	line = strings.TrimRight(line, " ")
+	if len(line) < 2 || !strings.ContainsRune("=-~^\"'`#*+:._", rune(line[0])) {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+// indentedEnd returns the index after the indented block starting at i,
+// not counting trailing blank lines.
+func indentedEnd(lines []string, i int) int {
+	j := i
+	for j < len(lines) && (isBlank(lines[j]) || indentOf(lines[j]) > 0) {
+		j++
+	}
+	for j > i && isBlank(lines[j-1]) {
+		j--
+	}
+	return j
+}
+
+func (p *rstParser) title(style, text string, start, end int) {
+	level := slices.Index(p.styles, style)
+	if level < 0 {
+		p.styles = append(p.styles, style)
+		level = len(p.styles) - 1
+	}
+	p.b.add(start, end, headingHTML(level+1, renderInline(strings.TrimSpace(text), rstInlineRules)))
+}
+
+// paragraph renders the paragraph at i, with the literal block that follows
+// it when it ends in "::", or as a definition when the next line is
+// indented.
+func (p *rstParser) paragraph(lines []string, i, first int) int {
+	j := i + 1
+	for j < len(lines) && !isBlank(lines[j]) && indentOf(lines[j]) == 0 {
+		j++
+	}
+	if j == i+1 && j < len(lines) && !isBlank(lines[j]) {
+		end := indentedEnd(lines, j)
+		p.b.add(first+i, first+end-1, "<dl><dt>"+renderInline(strings.TrimSpace(lines[i]), rstInlineRules)+"</dt><dd>"+p.nested(dedent(lines[j:end]))+"</dd></dl>")
+		return end
+	}
+	text := strings.Join(lines[i:j], "\n")
+	literal := strings.HasSuffix(strings.TrimRight(text, " "), "::")
+	if literal {
+		text = strings.TrimRight(text, " ")
+		switch {
+		case text == "::":
+			text = ""
+		case strings.HasSuffix(text, " ::"):
+			text = strings.TrimRight(strings.TrimSuffix(text, "::"), " ")
+		default:
+			text = strings.TrimSuffix(text, ":")
+		}
+	}
+	if text != "" {
+		p.b.add(first+i, first+j-1, "<p>"+renderInline(text, rstInlineRules)+"</p>")
+	}
+	if !literal {
+		return j
+	}
+	k := j
+	for k < len(lines) && isBlank(lines[k]) {
+		k++
+	}
+	if k == len(lines) || indentOf(lines[k]) == 0 {
+		return j
+	}
+	end := indentedEnd(lines, k)
+	p.b.add(first+k, first+end-1, preHTML(strings.Join(dedent(lines[k:end]), "\n")))
+	return end
+}
+
+// directive renders the explicit markup at i: a directive, or a comment or
+// link target, which are not shown.
+func (p *rstParser) directive(lines []string, i, first int) int {
+	end := indentedEnd(lines, i+1)
+	m := rstDirective.FindStringSubmatch(lines[i])
+	if m == nil {
+		return end
+	}
+	name, arg := strings.ToLower(m[1]), strings.TrimSpace(m[2])
+	body := dedent(lines[i+1 : end])
+	options := map[string]string{}
+	for len(body) > 0 && rstOption.MatchString(body[0]) {
+		o := rstOption.FindStringSubmatch(body[0])
+		options[o[1]] = o[2]
+		body = body[1:]
+	}
+	body = trimBlankEdges(body)
+
+	var out string
+	switch {
+	case name == "code" || name == "code-block" || name == "sourcecode":
+		out = p.b.codeBlockHTML(arg, strings.Join(body, "\n"))
+	case slices.Contains(admonitionDirectives, name):
+		if arg != "" {
+			body = append([]string{arg, ""}, body...)
+		}
+		out = admonitionHTML(name, p.nested(body))
+	case name == "admonition":
+		out = admonitionHTML(arg, p.nested(body))
+	case name == "image" || name == "figure":
+		out = "<p>" + imageHTML(arg, options["alt"]) + "</p>"
+		if len(body) > 0 {
+			out += p.nested(body)
+		}
+	default:
+		out = preHTML(strings.Join(lines[i:end], "\n"))
+	}
+	p.b.add(first+i, first+end-1, out)
+	return end
+}
+
+// list renders the list starting at i whose items start with marker.
+func (p *rstParser) list(lines []string, i, first int, marker *regexp.Regexp) int {
+	var items []docItem
+	for i < len(lines) {
+		loc := marker.FindStringIndex(lines[i])
+		if loc == nil {
+			break
+		}
+		j := i + 1
+		for j < len(lines) && (isBlank(lines[j]) || indentOf(lines[j]) > 0) {
+			j++
+		}
+		for j > i+1 && isBlank(lines[j-1]) {
+			j--
+		}
+		body := append([]string{lines[i][loc[1]:]}, dedent(lines[i+1:j])...)
+		items = append(items, docItem{start: first + i, end: first + j - 1, body: p.item(body)})
+
+		k := j
+		for k < len(lines) && isBlank(lines[k]) {
+			k++
+		}
+		if k == len(lines) || !marker.MatchString(lines[k]) {
+			i = j
+			break
+		}
+		i = k
+	}
+	p.b.addList(marker == rstEnumerated, items)
+	return i
+}
+
+// item renders a list item's lines, inline when they are a single
+// paragraph.
+func (p *rstParser) item(lines []string) string {
+	for _, line := range lines {
+		if isBlank(line) || indentOf(line) > 0 {
+			return p.nested(lines)
+		}
+	}
+	return renderInline(strings.Join(lines, "\n"), rstInlineRules)
+}
+
+// fields renders a field list, such as a document's metadata, as the same
+// table as markdown frontmatter.
+func (p *rstParser) fields(lines []string, i, first int) int {
+	var rows strings.Builder
+	j := i
+	for j < len(lines) && rstOption.MatchString(lines[j]) {
+		m := rstOption.FindStringSubmatch(lines[j])
+		value := m[2]
+		for j+1 < len(lines) && !isBlank(lines[j+1]) && indentOf(lines[j+1]) > 0 {
+			j++
+			value += " " + strings.TrimSpace(lines[j])
+		}
+		rows.WriteString(`<tr><td class="frontmatter-key">` + html.EscapeString(m[1]) + "</td><td>" + renderInline(value, rstInlineRules) + "</td></tr>\n")
+		j++
+	}
+	p.b.add(first+i, first+j-1, "<table class=\"frontmatter\">\n<tbody>\n"+rows.String()+"</tbody>\n</table>\n")
+	return j
+}
+
+// simpleTable renders a table drawn with "=" borders. Three borders mean
+// the first row is a header.
+func (p *rstParser) simpleTable(lines []string, i, first int) int {
+	borders := []int{i}
+	end := -1
+	for j := i + 1; j < len(lines) && end < 0; j++ {
+		if !rstTableBorder.MatchString(lines[j]) {
+			continue
+		}
+		borders = append(borders, j)
+		if len(borders) == 3 || j+1 == len(lines) || isBlank(lines[j+1]) {
+			end = j + 1
+		}
+	}
+	if end < 0 {
+		return p.paragraph(lines, i, first)
+	}
+
+	var starts []int
+	for c := 0; c < len(lines[i]); c++ {
+		if lines[i][c] == '=' && (c == 0 || lines[i][c-1] == ' ') {
+			starts = append(starts, c)
+		}
+	}
+	var rows [][]string
+	for _, line := range lines[i+1 : end-1] {
+		if isBlank(line) || rstTableBorder.MatchString(line) {
+			continue
+		}
+		row := make([]string, len(starts))
+		for c, start := range starts {
+			stop := len(line)
+			if c+1 < len(starts) {
+				stop = min(starts[c+1], len(line))
+			}
+			if start < stop {
+				row[c] = renderInline(strings.TrimSpace(line[start:stop]), rstInlineRules)
+			}
+		}
+		rows = append(rows, row)
+	}
+	p.b.add(first+i, first+end-1, tableHTML(rows, len(borders) == 3))
+	return end
+}
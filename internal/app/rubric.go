@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jfyne/live"
+)
+
+// Criterion is one scored item of a review rubric loaded with --rubric.
+type Criterion struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Min         int    `json:"min"`
+	Max         int    `json:"max"`
+}
+
+// Options returns the scores a reviewer can pick for c, lowest first.
+func (c Criterion) Options() []int {
+	out := make([]int, 0, c.Max-c.Min+1)
+	for n := c.Min; n <= c.Max; n++ {
+		out = append(out, n)
+	}
+	return out
+}
+
+// rubricScore is one row of the rubric section in the output. Score is 0
+// when the reviewer did not score the criterion.
+type rubricScore struct {
+	Criterion string `json:"criterion"`
+	Score     int    `json:"score"`
+	Min       int    `json:"min"`
+	Max       int    `json:"max"`
+}
+
+// ParseRubricFile reads a JSON array of criteria. Criteria without a range
+// are scored from 1 to 5.
+func ParseRubricFile(path string) ([]Criterion, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rubric file: %w", err)
+	}
+	var criteria []Criterion
+	if err := json.Unmarshal(data, &criteria); err != nil {
+		return nil, fmt.Errorf("parse rubric file: %w", err)
+	}
+	if len(criteria) == 0 {
+		return nil, fmt.Errorf("rubric file has no criteria")
+	}
+	seen := make(map[string]bool, len(criteria))
+	for i := range criteria {
+		c := &criteria[i]
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name == "" {
+			return nil, fmt.Errorf("criterion at index %d has empty name", i)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("criterion %q is listed twice", c.Name)
+		}
+		seen[c.Name] = true
+		if c.Min == 0 && c.Max == 0 {
+			c.Min, c.Max = 1, 5
+		}
+		if c.Min < 1 || c.Max <= c.Min || c.Max-c.Min > 10 {
+			return nil, fmt.Errorf("criterion %q has invalid range %d-%d", c.Name, c.Min, c.Max)
+		}
+	}
+	return criteria, nil
+}
+
+// rubricScores lists every criterion with its score, in rubric order.
+func rubricScores(model *ReviewModel) []rubricScore {
+	out := make([]rubricScore, 0, len(model.Rubric))
+	for _, c := range model.Rubric {
+		out = append(out, rubricScore{Criterion: c.Name, Score: model.Scores[c.Name], Min: c.Min, Max: c.Max})
+	}
+	return out
+}
+
+func registerRubricHandlers(h *live.Handler, rs *ReviewServer) {
+	h.HandleEvent("set-score", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		name := p.String("criterion")
+		score := p.Int("score")
+		var criterion *Criterion
+		for i := range view.Rubric {
+			if view.Rubric[i].Name == name {
+				criterion = &view.Rubric[i]
+			}
+		}
+		if criterion == nil || score < criterion.Min || score > criterion.Max {
+			return view, nil
+		}
+		if view.Scores == nil {
+			view.Scores = make(map[string]int)
+		}
+		// Clicking the current score again clears it.
+		if view.Scores[name] == score {
+			delete(view.Scores, name)
+		} else {
+			view.Scores[name] = score
+		}
+		rs.markChanged()
+		return view, nil
+	}))
+}
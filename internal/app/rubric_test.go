@@ -0,0 +1,74 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+// TestParseRubricFile verifies the default score range and that malformed
+// criteria are rejected.
+func TestParseRubricFile(t *testing.T) {
+	path := writeTempFile(t, "rubric.json", `[
+		{"name": "correctness", "description": "Does it do what it claims?"},
+		{"name": "tests", "min": 1, "max": 3}
+	]`)
+	criteria, err := ParseRubricFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(criteria) != 2 || criteria[0].Min != 1 || criteria[0].Max != 5 || len(criteria[1].Options()) != 3 {
+		t.Fatalf("unexpected criteria %+v", criteria)
+	}
+
+	for name, body := range map[string]string{
+		"empty":     `[]`,
+		"no name":   `[{"name": " "}]`,
+		"duplicate": `[{"name": "tests"}, {"name": "tests"}]`,
+		"range":     `[{"name": "tests", "min": 3, "max": 2}]`,
+	} {
+		if _, err := ParseRubricFile(writeTempFile(t, "rubric.json", body)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestRubricScoring verifies that scores set in the UI are written to the
+// rubric section of the output, with unscored criteria reported as 0.
+func TestRubricScoring(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	model.Rubric = []Criterion{{Name: "correctness", Min: 1, Max: 5}, {Name: "readability", Min: 1, Max: 5}}
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	s := live.NewSocket(ctx, engine, "reviewer")
+	s.Assign(model)
+
+	callEvent(t, engine, s, "set-score", map[string]string{"criterion": "correctness", "score": "4"})
+	callEvent(t, engine, s, "set-score", map[string]string{"criterion": "correctness", "score": "9"})
+	callEvent(t, engine, s, "set-score", map[string]string{"criterion": "unknown", "score": "3"})
+	if len(model.Scores) != 1 || model.Scores["correctness"] != 4 {
+		t.Fatalf("unexpected scores %v", model.Scores)
+	}
+
+	if html := renderReviewHTML(t, model); !strings.Contains(html, `live-click="set-score"`) || !strings.Contains(html, "4/5") {
+		t.Fatal("expected the scoring panel to show the current score")
+	}
+	var out bytes.Buffer
+	if err := emitReview(&out, model.ReviewModel); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "correctness,5,1,4") || !strings.Contains(out.String(), "readability,5,1,0") {
+		t.Fatalf("expected rubric scores in the output, got:\n%s", out.String())
+	}
+
+	callEvent(t, engine, s, "set-score", map[string]string{"criterion": "correctness", "score": "4"})
+	if _, ok := model.Scores["correctness"]; ok {
+		t.Fatal("expected a second click to clear the score")
+	}
+}
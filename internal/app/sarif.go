@@ -0,0 +1,182 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// outputSARIF is the --output-format value for SARIF 2.1.0, for uploading
+// the comments to GitHub code scanning or another SARIF consumer.
+const outputSARIF = "sarif"
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// sarifRuleID is the one rule every comment is reported under.
+	sarifRuleID = "review-comment"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	Properties          map[string]any    `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+// writeSARIF writes the comments as the results of a single SARIF run.
+// Rejected proposals and resolved threads need no action and are left out.
+// Comments on removed lines point at their file only, since the lines no
+// longer exist there, and say which lines they were on.
+func writeSARIF(w io.Writer, model *ReviewModel) error {
+	comments, _ := validateComments(model)
+	results := make([]sarifResult, 0, len(comments))
+	for _, c := range exportComments(model, comments) {
+		if c.Disposition == DispositionRejected || c.Status == ThreadResolved {
+			continue
+		}
+		results = append(results, sarifResultFor(c))
+	}
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "meatcheck",
+				InformationURI: "https://github.com/jfyne/meatcheck",
+				Rules: []sarifRule{{
+					ID:               sarifRuleID,
+					ShortDescription: sarifMessage{Text: "Comment left during a meatcheck review"},
+				}},
+			}},
+			Results: results,
+		}},
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+func sarifResultFor(c Comment) sarifResult {
+	text := c.Text
+	loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifact(c.Path)}
+	switch {
+	case c.isFileLevel():
+	case c.Side == "old":
+		text = fmt.Sprintf("On removed lines %d-%d: %s", c.StartLine, c.EndLine, text)
+	default:
+		loc.Region = &sarifRegion{StartLine: c.StartLine, EndLine: c.EndLine}
+	}
+	r := sarifResult{
+		RuleID:    sarifRuleID,
+		Level:     sarifLevel(c.Priority),
+		Message:   sarifMessage{Text: text},
+		Locations: []sarifLocation{{PhysicalLocation: loc}},
+	}
+	if c.UID != "" {
+		r.PartialFingerprints = map[string]string{"meatcheckCommentUid/v1": c.UID}
+	}
+	props := map[string]any{}
+	if c.Author != "" {
+		props["author"] = c.Author
+	}
+	if c.Priority != PriorityNone {
+		props["priority"] = string(c.Priority)
+	}
+	if c.Confidence != "" {
+		props["confidence"] = c.Confidence
+	}
+	if len(c.Tags) > 0 {
+		props["tags"] = []string(c.Tags)
+	}
+	if c.Commit != "" {
+		props["commit"] = c.Commit
+	}
+	if len(props) > 0 {
+		r.Properties = props
+	}
+	return r
+}
+
+// sarifLevel maps a comment's priority to a SARIF level: P0 and P1 are
+// errors, P3 a note, and anything else a warning.
+func sarifLevel(p Priority) string {
+	switch p {
+	case PriorityP0, PriorityP1:
+		return "error"
+	case PriorityP3:
+		return "note"
+	}
+	return "warning"
+}
+
+// sarifArtifact locates path for a SARIF consumer: relative paths are
+// resolved against the source root, absolute ones become file URIs.
+func sarifArtifact(path string) sarifArtifactLocation {
+	slashed := filepath.ToSlash(path)
+	if filepath.IsAbs(path) {
+		if !strings.HasPrefix(slashed, "/") {
+			slashed = "/" + slashed
+		}
+		return sarifArtifactLocation{URI: (&url.URL{Scheme: "file", Path: slashed}).String()}
+	}
+	slashed = strings.TrimPrefix(slashed, "./")
+	return sarifArtifactLocation{URI: (&url.URL{Path: slashed}).String(), URIBaseID: "%SRCROOT%"}
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	model := printModel()
+	model.Comments[0].Priority = PriorityP0
+	model.Comments = append(model.Comments,
+		Comment{ID: 3, UID: "uid-3", Path: "test.go", Text: "whole file", Tags: Tags{"style"}},
+		Comment{ID: 4, UID: "uid-4", Path: "test.go", StartLine: 1, EndLine: 1, Text: "dropped", Disposition: DispositionRejected},
+	)
+
+	var buf bytes.Buffer
+	if err := writeSARIF(&buf, model); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected log: %+v", log)
+	}
+	results := log.Runs[0].Results
+	if len(results) != 3 {
+		t.Fatalf("expected the rejected proposal to be left out, got %d results", len(results))
+	}
+
+	first := results[0]
+	region := first.Locations[0].PhysicalLocation.Region
+	if first.Level != "error" || first.Message.Text != "hello" || region == nil || region.StartLine != 1 || region.EndLine != 1 {
+		t.Fatalf("unexpected first result: %+v", first)
+	}
+	if loc := first.Locations[0].PhysicalLocation.ArtifactLocation; loc.URI != "test.go" || loc.URIBaseID != "%SRCROOT%" {
+		t.Fatalf("unexpected artifact: %+v", loc)
+	}
+	if first.PartialFingerprints["meatcheckCommentUid/v1"] == "" {
+		t.Fatal("expected the comment UID as a fingerprint")
+	}
+	if second := results[1]; second.Level != "warning" || second.Properties["author"] != "alice" || second.Properties["priority"] != "P2" {
+		t.Fatalf("unexpected second result: %+v", second)
+	}
+	if file := results[2]; file.Locations[0].PhysicalLocation.Region != nil {
+		t.Fatalf("expected a file-level comment to have no region: %+v", file)
+	}
+}
+
+func TestSARIFRemovedLines(t *testing.T) {
+	r := sarifResultFor(Comment{Path: "dir/a b.go", StartLine: 4, EndLine: 5, Side: "old", Text: "why"})
+	if r.Locations[0].PhysicalLocation.Region != nil || r.Message.Text != "On removed lines 4-5: why" {
+		t.Fatalf("unexpected result for removed lines: %+v", r)
+	}
+	if uri := r.Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "dir/a%20b.go" {
+		t.Fatalf("expected an escaped URI, got %q", uri)
+	}
+}
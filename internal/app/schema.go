@@ -0,0 +1,74 @@
+package app
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// reviewSchemaJSON is the JSON Schema for the document emitted on Finish.
+// Bump the version in its $id when the document changes incompatibly.
+//
+//go:embed review.schema.json
+var reviewSchemaJSON string
+
+var (
+	reviewSchemaOnce sync.Once
+	reviewSchema     *jsonschema.Schema
+	reviewSchemaErr  error
+)
+
+// PrintSchema writes the JSON Schema describing the review document in the
+// given output format. The schema describes the document's structure, which
+// is the same for every encoding.
+func PrintSchema(w io.Writer, format string) error {
+	if format != "" && format != outputTOON && format != outputJSON {
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+	_, err := fmt.Fprint(w, reviewSchemaJSON)
+	return err
+}
+
+func compiledReviewSchema() (*jsonschema.Schema, error) {
+	reviewSchemaOnce.Do(func() {
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(reviewSchemaJSON)))
+		if err != nil {
+			reviewSchemaErr = fmt.Errorf("parse review schema: %w", err)
+			return
+		}
+		c := jsonschema.NewCompiler()
+		const url = "review.schema.json"
+		if err := c.AddResource(url, doc); err != nil {
+			reviewSchemaErr = fmt.Errorf("load review schema: %w", err)
+			return
+		}
+		reviewSchema, reviewSchemaErr = c.Compile(url)
+	})
+	return reviewSchema, reviewSchemaErr
+}
+
+// validateDocument checks doc, as built by reviewDocument, against the
+// review schema.
+func validateDocument(doc map[string]any) error {
+	schema, err := compiledReviewSchema()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	value, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if err := schema.Validate(value); err != nil {
+		return fmt.Errorf("review does not match the output schema: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestReviewDocumentMatchesSchema verifies that a review using every
+// optional section conforms to the published schema, and that the schema
+// rejects documents that break it.
+func TestReviewDocumentMatchesSchema(t *testing.T) {
+	model := buildCommentModel()
+	model.Files[0].LineEnding = LineEndingCRLF
+	model.Comments = append(model.Comments,
+		Comment{ID: 2, UID: newCommentUID(), Path: "test.go", Text: "file level", Author: "alice"},
+		Comment{ID: 3, UID: newCommentUID(), Path: "test.go", StartLine: 1, EndLine: 1, Text: "proposal", Author: "agent", Disposition: DispositionAccepted},
+		Comment{ID: 4, UID: newCommentUID(), Path: "test.go", StartLine: 7, EndLine: 9, Text: "past the end"},
+	)
+	model.Replies = []Reply{{ID: 1, CommentID: 1, CommentUID: model.Comments[0].UID, Author: "agent", Text: "done", Time: time.Now().UTC().Format(time.RFC3339)}}
+	model.Chat = []ChatMessage{{Author: "alice", Text: "hi", Sent: time.Now()}}
+	model.Rubric = []Criterion{{Name: "tests", Min: 1, Max: 5}}
+	model.Scores = map[string]int{"tests": 3}
+	model.Summary = ReviewSummary{Text: "ok", Drafted: true}
+	model.Assignments = map[string]string{"test.go": "alice"}
+	setVerdict(model.ReviewModel, "alice", VerdictApprove)
+	model.StaleFiles = map[string]bool{"test.go": true}
+	model.UpdatedFiles = map[string]bool{"test.go": true}
+	model.OutdatedComments = map[int]bool{1: true}
+
+	doc := reviewDocument(model.ReviewModel)
+	if err := validateDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	doc["unexpected"] = true
+	if err := validateDocument(doc); err == nil {
+		t.Fatal("expected unknown top-level fields to be rejected")
+	}
+	delete(doc, "unexpected")
+	delete(doc, "summary")
+	if err := validateDocument(doc); err == nil {
+		t.Fatal("expected a missing summary to be rejected")
+	}
+}
+
+// TestPrintSchema verifies that the printed schema is JSON and that only
+// known output formats are accepted.
+func TestPrintSchema(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PrintSchema(&buf, "toon"); err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatalf("schema is not JSON: %v", err)
+	}
+	if !strings.HasSuffix(schema["$id"].(string), ":1") {
+		t.Fatalf("expected a versioned $id, got %v", schema["$id"])
+	}
+	if err := PrintSchema(io.Discard, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := PrintSchema(&buf, "yaml"); err == nil {
+		t.Fatal("expected an unsupported format to be rejected")
+	}
+}
+
+// TestEmitDocumentJSON verifies that --output-format json writes the same
+// document as JSON, estimates its own size and reads back with --previous.
+func TestEmitDocumentJSON(t *testing.T) {
+	model := buildCommentModel()
+	doc := reviewDocument(model.ReviewModel)
+	toonEstimate := doc["metadata"].(map[string]any)["token_estimate"].(int)
+	setTokenEstimate(doc, outputJSON)
+
+	var buf bytes.Buffer
+	if err := emitDocument(&buf, doc, outputJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	comment := decoded["comments"].([]any)[0].(map[string]any)
+	if comment["path"] != "test.go" || comment["start_line"] != 1.0 || comment["end_line"] != 1.0 || comment["text"] != "hello" {
+		t.Errorf("unexpected comment %v", comment)
+	}
+	if estimate := decoded["metadata"].(map[string]any)["token_estimate"].(float64); int(estimate) <= toonEstimate {
+		t.Errorf("expected the JSON estimate to exceed the TOON one (%d), got %v", toonEstimate, estimate)
+	}
+	if err := validateDocument(decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "round1.json")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prev, err := ParsePreviousFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prev.Comments) != 1 || prev.Comments[0].UID != model.Comments[0].UID {
+		t.Errorf("unexpected previous comments %+v", prev.Comments)
+	}
+
+	if err := emitDocument(io.Discard, doc, "yaml"); err == nil {
+		t.Error("expected an unsupported format to be rejected")
+	}
+}
+
+// TestValidateRejectsFormatsWithoutSchema verifies that --validate is refused
+// up front for output that is not the review document, rather than checking
+// a document that was never written.
+func TestValidateRejectsFormatsWithoutSchema(t *testing.T) {
+	for _, cfg := range []Config{
+		{Validate: true, OutputFormat: outputMarkdown},
+		{Validate: true, OutputFormat: outputSARIF},
+		{Validate: true, Emit: emitLLMContext},
+	} {
+		cfg.Paths, cfg.Headless = []string{"schema_test.go"}, true
+		if _, err := serve(context.Background(), cfg); err == nil || !strings.Contains(err.Error(), "--validate") {
+			t.Errorf("%+v: expected --validate to be refused, got %v", cfg, err)
+		}
+	}
+}
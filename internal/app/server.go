@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/jfyne/live"
+)
+
+// locked wraps an event handler so it runs with the review model locked.
+// Every connected socket shares one model, so handlers, rendering and the
+// agent API all take rs.mu before touching it.
+//
+// Broadcasts requested with markChanged are sent after the lock is released:
+// a broadcast re-renders every socket synchronously, and rendering takes the
+// lock in turn. Events from observers, and all events once the review has
+// been completed, are ignored; handlers that only change per-connection
+// state are registered without this wrapper.
+func (rs *ReviewServer) locked(fn live.EventHandler) live.EventHandler {
+	return rs.lockedHandler(fn, false)
+}
+
+// lockedReveal is like locked but also accepts observers, for handlers that
+// only reveal more of what is already on screen.
+func (rs *ReviewServer) lockedReveal(fn live.EventHandler) live.EventHandler {
+	return rs.lockedHandler(fn, true)
+}
+
+func (rs *ReviewServer) lockedHandler(fn live.EventHandler, observers bool) live.EventHandler {
+	return func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		rs.mu.Lock()
+		if rs.Model.Completed || !observers && rs.view(s).Observer {
+			rs.mu.Unlock()
+			return rs.view(s), nil
+		}
+		data, err := fn(ctx, s, p)
+		changed := rs.changed
+		rs.changed = false
+		rs.mu.Unlock()
+		if changed {
+			broadcastReviewChanged(s)
+		}
+		return data, err
+	}
+}
+
+// markChanged asks for every connected client to be re-rendered once the
+// current handler returns. It must be called with rs.mu held.
+func (rs *ReviewServer) markChanged() {
+	rs.changed = true
+}
+
+// finish completes the review on behalf of socket s. Only the first finish
+// wins; it returns false if the review stays open, because s is an
+// observer, another client already finished it or stale files need
+// confirming first.
+func (rs *ReviewServer) finish(s *live.Socket) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	model := rs.Model
+	if model.Completed || rs.view(s).Observer {
+		return false
+	}
+	if checkStaleFiles(model) {
+		// Keep the session open so the reviewer can see which comments
+		// may be stale; a second Finish submits anyway.
+		return false
+	}
+	model.Completed = true
+	model.CompletedBy = rs.view(s).Reviewer
+	rs.events.emit(eventFinished, "comments", len(model.Comments), "verdict", model.Verdict, "by", model.CompletedBy)
+	return true
+}
+
+func registerFinishHandler(h *live.Handler, rs *ReviewServer) {
+	h.HandleEvent("finish", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		if !rs.finish(s) {
+			return rs.view(s), nil
+		}
+		// Show everyone else who finished before the server goes away.
+		broadcastReviewChanged(s)
+		if s != nil {
+			_ = s.Send("close-tab", map[string]any{})
+			about, _ := url.Parse("about:blank")
+			if about != nil {
+				s.Redirect(about)
+			}
+		}
+		rs.DoneOnce.Do(func() {
+			close(rs.DoneCh)
+		})
+		return rs.view(s), nil
+	})
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+// TestConcurrentFinish verifies that when several clients finish at once the
+// session completes exactly once, later events are ignored and the other
+// clients are shown who completed the review.
+func TestConcurrentFinish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	model.SelectionStart, model.SelectionEnd = 1, 1
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+
+	var sockets []*live.Socket
+	for _, name := range []string{"alice", "bob", "carol"} {
+		s := live.NewSocket(ctx, engine, live.SocketID(name))
+		engine.AddSocket(s)
+		s.Assign(model)
+		go func() {
+			for range s.Messages() {
+			}
+		}()
+		data, _ := json.Marshal(map[string]string{"reviewer": name})
+		if err := engine.CallParams(ctx, s, live.Event{T: live.EventParams, Data: data}); err != nil {
+			t.Fatal(err)
+		}
+		sockets = append(sockets, s)
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range sockets {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			data, _ := json.Marshal(map[string]string{"comment": "racing " + string(s.ID())})
+			_ = engine.CallEvent(ctx, "add-comment", s, live.Event{T: "add-comment", Data: data})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = engine.CallEvent(ctx, "finish", s, live.Event{T: "finish"})
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-rs.DoneCh:
+	default:
+		t.Fatal("expected the session to be finished")
+	}
+	if !model.Completed || model.CompletedBy == "" {
+		t.Fatalf("expected completion to be attributed, got %+v", model.CompletedBy)
+	}
+
+	before := len(model.Comments)
+	data, _ := json.Marshal(map[string]string{"comment": "too late"})
+	if err := engine.CallEvent(ctx, "add-comment", sockets[0], live.Event{T: "add-comment", Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	if len(model.Comments) != before {
+		t.Fatal("expected events after completion to be ignored")
+	}
+
+	out, err := engine.Handler.RenderHandler(ctx, &live.RenderContext{Socket: sockets[1], Assigns: rs.view(sockets[1])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(out)
+	if !strings.Contains(buf.String(), "Review completed by "+model.CompletedBy) {
+		t.Fatal("expected other clients to see who completed the review")
+	}
+}
+
+// TestObserverCannotChangeReview verifies that a connection let in with the
+// observer token can watch but not comment, assign or finish, although its
+// URL carries no role.
+func TestObserverCannotChangeReview(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	model.SelectionStart, model.SelectionEnd = 1, 1
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+
+	var r *http.Request
+	auth := requireToken("secret", "watch", tokenCookieName("8080"), http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { r = req }))
+	rec := httptest.NewRecorder()
+	auth.ServeHTTP(rec, httptest.NewRequest("GET", "/?token=watch", nil))
+	req := httptest.NewRequest("GET", "/?reviewer=dana", nil)
+	req.AddCookie(rec.Result().Cookies()[0])
+	auth.ServeHTTP(httptest.NewRecorder(), req)
+
+	author := live.NewSocket(ctx, engine, "author")
+	view := rs.mountView(r)
+	author.Assign(view)
+	callEvent(t, engine, author, live.EventParams, map[string]string{"reviewer": "dana"})
+	view.SelectionStart, view.SelectionEnd = 1, 1
+
+	callEvent(t, engine, author, "add-comment", map[string]string{"comment": "from the author"})
+	callEvent(t, engine, author, "assign-file", map[string]string{"reviewer": "dana"})
+	callEvent(t, engine, author, "finish", nil)
+
+	if len(model.Comments) != 1 || len(model.Assignments) != 0 || len(model.Reviewers) != 0 {
+		t.Fatalf("expected observer events to be ignored, got comments=%d assignments=%v reviewers=%v", len(model.Comments), model.Assignments, model.Reviewers)
+	}
+	select {
+	case <-rs.DoneCh:
+		t.Fatal("observer must not be able to finish the review")
+	default:
+	}
+
+	out, err := engine.Handler.RenderHandler(ctx, &live.RenderContext{Socket: author, Assigns: view})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(out)
+	if html := buf.String(); !strings.Contains(html, `class="app observer"`) || !strings.Contains(html, "observing") {
+		t.Fatal("expected the observer to get the read-only UI")
+	}
+
+	reviewer := live.NewSocket(ctx, engine, "reviewer")
+	reviewer.Assign(rs.newView())
+	callEvent(t, engine, reviewer, live.EventParams, map[string]string{"reviewer": "erin", "role": "observer"})
+	callEvent(t, engine, reviewer, "assign-file", map[string]string{"reviewer": "erin"})
+	if model.Assignments[model.SelectedPath] != "erin" {
+		t.Fatalf("expected a role parameter not to change a reviewer's access, got %v", model.Assignments)
+	}
+}
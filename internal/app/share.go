@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net"
+	"strings"
+
+	"github.com/jfyne/live"
+	"rsc.io/qr"
+)
+
+// ShareInfo describes how colleagues can join a session started with
+// --share. ObserverURL carries the observer token, which lets people watch
+// but not change the review.
+type ShareInfo struct {
+	URL         string
+	QR          template.URL
+	ObserverURL string
+}
+
+// lanAddress returns the first private IPv4 address of an interface that is
+// up, for building a URL other machines on the network can reach.
+func lanAddress() (string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if ip := ipnet.IP.To4(); ip != nil && ip.IsPrivate() {
+				return ip.String(), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no LAN address found to share on")
+}
+
+// shareInfo encodes url as a QR code for display in the UI, alongside the
+// read-only observerURL.
+func shareInfo(url, observerURL string) (*ShareInfo, error) {
+	code, err := qr.Encode(url, qr.M)
+	if err != nil {
+		return nil, err
+	}
+	svg := qrSVG(code)
+	return &ShareInfo{
+		URL:         url,
+		ObserverURL: observerURL,
+		QR:          template.URL("data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(svg))),
+	}, nil
+}
+
+// qrSVG renders code as an SVG with a four module quiet zone.
+func qrSVG(code *qr.Code) string {
+	const quiet = 4
+	size := code.Size + 2*quiet
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, size, size)
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Black(x, y) {
+				fmt.Fprintf(&b, "M%d %dh1v1h-1z", x+quiet, y+quiet)
+			}
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return b.String()
+}
+
+// qrText renders url as a QR code using half-block characters, two modules
+// per character row, for printing to a terminal.
+func qrText(url string) (string, error) {
+	code, err := qr.Encode(url, qr.M)
+	if err != nil {
+		return "", err
+	}
+	const quiet = 2
+	black := func(x, y int) bool { return code.Black(x-quiet, y-quiet) }
+	size := code.Size + 2*quiet
+	var b strings.Builder
+	for y := 0; y < size; y += 2 {
+		for x := 0; x < size; x++ {
+			top, bottom := black(x, y), black(x, y+1)
+			// Light modules are drawn, so the code reads on dark terminals.
+			switch {
+			case !top && !bottom:
+				b.WriteRune('█')
+			case !top:
+				b.WriteRune('▀')
+			case !bottom:
+				b.WriteRune('▄')
+			default:
+				b.WriteRune(' ')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+func registerShareHandlers(h *live.Handler, rs *ReviewServer) {
+	h.HandleEvent("toggle-share", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		return rs.withView(s, func(view *ReviewView) { view.ShareOpen = !view.ShareOpen }), nil
+	})
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestShareQRCode verifies the terminal and UI renderings of the join URL.
+func TestShareQRCode(t *testing.T) {
+	url := "http://192.168.1.20:4000/?token=0123456789abcdef"
+	text, err := qrText(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	width := utf8.RuneCountInString(rows[0])
+	if width < 21 || len(rows) != (width+1)/2 {
+		t.Fatalf("unexpected QR dimensions: %d rows of %d", len(rows), width)
+	}
+	for i, row := range rows {
+		if n := utf8.RuneCountInString(row); n != width {
+			t.Fatalf("row %d has %d modules, want %d", i, n, width)
+		}
+	}
+
+	info, err := shareInfo(url, url+"&observer=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := buildCommentModel()
+	model.Share = info
+	if html := renderReviewHTML(t, model); !strings.Contains(html, `live-click="toggle-share"`) {
+		t.Fatal("expected a share button in shared sessions")
+	}
+}
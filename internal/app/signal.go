@@ -0,0 +1,54 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// signalReviewer is recorded as CompletedBy when a signal finishes the
+// review.
+const signalReviewer = "signal"
+
+// ErrAborted is returned by Run after the review has been written when
+// SIGINT or SIGTERM finished it, so callers can exit as interrupted.
+var ErrAborted = errors.New("review aborted")
+
+// abort finishes an open review when meatcheck receives sig. It reports
+// whether the review was finished.
+func (rs *ReviewServer) abort(sig os.Signal) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	model := rs.Model
+	if model.Completed {
+		return false
+	}
+	model.Aborted = true
+	model.Completed = true
+	model.CompletedBy = signalReviewer
+	rs.events.emit(eventAborted, "signal", sig.String())
+	rs.events.emit(eventFinished, "comments", len(model.Comments), "verdict", model.Verdict, "by", model.CompletedBy)
+	return true
+}
+
+// watchSignals finishes the review with the comments so far on the first
+// signal received on signals, re-rendering every client through notify
+// first. The returned function stops watching.
+func (rs *ReviewServer) watchSignals(signals <-chan os.Signal, notify func()) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			if !rs.abort(sig) {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "warning: %s received; writing the comments so far\n", sig)
+			notify()
+			rs.DoneOnce.Do(func() {
+				close(rs.DoneCh)
+			})
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
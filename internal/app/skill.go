@@ -4,11 +4,66 @@ import (
 	_ "embed"
 	"fmt"
 	"io"
+	"strings"
+	"text/template"
 )
 
+// skillMarkdown is the default SKILL.md template. It uses [[ ]] delimiters
+// so the {{ }} prompt placeholders it documents are printed as written.
+//
 //go:embed skill.md
 var skillMarkdown string
 
-func PrintSkill(w io.Writer) {
-	fmt.Fprint(w, skillMarkdown)
+// defaultSkillName is the skill name used when SkillOptions.Name is empty.
+const defaultSkillName = "meatcheck"
+
+// SkillOptions customises the SKILL.md printed by --skill.
+type SkillOptions struct {
+	// Template replaces the built-in skill.md template when non-empty.
+	Template string
+	// Name is the skill name in the front matter.
+	Name string
+	// Notes is markdown appended to the skill under "Team notes".
+	Notes string
+	// OutputFormat is the output the skill asks for: "toon" (the default)
+	// or "llm-context".
+	OutputFormat string
+}
+
+// skillData is what a skill template can refer to.
+type skillData struct {
+	Name         string
+	Notes        string
+	OutputFormat string
+}
+
+// PrintSkill renders the skill template with opts and writes it to w.
+func PrintSkill(w io.Writer, opts SkillOptions) error {
+	data := skillData{
+		Name:         strings.TrimSpace(opts.Name),
+		Notes:        strings.TrimSpace(opts.Notes),
+		OutputFormat: opts.OutputFormat,
+	}
+	if data.Name == "" {
+		data.Name = defaultSkillName
+	}
+	switch data.OutputFormat {
+	case "":
+		data.OutputFormat = "toon"
+	case "toon", emitLLMContext:
+	default:
+		return fmt.Errorf("unknown skill output format: %s", opts.OutputFormat)
+	}
+	text := opts.Template
+	if text == "" {
+		text = skillMarkdown
+	}
+	tmpl, err := template.New("skill").Delims("[[", "]]").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fmt.Errorf("parse skill template: %w", err)
+	}
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("render skill template: %w", err)
+	}
+	return nil
 }
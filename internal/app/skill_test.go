@@ -0,0 +1,74 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintSkillDefaultsToBuiltInTemplate(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PrintSkill(&buf, SkillOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "---\nname: meatcheck\n") {
+		t.Fatalf("unexpected front matter:\n%s", out[:min(len(out), 80)])
+	}
+	if strings.Contains(out, "[[") || strings.Contains(out, "Team notes") {
+		t.Fatalf("template markup or notes leaked into default skill")
+	}
+	// Prompt placeholders are documented literally, not rendered.
+	if !strings.Contains(out, "`{{.FileCount}}`") {
+		t.Fatalf("expected prompt placeholders to survive rendering")
+	}
+}
+
+func TestPrintSkillOptions(t *testing.T) {
+	var buf bytes.Buffer
+	err := PrintSkill(&buf, SkillOptions{
+		Name:         "acme-review",
+		Notes:        "Link the ticket in every comment.\n",
+		OutputFormat: emitLLMContext,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"name: acme-review\n",
+		"Always pass `--emit llm-context`",
+		"## Team notes\n\nLink the ticket in every comment.\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+}
+
+func TestPrintSkillCustomTemplate(t *testing.T) {
+	var buf bytes.Buffer
+	err := PrintSkill(&buf, SkillOptions{
+		Template: "# [[.Name]] ([[.OutputFormat]])\n[[.Notes]]\n",
+		Notes:    "be kind",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "# meatcheck (toon)\nbe kind\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestPrintSkillErrors(t *testing.T) {
+	cases := []SkillOptions{
+		{OutputFormat: "yaml"},
+		{Template: "[[.Name"},
+		{Template: "[[.Missing]]"},
+	}
+	for _, opts := range cases {
+		if err := PrintSkill(&bytes.Buffer{}, opts); err == nil {
+			t.Errorf("PrintSkill(%+v) succeeded, want error", opts)
+		}
+	}
+}
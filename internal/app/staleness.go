@@ -0,0 +1,63 @@
+package app
+
+import (
+	"os"
+	"sort"
+)
+
+// changedOnDisk returns the paths of files whose size or modification time
+// differs from when they were loaded. Files that can no longer be read also
+// count as changed.
+func changedOnDisk(files []File) []string {
+	var changed []string
+	for _, f := range files {
+		if f.ModTime.IsZero() {
+			continue
+		}
+		info, err := os.Stat(f.Path)
+		if err != nil || info.Size() != f.Size || !info.ModTime().Equal(f.ModTime) {
+			changed = append(changed, f.Path)
+		}
+	}
+	return changed
+}
+
+// checkStaleFiles records files that changed on disk since loading. It
+// reports true when a change was found that the reviewer has not been warned
+// about yet.
+func checkStaleFiles(model *ReviewModel) bool {
+	if model.Mode != ModeFile {
+		return false
+	}
+	found := false
+	for _, path := range changedOnDisk(model.Files) {
+		if model.StaleFiles == nil {
+			model.StaleFiles = make(map[string]bool)
+		}
+		if !model.StaleFiles[path] {
+			model.StaleFiles[path] = true
+			found = true
+		}
+	}
+	return found
+}
+
+// staleComments returns the IDs of comments on files that changed on disk.
+func staleComments(model *ReviewModel) []int {
+	var ids []int
+	for _, c := range model.Comments {
+		if model.StaleFiles[c.Path] {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCheckStaleFilesWarnsOnce verifies that a file modified after loading is
+// reported once, and that the output flags it and its comments.
+func TestCheckStaleFilesWarnsOnce(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(path, []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := loadFiles([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewModel{
+		Files:    files,
+		Mode:     ModeFile,
+		Comments: []Comment{{ID: 7, Path: path, StartLine: 1, EndLine: 1, Text: "x"}},
+	}
+
+	if checkStaleFiles(model) {
+		t.Fatal("expected no stale files before modification")
+	}
+	if err := os.WriteFile(path, []byte("package a\n\nfunc A() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !checkStaleFiles(model) {
+		t.Fatal("expected modified file to be reported")
+	}
+	if checkStaleFiles(model) {
+		t.Fatal("expected already reported file not to block again")
+	}
+
+	var buf bytes.Buffer
+	if err := emitReview(&buf, model); err != nil {
+		t.Fatalf("emitReview: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "changed_files") || !strings.Contains(out, "stale_comments[1]: 7") {
+		t.Fatalf("expected stale metadata in output, got:\n%s", out)
+	}
+}
+
+// TestCheckStaleFilesIgnoresDiffMode verifies that diff mode, which has no
+// files loaded from disk, never reports staleness.
+func TestCheckStaleFilesIgnoresDiffMode(t *testing.T) {
+	model := &ReviewModel{Mode: ModeDiff, DiffFiles: []DiffFile{{Path: "x.go"}}}
+	if checkStaleFiles(model) {
+		t.Fatal("expected diff mode to skip staleness checks")
+	}
+}
+
+// TestStaleCheckUsesStatAtRead verifies that a file changed after startup but
+// before it was first opened is not reported: the reviewer saw the new
+// content.
+func TestStaleCheckUsesStatAtRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.go")
+	if err := os.WriteFile(path, []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := loadFiles([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(path, []byte("package a\n\nfunc A() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewModel{Files: files, Mode: ModeFile}
+	if err := ensureFileLoaded(nil, &model.Files[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(model.Files[0].Lines) != 3 {
+		t.Fatalf("expected the new content, got %q", model.Files[0].Lines)
+	}
+	if checkStaleFiles(model) {
+		t.Fatal("expected the file to match the content that was read")
+	}
+}
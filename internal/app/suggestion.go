@@ -0,0 +1,221 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// suggestionContext is how many unchanged lines either side of the
+// suggested change its patch carries, as git diff does by default.
+const suggestionContext = 3
+
+// suggestionRecord is a suggested change as written to the review
+// document: the comment it came from and a unified diff replacing the
+// commented lines with the suggestion.
+type suggestionRecord struct {
+	ID        int    `json:"id"`
+	UID       string `json:"uid"`
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Patch     string `json:"patch"`
+}
+
+// commentSuggestion returns the body of the first ```suggestion block in
+// text, as GitHub does, and whether there is one. An empty block suggests
+// deleting the lines.
+func commentSuggestion(text string) ([]string, bool) {
+	var (
+		open, found bool
+		fence       string
+		body        []string
+	)
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		marker := ""
+		if len(line)-len(trimmed) <= 3 {
+			marker = fenceMarker(trimmed)
+		}
+		switch {
+		case !open && marker != "":
+			open, fence = true, marker
+			found = strings.TrimSpace(trimmed[len(marker):]) == "suggestion"
+		case open && marker != "" && strings.HasPrefix(marker, fence) && strings.TrimSpace(trimmed[len(marker):]) == "":
+			if found {
+				return body, true
+			}
+			open = false
+		case open && found:
+			body = append(body, line)
+		}
+	}
+	return nil, false
+}
+
+// suggestionPatch returns a unified diff applying the suggestion in c to
+// the lines it is anchored to, or "" when c has no suggestion or cannot
+// carry one: file-level comments, comments on removed lines and comments
+// whose lines are not all shown.
+func suggestionPatch(model *ReviewModel, c Comment) string {
+	lines, ok := commentSuggestion(c.Text)
+	if !ok || c.isFileLevel() || c.Side == "old" {
+		return ""
+	}
+	excerpt := commentExcerpt(model, c, suggestionContext)
+	// Context stops at the first gap, which in diff mode is a line the
+	// diff does not show.
+	first, last := -1, -1
+	for i, l := range excerpt {
+		if l.Number == c.StartLine {
+			first = i
+		}
+		if l.Number == c.EndLine {
+			last = i
+		}
+	}
+	if first < 0 || last < 0 || excerpt[last].Number-excerpt[first].Number != last-first {
+		return ""
+	}
+	for first > 0 && excerpt[first-1].Number == excerpt[first].Number-1 {
+		first--
+	}
+	for last < len(excerpt)-1 && excerpt[last+1].Number == excerpt[last].Number+1 {
+		last++
+	}
+	excerpt = excerpt[first : last+1]
+
+	// The patch keeps the file's line breaks and UTF-8 byte order mark,
+	// and marks a last line without a break as git does, so that git apply
+	// accepts it.
+	source, count := suggestionSource(model, c.Path)
+	eol, lastLine := "\n", 0
+	if source.lineEnding == LineEndingCRLF {
+		eol = "\r\n"
+	}
+	if source.noFinalNewline {
+		lastLine = count
+	}
+	var hunk strings.Builder
+	writeLine := func(prefix string, n int, text string, last bool) {
+		// A diff may carry the mark in its own line 1 text already.
+		if bom := string(bomBytes[BOMUTF8]); n == 1 && source.bom == BOMUTF8 && !strings.HasPrefix(text, bom) {
+			text = bom + text
+		}
+		if last {
+			hunk.WriteString(prefix + text + "\n" + noNewlineMarker + "\n")
+			return
+		}
+		hunk.WriteString(prefix + text + eol)
+	}
+	oldCount, newCount := 0, 0
+	for _, l := range excerpt {
+		last := l.Number == lastLine
+		switch {
+		case l.Number < c.StartLine || l.Number > c.EndLine:
+			writeLine(" ", l.Number, l.Text, last)
+			oldCount++
+			newCount++
+		default:
+			writeLine("-", l.Number, l.Text, last)
+			oldCount++
+		}
+		if l.Number == c.EndLine {
+			for i, s := range lines {
+				// The first suggested line takes the place of line 1, and
+				// its byte order mark, when the suggestion starts there.
+				writeLine("+", c.StartLine+i, s, last && i == len(lines)-1)
+				newCount++
+			}
+		}
+	}
+	start := excerpt[0].Number
+	newStart := start
+	if newCount == 0 {
+		newStart--
+	}
+	path := repoPath(model.seriesPath(c.Path), patchRoot(model))
+	return fmt.Sprintf("--- a/%s\n+++ b/%s\n@@ -%s +%s @@\n%s", path, path,
+		hunkRange(start, oldCount), hunkRange(newStart, newCount), hunk.String())
+}
+
+// suggestionSource returns the layout on disk of the file path names, which
+// its patch must keep, and how many lines it has: the reviewed file, or in
+// diff mode the source the diff was expanded from. Without one the patch
+// assumes LF breaks and a final newline.
+func suggestionSource(model *ReviewModel, path string) (fileContent, int) {
+	if model.Mode == ModeDiff {
+		if file := model.lookupDiffFile(path); file != nil && diffSource(file) != nil {
+			return file.source, len(file.source.lines)
+		}
+		return fileContent{}, 0
+	}
+	file := model.lookupFile(path)
+	if file == nil || ensureFileLoaded(model.loader, file) != nil {
+		return fileContent{}, 0
+	}
+	return fileContent{lineEnding: file.LineEnding, noFinalNewline: file.NoFinalNewline, bom: file.BOM}, fileLineCount(file)
+}
+
+// patchRoot is the directory a suggestion's patch names files relative to,
+// so git apply finds them: the repository root when files were named on
+// the command line, or the working directory outside a repository. Diff
+// paths already are relative.
+func patchRoot(model *ReviewModel) string {
+	if model.Mode != ModeFile {
+		return ""
+	}
+	if model.Git != nil && model.Git.RepoRoot != "" {
+		return model.Git.RepoRoot
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// noNewlineMarker follows a patch line that has no line break in the file.
+const noNewlineMarker = `\ No newline at end of file`
+
+// hunkRange formats one side of a hunk header, leaving out a count of 1.
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// suggestionRecords lists the suggested changes among comments.
+func suggestionRecords(model *ReviewModel, comments []Comment) []suggestionRecord {
+	var out []suggestionRecord
+	for _, c := range comments {
+		if patch := suggestionPatch(model, c); patch != "" {
+			out = append(out, suggestionRecord{
+				ID:        c.ID,
+				UID:       c.UID,
+				Path:      model.seriesPath(c.Path),
+				StartLine: c.StartLine,
+				EndLine:   c.EndLine,
+				Patch:     patch,
+			})
+		}
+	}
+	return out
+}
+
+// suggestionSeed returns the selected lines, each ending in a newline, for
+// the "Suggest change" button to start a suggestion block from, or "" when
+// the selection cannot take one.
+func suggestionSeed(view *ReviewView) string {
+	if view.SelectionStart == 0 || view.SelectionSide == "old" {
+		return ""
+	}
+	c := Comment{Path: view.SelectedPath, StartLine: view.SelectionStart, EndLine: view.SelectionEnd}
+	excerpt := commentExcerpt(view.ReviewModel, c, 0)
+	if len(excerpt) != c.EndLine-c.StartLine+1 {
+		return ""
+	}
+	var b strings.Builder
+	for _, l := range excerpt {
+		b.WriteString(l.Text + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,265 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCommentSuggestion(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want []string
+		ok   bool
+	}{
+		{"Rename it:\n```suggestion\nfunc Run() {\n```\nthanks", []string{"func Run() {"}, true},
+		{"Drop these.\n```suggestion\n```", nil, true},
+		{"````suggestion\n```go\nx\n```\n````", []string{"```go", "x", "```"}, true},
+		{"```go\nx\n```\n~~~suggestion\ny\n~~~", []string{"y"}, true},
+		{"```go\nx\n```", nil, false},
+		{"```suggestion\nnever closed", nil, false},
+	} {
+		got, ok := commentSuggestion(tc.text)
+		if ok != tc.ok || !slices.Equal(got, tc.want) {
+			t.Errorf("commentSuggestion(%q) = %q, %v; want %q, %v", tc.text, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// TestSuggestionPatchApplies verifies that the patch for a suggestion on a
+// file applies with git apply and makes the suggested change.
+func TestSuggestionPatchApplies(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	var content strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	if err := os.WriteFile("main.go", []byte(content.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := loadFiles([]string{"main.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewModel{Mode: ModeFile, Files: files}
+
+	c := Comment{Path: "main.go", StartLine: 2, EndLine: 3, Text: "Merge these.\n```suggestion\nlines 2 and 3\n```"}
+	patch := suggestionPatch(model, c)
+	want := "--- a/main.go\n+++ b/main.go\n@@ -1,6 +1,5 @@\n line 1\n-line 2\n-line 3\n+lines 2 and 3\n line 4\n line 5\n line 6\n"
+	if patch != want {
+		t.Fatalf("suggestionPatch = %q, want %q", patch, want)
+	}
+	if err := os.WriteFile("s.patch", []byte(patch), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "apply", "s.patch").CombinedOutput(); err != nil {
+		t.Fatalf("git apply: %v\n%s", err, out)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "line 1\nlines 2 and 3\nline 4\n") {
+		t.Fatalf("unexpected file after applying:\n%s", data)
+	}
+
+	deletion := Comment{Path: "main.go", StartLine: 1, EndLine: 1, Text: "```suggestion\n```"}
+	if patch := suggestionPatch(model, deletion); !strings.Contains(patch, "@@ -1,4 +1,3 @@\n-line 1\n line 2\n") {
+		t.Errorf("unexpected deletion patch %q", patch)
+	}
+	for _, c := range []Comment{
+		{Path: "main.go", Text: "```suggestion\nx\n```"},
+		{Path: "main.go", StartLine: 2, EndLine: 2, Text: "no suggestion"},
+		{Path: "main.go", StartLine: 9, EndLine: 12, Text: "```suggestion\nx\n```"},
+	} {
+		if patch := suggestionPatch(model, c); patch != "" {
+			t.Errorf("expected no patch for %+v, got %q", c, patch)
+		}
+	}
+}
+
+// TestSuggestionPatchLineEndings verifies that patches for CRLF files and
+// files without a final newline keep them, so git apply accepts the patch.
+func TestSuggestionPatchLineEndings(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	for _, tc := range []struct {
+		name, content string
+		line          int
+		want          string
+	}{
+		{"crlf", "one\r\ntwo\r\nthree\r\n", 2, "one\r\nTWO\r\nthree\r\n"},
+		{"no final newline", "one\ntwo", 2, "one\nTWO"},
+		{"no final newline context", "one\ntwo", 1, "TWO\ntwo"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			if err := os.WriteFile("f.txt", []byte(tc.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			files, err := loadFiles([]string{"f.txt"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			model := &ReviewModel{Mode: ModeFile, Files: files}
+			c := Comment{Path: "f.txt", StartLine: tc.line, EndLine: tc.line, Text: "```suggestion\nTWO\n```"}
+			if err := os.WriteFile("s.patch", []byte(suggestionPatch(model, c)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if out, err := exec.Command("git", "apply", "--check", "s.patch").CombinedOutput(); err != nil {
+				t.Fatalf("git apply --check: %v\n%s", err, out)
+			}
+			if out, err := exec.Command("git", "apply", "s.patch").CombinedOutput(); err != nil {
+				t.Fatalf("git apply: %v\n%s", err, out)
+			}
+			data, err := os.ReadFile("f.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.want {
+				t.Fatalf("file after applying = %q, want %q", data, tc.want)
+			}
+		})
+	}
+}
+
+// TestSuggestionPatchGitApply verifies with git apply --check that patches
+// keep a UTF-8 byte order mark, name absolute paths relative to the
+// repository, and in diff mode keep the line breaks of the file on disk.
+func TestSuggestionPatchGitApply(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	check := func(t *testing.T, dir, patch string) {
+		t.Helper()
+		cmd := exec.Command("git", "apply", "--check", "-")
+		cmd.Dir, cmd.Stdin = dir, strings.NewReader(patch)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git apply --check: %v\n%s\npatch:\n%q", err, out, patch)
+		}
+	}
+	suggest := "```suggestion\nTWO\n```"
+
+	t.Run("bom", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Chdir(dir)
+		if err := os.WriteFile("f.txt", []byte("\ufeffone\ntwo\nthree\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files, err := loadFiles([]string{"f.txt"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		model := &ReviewModel{Mode: ModeFile, Files: files}
+		for _, line := range []int{1, 2} {
+			check(t, dir, suggestionPatch(model, Comment{Path: "f.txt", StartLine: line, EndLine: line, Text: suggest}))
+		}
+	})
+
+	t.Run("absolute path in a repository", func(t *testing.T) {
+		root := t.TempDir()
+		t.Chdir(t.TempDir())
+		if err := os.MkdirAll(filepath.Join(root, "pkg"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(root, "pkg", "a.go")
+		if err := os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := exec.Command("git", "-C", root, "init", "-q").CombinedOutput(); err != nil {
+			t.Fatalf("git init: %v\n%s", err, out)
+		}
+		files, err := loadFiles([]string{path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		model := &ReviewModel{Mode: ModeFile, Files: files, Git: &GitContext{RepoRoot: root}}
+		patch := suggestionPatch(model, Comment{Path: path, StartLine: 2, EndLine: 2, Text: suggest})
+		if !strings.HasPrefix(patch, "--- a/pkg/a.go\n+++ b/pkg/a.go\n") {
+			t.Fatalf("expected repository relative paths, got %q", patch)
+		}
+		check(t, root, patch)
+	})
+
+	for _, tc := range []struct {
+		name, content, diff string
+		line                int
+	}{
+		{"diff crlf", "one\r\ntwo\r\nthree\r\n", "@@ -1,3 +1,3 @@\n one\n-2\n+two\n three\n", 2},
+		{"diff no final newline", "one\ntwo", "@@ -1,2 +1,2 @@\n one\n-2\n+two\n\\ No newline at end of file\n", 2},
+		{"diff bom", "\ufeffone\ntwo\n", "@@ -1,2 +1,2 @@\n \ufeffone\n-2\n+two\n", 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Chdir(dir)
+			if err := os.WriteFile("f.txt", []byte(tc.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			files, err := parseUnifiedDiff("diff --git a/f.txt b/f.txt\n--- a/f.txt\n+++ b/f.txt\n"+tc.diff, diffStrict)
+			if err != nil {
+				t.Fatal(err)
+			}
+			model := &ReviewModel{Mode: ModeDiff, DiffFiles: files}
+			check(t, dir, suggestionPatch(model, Comment{Path: "f.txt", StartLine: tc.line, EndLine: tc.line, Text: suggest}))
+		})
+	}
+}
+
+// TestSuggestionPatchDiff verifies that in diff mode the patch applies to
+// the new side and its context stops where the diff does.
+func TestSuggestionPatchDiff(t *testing.T) {
+	files, err := parseUnifiedDiff("diff --git a/c.go b/c.go\n--- a/c.go\n+++ b/c.go\n@@ -10,4 +10,5 @@\n ten\n eleven\n+twelve\n thirteen\n fourteen\n", diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewModel{Mode: ModeDiff, DiffFiles: files}
+	c := Comment{ID: 1, UID: "uid-1", Path: "c.go", StartLine: 12, EndLine: 12, Text: "```suggestion\ntwelve := 12\n```"}
+	want := "--- a/c.go\n+++ b/c.go\n@@ -10,5 +10,5 @@\n ten\n eleven\n-twelve\n+twelve := 12\n thirteen\n fourteen\n"
+	if patch := suggestionPatch(model, c); patch != want {
+		t.Fatalf("suggestionPatch = %q, want %q", patch, want)
+	}
+	if patch := suggestionPatch(model, Comment{Path: "c.go", StartLine: 11, EndLine: 11, Side: "old", Text: c.Text}); patch != "" {
+		t.Errorf("expected no patch on the old side, got %q", patch)
+	}
+
+	model.Comments = []Comment{c}
+	doc := reviewDocument(model)
+	if records := doc["suggestions"].([]suggestionRecord); len(records) != 1 || records[0].UID != "uid-1" || records[0].Patch != want {
+		t.Fatalf("unexpected suggestions %+v", doc["suggestions"])
+	}
+	if err := validateDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSuggestionSeed(t *testing.T) {
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode:  ModeFile,
+			Files: []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"one", "", "three"}}},
+		},
+		SelectedPath:   "a.go",
+		SelectionStart: 1,
+		SelectionEnd:   2,
+	}
+	if got := suggestionSeed(model); got != "one\n\n" {
+		t.Errorf("suggestionSeed = %q", got)
+	}
+	model.SelectionSide = "old"
+	if got := suggestionSeed(model); got != "" {
+		t.Errorf("expected no seed on the old side, got %q", got)
+	}
+	model.SelectionSide, model.SelectionStart, model.SelectionEnd = "", 0, 0
+	if got := suggestionSeed(model); got != "" {
+		t.Errorf("expected no seed without a selection, got %q", got)
+	}
+}
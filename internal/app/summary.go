@@ -0,0 +1,41 @@
+package app
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jfyne/live"
+)
+
+// ReviewSummary is the overview of the review written to the output. It can
+// be drafted with --summary-file and is edited by the reviewer in the UI.
+type ReviewSummary struct {
+	Text string
+	// Drafted is true when the session started with a drafted summary.
+	Drafted bool
+	// Edited is true once a reviewer saved the summary in the UI, even
+	// without changing the draft.
+	Edited bool
+	// Revision counts saves so the form re-renders with the saved text.
+	Revision int
+}
+
+// summaryDocument converts the summary to the output document shape.
+func summaryDocument(s ReviewSummary) map[string]any {
+	return map[string]any{
+		"text":    s.Text,
+		"drafted": s.Drafted,
+		"edited":  s.Edited,
+	}
+}
+
+func registerSummaryHandlers(h *live.Handler, rs *ReviewServer) {
+	h.HandleEvent("save-summary", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		view.Summary.Text = strings.TrimSpace(p.String("summary"))
+		view.Summary.Edited = true
+		view.Summary.Revision++
+		rs.markChanged()
+		return view, nil
+	}))
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+// TestReviewSummary verifies that a drafted summary is shown for editing and
+// that the output always carries the summary, marked once a reviewer saved
+// it.
+func TestReviewSummary(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	emit := func(model *ReviewModel) string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := emitReview(&buf, model); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	if out := emit(buildCommentModel().ReviewModel); !strings.Contains(out, "summary:") {
+		t.Fatalf("expected an empty summary in the output, got:\n%s", out)
+	}
+
+	model := buildCommentModel()
+	model.Summary = ReviewSummary{Text: "Adds retries to the client.", Drafted: true}
+	if html := renderReviewHTML(t, model); !strings.Contains(html, "Adds retries to the client.") || !strings.Contains(html, "drafted by the agent") {
+		t.Fatal("expected the drafted summary in the UI")
+	}
+
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	s := live.NewSocket(ctx, engine, "reviewer")
+	s.Assign(model)
+	callEvent(t, engine, s, "save-summary", map[string]string{"summary": "  Adds bounded retries to the client.  "})
+
+	if model.Summary.Text != "Adds bounded retries to the client." || !model.Summary.Edited || !model.Summary.Drafted {
+		t.Fatalf("unexpected summary %+v", model.Summary)
+	}
+	out := emit(model.ReviewModel)
+	if !strings.Contains(out, "Adds bounded retries to the client.") || !strings.Contains(out, "edited: true") {
+		t.Fatalf("expected the edited summary in the output, got:\n%s", out)
+	}
+}
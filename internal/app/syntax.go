@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jfyne/live"
+	"github.com/jfyne/meatcheck/internal/highlight"
+)
+
+// SyntaxRule forces the highlighting language of the files matching
+// Pattern, for extensions chroma detects wrongly or not at all.
+type SyntaxRule struct {
+	Pattern  string
+	Language string
+}
+
+// ParseSyntaxFlag parses --syntax values of the form pattern=language, where
+// pattern is matched like --exclude and language is a lexer name or alias,
+// e.g. "*.tpl=html" or "scripts/deploy=bash".
+func ParseSyntaxFlag(values []string) ([]SyntaxRule, error) {
+	var rules []SyntaxRule
+	for _, val := range values {
+		i := strings.LastIndex(val, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid syntax override %q: want pattern=language", val)
+		}
+		pattern, lang := strings.TrimSpace(val[:i]), strings.TrimSpace(val[i+1:])
+		if err := validateGlob(pattern); err != nil {
+			return nil, fmt.Errorf("invalid syntax override %q: %w", val, err)
+		}
+		name := highlight.LanguageName(lang)
+		if name == "" {
+			return nil, fmt.Errorf("invalid syntax override %q: unknown language %q", val, lang)
+		}
+		rules = append(rules, SyntaxRule{Pattern: pattern, Language: name})
+	}
+	return rules, nil
+}
+
+// languageFor returns the language path is highlighted as: the one picked
+// in the UI, else that of the last matching --syntax rule, else "" to detect
+// it from the name and content.
+func languageFor(model *ReviewModel, path string) string {
+	if lang, ok := model.SyntaxOverrides[path]; ok {
+		return lang
+	}
+	lang := ""
+	for _, r := range model.SyntaxRules {
+		if pathMatches(r.Pattern, path) {
+			lang = r.Language
+		}
+	}
+	return lang
+}
+
+// applyLanguages sets the highlighting language of every file under review.
+// It runs again whenever the overrides or the diff change.
+func applyLanguages(model *ReviewModel) {
+	for i := range model.Files {
+		model.Files[i].Language = languageFor(model, model.Files[i].Path)
+	}
+	for i := range model.DiffFiles {
+		model.DiffFiles[i].Language = languageFor(model, model.DiffFiles[i].Path)
+	}
+}
+
+// languageChoices are the languages offered in the file header's picker.
+var languageChoices = highlight.Languages()
+
+func registerSyntaxHandlers(h *live.Handler, rs *ReviewServer) {
+	// The language is picked for everyone: it is a property of the file,
+	// like its content, rather than of one reviewer's view.
+	h.HandleEvent("set-syntax", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		if view.SelectedPath == "" {
+			return view, nil
+		}
+		lang := highlight.LanguageName(strings.TrimSpace(p.String("language")))
+		if view.SyntaxOverrides == nil {
+			view.SyntaxOverrides = make(map[string]string)
+		}
+		// An empty choice goes back to detection, even over a --syntax rule.
+		view.SyntaxOverrides[view.SelectedPath] = lang
+		applyLanguages(view.ReviewModel)
+		updateView(view)
+		rs.markChanged()
+		return view, nil
+	}))
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+func TestParseSyntaxFlag(t *testing.T) {
+	rules, err := ParseSyntaxFlag([]string{"*.tpl=html", "scripts/deploy = sh"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []SyntaxRule{{Pattern: "*.tpl", Language: "HTML"}, {Pattern: "scripts/deploy", Language: "Bash"}}
+	if len(rules) != len(want) {
+		t.Fatalf("expected %d rules, got %+v", len(want), rules)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Fatalf("rule %d: expected %+v, got %+v", i, want[i], rules[i])
+		}
+	}
+
+	for _, bad := range []string{"html", "=html", "*.tpl=nosuchlanguage", "[=html"} {
+		if _, err := ParseSyntaxFlag([]string{bad}); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+// TestLanguageFor verifies that a choice made in the UI wins over --syntax
+// rules and that the last matching rule wins among them.
+func TestLanguageFor(t *testing.T) {
+	model := &ReviewModel{SyntaxRules: []SyntaxRule{
+		{Pattern: "*.tpl", Language: "HTML"},
+		{Pattern: "mail/*.tpl", Language: "plaintext"},
+	}}
+	if got := languageFor(model, "page.tpl"); got != "HTML" {
+		t.Fatalf("expected HTML, got %q", got)
+	}
+	if got := languageFor(model, "mail/welcome.tpl"); got != "plaintext" {
+		t.Fatalf("expected the later rule to win, got %q", got)
+	}
+	if got := languageFor(model, "main.go"); got != "" {
+		t.Fatalf("expected detection for unmatched files, got %q", got)
+	}
+	model.SyntaxOverrides = map[string]string{"page.tpl": ""}
+	if got := languageFor(model, "page.tpl"); got != "" {
+		t.Fatalf("expected the UI choice to restore detection, got %q", got)
+	}
+}
+
+// TestLanguageForDiff verifies that a --syntax rule highlights the hunks of
+// a matching file in diff mode, in both diff formats.
+func TestLanguageForDiff(t *testing.T) {
+	files, err := parseUnifiedDiff("diff --git a/page.tpl b/page.tpl\n--- a/page.tpl\n+++ b/page.tpl\n@@ -1,1 +1,1 @@\n-pass\n+def run(): pass\n", diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode:        ModeDiff,
+			DiffFiles:   files,
+			SyntaxRules: []SyntaxRule{{Pattern: "*.tpl", Language: "Python"}},
+		},
+		SelectedPath: "page.tpl",
+		RenderFile:   true,
+	}
+	applyLanguages(model.ReviewModel)
+	updateView(model)
+	if model.ViewDiff.Language != "Python" {
+		t.Fatalf("expected the diff view in Python, got %q", model.ViewDiff.Language)
+	}
+	if html := model.ViewDiff.Hunks[0].Lines[1].HTML; !strings.Contains(string(html), `class="k"`) {
+		t.Fatalf("expected def highlighted as a Python keyword, got %s", html)
+	}
+
+	model.DiffFormat = DiffFormatSplit
+	updateView(model)
+	if html := model.ViewDiffSplit[0].Rows[0].Right.HTML; !strings.Contains(string(html), `class="k"`) {
+		t.Fatalf("expected def highlighted as a Python keyword in the split view, got %s", html)
+	}
+}
+
+// TestSetSyntax verifies that picking a language in the file header changes
+// the file's highlighting for every reviewer.
+func TestSetSyntax(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	s := live.NewSocket(ctx, engine, "alice")
+	s.Assign(model)
+
+	callEvent(t, engine, s, "set-syntax", map[string]string{"language": "python"})
+	if model.Files[0].Language != "Python" {
+		t.Fatalf("expected Python, got %q", model.Files[0].Language)
+	}
+	out, err := engine.Handler.RenderHandler(ctx, &live.RenderContext{Socket: s, Assigns: model})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(out)
+	if !strings.Contains(buf.String(), `<option value="Python" selected>`) {
+		t.Fatal("expected Python to be selected in the picker")
+	}
+
+	callEvent(t, engine, s, "set-syntax", map[string]string{"language": ""})
+	if model.Files[0].Language != "" {
+		t.Fatalf("expected detection to be restored, got %q", model.Files[0].Language)
+	}
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jfyne/live"
+)
+
+// Tags are free-form labels on a comment, such as security, style or perf,
+// for filtering the review and triaging its output.
+type Tags []string
+
+// UnmarshalJSON accepts tags as a list or, as the review document writes
+// them, as one comma separated string.
+func (t *Tags) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = parseTags(s)
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("tags must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+// String joins the tags the way the review document writes them.
+func (t Tags) String() string {
+	return strings.Join(t, ",")
+}
+
+const (
+	// maxTags bounds the tags on one comment.
+	maxTags = 10
+	// maxTagLen bounds the length of one tag.
+	maxTagLen = 32
+)
+
+// parseTags splits the tags typed in a comment form, separated by commas or
+// spaces.
+func parseTags(s string) Tags {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+}
+
+// normalizeTags lowercases the tags, drops empty ones and duplicates, and
+// checks them against the limits. Order is kept as given.
+func normalizeTags(tags Tags) (Tags, error) {
+	var out Tags
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+		if tag == "" || slices.Contains(out, tag) {
+			continue
+		}
+		if strings.ContainsFunc(tag, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+			return nil, fmt.Errorf("tags cannot contain commas or spaces")
+		}
+		if len(tag) > maxTagLen {
+			return nil, fmt.Errorf("tags must be at most %d characters", maxTagLen)
+		}
+		out = append(out, tag)
+	}
+	if len(out) > maxTags {
+		return nil, fmt.Errorf("a comment can have at most %d tags", maxTags)
+	}
+	return out, nil
+}
+
+// commentTags returns every tag used on a comment in the review, sorted,
+// for the filter bar.
+func commentTags(model *ReviewModel) []string {
+	var tags []string
+	for _, c := range model.Comments {
+		for _, tag := range c.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+// filterByTags keeps the comments carrying at least one of tags, or all of
+// them when tags is empty.
+func filterByTags(comments []ViewComment, tags []string) []ViewComment {
+	if len(tags) == 0 {
+		return comments
+	}
+	var out []ViewComment
+	for _, c := range comments {
+		if slices.ContainsFunc(c.Tags, func(tag string) bool { return slices.Contains(tags, tag) }) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func registerTagHandlers(h *live.Handler, rs *ReviewServer) {
+	// The filter is per connection, like the "my files" filter, so
+	// observers can use it too.
+	h.HandleEvent("toggle-tag-filter", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		tag := p.String("tag")
+		return rs.withView(s, func(view *ReviewView) {
+			if i := slices.Index(view.TagFilter, tag); i >= 0 {
+				view.TagFilter = slices.Delete(view.TagFilter, i, i+1)
+			} else if tag != "" {
+				view.TagFilter = append(view.TagFilter, tag)
+			}
+		}), nil
+	})
+
+	h.HandleEvent("clear-tag-filter", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		return rs.withView(s, func(view *ReviewView) { view.TagFilter = nil }), nil
+	})
+}
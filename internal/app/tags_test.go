@@ -0,0 +1,110 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got, err := normalizeTags(parseTags(" Security, #perf style,security "))
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if want := (Tags{"security", "perf", "style"}); !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if _, err := normalizeTags(Tags{"a b"}); err == nil {
+		t.Fatal("expected tags with spaces to be rejected")
+	}
+	if _, err := normalizeTags(Tags{strings.Repeat("x", maxTagLen+1)}); err == nil {
+		t.Fatal("expected an overlong tag to be rejected")
+	}
+}
+
+// TestTagsRoundTrip verifies that tags written to the review document, as
+// one comma separated string, are read back by --previous, and that the API
+// form of a list is accepted too.
+func TestTagsRoundTrip(t *testing.T) {
+	var c Comment
+	if err := json.Unmarshal([]byte(`{"path":"a.go","text":"x","tags":["perf","style"]}`), &c); err != nil {
+		t.Fatalf("unmarshal list: %v", err)
+	}
+	if !slices.Equal(c.Tags, Tags{"perf", "style"}) {
+		t.Fatalf("expected tags from a list, got %v", c.Tags)
+	}
+
+	model := buildCommentModel()
+	model.Comments[0].Tags = Tags{"security", "perf"}
+	doc := reviewDocument(model.ReviewModel)
+	if err := validateDocument(doc); err != nil {
+		t.Fatalf("document does not match the schema: %v", err)
+	}
+	if got := doc["comments"].([]commentRecord)[0].Tags; got != "security,perf" {
+		t.Fatalf("expected joined tags, got %q", got)
+	}
+	var buf bytes.Buffer
+	if err := emitReview(&buf, model.ReviewModel); err != nil {
+		t.Fatal(err)
+	}
+	prev, err := parseReviewDocument(buf.Bytes())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !slices.Equal(prev.Comments[0].Tags, Tags{"security", "perf"}) {
+		t.Fatalf("expected tags to survive the round trip, got %v", prev.Comments[0].Tags)
+	}
+}
+
+// TestTagFilter verifies that tags typed in the comment form are saved and
+// that filtering by tag hides other comments for that connection only.
+func TestTagFilter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	model.Comments[0].Text = "untagged"
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	alice := live.NewSocket(ctx, engine, "alice")
+	bob := live.NewSocket(ctx, engine, "bob")
+	for _, s := range []*live.Socket{alice, bob} {
+		s.Assign(rs.newView())
+	}
+	render := func(s *live.Socket) string {
+		out, err := engine.Handler.RenderHandler(ctx, &live.RenderContext{Socket: s, Assigns: rs.view(s)})
+		if err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(out)
+		return buf.String()
+	}
+
+	callEvent(t, engine, alice, "start-file-comment", nil)
+	callEvent(t, engine, alice, "add-comment", map[string]string{"comment": "tagged", "tags": "Security, perf"})
+	if got := model.Comments[1].Tags; !slices.Equal(got, Tags{"security", "perf"}) {
+		t.Fatalf("expected the typed tags to be saved, got %v", got)
+	}
+	if got := commentTags(model.ReviewModel); !slices.Equal(got, []string{"perf", "security"}) {
+		t.Fatalf("expected the tags in use, got %v", got)
+	}
+
+	callEvent(t, engine, alice, "toggle-tag-filter", map[string]string{"tag": "security"})
+	html := render(alice)
+	if strings.Contains(html, "untagged") || !strings.Contains(html, "tagged") {
+		t.Fatal("expected alice to see only the tagged comment")
+	}
+	if !strings.Contains(render(bob), "untagged") {
+		t.Fatal("expected bob to still see every comment")
+	}
+	callEvent(t, engine, alice, "clear-tag-filter", nil)
+	if !strings.Contains(render(alice), "untagged") {
+		t.Fatal("expected clearing the filter to show every comment")
+	}
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jfyne/live"
+)
+
+// themeCookie remembers a browser's choice of theme across reloads and
+// sessions. It is written by the page, since events arrive over the
+// websocket and cannot set cookies themselves.
+const themeCookie = "meatcheck_theme"
+
+const (
+	themeDark  = "dark"
+	themeLight = "light"
+)
+
+// prefersLightTheme reports whether the request carries the light theme
+// cookie; without it the dark theme is used.
+func prefersLightTheme(r *http.Request) bool {
+	c, err := r.Cookie(themeCookie)
+	return err == nil && c.Value == themeLight
+}
+
+func registerThemeHandlers(h *live.Handler, rs *ReviewServer) {
+	h.HandleEvent("toggle-theme", func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		light := false
+		rs.withView(s, func(view *ReviewView) {
+			view.LightTheme = !view.LightTheme
+			light = view.LightTheme
+		})
+		if s != nil {
+			theme := themeDark
+			if light {
+				theme = themeLight
+			}
+			_ = s.Send("set-theme", map[string]any{"theme": theme, "cookie": themeCookie})
+		}
+		return rs.view(s), nil
+	})
+}
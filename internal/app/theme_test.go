@@ -0,0 +1,57 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+// TestToggleTheme verifies that the theme is chosen per connection and that
+// a browser's saved choice is honoured.
+func TestToggleTheme(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	alice := live.NewSocket(ctx, engine, "alice")
+	bob := live.NewSocket(ctx, engine, "bob")
+	for _, s := range []*live.Socket{alice, bob} {
+		s.Assign(rs.newView())
+	}
+	render := func(s *live.Socket) string {
+		out, err := engine.Handler.RenderHandler(ctx, &live.RenderContext{Socket: s, Assigns: rs.view(s)})
+		if err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(out)
+		return buf.String()
+	}
+
+	if !strings.Contains(render(alice), `<body class="theme-dark">`) {
+		t.Fatal("expected the dark theme by default")
+	}
+	callEvent(t, engine, alice, "toggle-theme", nil)
+	if !strings.Contains(render(alice), `<body class="theme-light">`) {
+		t.Fatal("expected alice to switch to the light theme")
+	}
+	if !strings.Contains(render(bob), `<body class="theme-dark">`) {
+		t.Fatal("expected bob to keep the dark theme")
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	if prefersLightTheme(req) {
+		t.Fatal("expected no preference without the cookie")
+	}
+	req.AddCookie(&http.Cookie{Name: themeCookie, Value: themeLight})
+	if !prefersLightTheme(req) {
+		t.Fatal("expected the cookie to select the light theme")
+	}
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxTitleSubject bounds the part of the tab title taken from the prompt.
+const maxTitleSubject = 40
+
+// tabTitle is the browser tab title for the review: the number of comments
+// while there are any, a check mark once the review is finished, and what
+// is being reviewed, taken from the first line of the prompt or else the
+// git branch and directory.
+//
+//	(3) Meatcheck - fix auth token refresh
+//	✓ (3) Meatcheck - main /src/app
+func tabTitle(model *ReviewModel) string {
+	if model == nil {
+		return "Meatcheck"
+	}
+	var b strings.Builder
+	if model.Completed {
+		b.WriteString("✓ ")
+	}
+	if n := len(model.Comments); n > 0 {
+		fmt.Fprintf(&b, "(%d) ", n)
+	}
+	b.WriteString("Meatcheck")
+	if subject := titleSubject(model); subject != "" {
+		b.WriteString(" - ")
+		b.WriteString(subject)
+	}
+	return b.String()
+}
+
+func titleSubject(model *ReviewModel) string {
+	if line, _, _ := strings.Cut(strings.TrimSpace(model.Prompt), "\n"); line != "" {
+		line = strings.TrimSpace(strings.TrimLeft(line, "#>*- "))
+		if r := []rune(line); len(r) > maxTitleSubject {
+			line = strings.TrimSpace(string(r[:maxTitleSubject])) + "…"
+		}
+		if line != "" {
+			return line
+		}
+	}
+	if model.Git == nil {
+		return ""
+	}
+	return strings.TrimSpace(model.Git.Branch + " " + model.Git.WorkDir)
+}
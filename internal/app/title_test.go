@@ -0,0 +1,46 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTabTitle(t *testing.T) {
+	model := buildGitModel(&GitContext{Branch: "main", WorkDir: "/src/app"})
+	if got := tabTitle(model.ReviewModel); got != "Meatcheck - main /src/app" {
+		t.Errorf("unexpected title %q", got)
+	}
+
+	model.Prompt = "## Fix auth token refresh\n\nFocus on expiry handling."
+	model.Comments = []Comment{{ID: 1}, {ID: 2}, {ID: 3}}
+	if got := tabTitle(model.ReviewModel); got != "(3) Meatcheck - Fix auth token refresh" {
+		t.Errorf("unexpected title %q", got)
+	}
+
+	model.Prompt = strings.Repeat("word ", 20)
+	model.Completed = true
+	if got := tabTitle(model.ReviewModel); got != "✓ (3) Meatcheck - word word word word word word word word…" {
+		t.Errorf("unexpected title %q", got)
+	}
+
+	if got := tabTitle(nil); got != "Meatcheck" {
+		t.Errorf("unexpected title %q", got)
+	}
+}
+
+func TestRenderTabTitleHook(t *testing.T) {
+	model := buildCommentModel()
+	html := renderReviewHTML(t, model)
+	for _, want := range []string{
+		"<title>(1) Meatcheck</title>",
+		`live-hook="tab-title" data-title="(1) Meatcheck" data-state="waiting"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in the page", want)
+		}
+	}
+	model.Completed = true
+	if html := renderReviewHTML(t, model); !strings.Contains(html, `data-state="finished"`) {
+		t.Error("expected the finished state once the review is completed")
+	}
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSelfSignedCert verifies that the generated certificate covers the
+// loopback names and the hosts the session is reachable on.
+func TestSelfSignedCert(t *testing.T) {
+	cert, err := selfSignedCert([]string{"0.0.0.0", "192.168.1.20", "devbox"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "::1", "192.168.1.20", "devbox"} {
+		if err := leaf.VerifyHostname(host); err != nil {
+			t.Errorf("%s: %v", host, err)
+		}
+	}
+	if err := leaf.VerifyHostname("0.0.0.0"); err == nil {
+		t.Error("expected the wildcard bind address to be left out")
+	}
+	if fp := certFingerprint(cert); len(fp) != 95 || strings.Count(fp, ":") != 31 {
+		t.Errorf("unexpected fingerprint %q", fp)
+	}
+}
+
+// TestTLSConfigFromFiles verifies that a given certificate and key are
+// served, and that a missing pair is reported.
+func TestTLSConfigFromFiles(t *testing.T) {
+	cert, err := selfSignedCert(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := tlsConfig(certFile, keyFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(requireToken("secret", "", tokenCookieName("443"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	srv.TLS = cfg
+	srv.StartTLS()
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(mustParseCert(t, cert.Certificate[0]))
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Get(srv.URL + "/?token=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d", resp.StatusCode)
+	}
+	if cookies := resp.Cookies(); len(cookies) != 1 || !cookies[0].Secure {
+		t.Fatalf("expected a secure token cookie over HTTPS, got %v", cookies)
+	}
+
+	if _, err := tlsConfig(filepath.Join(dir, "missing.pem"), keyFile, nil); err == nil || !strings.Contains(err.Error(), "load TLS certificate") {
+		t.Fatalf("expected a load error, got %v", err)
+	}
+}
+
+func mustParseCert(t *testing.T, der []byte) *x509.Certificate {
+	t.Helper()
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
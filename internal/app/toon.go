@@ -0,0 +1,138 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// toonTableHeader matches a top-level tabular array header such as
+// comments[2]{author,end_line,path}: as written by gotoon.
+var toonTableHeader = regexp.MustCompile(`^("(?:[^"\\]|\\.)*"|[A-Za-z_][\w.]*)\[(\d+)\]\{(.*)\}:$`)
+
+// readToonTables reads the top-level tabular arrays of a TOON document, the
+// shape meatcheck writes its comment and reply lists in, keyed by name. Other
+// top-level values are skipped. Field names keep only the part before any
+// comma, so headers written from json tags like "side,omitempty" read as
+// side.
+func readToonTables(text string) (map[string][]map[string]any, error) {
+	tables := make(map[string][]map[string]any)
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		m := toonTableHeader.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		name, err := toonString(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		count, _ := strconv.Atoi(m[2])
+		fields, err := splitToonRow(m[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		for j, f := range fields {
+			key, err := toonString(f)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			key, _, _ = strings.Cut(key, ",")
+			fields[j] = key
+		}
+		rows := make([]map[string]any, 0, count)
+		for n := 0; n < count; n++ {
+			i++
+			if i >= len(lines) || !strings.HasPrefix(lines[i], "  ") {
+				return nil, fmt.Errorf("%s: expected %d rows, found %d", name, count, n)
+			}
+			cells, err := splitToonRow(strings.TrimSpace(lines[i]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			if len(cells) != len(fields) {
+				return nil, fmt.Errorf("line %d: expected %d values, found %d", i+1, len(fields), len(cells))
+			}
+			row := make(map[string]any, len(fields))
+			for k, cell := range cells {
+				v, err := toonValue(cell)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", i+1, err)
+				}
+				row[fields[k]] = v
+			}
+			rows = append(rows, row)
+		}
+		tables[name] = rows
+	}
+	return tables, nil
+}
+
+// splitToonRow splits comma-delimited cells, keeping quoted cells intact.
+func splitToonRow(row string) ([]string, error) {
+	var cells []string
+	start, quoted := 0, false
+	for i := 0; i < len(row); i++ {
+		switch {
+		case quoted && row[i] == '\\':
+			i++
+		case row[i] == '"':
+			quoted = !quoted
+		case !quoted && row[i] == ',':
+			cells = append(cells, row[start:i])
+			start = i + 1
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated string")
+	}
+	return append(cells, row[start:]), nil
+}
+
+// toonValue decodes a primitive cell: a quoted string, null, a boolean, a
+// number or a bare string.
+func toonValue(cell string) (any, error) {
+	switch {
+	case strings.HasPrefix(cell, `"`):
+		return toonString(cell)
+	case cell == "null":
+		return nil, nil
+	case cell == "true", cell == "false":
+		return cell == "true", nil
+	}
+	if f, err := strconv.ParseFloat(cell, 64); err == nil {
+		return f, nil
+	}
+	return cell, nil
+}
+
+// toonString decodes a bare or quoted string.
+func toonString(s string) (string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, nil
+	}
+	if len(s) < 2 || !strings.HasSuffix(s, `"`) {
+		return "", fmt.Errorf("unterminated string %s", s)
+	}
+	var b strings.Builder
+	body := s[1 : len(s)-1]
+	for i := 0; i < len(body); i++ {
+		if body[i] != '\\' || i+1 == len(body) {
+			b.WriteByte(body[i])
+			continue
+		}
+		i++
+		switch body[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(body[i])
+		}
+	}
+	return b.String(), nil
+}
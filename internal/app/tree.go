@@ -16,7 +16,26 @@ func fileHasComments(path string, comments []Comment) bool {
 	return false
 }
 
+// commentedPaths returns the set of file paths that have at least one comment.
+func commentedPaths(comments []Comment) map[string]bool {
+	paths := make(map[string]bool, len(comments))
+	for _, c := range comments {
+		paths[c.Path] = true
+	}
+	return paths
+}
+
+// commentCounts returns the number of comments on each file path.
+func commentCounts(comments []Comment) map[string]int {
+	counts := make(map[string]int, len(comments))
+	for _, c := range comments {
+		counts[c.Path]++
+	}
+	return counts
+}
+
 func buildTree(files []File, selectedPath string, viewed map[string]bool, comments []Comment) []TreeItem {
+	commented := commentedPaths(comments)
 	root := &treeNode{Name: "", Path: "", IsDir: true, Children: map[string]*treeNode{}}
 	for i := range files {
 		pathSlash := files[i].PathSlash
@@ -55,7 +74,7 @@ func buildTree(files []File, selectedPath string, viewed map[string]bool, commen
 				if viewed != nil {
 					item.Viewed = viewed[n.File.Path]
 				}
-				item.HasComments = fileHasComments(n.File.Path, comments)
+				item.HasComments = commented[n.File.Path]
 			}
 			items = append(items, item)
 		}
@@ -80,6 +99,15 @@ func buildTree(files []File, selectedPath string, viewed map[string]bool, commen
 func buildGroupedTree(groups []Group, files []File, selectedPath string, viewed map[string]bool, comments []Comment) []TreeItem {
 	var items []TreeItem
 	grouped := make(map[string]bool)
+	commented := commentedPaths(comments)
+	bySlash := make(map[string]*File, 2*len(files))
+	for i := range files {
+		for _, k := range []string{files[i].PathSlash, files[i].Path} {
+			if _, ok := bySlash[k]; !ok {
+				bySlash[k] = &files[i]
+			}
+		}
+	}
 
 	for _, g := range groups {
 		// Determine if the selected path belongs to this group.
@@ -96,7 +124,7 @@ func buildGroupedTree(groups []Group, files []File, selectedPath string, viewed
 		// Add files within this group.
 		for _, gf := range g.Files {
 			grouped[gf] = true
-			file := findFileBySlash(files, gf)
+			file := bySlash[gf]
 			if file == nil {
 				continue
 			}
@@ -106,7 +134,7 @@ func buildGroupedTree(groups []Group, files []File, selectedPath string, viewed
 				Depth:       1,
 				Selected:    file.Path == selectedPath,
 				GroupName:   g.Name,
-				HasComments: fileHasComments(file.Path, comments),
+				HasComments: commented[file.Path],
 			}
 			if viewed != nil {
 				item.Viewed = viewed[file.Path]
@@ -147,7 +175,7 @@ func buildGroupedTree(groups []Group, files []File, selectedPath string, viewed
 				Depth:       1,
 				Selected:    f.Path == selectedPath,
 				GroupName:   "Other",
-				HasComments: fileHasComments(f.Path, comments),
+				HasComments: commented[f.Path],
 			}
 			if viewed != nil {
 				item.Viewed = viewed[f.Path]
@@ -159,6 +187,40 @@ func buildGroupedTree(groups []Group, files []File, selectedPath string, viewed
 	return items
 }
 
+// refreshTree updates selection, viewed and comment markers on the existing
+// tree in place. The tree structure only depends on the file set, so it is
+// built (and sorted) once; an empty tree, or one ordered by comment count,
+// falls back to a full rebuild.
+func refreshTree(view *ReviewView) {
+	if len(view.Tree) == 0 || view.TreeSort == TreeSortComments {
+		rebuildTree(view)
+		return
+	}
+	counts := commentCounts(view.Comments)
+	activeGroups := make(map[string]bool)
+	for i := range view.Tree {
+		item := &view.Tree[i]
+		if item.IsGroup || item.IsDir {
+			continue
+		}
+		item.Selected = item.Path == view.SelectedPath
+		item.Viewed = view.Viewed[item.Path]
+		item.HasComments = counts[item.Path] > 0
+		item.Comments = counts[item.Path]
+		item.Assignee = view.Assignments[item.Path]
+		item.Updated = view.UpdatedFiles[item.Path]
+		item.Collapsed = view.collapsedReason(item.Path)
+		if item.Selected && item.GroupName != "" {
+			activeGroups[item.GroupName] = true
+		}
+	}
+	for i := range view.Tree {
+		if view.Tree[i].IsGroup {
+			view.Tree[i].GroupActive = activeGroups[view.Tree[i].Name]
+		}
+	}
+}
+
 type treeNode struct {
 	Name     string
 	Path     string
@@ -174,15 +236,6 @@ func joinPath(dir, name string) string {
 	return dir + "/" + name
 }
 
-func hasFile(files []File, path string) bool {
-	for _, f := range files {
-		if f.Path == path {
-			return true
-		}
-	}
-	return false
-}
-
 func findFile(files []File, path string) *File {
 	for i := range files {
 		if files[i].Path == path {
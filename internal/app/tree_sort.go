@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+
+	"github.com/jfyne/live"
+)
+
+// TreeSort orders the files in the tree.
+type TreeSort string
+
+const (
+	// TreeSortPath nests files under their directories, by name.
+	TreeSortPath TreeSort = ""
+	// TreeSortStatus lists added, then modified, renamed and deleted files.
+	TreeSortStatus TreeSort = "status"
+	// TreeSortComments lists the files with the most comments first.
+	TreeSortComments TreeSort = "comments"
+)
+
+// parseTreeSort returns the TreeSort named s, falling back to TreeSortPath
+// for unknown names and for status outside diff mode.
+func parseTreeSort(s string, mode ViewMode) TreeSort {
+	switch TreeSort(s) {
+	case TreeSortStatus:
+		if mode == ModeDiff {
+			return TreeSortStatus
+		}
+	case TreeSortComments:
+		return TreeSortComments
+	}
+	return TreeSortPath
+}
+
+// statusRank orders diff statuses for TreeSortStatus.
+var statusRank = map[DiffFileStatus]int{
+	DiffAdded:    0,
+	DiffModified: 1,
+	DiffRenamed:  2,
+	DiffDeleted:  3,
+}
+
+// sortTree reorders a built tree by model.TreeSort. Files are listed by
+// their full path without directory rows, each run of files under a group
+// or commit sorted on its own.
+func sortTree(view *ReviewView, items []TreeItem) []TreeItem {
+	if view.TreeSort == TreeSortPath {
+		return items
+	}
+	out := make([]TreeItem, 0, len(items))
+	var run []TreeItem
+	flush := func() {
+		sort.SliceStable(run, func(i, j int) bool {
+			a, b := run[i], run[j]
+			switch view.TreeSort {
+			case TreeSortStatus:
+				if statusRank[a.Status] != statusRank[b.Status] {
+					return statusRank[a.Status] < statusRank[b.Status]
+				}
+			case TreeSortComments:
+				if a.Comments != b.Comments {
+					return a.Comments > b.Comments
+				}
+			}
+			return a.Name < b.Name
+		})
+		out = append(out, run...)
+		run = run[:0]
+	}
+	for _, item := range items {
+		if item.IsGroup || (len(run) > 0 && item.Commit != run[0].Commit) {
+			flush()
+		}
+		switch {
+		case item.IsGroup:
+			out = append(out, item)
+		case !item.IsDir:
+			item.Name = filepath.ToSlash(view.seriesPath(item.Path))
+			item.Depth = min(item.Depth, 1)
+			if item.GroupName == "" {
+				item.Depth = 0
+			}
+			run = append(run, item)
+		}
+	}
+	flush()
+	return out
+}
+
+// diffLineStats counts the lines file adds and removes.
+func diffLineStats(file *DiffFile) (added, removed int) {
+	if file.section != nil {
+		return file.section.added, file.section.removed
+	}
+	for _, h := range file.Hunks {
+		for _, dl := range h.Lines {
+			switch dl.Kind {
+			case DiffAdd:
+				added++
+			case DiffDel:
+				removed++
+			}
+		}
+	}
+	return added, removed
+}
+
+func registerTreeHandlers(h *live.Handler, rs *ReviewServer) {
+	// Like the diff format, the order is a preference shared by every viewer.
+	h.HandleEvent("set-tree-sort", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		view.TreeSort = parseTreeSort(p.String("sort"), view.Mode)
+		savePreference(func(p *Preferences) { p.TreeSort = view.TreeSort })
+		rebuildTree(view)
+		return view, nil
+	}))
+}
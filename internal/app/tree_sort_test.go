@@ -0,0 +1,84 @@
+package app
+
+import "testing"
+
+func TestSortTree(t *testing.T) {
+	files, err := parseUnifiedDiff(`--- a/b/mod.go
++++ b/b/mod.go
+@@ -1,2 +1,2 @@
+-x
++y
+ z
+--- /dev/null
++++ b/z/new.go
+@@ -0,0 +1,2 @@
++a
++b
+--- a/a/gone.go
++++ /dev/null
+@@ -1 +0,0 @@
+-c
+`, diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			DiffFiles: files,
+			Mode:      ModeDiff,
+			Comments: []Comment{
+				{Path: "a/gone.go"},
+				{Path: "a/gone.go"},
+				{Path: "b/mod.go"},
+			},
+		},
+	}
+	names := func() []string {
+		var out []string
+		for _, item := range model.Tree {
+			out = append(out, item.Name)
+		}
+		return out
+	}
+	tests := []struct {
+		sort TreeSort
+		want []string
+	}{
+		{TreeSortPath, []string{"a", "gone.go", "b", "mod.go", "z", "new.go"}},
+		{TreeSortStatus, []string{"z/new.go", "b/mod.go", "a/gone.go"}},
+		{TreeSortComments, []string{"a/gone.go", "b/mod.go", "z/new.go"}},
+	}
+	for _, tt := range tests {
+		model.TreeSort = tt.sort
+		rebuildTree(model)
+		got := names()
+		if len(got) != len(tt.want) {
+			t.Fatalf("sort %q: got %v, want %v", tt.sort, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("sort %q: got %v, want %v", tt.sort, got, tt.want)
+			}
+		}
+	}
+
+	item := model.Tree[0]
+	if item.Comments != 2 || item.Added != 0 || item.Removed != 1 {
+		t.Fatalf("expected 2 comments and -1 on a/gone.go, got %+v", item)
+	}
+	if item := model.Tree[1]; item.Added != 1 || item.Removed != 1 {
+		t.Fatalf("expected +1 -1 on b/mod.go, got %+v", item)
+	}
+}
+
+func TestParseTreeSort(t *testing.T) {
+	if got := parseTreeSort("status", ModeFile); got != TreeSortPath {
+		t.Fatalf("expected status to fall back to path outside diff mode, got %q", got)
+	}
+	if got := parseTreeSort("comments", ModeFile); got != TreeSortComments {
+		t.Fatalf("expected comments, got %q", got)
+	}
+	if got := parseTreeSort("bogus", ModeDiff); got != TreeSortPath {
+		t.Fatalf("expected an unknown sort to fall back to path, got %q", got)
+	}
+}
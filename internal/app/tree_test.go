@@ -378,3 +378,55 @@ func TestBuildGroupedTreeGroupActiveSetWhenSelectedBelongsToGroup(t *testing.T)
 		t.Errorf("API group: expected GroupActive=false when auth.go is selected, got true")
 	}
 }
+
+// TestRefreshTreeUpdatesStateInPlace verifies that refreshTree updates the
+// selection, viewed and comment markers without rebuilding the tree.
+//
+// Scenario: Selecting a file only updates item state on the existing tree
+func TestRefreshTreeUpdatesStateInPlace(t *testing.T) {
+	files := []File{
+		{Path: "a.go", PathSlash: "a.go"},
+		{Path: "b.go", PathSlash: "b.go"},
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files:     files,
+			Mode:      ModeFile,
+			Viewed:    map[string]bool{},
+			Groups:    []Group{{Name: "G1", Files: []string{"a.go"}}},
+			HasGroups: true,
+		},
+		SelectedPath: "a.go",
+	}
+	rebuildTree(model)
+	before := len(model.Tree)
+
+	model.SelectedPath = "b.go"
+	model.Viewed["a.go"] = true
+	model.Comments = []Comment{{ID: 1, Path: "b.go", StartLine: 1, EndLine: 1}}
+	refreshTree(model)
+
+	if len(model.Tree) != before {
+		t.Fatalf("tree size changed: got %d, want %d", len(model.Tree), before)
+	}
+	for _, item := range model.Tree {
+		switch {
+		case item.IsGroup && item.Name == "G1":
+			if item.GroupActive {
+				t.Error("expected G1 to be inactive")
+			}
+		case item.IsGroup && item.Name == "Other":
+			if !item.GroupActive {
+				t.Error("expected Other to be active")
+			}
+		case item.Path == "a.go":
+			if item.Selected || !item.Viewed {
+				t.Errorf("unexpected state for a.go: %+v", item)
+			}
+		case item.Path == "b.go":
+			if !item.Selected || !item.HasComments {
+				t.Errorf("unexpected state for b.go: %+v", item)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strconv"
+
+	"github.com/jfyne/live"
+)
+
+// newCommentUID returns a random version 4 UUID. Unlike the numeric ID,
+// which is only unique within a session, a comment keeps its UID across
+// rounds so downstream systems can track it.
+func newCommentUID() string {
+	var b [16]byte
+	// crypto/rand.Read never fails; it crashes the program instead.
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// shortUID is the prefix of uid shown in the UI.
+func shortUID(uid string) string {
+	if len(uid) > 8 {
+		return uid[:8]
+	}
+	return uid
+}
+
+func findCommentByUID(model *ReviewModel, uid string) *Comment {
+	if uid == "" {
+		return nil
+	}
+	for i := range model.Comments {
+		if model.Comments[i].UID == uid {
+			return &model.Comments[i]
+		}
+	}
+	return nil
+}
+
+// lookupComment finds a comment by its numeric ID or its UID.
+func lookupComment(model *ReviewModel, ref string) *Comment {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return findComment(model, id)
+	}
+	return findCommentByUID(model, ref)
+}
+
+// eventCommentID resolves the comment an event refers to. The UI sends the
+// comment's "uid"; a numeric "id" is still accepted.
+func eventCommentID(model *ReviewModel, p live.Params) int {
+	if uid := p.String("uid"); uid != "" {
+		if c := findCommentByUID(model, uid); c != nil {
+			return c.ID
+		}
+		return 0
+	}
+	return p.Int("id")
+}
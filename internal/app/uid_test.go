@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewCommentUID(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 100 {
+		uid := newCommentUID()
+		if !uuidPattern.MatchString(uid) {
+			t.Fatalf("%q is not a version 4 UUID", uid)
+		}
+		if seen[uid] {
+			t.Fatalf("duplicate UID %s", uid)
+		}
+		seen[uid] = true
+	}
+}
+
+// TestCommentUIDs verifies that comments get a UID when they are created and
+// that the UI events and the API can address comments by it.
+func TestCommentUIDs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	rebuildTree(model)
+	updateView(model)
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	s := live.NewSocket(ctx, engine, "alice")
+	s.Assign(model)
+
+	callEvent(t, engine, s, "select-line", map[string]string{"line": "1"})
+	callEvent(t, engine, s, "add-comment", map[string]string{"comment": "second"})
+	added := model.Comments[1]
+	if !uuidPattern.MatchString(added.UID) || added.UID == model.Comments[0].UID {
+		t.Fatalf("expected a fresh UID on the new comment, got %q", added.UID)
+	}
+
+	callEvent(t, engine, s, "edit-comment", map[string]string{"uid": added.UID, "comment": "second, edited"})
+	if model.Comments[1].Text != "second, edited" {
+		t.Fatalf("expected the edit to reach the comment by UID, got %q", model.Comments[1].Text)
+	}
+
+	srv := httptest.NewServer(apiHandler(rs, nil))
+	defer srv.Close()
+	resp, err := http.Post(srv.URL+"/api/comments/"+added.UID+"/replies", "application/json", strings.NewReader(`{"text":"ack"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("reply by UID: got status %d", resp.StatusCode)
+	}
+	if r := model.Replies[0]; r.CommentID != added.ID || r.CommentUID != added.UID {
+		t.Fatalf("expected the reply to reference both IDs, got %+v", r)
+	}
+	resp, err = http.Post(srv.URL+"/api/comments/no-such-uid/replies", "application/json", strings.NewReader(`{"text":"ack"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("reply to unknown UID: got status %d", resp.StatusCode)
+	}
+
+	callEvent(t, engine, s, "delete-comment", map[string]string{"uid": added.UID})
+	if len(model.Comments) != 1 || findCommentByUID(model.ReviewModel, added.UID) != nil {
+		t.Fatalf("expected the comment to be deleted by UID, got %+v", model.Comments)
+	}
+}
+
+// TestCarryOverKeepsUIDs verifies that comments keep their UID from round to
+// round, and that comments from output without UIDs are given new ones.
+func TestCarryOverKeepsUIDs(t *testing.T) {
+	model := buildCommentModel()
+	model.Comments = nil
+	carryOverComments(model.ReviewModel, &PreviousReview{
+		Comments: []Comment{
+			{ID: 1, UID: "round-1-uid", Path: "test.go", StartLine: 1, EndLine: 1, Text: "kept"},
+			{ID: 2, Path: "test.go", Text: "from older output"},
+			{ID: 3, UID: "round-1-uid", Path: "test.go", Text: "duplicate UID"},
+		},
+		Replies: []Reply{{ID: 1, CommentID: 1, Text: "fixed"}},
+	})
+	if model.Comments[0].UID != "round-1-uid" {
+		t.Fatalf("expected the UID to be kept, got %q", model.Comments[0].UID)
+	}
+	for _, c := range model.Comments[1:] {
+		if !uuidPattern.MatchString(c.UID) {
+			t.Fatalf("expected comment %d to get a new UID, got %q", c.ID, c.UID)
+		}
+	}
+	if model.Replies[0].CommentUID != "round-1-uid" {
+		t.Fatalf("expected the reply to point at the comment's UID, got %q", model.Replies[0].CommentUID)
+	}
+}
@@ -0,0 +1,75 @@
+package app
+
+import "fmt"
+
+// invalidComment describes a comment whose anchor does not point at real
+// lines in the reviewed content.
+type invalidComment struct {
+	ID     int    `json:"id"`
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// validateComments splits comments into those whose anchors resolve against
+// the reviewed files and those that don't. In file mode the range must lie
+// within the file; in diff mode both ends must be lines shown on the
+// comment's side of the diff.
+func validateComments(model *ReviewModel) ([]Comment, []invalidComment) {
+	valid := make([]Comment, 0, len(model.Comments))
+	var invalid []invalidComment
+	for _, c := range model.Comments {
+		if reason := commentAnchorProblem(model, c); reason != "" {
+			invalid = append(invalid, invalidComment{ID: c.ID, Path: c.Path, Reason: reason})
+			continue
+		}
+		valid = append(valid, c)
+	}
+	return valid, invalid
+}
+
+func commentAnchorProblem(model *ReviewModel, c Comment) string {
+	if c.isFileLevel() {
+		if model.lookupFile(c.Path) == nil && model.lookupDiffFile(c.Path) == nil {
+			return "file not under review"
+		}
+		return ""
+	}
+	if c.StartLine < 1 || c.EndLine < c.StartLine {
+		return fmt.Sprintf("invalid line range %d-%d", c.StartLine, c.EndLine)
+	}
+	if model.Mode == ModeDiff {
+		file := model.lookupDiffFile(c.Path)
+		if file == nil {
+			return "file not in diff"
+		}
+		exists := hasNewLine
+		if c.Side == "old" {
+			exists = hasOldLine
+		}
+		for _, line := range []int{c.StartLine, c.EndLine} {
+			if !exists(file, line) {
+				return fmt.Sprintf("line %d is not part of the diff", line)
+			}
+		}
+		return ""
+	}
+	file := model.lookupFile(c.Path)
+	if file == nil {
+		return "file not under review"
+	}
+	if err := ensureFileLoaded(model.loader, file); err != nil {
+		return err.Error()
+	}
+	if count := fileLineCount(file); c.EndLine > count {
+		return fmt.Sprintf("line %d is past the end of the file (%d lines)", c.EndLine, count)
+	}
+	return ""
+}
+
+// fileLineCount returns the number of lines in a loaded file.
+func fileLineCount(f *File) int {
+	if f.index != nil {
+		return f.index.lineCount()
+	}
+	return len(f.Lines)
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestValidateCommentsFileMode(t *testing.T) {
+	model := &ReviewModel{
+		Mode:  ModeFile,
+		Files: []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"one", "two", "three"}}},
+		Comments: []Comment{
+			{ID: 1, Path: "a.go", StartLine: 1, EndLine: 3},
+			{ID: 2, Path: "a.go", StartLine: 2, EndLine: 9},
+			{ID: 3, Path: "b.go", StartLine: 1, EndLine: 1},
+			{ID: 4, Path: "a.go", StartLine: 3, EndLine: 2},
+		},
+	}
+
+	valid, invalid := validateComments(model)
+	if len(valid) != 1 || valid[0].ID != 1 {
+		t.Fatalf("expected only comment 1 to be valid, got %+v", valid)
+	}
+	if len(invalid) != 3 {
+		t.Fatalf("expected 3 invalid comments, got %+v", invalid)
+	}
+	for _, ic := range invalid {
+		if ic.Reason == "" {
+			t.Errorf("expected a reason for comment %d", ic.ID)
+		}
+	}
+}
+
+func TestValidateCommentsDiffMode(t *testing.T) {
+	model := &ReviewModel{
+		Mode: ModeDiff,
+		DiffFiles: []DiffFile{{Path: "x.go", Hunks: []DiffHunk{{Lines: []DiffLine{
+			{Kind: DiffDel, OldLine: 4},
+			{Kind: DiffAdd, NewLine: 4},
+			{Kind: DiffContext, OldLine: 5, NewLine: 5},
+		}}}}},
+		Comments: []Comment{
+			{ID: 1, Path: "x.go", StartLine: 4, EndLine: 5},
+			{ID: 2, Path: "x.go", StartLine: 4, EndLine: 4, Side: "old"},
+			{ID: 3, Path: "x.go", StartLine: 10, EndLine: 10},
+			{ID: 4, Path: "x.go", StartLine: 6, EndLine: 6, Side: "old"},
+		},
+	}
+
+	valid, invalid := validateComments(model)
+	if len(valid) != 2 || valid[0].ID != 1 || valid[1].ID != 2 {
+		t.Fatalf("expected comments 1 and 2 to be valid, got %+v", valid)
+	}
+	if len(invalid) != 2 || invalid[0].ID != 3 || invalid[1].ID != 4 {
+		t.Fatalf("expected comments 3 and 4 to be invalid, got %+v", invalid)
+	}
+}
+
+// TestEmitReviewDropsInvalidComments verifies that comments with impossible
+// anchors are reported in metadata instead of the comment list.
+func TestEmitReviewDropsInvalidComments(t *testing.T) {
+	model := &ReviewModel{
+		Mode:  ModeFile,
+		Files: []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"one"}}},
+		Comments: []Comment{
+			{ID: 1, Path: "a.go", StartLine: 1, EndLine: 1, Text: "fine"},
+			{ID: 2, Path: "a.go", StartLine: 5, EndLine: 5, Text: "bogus"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := emitReview(&buf, model); err != nil {
+		t.Fatalf("emitReview: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "comments[1]") || strings.Contains(out, "bogus") {
+		t.Fatalf("expected invalid comment to be dropped, got:\n%s", out)
+	}
+	if !strings.Contains(out, "invalid_comments") {
+		t.Fatalf("expected invalid comment in metadata, got:\n%s", out)
+	}
+}
+
+// TestValidateFileLevelComments verifies that comments without a line range
+// are kept as file-level comments when their file is under review.
+func TestValidateFileLevelComments(t *testing.T) {
+	model := &ReviewModel{
+		Mode:  ModeFile,
+		Files: []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{}}},
+		Comments: []Comment{
+			{ID: 1, Path: "a.go", Text: "file note"},
+			{ID: 2, Path: "b.go", Text: "unknown file"},
+		},
+	}
+	valid, invalid := validateComments(model)
+	if len(valid) != 1 || valid[0].ID != 1 {
+		t.Fatalf("expected file-level comment to be valid, got %+v", valid)
+	}
+	if len(invalid) != 1 || invalid[0].ID != 2 {
+		t.Fatalf("expected comment on unknown file to be invalid, got %+v", invalid)
+	}
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jfyne/live"
+)
+
+// Verdict is a reviewer's overall conclusion about the change under review.
+type Verdict string
+
+const (
+	VerdictNone           Verdict = ""
+	VerdictApprove        Verdict = "approve"
+	VerdictComment        Verdict = "comment"
+	VerdictRequestChanges Verdict = "request-changes"
+)
+
+// ErrChangesRequested is returned by Run after the review has been written
+// when the aggregate verdict is VerdictRequestChanges, so callers can reflect
+// it in the exit code.
+var ErrChangesRequested = errors.New("changes requested")
+
+func validVerdict(v Verdict) bool {
+	switch v {
+	case VerdictApprove, VerdictComment, VerdictRequestChanges:
+		return true
+	}
+	return false
+}
+
+// aggregateVerdict combines per-reviewer verdicts: any request for changes
+// wins, the review is approved only when every verdict approves, and any
+// other mix is a plain comment. No verdicts yields VerdictNone.
+func aggregateVerdict(verdicts map[string]Verdict) Verdict {
+	if len(verdicts) == 0 {
+		return VerdictNone
+	}
+	result := VerdictApprove
+	for _, v := range verdicts {
+		switch v {
+		case VerdictRequestChanges:
+			return VerdictRequestChanges
+		case VerdictComment:
+			result = VerdictComment
+		}
+	}
+	return result
+}
+
+// setVerdict records reviewer's verdict, or clears it when v is VerdictNone.
+func setVerdict(model *ReviewModel, reviewer string, v Verdict) {
+	if v == VerdictNone {
+		delete(model.Verdicts, reviewer)
+	} else {
+		if model.Verdicts == nil {
+			model.Verdicts = make(map[string]Verdict)
+		}
+		model.Verdicts[reviewer] = v
+	}
+	model.Verdict = aggregateVerdict(model.Verdicts)
+}
+
+func registerVerdictHandlers(h *live.Handler, rs *ReviewServer) {
+	h.HandleEvent("set-verdict", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		reviewer := view.Reviewer
+		v := Verdict(p.String("verdict"))
+		if reviewer == "" || (v != VerdictNone && !validVerdict(v)) {
+			return view, nil
+		}
+		// Clicking the current verdict again withdraws it.
+		if view.Verdicts[reviewer] == v {
+			v = VerdictNone
+		}
+		setVerdict(view.ReviewModel, reviewer, v)
+		rs.markChanged()
+		return view, nil
+	}))
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jfyne/live"
+)
+
+func TestAggregateVerdict(t *testing.T) {
+	tests := []struct {
+		verdicts map[string]Verdict
+		want     Verdict
+	}{
+		{nil, VerdictNone},
+		{map[string]Verdict{"a": VerdictApprove, "b": VerdictApprove}, VerdictApprove},
+		{map[string]Verdict{"a": VerdictApprove, "b": VerdictComment}, VerdictComment},
+		{map[string]Verdict{"a": VerdictApprove, "b": VerdictRequestChanges, "c": VerdictComment}, VerdictRequestChanges},
+	}
+	for _, tt := range tests {
+		if got := aggregateVerdict(tt.verdicts); got != tt.want {
+			t.Errorf("aggregateVerdict(%v) = %q, want %q", tt.verdicts, got, tt.want)
+		}
+	}
+}
+
+// TestSetVerdictPerReviewer verifies that verdicts are recorded per
+// identified reviewer, that anonymous connections cannot vote and that the
+// aggregate ends up in the output metadata.
+func TestSetVerdictPerReviewer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := buildCommentModel()
+	rs := &ReviewServer{Model: model.ReviewModel, start: model, DoneCh: make(chan struct{})}
+	engine := live.NewHttpHandler(ctx, buildLiveHandler(rs))
+	alice := live.NewSocket(ctx, engine, "alice")
+	bob := live.NewSocket(ctx, engine, "bob")
+	anon := live.NewSocket(ctx, engine, "anon")
+	for _, s := range []*live.Socket{alice, bob, anon} {
+		s.Assign(rs.newView())
+	}
+	callEvent(t, engine, alice, live.EventParams, map[string]string{"reviewer": "alice"})
+	callEvent(t, engine, bob, live.EventParams, map[string]string{"reviewer": "bob"})
+
+	callEvent(t, engine, anon, "set-verdict", map[string]string{"verdict": "request-changes"})
+	callEvent(t, engine, alice, "set-verdict", map[string]string{"verdict": "approve"})
+	callEvent(t, engine, bob, "set-verdict", map[string]string{"verdict": "request-changes"})
+	if len(model.Verdicts) != 2 || model.Verdict != VerdictRequestChanges {
+		t.Fatalf("unexpected verdicts: %v (aggregate %q)", model.Verdicts, model.Verdict)
+	}
+
+	var buf bytes.Buffer
+	if err := emitReview(&buf, model.ReviewModel); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "verdict: request-changes") || !strings.Contains(out, "alice: approve") {
+		t.Fatalf("expected verdicts in metadata, got:\n%s", out)
+	}
+
+	// Choosing the same verdict again withdraws it.
+	callEvent(t, engine, bob, "set-verdict", map[string]string{"verdict": "request-changes"})
+	if model.Verdict != VerdictApprove {
+		t.Fatalf("expected approval once bob withdraws, got %q", model.Verdict)
+	}
+}
@@ -2,92 +2,352 @@ package app
 
 import (
 	"fmt"
+	"hash/fnv"
 	"html/template"
+	"io"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 )
 
-func updateView(model *ReviewModel) {
-	switch model.Mode {
+func updateView(view *ReviewView) {
+	renderComments(view.ReviewModel)
+	switch view.Mode {
 	case ModeDiff:
-		updateDiffView(model)
+		updateDiffView(view)
 	default:
-		updateFileView(model)
+		updateFileView(view)
 	}
+	view.FileComments = projectFileComments(view.SelectedPath, view.Comments, view.EditingCommentID)
+	view.CodeViewKey = codeViewKey(view)
 }
 
-func updateFileView(model *ReviewModel) {
-	selectedFile := findFile(model.Files, model.SelectedPath)
-	viewFile := ViewFile{Path: model.SelectedPath}
+// projectFileComments returns the file-level comments on path, i.e. those
+// not anchored to any line.
+func projectFileComments(path string, comments []Comment, editingID int) []ViewComment {
+	var out []ViewComment
+	for i := range comments {
+		c := &comments[i]
+		if c.Path != path || !c.isFileLevel() {
+			continue
+		}
+		out = append(out, ViewComment{Comment: *c, Rendered: c.renderedHTML(), Editing: c.ID == editingID})
+	}
+	return out
+}
+
+// codeViewKey derives the code view's element key from what it shows: the
+// selected path, a hash of its content and everything else that affects how
+// it is rendered. Identical views key identically, so the element is only
+// replaced when its content or layout actually changes. The content hash is
+// kept on the file rather than taken on every update. Extending a window
+// only appends rows, so its end is left out.
+func codeViewKey(view *ReviewView) string {
+	h := fnv.New64a()
+	path := view.SelectedPath
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%t\x00%t\x00%d\x00%d\x00%v\x00%t\x00%t\x00%s\x00",
+		view.Mode, path, view.DiffFormat, view.RenderFile,
+		view.MarkdownRenderByPath[path], view.TabWidth, view.WindowStart, view.Ranges[path],
+		view.HideWhitespace, view.ShowWhitespace, view.collapsedReason(path))
+	switch view.Mode {
+	case ModeDiff:
+		if f := view.lookupDiffFile(path); f != nil {
+			rendered := make([]int, 0, len(view.RenderedHunks[path]))
+			for i, ok := range view.RenderedHunks[path] {
+				if ok {
+					rendered = append(rendered, i)
+				}
+			}
+			sort.Ints(rendered)
+			fmt.Fprintf(h, "%s\x00%x\x00%v", f.Language, f.contentSum(), rendered)
+		}
+	default:
+		if f := view.lookupFile(path); f != nil {
+			fmt.Fprintf(h, "%s\x00%x", f.Language, f.contentSum())
+		}
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// contentSum returns the hash of the file's content recorded when it was
+// loaded, taking it now for files built with their lines in hand.
+func (f *File) contentSum() uint64 {
+	if f.sum == 0 && f.Lines != nil {
+		f.sum = sumLines(f.Lines)
+	}
+	return f.sum
+}
+
+// contentSum returns a hash of the file's hunks, taken the first time it is
+// asked for after they change.
+func (f *DiffFile) contentSum() uint64 {
+	if f.sum == 0 {
+		h := fnv.New64a()
+		for _, hunk := range f.Hunks {
+			fmt.Fprintf(h, "%s\n", hunkHeader(hunk))
+			for _, dl := range hunk.Lines {
+				fmt.Fprintf(h, "%s %s\n", dl.Kind, dl.Text)
+			}
+		}
+		f.sum = h.Sum64()
+	}
+	return f.sum
+}
+
+func sumLines(lines []string) uint64 {
+	h := fnv.New64a()
+	for _, line := range lines {
+		io.WriteString(h, line)
+		io.WriteString(h, "\n")
+	}
+	return h.Sum64()
+}
+
+// updateSelection refreshes only the Selected flags of the current view after
+// a selection change. Highlighting and comment projection are left as they
+// are, so line clicks don't rebuild the whole view.
+func updateSelection(view *ReviewView) {
+	if view.Mode != ModeDiff && view.ViewFile.Path != view.SelectedPath {
+		updateView(view)
+		return
+	}
+	start, end := view.SelectionStart, view.SelectionEnd
+	inRange := func(n int) bool {
+		return start > 0 && end > 0 && n > 0 && n >= start && n <= end
+	}
+	oldSide := view.SelectionSide == "old"
+
+	for i := range view.ViewFile.Lines {
+		view.ViewFile.Lines[i].Selected = inRange(view.ViewFile.Lines[i].Number)
+	}
+	for i := range view.ViewFile.MarkdownBlocks {
+		b := &view.ViewFile.MarkdownBlocks[i]
+		b.Selected = start > 0 && end > 0 && b.EndLine >= start && b.StartLine <= end
+	}
+	for hi := range view.ViewDiff.Hunks {
+		lines := view.ViewDiff.Hunks[hi].Lines
+		for li := range lines {
+			if oldSide {
+				lines[li].Selected = inRange(lines[li].OldLine)
+			} else {
+				lines[li].Selected = inRange(lines[li].NewLine)
+			}
+		}
+	}
+	for hi := range view.ViewDiffSplit {
+		rows := view.ViewDiffSplit[hi].Rows
+		for ri := range rows {
+			rows[ri].Left.Selected = oldSide && !rows[ri].Left.Empty && inRange(rows[ri].Left.Line)
+			rows[ri].Right.Selected = !oldSide && !rows[ri].Right.Empty && inRange(rows[ri].Right.Line)
+		}
+	}
+}
+
+func updateFileView(view *ReviewView) {
+	selectedFile := view.lookupFile(view.SelectedPath)
+	viewFile := ViewFile{Path: view.SelectedPath}
+	if reason := view.collapsedReason(view.SelectedPath); reason != "" && selectedFile != nil {
+		// Collapsed files are not read until loaded anyway.
+		viewFile.Language = selectedFile.Language
+		viewFile.Collapsed = reason
+		view.ViewFile = viewFile
+		view.SelectedLabel = formatSelectedLabel(view.SelectedPath, view.Ranges[view.SelectedPath])
+		return
+	}
+	if err := ensureFileLoaded(view.loader, selectedFile); err != nil {
+		view.Error = err.Error()
+		selectedFile = nil
+	}
+	if selectedFile != nil {
+		viewFile.LineEnding = selectedFile.LineEnding
+		viewFile.TotalLines = fileLineCount(selectedFile)
+		viewFile.Binary = selectedFile.Binary
+		viewFile.Language = selectedFile.Language
+		viewFile.ImageURL = imagePreviewURL(selectedFile.Path)
+		viewFile.Empty = viewFile.TotalLines == 0 && !selectedFile.Binary
+		ranges := view.Ranges[selectedFile.Path]
+		viewFile.OutOfRange = !viewFile.Empty && len(normalizeRanges(ranges)) > 0 &&
+			len(clampRanges(ranges, viewFile.TotalLines)) == 0
+	}
+	if selectedFile != nil && selectedFile.index != nil {
+		outOfRange := viewFile.OutOfRange
+		viewFile = buildWindowedViewFile(view, selectedFile)
+		viewFile.LineEnding = selectedFile.LineEnding
+		viewFile.Empty = viewFile.TotalLines == 0
+		viewFile.OutOfRange = outOfRange
+		viewFile.NoFinalNewline = showsMissingFinalNewline(selectedFile, viewFile.Lines)
+		viewFile.Language = selectedFile.Language
+		selectedFile = nil
+	}
 	if selectedFile != nil {
 		viewFile.MarkdownFile = isMarkdownPath(selectedFile.Path)
 		if viewFile.MarkdownFile {
-			if model.MarkdownRenderByPath == nil {
-				model.MarkdownRenderByPath = make(map[string]bool)
+			if view.MarkdownRenderByPath == nil {
+				view.MarkdownRenderByPath = make(map[string]bool)
 			}
-			rendered, ok := model.MarkdownRenderByPath[selectedFile.Path]
+			rendered, ok := view.MarkdownRenderByPath[selectedFile.Path]
 			if !ok {
 				rendered = true
-				model.MarkdownRenderByPath[selectedFile.Path] = true
+				view.MarkdownRenderByPath[selectedFile.Path] = true
 			}
 			viewFile.MarkdownRendered = rendered
 		}
 		if viewFile.MarkdownFile && viewFile.MarkdownRendered {
-			blocks := renderMarkdownBlocks(selectedFile.Path, strings.Join(selectedFile.Lines, "\n"))
+			blocks := renderDocumentBlocks(view.renderers(), selectedFile.Path, strings.Join(selectedFile.Lines, "\n"))
 			for i := range blocks {
-				blocks[i].Selected = model.SelectionStart > 0 && model.SelectionEnd > 0 &&
-					blocks[i].EndLine >= model.SelectionStart && blocks[i].StartLine <= model.SelectionEnd
+				blocks[i].Selected = view.SelectionStart > 0 && view.SelectionEnd > 0 &&
+					blocks[i].EndLine >= view.SelectionStart && blocks[i].StartLine <= view.SelectionEnd
 				blocks[i].Commented, blocks[i].Comments = projectBlockComments(
 					selectedFile.Path, blocks[i].StartLine, blocks[i].EndLine,
-					model.Comments, model.EditingCommentID,
+					view.Comments, view.EditingCommentID,
 				)
 			}
 			viewFile.MarkdownBlocks = blocks
-			model.ViewFile = viewFile
-			model.SelectedLabel = formatSelectedLabel(model.SelectedPath, model.Ranges[model.SelectedPath])
+			view.ViewFile = viewFile
+			view.SelectedLabel = formatSelectedLabel(view.SelectedPath, view.Ranges[view.SelectedPath])
 			return
 		}
-		var rendered []template.HTML
-		if model.RenderFile {
-			rendered = codeRenderer.RenderLines(selectedFile.Path, selectedFile.Lines)
+		if windowed(selectedFile) && len(normalizeRanges(view.Ranges[selectedFile.Path])) == 0 {
+			window := buildWindowedViewFile(view, selectedFile)
+			viewFile.Lines = window.Lines
+			viewFile.Windowed = true
+			viewFile.WindowStart, viewFile.WindowEnd = window.WindowStart, window.WindowEnd
+			viewFile.HasPrevWindow, viewFile.HasNextWindow = window.HasPrevWindow, window.HasNextWindow
+		} else {
+			var rendered []template.HTML
+			if view.RenderFile {
+				rendered = view.highlighter().fileLines(selectedFile.Path, selectedFile.Language, selectedFile.Lines)
+			}
+			viewFile.Lines = buildViewLinesWithRanges(selectedFile, view.Comments, view.SelectionStart, view.SelectionEnd, rendered, view.Ranges[selectedFile.Path], view.EditingCommentID)
 		}
-		viewFile.Lines = buildViewLinesWithRanges(selectedFile, model.Comments, model.SelectionStart, model.SelectionEnd, rendered, model.Ranges[selectedFile.Path], model.EditingCommentID)
+		viewFile.NoFinalNewline = showsMissingFinalNewline(selectedFile, viewFile.Lines)
 	}
-	model.ViewFile = viewFile
-	model.SelectedLabel = formatSelectedLabel(model.SelectedPath, model.Ranges[model.SelectedPath])
+	view.ViewFile = viewFile
+	view.SelectedLabel = formatSelectedLabel(view.SelectedPath, view.Ranges[view.SelectedPath])
 }
 
-func updateDiffView(model *ReviewModel) {
-	model.ViewDiff = ViewDiffFile{}
-	model.ViewDiffSplit = nil
+func updateDiffView(view *ReviewView) {
+	view.ViewDiff = ViewDiffFile{}
+	view.ViewDiffSplit = nil
 
-	diffFile := findDiffFile(model.DiffFiles, model.SelectedPath)
+	diffFile := view.lookupDiffFile(view.SelectedPath)
+	if reason := view.collapsedReason(view.SelectedPath); reason != "" && diffFile != nil {
+		// Collapsed files are not read until loaded anyway.
+		view.ViewDiff = ViewDiffFile{Path: diffFile.Path, Status: diffFile.Status, OldPath: diffFile.OldPath, Collapsed: reason}
+		view.ViewDiff.Commit = view.commitOf(diffFile.Path)
+		view.SelectedLabel = view.seriesPath(view.SelectedPath)
+		return
+	}
+	if err := ensureDiffLoaded(diffFile); err != nil {
+		view.Error = err.Error()
+		diffFile = nil
+	}
 	if diffFile != nil {
-		switch model.DiffFormat {
-		case DiffFormatSplit:
-			model.ViewDiffSplit = buildViewDiffSplit(diffFile, model.Comments, model.SelectionStart, model.SelectionEnd, model.RenderFile, model.EditingCommentID, model.SelectionSide)
-		default:
-			model.ViewDiff = buildViewDiff(diffFile, model.Comments, model.SelectionStart, model.SelectionEnd, model.RenderFile, model.EditingCommentID, model.SelectionSide)
+		view.ViewDiff.Path = diffFile.Path
+		view.ViewDiff.Language = diffFile.Language
+		view.ViewDiff.Status = diffFile.Status
+		view.ViewDiff.OldPath = diffFile.OldPath
+		view.ViewDiff.OldMode, view.ViewDiff.NewMode = diffFile.OldMode, diffFile.NewMode
+		view.ViewDiff.Binary = diffFile.Binary
+		view.ViewDiff.Warnings = diffFile.Warnings
+		view.ViewDiff.Commit = view.commitOf(diffFile.Path)
+		source := diffSource(diffFile)
+		var hl *highlighter
+		if view.RenderFile {
+			hl = view.highlighter()
+		}
+		for i, h := range diffFile.Hunks {
+			if view.HideWhitespace {
+				var changed bool
+				if h, changed = ignoreWhitespace(h); !changed {
+					view.ViewDiff.WhitespaceHunks++
+					continue
+				}
+			}
+			single := &DiffFile{OldPath: diffFile.OldPath, NewPath: diffFile.NewPath, Path: diffFile.Path, Binary: diffFile.Binary, Language: diffFile.Language, Hunks: []DiffHunk{h}}
+			deferred := !hunkVisible(view, diffFile, i)
+			var up, down bool
+			if source != nil {
+				from, to := hunkGapAbove(diffFile, i)
+				up = from <= to
+				if i == len(diffFile.Hunks)-1 {
+					from, to = hunkGapBelow(diffFile, source)
+					down = from <= to
+				}
+			}
+			switch view.DiffFormat {
+			case DiffFormatSplit:
+				vh := ViewDiffSplitHunk{Header: hunkHeader(h)}
+				if !deferred {
+					vh = buildViewDiffSplit(single, view.Comments, view.SelectionStart, view.SelectionEnd, hl, view.EditingCommentID, view.SelectionSide)[0]
+				}
+				vh.Index, vh.Deferred, vh.LineCount = i, deferred, len(h.Lines)
+				vh.ExpandUp, vh.ExpandDown = up, down
+				view.ViewDiffSplit = append(view.ViewDiffSplit, vh)
+			default:
+				vh := ViewDiffHunk{Header: hunkHeader(h)}
+				if !deferred {
+					vh = buildViewDiff(single, view.Comments, view.SelectionStart, view.SelectionEnd, hl, view.EditingCommentID, view.SelectionSide).Hunks[0]
+				}
+				vh.Index, vh.Deferred, vh.LineCount = i, deferred, len(h.Lines)
+				vh.ExpandUp, vh.ExpandDown = up, down
+				view.ViewDiff.Hunks = append(view.ViewDiff.Hunks, vh)
+			}
+		}
+	}
+	view.SelectedLabel = view.seriesPath(view.SelectedPath)
+}
+
+// hunkVisible reports whether hunk i of file should be built now. The first
+// diffHunkBudget hunks are always built; later ones are deferred until the
+// client scrolls them into view, unless they carry comments.
+func hunkVisible(view *ReviewView, file *DiffFile, i int) bool {
+	if i < diffHunkBudget || view.RenderedHunks[file.Path][i] {
+		return true
+	}
+	h := file.Hunks[i]
+	for _, c := range view.Comments {
+		if c.Path != file.Path {
+			continue
+		}
+		for _, dl := range h.Lines {
+			line := dl.NewLine
+			if c.Side == "old" {
+				line = dl.OldLine
+			}
+			if line > 0 && line >= c.StartLine && line <= c.EndLine {
+				return true
+			}
 		}
 	}
-	model.SelectedLabel = model.SelectedPath
+	return false
 }
 
-func buildViewDiffSplit(file *DiffFile, comments []Comment, start, end int, render bool, editingID int, selectionSide string) []ViewDiffSplitHunk {
+// revealHunk marks hunk i of the selected diff file as built.
+func revealHunk(view *ReviewView, i int) {
+	if view.RenderedHunks == nil {
+		view.RenderedHunks = make(map[string]map[int]bool)
+	}
+	if view.RenderedHunks[view.SelectedPath] == nil {
+		view.RenderedHunks[view.SelectedPath] = make(map[int]bool)
+	}
+	view.RenderedHunks[view.SelectedPath][i] = true
+}
+
+func hunkHeader(h DiffHunk) string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldCount, h.NewStart, h.NewCount)
+}
+
+func buildViewDiffSplit(file *DiffFile, comments []Comment, start, end int, hl *highlighter, editingID int, selectionSide string) []ViewDiffSplitHunk {
 	hunks := make([]ViewDiffSplitHunk, 0, len(file.Hunks))
-	for _, h := range file.Hunks {
-		hdr := fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldCount, h.NewStart, h.NewCount)
-		vh := ViewDiffSplitHunk{Header: hdr}
+	for hi, h := range file.Hunks {
+		vh := ViewDiffSplitHunk{Header: hunkHeader(h)}
 
 		// Render syntax highlighting for all lines in the hunk if requested.
 		var rendered []template.HTML
-		if render {
-			texts := make([]string, 0, len(h.Lines))
-			for _, dl := range h.Lines {
-				texts = append(texts, dl.Text)
-			}
-			rendered = codeRenderer.RenderLines(file.Path, texts)
+		if hl != nil {
+			rendered = hl.hunkLines(file, hi)
 		}
 
 		// Process lines: walk sequentially, grouping del/add blocks together.
@@ -219,15 +479,16 @@ func buildViewLinesWithRanges(file *File, comments []Comment, start, end int, re
 }
 
 func buildSingleViewLine(file *File, comments []Comment, start, end int, rendered []template.HTML, idx int, editingID int) ViewLine {
-	lineNum := idx + 1
-	raw := file.Lines[idx]
-	selected := start > 0 && end > 0 && lineNum >= start && lineNum <= end
-	commented, lineComments := projectLineComments(file.Path, lineNum, comments, editingID, "")
-
 	lineHTML := template.HTML("")
 	if len(rendered) > idx {
 		lineHTML = rendered[idx]
 	}
+	return buildViewLine(file.Path, idx+1, file.Lines[idx], lineHTML, comments, start, end, editingID)
+}
+
+func buildViewLine(path string, lineNum int, raw string, lineHTML template.HTML, comments []Comment, start, end int, editingID int) ViewLine {
+	selected := start > 0 && end > 0 && lineNum >= start && lineNum <= end
+	commented, lineComments := projectLineComments(path, lineNum, comments, editingID, "")
 	return ViewLine{
 		Number:    lineNum,
 		Text:      raw,
@@ -238,6 +499,66 @@ func buildSingleViewLine(file *File, comments []Comment, start, end int, rendere
 	}
 }
 
+// showsMissingFinalNewline reports whether the "no newline at end of file"
+// marker belongs after lines, i.e. the file lacks one and its last line is
+// the last one shown.
+func showsMissingFinalNewline(file *File, lines []ViewLine) bool {
+	if !file.NoFinalNewline || len(lines) == 0 {
+		return false
+	}
+	return lines[len(lines)-1].Number == fileLineCount(file)
+}
+
+// buildWindowedViewFile builds the visible window of a windowed file, reading
+// it from disk when the file is indexed. Markdown preview is not offered for
+// indexed files since it needs the whole document.
+func buildWindowedViewFile(view *ReviewView, file *File) ViewFile {
+	viewFile := ViewFile{
+		Path:       file.Path,
+		Windowed:   true,
+		TotalLines: fileLineCount(file),
+	}
+	ranges := view.Ranges[file.Path]
+	var whole []template.HTML
+	if view.RenderFile && file.index == nil {
+		// A file held in memory is highlighted as a whole, and cached, so
+		// strings and comments crossing the window's edges are lexed right.
+		whole = view.highlighter().fileLines(file.Path, file.Language, file.Lines)
+	}
+	for _, r := range windowRanges(viewFile.TotalLines, ranges, view.WindowStart, view.WindowLines) {
+		lines, err := readWindow(file, r.Start, r.End)
+		if err != nil {
+			view.Error = err.Error()
+			return viewFile
+		}
+		var rendered []template.HTML
+		switch {
+		case whole != nil:
+			if r.Start-1+len(lines) <= len(whole) {
+				rendered = whole[r.Start-1 : r.Start-1+len(lines)]
+			}
+		case view.RenderFile:
+			rendered = view.highlighter().windowLines(file, lines)
+		}
+		for i, raw := range lines {
+			lineHTML := template.HTML("")
+			if len(rendered) > i {
+				lineHTML = rendered[i]
+			}
+			viewFile.Lines = append(viewFile.Lines, buildViewLine(file.Path, r.Start+i, raw, lineHTML, view.Comments, view.SelectionStart, view.SelectionEnd, view.EditingCommentID))
+		}
+		if viewFile.WindowStart == 0 {
+			viewFile.WindowStart = r.Start
+		}
+		viewFile.WindowEnd = r.Start + len(lines) - 1
+	}
+	if len(normalizeRanges(ranges)) == 0 {
+		viewFile.HasPrevWindow = viewFile.WindowStart > 1
+		viewFile.HasNextWindow = viewFile.WindowEnd < viewFile.TotalLines
+	}
+	return viewFile
+}
+
 func buildViewLines(file *File, comments []Comment, start, end int, rendered []template.HTML, editingID int) []ViewLine {
 	lines := make([]ViewLine, 0, len(file.Lines))
 	for i := range file.Lines {
@@ -246,18 +567,13 @@ func buildViewLines(file *File, comments []Comment, start, end int, rendered []t
 	return lines
 }
 
-func buildViewDiff(file *DiffFile, comments []Comment, start, end int, render bool, editingID int, selectionSide string) ViewDiffFile {
-	view := ViewDiffFile{Path: file.Path}
-	for _, h := range file.Hunks {
-		hdr := fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldCount, h.NewStart, h.NewCount)
-		vh := ViewDiffHunk{Header: hdr}
+func buildViewDiff(file *DiffFile, comments []Comment, start, end int, hl *highlighter, editingID int, selectionSide string) ViewDiffFile {
+	view := ViewDiffFile{Path: file.Path, Language: file.Language}
+	for hi, h := range file.Hunks {
+		vh := ViewDiffHunk{Header: hunkHeader(h)}
 		var rendered []template.HTML
-		if render {
-			lines := make([]string, 0, len(h.Lines))
-			for _, dl := range h.Lines {
-				lines = append(lines, dl.Text)
-			}
-			rendered = codeRenderer.RenderLines(file.Path, lines)
+		if hl != nil {
+			rendered = hl.hunkLines(file, hi)
 		}
 		for i, dl := range h.Lines {
 			line := ViewDiffLine{
@@ -308,7 +624,8 @@ func buildViewDiff(file *DiffFile, comments []Comment, start, end int, render bo
 func projectLineComments(path string, lineNum int, comments []Comment, editingID int, side string) (bool, []ViewComment) {
 	commented := false
 	lineComments := make([]ViewComment, 0)
-	for _, c := range comments {
+	for i := range comments {
+		c := &comments[i]
 		if c.Path != path {
 			continue
 		}
@@ -327,8 +644,8 @@ func projectLineComments(path string, lineNum int, comments []Comment, editingID
 		}
 		if lineNum == c.StartLine {
 			lineComments = append(lineComments, ViewComment{
-				Comment:  c,
-				Rendered: renderMarkdown(c.Text),
+				Comment:  *c,
+				Rendered: c.renderedHTML(),
 				Editing:  c.ID == editingID,
 			})
 		}
@@ -339,7 +656,8 @@ func projectLineComments(path string, lineNum int, comments []Comment, editingID
 func projectBlockComments(path string, startLine, endLine int, comments []Comment, editingID int) (bool, []ViewComment) {
 	commented := false
 	blockComments := make([]ViewComment, 0)
-	for _, c := range comments {
+	for i := range comments {
+		c := &comments[i]
 		if c.Path != path {
 			continue
 		}
@@ -348,8 +666,8 @@ func projectBlockComments(path string, startLine, endLine int, comments []Commen
 		}
 		if c.StartLine >= startLine && c.StartLine <= endLine {
 			blockComments = append(blockComments, ViewComment{
-				Comment:  c,
-				Rendered: renderMarkdown(c.Text),
+				Comment:  *c,
+				Rendered: c.renderedHTML(),
 				Editing:  c.ID == editingID,
 			})
 		}
@@ -374,13 +692,13 @@ func findDiffFile(files []DiffFile, path string) *DiffFile {
 	return nil
 }
 
-func hasDiffFile(files []DiffFile, path string) bool {
-	return findDiffFile(files, path) != nil
+func diffLineExists(files []DiffFile, path string, line int) bool {
+	return hasNewLine(findDiffFile(files, path), line)
 }
 
-func diffLineExists(files []DiffFile, path string, line int) bool {
-	file := findDiffFile(files, path)
-	if file == nil {
+// hasNewLine reports whether line is an add or context line in file.
+func hasNewLine(file *DiffFile, line int) bool {
+	if file == nil || ensureDiffLoaded(file) != nil {
 		return false
 	}
 	for _, h := range file.Hunks {
@@ -430,6 +748,20 @@ func normalizeRanges(ranges []LineRange) []LineRange {
 	return merged
 }
 
+// clampRanges normalizes ranges and limits them to lines 1..count, dropping
+// any that start past the end of the file.
+func clampRanges(ranges []LineRange, count int) []LineRange {
+	var out []LineRange
+	for _, r := range normalizeRanges(ranges) {
+		if r.Start > count {
+			continue
+		}
+		r.End = min(r.End, count)
+		out = append(out, r)
+	}
+	return out
+}
+
 func formatSelectedLabel(path string, ranges []LineRange) string {
 	if len(ranges) == 0 {
 		return path
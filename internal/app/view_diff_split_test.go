@@ -22,7 +22,7 @@ func TestBuildViewDiffSplitContextLines(t *testing.T) {
 		},
 	}}}
 
-	hunks := buildViewDiffSplit(df, nil, 0, 0, false, 0, "")
+	hunks := buildViewDiffSplit(df, nil, 0, 0, nil, 0, "")
 
 	if len(hunks) != 1 {
 		t.Fatalf("expected 1 hunk, got %d", len(hunks))
@@ -95,7 +95,7 @@ func TestBuildViewDiffSplitDelAddPairing(t *testing.T) {
 		},
 	}}}
 
-	hunks := buildViewDiffSplit(df, nil, 0, 0, false, 0, "")
+	hunks := buildViewDiffSplit(df, nil, 0, 0, nil, 0, "")
 
 	if len(hunks) != 1 {
 		t.Fatalf("expected 1 hunk, got %d", len(hunks))
@@ -191,7 +191,7 @@ func TestBuildViewDiffSplitMoreDelsThanAdds(t *testing.T) {
 		},
 	}}}
 
-	hunks := buildViewDiffSplit(df, nil, 0, 0, false, 0, "")
+	hunks := buildViewDiffSplit(df, nil, 0, 0, nil, 0, "")
 
 	if len(hunks) != 1 {
 		t.Fatalf("expected 1 hunk, got %d", len(hunks))
@@ -271,7 +271,7 @@ func TestBuildViewDiffSplitSelection(t *testing.T) {
 	}}}
 
 	// selectionSide="old", range [5,5]: only the del line (OldLine=5) should be selected.
-	hunks := buildViewDiffSplit(df, nil, 5, 5, false, 0, "old")
+	hunks := buildViewDiffSplit(df, nil, 5, 5, nil, 0, "old")
 
 	if len(hunks) != 1 {
 		t.Fatalf("expected 1 hunk, got %d", len(hunks))
@@ -312,7 +312,7 @@ func TestBuildViewDiffSplitComments(t *testing.T) {
 	newComment := Comment{ID: 11, Path: "cmt.go", StartLine: 4, EndLine: 4, Text: "new comment", Side: ""}
 	comments := []Comment{oldComment, newComment}
 
-	hunks := buildViewDiffSplit(df, comments, 0, 0, false, 0, "")
+	hunks := buildViewDiffSplit(df, comments, 0, 0, nil, 0, "")
 
 	if len(hunks) != 1 {
 		t.Fatalf("expected 1 hunk, got %d", len(hunks))
@@ -362,7 +362,7 @@ func TestBuildViewDiffSplitHunkHeader(t *testing.T) {
 		},
 	}}}
 
-	hunks := buildViewDiffSplit(df, nil, 0, 0, false, 0, "")
+	hunks := buildViewDiffSplit(df, nil, 0, 0, nil, 0, "")
 
 	if len(hunks) != 1 {
 		t.Fatalf("expected 1 hunk, got %d", len(hunks))
@@ -388,7 +388,7 @@ func TestBuildViewDiffSplitIntraLineHTML(t *testing.T) {
 		},
 	}}}
 
-	hunks := buildViewDiffSplit(df, nil, 0, 0, false, 0, "")
+	hunks := buildViewDiffSplit(df, nil, 0, 0, nil, 0, "")
 
 	if len(hunks) != 1 {
 		t.Fatalf("expected 1 hunk, got %d", len(hunks))
@@ -428,7 +428,7 @@ func TestBuildViewDiffSplitIntraLineNotSetForUnpaired(t *testing.T) {
 		},
 	}}}
 
-	hunks := buildViewDiffSplit(df, nil, 0, 0, false, 0, "")
+	hunks := buildViewDiffSplit(df, nil, 0, 0, nil, 0, "")
 
 	rows := hunks[0].Rows
 	if len(rows) != 2 {
@@ -458,7 +458,7 @@ func TestBuildViewDiffSplitIntraLineContextLines(t *testing.T) {
 		},
 	}}}
 
-	hunks := buildViewDiffSplit(df, nil, 0, 0, false, 0, "")
+	hunks := buildViewDiffSplit(df, nil, 0, 0, nil, 0, "")
 
 	rows := hunks[0].Rows
 	if strings.Contains(string(rows[0].Left.HTML), "intra-") {
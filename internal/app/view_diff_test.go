@@ -17,7 +17,7 @@ func TestBuildViewDiffCommentsNewLinesOnly(t *testing.T) {
 		},
 	}}}
 	comments := []Comment{{ID: 1, Path: "x.go", StartLine: 1, EndLine: 1, Text: "hi"}}
-	view := buildViewDiff(df, comments, 1, 1, false, 0, "")
+	view := buildViewDiff(df, comments, 1, 1, nil, 0, "")
 	if len(view.Hunks) != 1 {
 		t.Fatalf("expected 1 hunk")
 	}
@@ -53,7 +53,7 @@ func TestBuildViewDiffOldLineSelection(t *testing.T) {
 	}}}
 
 	// selectionSide="old", select range [5,5]: only the del line (OldLine==5) should be selected.
-	view := buildViewDiff(df, nil, 5, 5, false, 0, "old")
+	view := buildViewDiff(df, nil, 5, 5, nil, 0, "old")
 
 	if len(view.Hunks) != 1 {
 		t.Fatalf("expected 1 hunk, got %d", len(view.Hunks))
@@ -104,7 +104,7 @@ func TestBuildViewDiffOldLineComments(t *testing.T) {
 	newSideComment := Comment{ID: 11, Path: "g.go", StartLine: 4, EndLine: 4, Text: "new comment", Side: ""}
 	comments := []Comment{oldSideComment, newSideComment}
 
-	view := buildViewDiff(df, comments, 0, 0, false, 0, "")
+	view := buildViewDiff(df, comments, 0, 0, nil, 0, "")
 
 	if len(view.Hunks) != 1 {
 		t.Fatalf("expected 1 hunk, got %d", len(view.Hunks))
@@ -161,7 +161,7 @@ func TestBuildViewDiffDeletedLinesSelectable(t *testing.T) {
 	}}}
 
 	// First call: selectionSide="" with no selection range — line must appear in output.
-	view := buildViewDiff(df, nil, 0, 0, false, 0, "")
+	view := buildViewDiff(df, nil, 0, 0, nil, 0, "")
 
 	if len(view.Hunks) != 1 {
 		t.Fatalf("case 1: expected 1 hunk, got %d", len(view.Hunks))
@@ -182,7 +182,7 @@ func TestBuildViewDiffDeletedLinesSelectable(t *testing.T) {
 	}
 
 	// Second call: selectionSide="old", range covers OldLine=10 — del line must be selected.
-	view2 := buildViewDiff(df, nil, 10, 10, false, 0, "old")
+	view2 := buildViewDiff(df, nil, 10, 10, nil, 0, "old")
 
 	if len(view2.Hunks) != 1 {
 		t.Fatalf("case 2: expected 1 hunk, got %d", len(view2.Hunks))
@@ -210,7 +210,7 @@ func TestBuildViewDiffIntraLineUnified(t *testing.T) {
 		},
 	}}}
 
-	view := buildViewDiff(df, nil, 0, 0, false, 0, "")
+	view := buildViewDiff(df, nil, 0, 0, nil, 0, "")
 
 	lines := view.Hunks[0].Lines
 	if len(lines) != 2 {
@@ -239,7 +239,7 @@ func TestBuildViewDiffIntraLineUnifiedNonAdjacent(t *testing.T) {
 		},
 	}}}
 
-	view := buildViewDiff(df, nil, 0, 0, false, 0, "")
+	view := buildViewDiff(df, nil, 0, 0, nil, 0, "")
 
 	lines := view.Hunks[0].Lines
 	if len(lines) != 3 {
@@ -257,3 +257,129 @@ func TestBuildViewDiffIntraLineUnifiedNonAdjacent(t *testing.T) {
 		t.Errorf("add line should have intra-add, got: %s", lines[2].HTML)
 	}
 }
+
+// TestUpdateSelectionMatchesFullRebuild verifies that the partial selection
+// update produces the same Selected flags as a full view rebuild, for both
+// diff formats and both selection sides.
+func TestUpdateSelectionMatchesFullRebuild(t *testing.T) {
+	df := DiffFile{Path: "x.go", Hunks: []DiffHunk{{
+		OldStart: 1, OldCount: 3, NewStart: 1, NewCount: 3,
+		Lines: []DiffLine{
+			{Kind: DiffContext, OldLine: 1, NewLine: 1, Text: "a"},
+			{Kind: DiffDel, OldLine: 2, Text: "b"},
+			{Kind: DiffAdd, NewLine: 2, Text: "B"},
+			{Kind: DiffContext, OldLine: 3, NewLine: 3, Text: "c"},
+		},
+	}}}
+	for _, format := range []DiffFormat{DiffFormatUnified, DiffFormatSplit} {
+		for _, side := range []string{"", "old"} {
+			model := &ReviewView{ReviewModel: &ReviewModel{DiffFiles: []DiffFile{df}, Mode: ModeDiff}, SelectedPath: "x.go", DiffFormat: format}
+			updateView(model)
+
+			model.SelectionStart, model.SelectionEnd, model.SelectionSide = 2, 3, side
+			updateSelection(model)
+			partialUnified, partialSplit := model.ViewDiff, model.ViewDiffSplit
+
+			updateView(model)
+			for hi, h := range model.ViewDiff.Hunks {
+				for li, l := range h.Lines {
+					if got := partialUnified.Hunks[hi].Lines[li].Selected; got != l.Selected {
+						t.Errorf("%s/%q unified line %d: got Selected=%v, want %v", format, side, li, got, l.Selected)
+					}
+				}
+			}
+			for hi, h := range model.ViewDiffSplit {
+				for ri, r := range h.Rows {
+					got := partialSplit[hi].Rows[ri]
+					if got.Left.Selected != r.Left.Selected || got.Right.Selected != r.Right.Selected {
+						t.Errorf("%s/%q split row %d: got %v/%v, want %v/%v", format, side, ri, got.Left.Selected, got.Right.Selected, r.Left.Selected, r.Right.Selected)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestUpdateSelectionFileMode verifies that file-mode lines pick up the new
+// selection without a rebuild.
+func TestUpdateSelectionFileMode(t *testing.T) {
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files: []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"1", "2", "3"}}},
+			Mode:  ModeFile,
+		},
+		SelectedPath: "a.go",
+	}
+	updateView(model)
+	model.SelectionStart, model.SelectionEnd = 2, 3
+	updateSelection(model)
+
+	var selected []int
+	for _, l := range model.ViewFile.Lines {
+		if l.Selected {
+			selected = append(selected, l.Number)
+		}
+	}
+	if len(selected) != 2 || selected[0] != 2 || selected[1] != 3 {
+		t.Fatalf("expected lines 2-3 selected, got %v", selected)
+	}
+}
+
+// manyHunkDiff returns a diff file with n single-line hunks.
+func manyHunkDiff(n int) DiffFile {
+	df := DiffFile{Path: "big.go"}
+	for i := range n {
+		line := i*10 + 1
+		df.Hunks = append(df.Hunks, DiffHunk{
+			OldStart: line, OldCount: 1, NewStart: line, NewCount: 1,
+			Lines: []DiffLine{{Kind: DiffAdd, NewLine: line, Text: "x"}},
+		})
+	}
+	return df
+}
+
+// TestUpdateDiffViewDefersHunksBeyondBudget verifies that hunks past the
+// eager budget are emitted as placeholders until revealed, and that hunks
+// carrying comments are always built.
+func TestUpdateDiffViewDefersHunksBeyondBudget(t *testing.T) {
+	for _, format := range []DiffFormat{DiffFormatUnified, DiffFormatSplit} {
+		model := &ReviewView{
+			ReviewModel: &ReviewModel{
+				DiffFiles: []DiffFile{manyHunkDiff(diffHunkBudget + 3)},
+				Mode:      ModeDiff,
+				Comments:  []Comment{{ID: 1, Path: "big.go", StartLine: (diffHunkBudget+2)*10 + 1, EndLine: (diffHunkBudget+2)*10 + 1}},
+			},
+			SelectedPath: "big.go",
+			DiffFormat:   format,
+		}
+		updateView(model)
+
+		deferred := func() []int {
+			var out []int
+			if format == DiffFormatSplit {
+				for _, h := range model.ViewDiffSplit {
+					if h.Deferred {
+						out = append(out, h.Index)
+					}
+				}
+			} else {
+				for _, h := range model.ViewDiff.Hunks {
+					if h.Deferred {
+						out = append(out, h.Index)
+					}
+				}
+			}
+			return out
+		}
+		got := deferred()
+		if len(got) != 2 || got[0] != diffHunkBudget || got[1] != diffHunkBudget+1 {
+			t.Fatalf("%s: expected hunks %d and %d deferred, got %v", format, diffHunkBudget, diffHunkBudget+1, got)
+		}
+
+		revealHunk(model, diffHunkBudget)
+		updateView(model)
+		if got := deferred(); len(got) != 1 || got[0] != diffHunkBudget+1 {
+			t.Fatalf("%s: expected only hunk %d deferred after reveal, got %v", format, diffHunkBudget+1, got)
+		}
+	}
+}
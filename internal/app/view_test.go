@@ -0,0 +1,92 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCodeViewKeyDeterministic verifies that the code view key depends only on
+// what is shown: identical views share a key, while different content or
+// view options produce a new one.
+func TestCodeViewKeyDeterministic(t *testing.T) {
+	newModel := func(lines ...string) *ReviewView {
+		return &ReviewView{
+			ReviewModel: &ReviewModel{
+				Files: []File{{Path: "a.go", PathSlash: "a.go", Lines: lines}},
+				Mode:  ModeFile,
+			},
+			SelectedPath:         "a.go",
+			MarkdownRenderByPath: map[string]bool{},
+		}
+	}
+
+	a := newModel("package a")
+	b := newModel("package a")
+	updateView(a)
+	updateView(b)
+	if a.CodeViewKey == "" || a.CodeViewKey != b.CodeViewKey {
+		t.Fatalf("expected identical views to share a key, got %q and %q", a.CodeViewKey, b.CodeViewKey)
+	}
+
+	c := newModel("package c")
+	updateView(c)
+	if c.CodeViewKey == a.CodeViewKey {
+		t.Fatal("expected different content to change the key")
+	}
+
+	key := a.CodeViewKey
+	a.RenderFile = true
+	updateView(a)
+	if a.CodeViewKey == key {
+		t.Fatal("expected a view option change to change the key")
+	}
+	a.Comments = append(a.Comments, Comment{ID: 1, Path: "a.go", StartLine: 1, EndLine: 1, Text: "hi"})
+	rendered := a.CodeViewKey
+	updateView(a)
+	if a.CodeViewKey != rendered {
+		t.Fatal("expected comments not to change the key")
+	}
+	a.Files[0].Language = "Go Text Template"
+	updateView(a)
+	if a.CodeViewKey == rendered {
+		t.Fatal("expected a language change to change the key")
+	}
+}
+
+// TestCodeViewKeyFollowsDiffChanges verifies that expanding context and
+// building a deferred hunk both give the diff view a new key.
+func TestCodeViewKeyFollowsDiffChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	var source []string
+	for i := 1; i <= 40; i++ {
+		source = append(source, fmt.Sprintf("line %d", i))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(source, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := parseUnifiedDiff("--- a/"+path+"\n+++ b/"+path+"\n@@ -20,1 +20,1 @@\n-old\n+line 20\n", diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := &ReviewView{ReviewModel: &ReviewModel{Mode: ModeDiff, DiffFiles: files}, SelectedPath: files[0].Path}
+	updateView(view)
+	key := view.CodeViewKey
+
+	if !expandHunk(view, &view.DiffFiles[0], 0, false) {
+		t.Fatal("expected context to be revealed above the hunk")
+	}
+	updateView(view)
+	if view.CodeViewKey == key {
+		t.Fatal("expected expanded context to change the key")
+	}
+	key = view.CodeViewKey
+	revealHunk(view, diffHunkBudget)
+	updateView(view)
+	if view.CodeViewKey == key {
+		t.Fatal("expected a newly built hunk to change the key")
+	}
+}
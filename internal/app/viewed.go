@@ -3,37 +3,37 @@ package app
 // nextUnviewedFile returns the path of the next unviewed file starting from
 // the file after SelectedPath, wrapping around. Returns "" if all files are
 // viewed.
-func nextUnviewedFile(model *ReviewModel) string {
+func nextUnviewedFile(view *ReviewView) string {
 	var paths []string
 
-	if model.HasGroups {
+	if view.HasGroups {
 		grouped := make(map[string]bool)
-		for _, g := range model.Groups {
+		for _, g := range view.Groups {
 			paths = append(paths, g.Files...)
 			for _, f := range g.Files {
 				grouped[f] = true
 			}
 		}
 		// Include ungrouped files ("Other" group) at the end.
-		if model.Mode == ModeDiff {
-			for _, df := range model.DiffFiles {
+		if view.Mode == ModeDiff {
+			for _, df := range view.DiffFiles {
 				if !grouped[df.Path] {
 					paths = append(paths, df.Path)
 				}
 			}
 		} else {
-			for _, f := range model.Files {
+			for _, f := range view.Files {
 				if !grouped[f.Path] && !grouped[f.PathSlash] {
 					paths = append(paths, f.Path)
 				}
 			}
 		}
-	} else if model.Mode == ModeDiff {
-		for _, df := range model.DiffFiles {
+	} else if view.Mode == ModeDiff {
+		for _, df := range view.DiffFiles {
 			paths = append(paths, df.Path)
 		}
 	} else {
-		for _, f := range model.Files {
+		for _, f := range view.Files {
 			paths = append(paths, f.Path)
 		}
 	}
@@ -45,7 +45,7 @@ func nextUnviewedFile(model *ReviewModel) string {
 	// Find current index.
 	currentIdx := -1
 	for i, p := range paths {
-		if p == model.SelectedPath {
+		if p == view.SelectedPath {
 			currentIdx = i
 			break
 		}
@@ -63,7 +63,7 @@ func nextUnviewedFile(model *ReviewModel) string {
 			}
 		}
 		p := paths[idx]
-		if model.Viewed == nil || !model.Viewed[p] {
+		if view.Viewed == nil || !view.Viewed[p] {
 			return p
 		}
 	}
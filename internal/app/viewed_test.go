@@ -8,17 +8,19 @@ import "testing"
 //
 // Scenario: Next unviewed file found (file mode) — current is first of 3, first is viewed, returns second
 func TestNextUnviewedFileUngroupedFileMode(t *testing.T) {
-	model := &ReviewModel{
-		Mode: ModeFile,
-		Files: []File{
-			{Path: "a.go"},
-			{Path: "b.go"},
-			{Path: "c.go"},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode: ModeFile,
+			Files: []File{
+				{Path: "a.go"},
+				{Path: "b.go"},
+				{Path: "c.go"},
+			},
+			Viewed: map[string]bool{
+				"a.go": true,
+			},
 		},
 		SelectedPath: "a.go",
-		Viewed: map[string]bool{
-			"a.go": true,
-		},
 	}
 
 	got := nextUnviewedFile(model)
@@ -33,18 +35,20 @@ func TestNextUnviewedFileUngroupedFileMode(t *testing.T) {
 //
 // Scenario: Wrap-around — current is last, next unviewed is first
 func TestNextUnviewedFileUngroupedWrapAround(t *testing.T) {
-	model := &ReviewModel{
-		Mode: ModeFile,
-		Files: []File{
-			{Path: "a.go"},
-			{Path: "b.go"},
-			{Path: "c.go"},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode: ModeFile,
+			Files: []File{
+				{Path: "a.go"},
+				{Path: "b.go"},
+				{Path: "c.go"},
+			},
+			Viewed: map[string]bool{
+				"b.go": true,
+				"c.go": true,
+			},
 		},
 		SelectedPath: "c.go",
-		Viewed: map[string]bool{
-			"b.go": true,
-			"c.go": true,
-		},
 	}
 
 	got := nextUnviewedFile(model)
@@ -58,19 +62,21 @@ func TestNextUnviewedFileUngroupedWrapAround(t *testing.T) {
 //
 // Scenario: All viewed returns "" — all files viewed, returns empty string
 func TestNextUnviewedFileUngroupedAllViewed(t *testing.T) {
-	model := &ReviewModel{
-		Mode: ModeFile,
-		Files: []File{
-			{Path: "a.go"},
-			{Path: "b.go"},
-			{Path: "c.go"},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode: ModeFile,
+			Files: []File{
+				{Path: "a.go"},
+				{Path: "b.go"},
+				{Path: "c.go"},
+			},
+			Viewed: map[string]bool{
+				"a.go": true,
+				"b.go": true,
+				"c.go": true,
+			},
 		},
 		SelectedPath: "a.go",
-		Viewed: map[string]bool{
-			"a.go": true,
-			"b.go": true,
-			"c.go": true,
-		},
 	}
 
 	got := nextUnviewedFile(model)
@@ -84,17 +90,19 @@ func TestNextUnviewedFileUngroupedAllViewed(t *testing.T) {
 //
 // Scenario: Diff mode — same as file mode but using DiffFiles
 func TestNextUnviewedFileDiffMode(t *testing.T) {
-	model := &ReviewModel{
-		Mode: ModeDiff,
-		DiffFiles: []DiffFile{
-			{Path: "x.go"},
-			{Path: "y.go"},
-			{Path: "z.go"},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode: ModeDiff,
+			DiffFiles: []DiffFile{
+				{Path: "x.go"},
+				{Path: "y.go"},
+				{Path: "z.go"},
+			},
+			Viewed: map[string]bool{
+				"x.go": true,
+			},
 		},
 		SelectedPath: "x.go",
-		Viewed: map[string]bool{
-			"x.go": true,
-		},
 	}
 
 	got := nextUnviewedFile(model)
@@ -109,16 +117,18 @@ func TestNextUnviewedFileDiffMode(t *testing.T) {
 //
 // Scenario: Within-group advance — next unviewed in same group
 func TestNextUnviewedFileGroupedWithinGroup(t *testing.T) {
-	model := &ReviewModel{
-		HasGroups: true,
-		Groups: []Group{
-			{Name: "backend", Files: []string{"a.go", "b.go", "c.go"}},
-			{Name: "frontend", Files: []string{"d.js", "e.js"}},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			HasGroups: true,
+			Groups: []Group{
+				{Name: "backend", Files: []string{"a.go", "b.go", "c.go"}},
+				{Name: "frontend", Files: []string{"d.js", "e.js"}},
+			},
+			Viewed: map[string]bool{
+				"a.go": true,
+			},
 		},
 		SelectedPath: "a.go",
-		Viewed: map[string]bool{
-			"a.go": true,
-		},
 	}
 
 	got := nextUnviewedFile(model)
@@ -133,17 +143,19 @@ func TestNextUnviewedFileGroupedWithinGroup(t *testing.T) {
 //
 // Scenario: Cross-group advance — last file in group A viewed, advances to first unviewed in group B
 func TestNextUnviewedFileGroupedCrossGroup(t *testing.T) {
-	model := &ReviewModel{
-		HasGroups: true,
-		Groups: []Group{
-			{Name: "backend", Files: []string{"a.go", "b.go"}},
-			{Name: "frontend", Files: []string{"c.js", "d.js"}},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			HasGroups: true,
+			Groups: []Group{
+				{Name: "backend", Files: []string{"a.go", "b.go"}},
+				{Name: "frontend", Files: []string{"c.js", "d.js"}},
+			},
+			Viewed: map[string]bool{
+				"a.go": true,
+				"b.go": true,
+			},
 		},
 		SelectedPath: "b.go",
-		Viewed: map[string]bool{
-			"a.go": true,
-			"b.go": true,
-		},
 	}
 
 	got := nextUnviewedFile(model)
@@ -157,18 +169,20 @@ func TestNextUnviewedFileGroupedCrossGroup(t *testing.T) {
 //
 // Scenario: All viewed in grouped mode returns ""
 func TestNextUnviewedFileGroupedAllViewed(t *testing.T) {
-	model := &ReviewModel{
-		HasGroups: true,
-		Groups: []Group{
-			{Name: "backend", Files: []string{"a.go", "b.go"}},
-			{Name: "frontend", Files: []string{"c.js"}},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			HasGroups: true,
+			Groups: []Group{
+				{Name: "backend", Files: []string{"a.go", "b.go"}},
+				{Name: "frontend", Files: []string{"c.js"}},
+			},
+			Viewed: map[string]bool{
+				"a.go": true,
+				"b.go": true,
+				"c.js": true,
+			},
 		},
 		SelectedPath: "a.go",
-		Viewed: map[string]bool{
-			"a.go": true,
-			"b.go": true,
-			"c.js": true,
-		},
 	}
 
 	got := nextUnviewedFile(model)
@@ -183,15 +197,17 @@ func TestNextUnviewedFileGroupedAllViewed(t *testing.T) {
 //
 // Scenario: nil viewed map — treated as all unviewed, returns next file
 func TestNextUnviewedFileNilViewedMap(t *testing.T) {
-	model := &ReviewModel{
-		Mode: ModeFile,
-		Files: []File{
-			{Path: "a.go"},
-			{Path: "b.go"},
-			{Path: "c.go"},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode: ModeFile,
+			Files: []File{
+				{Path: "a.go"},
+				{Path: "b.go"},
+				{Path: "c.go"},
+			},
+			Viewed: nil,
 		},
 		SelectedPath: "a.go",
-		Viewed:       nil,
 	}
 
 	got := nextUnviewedFile(model)
@@ -206,17 +222,19 @@ func TestNextUnviewedFileNilViewedMap(t *testing.T) {
 //
 // Scenario: Selected path not found — returns first unviewed file or ""
 func TestNextUnviewedFileSelectedPathNotFound(t *testing.T) {
-	model := &ReviewModel{
-		Mode: ModeFile,
-		Files: []File{
-			{Path: "a.go"},
-			{Path: "b.go"},
-			{Path: "c.go"},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode: ModeFile,
+			Files: []File{
+				{Path: "a.go"},
+				{Path: "b.go"},
+				{Path: "c.go"},
+			},
+			Viewed: map[string]bool{
+				"a.go": true,
+			},
 		},
 		SelectedPath: "nonexistent.go",
-		Viewed: map[string]bool{
-			"a.go": true,
-		},
 	}
 
 	got := nextUnviewedFile(model)
@@ -229,20 +247,22 @@ func TestNextUnviewedFileSelectedPathNotFound(t *testing.T) {
 // is true, files not assigned to any group (the "Other" group) are still
 // included in the navigation order after all grouped files.
 func TestNextUnviewedFileGroupedIncludesOtherFiles(t *testing.T) {
-	model := &ReviewModel{
-		Mode:      ModeFile,
-		HasGroups: true,
-		Groups: []Group{
-			{Name: "backend", Files: []string{"a.go"}},
-		},
-		Files: []File{
-			{Path: "a.go"},
-			{Path: "ungrouped.go"},
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode:      ModeFile,
+			HasGroups: true,
+			Groups: []Group{
+				{Name: "backend", Files: []string{"a.go"}},
+			},
+			Files: []File{
+				{Path: "a.go"},
+				{Path: "ungrouped.go"},
+			},
+			Viewed: map[string]bool{
+				"a.go": true,
+			},
 		},
 		SelectedPath: "a.go",
-		Viewed: map[string]bool{
-			"a.go": true,
-		},
 	}
 
 	got := nextUnviewedFile(model)
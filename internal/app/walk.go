@@ -0,0 +1,274 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is one pattern from a .gitignore file, matched against paths
+// relative to the directory holding the file.
+type ignoreRule struct {
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// parseIgnoreFile reads the rules in the .gitignore file in dir, if any.
+func parseIgnoreFile(dir string) ([]ignoreRule, error) {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: dir}
+		if strings.HasPrefix(line, "!") {
+			rule.negate, line = true, line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		rule.anchored = strings.Contains(line, "/")
+		rule.pattern = strings.TrimPrefix(line, "/")
+		if rule.pattern == "" {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// match reports whether the rule applies to the absolute path p.
+func (r ignoreRule) match(p string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	rel, err := filepath.Rel(r.base, p)
+	rel = filepath.ToSlash(rel)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return false
+	}
+	if !r.anchored {
+		return globMatch(r.pattern, path.Base(rel))
+	}
+	return globMatch(r.pattern, rel)
+}
+
+// ignored reports whether the last rule matching the absolute path p
+// ignores it, the way git lets later and deeper patterns override earlier
+// ones.
+func ignored(rules []ignoreRule, p string, isDir bool) bool {
+	out := false
+	for _, r := range rules {
+		if r.match(p, isDir) {
+			out = !r.negate
+		}
+	}
+	return out
+}
+
+// globMatch matches a slash-separated name against pattern, where each
+// segment is a path.Match pattern and "**" stands for any number of
+// segments.
+func globMatch(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// excluded reports whether p matches one of the --exclude patterns.
+func excluded(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if pathMatches(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathMatches reports whether p matches pattern. A pattern without a slash
+// matches the last element of p, so "*.pb.go" or "vendor" match at any
+// depth; one with a slash matches the whole path.
+func pathMatches(pattern, p string) bool {
+	p = filepath.ToSlash(filepath.Clean(p))
+	pattern = strings.TrimPrefix(pattern, "./")
+	if strings.Contains(pattern, "/") {
+		return globMatch(pattern, p)
+	}
+	return globMatch(pattern, path.Base(p))
+}
+
+// validateExcludes checks that every --exclude pattern is a valid glob.
+func validateExcludes(patterns []string) error {
+	for _, pattern := range patterns {
+		if err := validateGlob(pattern); err != nil {
+			return fmt.Errorf("invalid --exclude pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func validateGlob(pattern string) error {
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandPaths replaces each directory in paths with the files below it,
+// skipping .git, files matched by .gitignore and anything matching
+// exclude. Other paths are kept unless excluded, and are left for the
+// loader to report when they cannot be read. Each file is listed once.
+func expandPaths(paths, exclude []string) ([]string, error) {
+	var out []string
+	seen := make(map[string]bool)
+	add := func(p string) {
+		if key := filepath.Clean(p); !seen[key] {
+			seen[key] = true
+			out = append(out, p)
+		}
+	}
+	for _, p := range paths {
+		if excluded(exclude, p) {
+			continue
+		}
+		info, err := os.Stat(p)
+		if err != nil || !info.IsDir() {
+			add(p)
+			continue
+		}
+		files, err := walkDir(p, exclude)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			add(f)
+		}
+	}
+	return out, nil
+}
+
+// walkDir lists the files below root in lexical order, applying the
+// .gitignore files in root, its subdirectories and the directories above
+// it up to the enclosing git repository.
+func walkDir(root string, exclude []string) ([]string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := parentIgnoreRules(absRoot)
+	if err != nil {
+		return nil, err
+	}
+	// Rules from a .gitignore apply only below its directory, so each
+	// directory keeps the rules in force for its entries.
+	rules := map[string][]ignoreRule{}
+	var files []string
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		abs := filepath.Join(absRoot, rel)
+		active := parent
+		if p != root {
+			active = rules[filepath.Dir(abs)]
+			if d.IsDir() && d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if excluded(exclude, p) || ignored(active, abs, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if d.IsDir() {
+			own, err := parseIgnoreFile(abs)
+			if err != nil {
+				return err
+			}
+			rules[abs] = append(active[:len(active):len(active)], own...)
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			if info, err := os.Stat(p); err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+		}
+		files = append(files, p)
+		return nil
+	})
+	return files, err
+}
+
+// parentIgnoreRules collects the .gitignore rules of the directories above
+// the absolute path root, outermost first, when root is inside a git
+// repository.
+func parentIgnoreRules(root string) ([]ignoreRule, error) {
+	if _, err := os.Stat(filepath.Join(root, ".git")); err == nil {
+		return nil, nil
+	}
+	var dirs []string
+	for dir := filepath.Dir(root); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			// Not in a repository: only the walked tree's own files count.
+			return nil, nil
+		}
+	}
+	var rules []ignoreRule
+	for i := len(dirs) - 1; i >= 0; i-- {
+		own, err := parseIgnoreFile(dirs[i])
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, own...)
+	}
+	return rules, nil
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGlobMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "a/main.go", false},
+		{"a/**/z.go", "a/z.go", true},
+		{"a/**/z.go", "a/b/c/z.go", true},
+		{"**/gen", "x/y/gen", true},
+		{"vendor/**", "vendor/x/y.go", true},
+		{"a/*.go", "a/b/c.go", false},
+	} {
+		if got := globMatch(tc.pattern, tc.name); got != tc.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestExpandPaths verifies that directories are walked with .gitignore
+// files at every level, including the repository's above the walked
+// directory, and that --exclude applies to walked and explicit paths.
+func TestExpandPaths(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for name, content := range map[string]string{
+		".git/HEAD":              "ref: refs/heads/main\n",
+		".gitignore":             "*.log\n/build/\n",
+		"build/out.go":           "",
+		"src/.gitignore":         "gen/\n!keep.log\n",
+		"src/main.go":            "",
+		"src/debug.log":          "",
+		"src/keep.log":           "",
+		"src/gen/types.go":       "",
+		"src/api/api.pb.go":      "",
+		"src/api/api.go":         "",
+		"src/vendor/lib/lib.go":  "",
+		"src/sub/build/notes.md": "",
+		"top.go":                 "",
+	} {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := expandPaths([]string{"src", "top.go", "src/main.go", "missing.go"}, []string{"vendor", "*.pb.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join("src", ".gitignore"),
+		filepath.Join("src", "api", "api.go"),
+		filepath.Join("src", "keep.log"),
+		filepath.Join("src", "main.go"),
+		filepath.Join("src", "sub", "build", "notes.md"),
+		"top.go",
+		"missing.go",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expandPaths = %q, want %q", got, want)
+	}
+
+	got, err = expandPaths([]string{"."}, []string{"src"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".gitignore", "top.go"}; !slices.Equal(got, want) {
+		t.Fatalf("expandPaths(.) = %q, want %q", got, want)
+	}
+
+	if err := validateExcludes([]string{"[a-"}); err == nil {
+		t.Fatal("expected an invalid pattern to be rejected")
+	}
+}
@@ -0,0 +1,166 @@
+package app
+
+import "time"
+
+// watchInterval is how often --watch checks the reviewed files for changes.
+// The files are polled with stat rather than watched through fsnotify, which
+// is not a dependency and misses changes on network and container mounts.
+// The stats and reads happen outside the review lock, so a slow filesystem
+// holds up the watcher but not the reviewers.
+const watchInterval = 500 * time.Millisecond
+
+// reloadChangedFiles reads files that changed on disk since they were loaded
+// back into the review, for --watch, and returns their paths.
+func reloadChangedFiles(model *ReviewModel) []string {
+	return applyReloads(model, readChangedFiles(model.Files))
+}
+
+// readChangedFiles stats files and loads again those that changed on disk
+// since they were loaded, reading the content of those whose content was
+// read before. Files that can no longer be read are left out, and the
+// finish check flags them as stale. It touches nothing shared, so it runs
+// without the review lock on a copy of the review's files.
+func readChangedFiles(files []File) []File {
+	var changed []File
+	for _, path := range changedOnDisk(files) {
+		next, err := loadFile(path)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			if f.Path != path {
+				continue
+			}
+			next.Language = f.Language
+			// Not through the loader: a prefetch of the file was read before
+			// it changed.
+			if f.Lines == nil || ensureFileLoaded(nil, &next) == nil {
+				changed = append(changed, next)
+			}
+			break
+		}
+	}
+	return changed
+}
+
+// applyReloads puts files read by readChangedFiles into the review and
+// returns their paths.
+//
+// Reloaded files are flagged as updated and marked unviewed. Comments on
+// them move to the nearest lines with the same text; comments whose lines
+// are gone keep their line numbers and are flagged as outdated.
+func applyReloads(model *ReviewModel, changed []File) []string {
+	var reloaded []string
+	for _, next := range changed {
+		path := next.Path
+		file := model.lookupFile(path)
+		if file == nil {
+			continue
+		}
+		old := file.Lines
+		if old != nil && next.Lines == nil {
+			// The file was opened while it was being read again.
+			if err := ensureFileLoaded(nil, &next); err != nil {
+				continue
+			}
+		}
+		*file = next
+		// A prefetch of the file was read before it changed.
+		model.loader.drop(path)
+
+		if model.UpdatedFiles == nil {
+			model.UpdatedFiles = make(map[string]bool)
+		}
+		model.UpdatedFiles[path] = true
+		model.Viewed[path] = false
+		delete(model.StaleFiles, path)
+		reloaded = append(reloaded, path)
+
+		for j := range model.Comments {
+			c := &model.Comments[j]
+			if c.Path != path || c.isFileLevel() {
+				continue
+			}
+			if old != nil && file.Lines != nil && reanchorFileComment(c, old, file.Lines) {
+				delete(model.OutdatedComments, c.ID)
+				continue
+			}
+			if model.OutdatedComments == nil {
+				model.OutdatedComments = make(map[int]bool)
+			}
+			model.OutdatedComments[c.ID] = true
+		}
+	}
+	return reloaded
+}
+
+// reanchorFileComment moves c from its lines in old to the lines with the
+// same text in next, preferring the match closest to the original position,
+// as reanchorComment does for diffs. It reports false if the commented lines
+// are no longer in the file.
+func reanchorFileComment(c *Comment, old, next []string) bool {
+	if c.StartLine < 1 || c.EndLine > len(old) || c.EndLine < c.StartLine {
+		return false
+	}
+	want := old[c.StartLine-1 : c.EndLine]
+	best, found := 0, false
+	for start := 1; start+len(want)-1 <= len(next); start++ {
+		if found && abs(start-c.StartLine) >= abs(best-c.StartLine) {
+			continue
+		}
+		match := true
+		for i, text := range want {
+			if next[start-1+i] != text {
+				match = false
+				break
+			}
+		}
+		if match {
+			best, found = start, true
+		}
+	}
+	if !found {
+		return false
+	}
+	c.EndLine += best - c.StartLine
+	c.StartLine = best
+	return true
+}
+
+// watchFiles reloads reviewed files as they change on disk until the review
+// is finished, re-rendering every client through notify after each reload.
+// The returned function stops watching.
+func (rs *ReviewServer) watchFiles(notify func()) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(watchInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			rs.mu.Lock()
+			var files []File
+			if !rs.Model.Completed {
+				files = append(files, rs.Model.Files...)
+			}
+			rs.mu.Unlock()
+			changed := readChangedFiles(files)
+			if len(changed) == 0 {
+				continue
+			}
+			rs.mu.Lock()
+			var reloaded []string
+			if !rs.Model.Completed {
+				reloaded = applyReloads(rs.Model, changed)
+			}
+			rs.mu.Unlock()
+			if len(reloaded) > 0 {
+				notify()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
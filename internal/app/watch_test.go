@@ -0,0 +1,139 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestReloadChangedFiles verifies that --watch reloads a file changed on
+// disk, moves comments with their lines and flags those whose lines are gone.
+func TestReloadChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(path, []byte("package a\n\nfunc A() {}\n\nfunc B() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := loadFiles([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Files:  files,
+			Mode:   ModeFile,
+			Viewed: map[string]bool{path: true},
+			Comments: []Comment{
+				{ID: 1, Path: path, StartLine: 3, EndLine: 3, Text: "doc comment?"},
+				{ID: 2, Path: path, StartLine: 5, EndLine: 5, Text: "unused"},
+			},
+		},
+		SelectedPath: path,
+	}
+	updateView(model)
+
+	if got := reloadChangedFiles(model.ReviewModel); len(got) != 0 {
+		t.Fatalf("expected nothing to reload, got %v", got)
+	}
+	// Make sure the modification time moves on coarse filesystems.
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(path, []byte("package a\n\n// A does a.\nfunc A() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := reloadChangedFiles(model.ReviewModel); len(got) != 1 || got[0] != path {
+		t.Fatalf("expected %s to be reloaded, got %v", path, got)
+	}
+	if lines := model.Files[0].Lines; len(lines) != 4 || lines[2] != "// A does a." {
+		t.Fatalf("expected the new content, got %q", lines)
+	}
+	if !model.UpdatedFiles[path] || model.Viewed[path] {
+		t.Fatal("expected the file to be flagged as updated and unviewed")
+	}
+	if c := model.Comments[0]; c.StartLine != 4 || model.OutdatedComments[c.ID] {
+		t.Fatalf("expected the comment to follow func A, got %+v", c)
+	}
+	if c := model.Comments[1]; c.StartLine != 5 || !model.OutdatedComments[c.ID] {
+		t.Fatalf("expected the comment on removed lines to be outdated, got %+v", c)
+	}
+	if got := reloadChangedFiles(model.ReviewModel); len(got) != 0 {
+		t.Fatalf("expected the reloaded file to be current, got %v", got)
+	}
+}
+
+// TestReloadDropsPrefetch verifies that a file prefetched but never opened is
+// read again after it changes, rather than from the stale prefetch.
+func TestReloadDropsPrefetch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "b.go")
+	if err := os.WriteFile(path, []byte("package b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := loadFiles([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewModel{Files: files, Mode: ModeFile, Viewed: map[string]bool{}, loader: newFileLoader()}
+	model.loader.start(path)
+	model.loader.mu.Lock()
+	done := model.loader.pending[path]
+	model.loader.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(path, []byte("package b\n\nfunc B() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := reloadChangedFiles(model); len(got) != 1 {
+		t.Fatalf("expected %s to be reloaded, got %v", path, got)
+	}
+	if err := ensureFileLoaded(model.loader, &model.Files[0]); err != nil {
+		t.Fatal(err)
+	}
+	if lines := model.Files[0].Lines; len(lines) != 3 || lines[2] != "func B() {}" {
+		t.Fatalf("expected the changed content, got %q", lines)
+	}
+}
+
+// TestReadChangedFilesLeavesModel verifies that the watcher's reads, done
+// without the review lock, only return the new content and leave applying it
+// to applyReloads.
+func TestReadChangedFilesLeavesModel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "c.go")
+	if err := os.WriteFile(path, []byte("package c\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := loadFiles([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ensureFileLoaded(nil, &files[0]); err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewModel{Files: files, Mode: ModeFile, Viewed: map[string]bool{}}
+
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(path, []byte("package c\n\nfunc C() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	changed := readChangedFiles(append([]File(nil), model.Files...))
+	if len(changed) != 1 || len(changed[0].Lines) != 3 {
+		t.Fatalf("expected the new content of %s, got %+v", path, changed)
+	}
+	if len(model.Files[0].Lines) != 1 || model.UpdatedFiles[path] {
+		t.Fatal("expected the review to be untouched until the reload is applied")
+	}
+	if got := applyReloads(model, changed); len(got) != 1 || len(model.Files[0].Lines) != 3 {
+		t.Fatalf("expected %s to be reloaded, got %v", path, got)
+	}
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jfyne/live"
+)
+
+// ignoreWhitespace returns h with each run of removed lines that differs
+// from the added lines following it only in whitespace shown as unchanged,
+// as git diff -w compares them. It reports whether any change is left.
+func ignoreWhitespace(h DiffHunk) (DiffHunk, bool) {
+	out := h
+	out.Lines = make([]DiffLine, 0, len(h.Lines))
+	changed := false
+	lines := h.Lines
+	for i := 0; i < len(lines); {
+		if lines[i].Kind == DiffContext {
+			out.Lines = append(out.Lines, lines[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(lines) && lines[j].Kind == DiffDel {
+			j++
+		}
+		k := j
+		for k < len(lines) && lines[k].Kind == DiffAdd {
+			k++
+		}
+		dels, adds := lines[i:j], lines[j:k]
+		if sameIgnoringWhitespace(dels, adds) {
+			for n, add := range adds {
+				out.Lines = append(out.Lines, DiffLine{Kind: DiffContext, OldLine: dels[n].OldLine, NewLine: add.NewLine, Text: add.Text})
+			}
+		} else {
+			out.Lines = append(out.Lines, lines[i:k]...)
+			changed = true
+		}
+		i = k
+	}
+	return out, changed
+}
+
+// sameIgnoringWhitespace reports whether dels and adds hold the same lines
+// once all whitespace is removed from them.
+func sameIgnoringWhitespace(dels, adds []DiffLine) bool {
+	if len(dels) != len(adds) {
+		return false
+	}
+	for i := range dels {
+		if strings.Join(strings.Fields(dels[i].Text), "") != strings.Join(strings.Fields(adds[i].Text), "") {
+			return false
+		}
+	}
+	return true
+}
+
+func registerWhitespaceHandlers(h *live.Handler, rs *ReviewServer) {
+	// Like the diff format, both are preferences shared by every viewer.
+	h.HandleEvent("toggle-hide-whitespace", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		view.HideWhitespace = !view.HideWhitespace
+		savePreference(func(p *Preferences) { p.HideWhitespace = view.HideWhitespace })
+		updateView(view)
+		return view, nil
+	}))
+
+	h.HandleEvent("toggle-show-whitespace", rs.locked(func(ctx context.Context, s *live.Socket, p live.Params) (any, error) {
+		view := rs.view(s)
+		// The view picks the renderer marking whitespace; see highlighter.
+		view.ShowWhitespace = !view.ShowWhitespace
+		savePreference(func(p *Preferences) { p.ShowWhitespace = view.ShowWhitespace })
+		updateView(view)
+		return view, nil
+	}))
+}
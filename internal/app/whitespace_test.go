@@ -0,0 +1,98 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestIgnoreWhitespace verifies that lines changing only whitespace become
+// context while real changes around them are kept.
+func TestIgnoreWhitespace(t *testing.T) {
+	df, err := parseUnifiedDiff("--- a/a.go\n+++ b/a.go\n"+
+		"@@ -1,4 +1,4 @@\n"+
+		" func A() {\n"+
+		"-  return 1\n"+
+		"+\treturn  1\n"+
+		"-}\n"+
+		"+} // done\n"+
+		" \n", diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, changed := ignoreWhitespace(df[0].Hunks[0])
+	if !changed {
+		t.Fatal("expected the comment on the closing brace to remain a change")
+	}
+	var kinds []string
+	for _, dl := range h.Lines {
+		kinds = append(kinds, string(dl.Kind))
+	}
+	if strings.Join(kinds, " ") != "context context del add context" {
+		t.Fatalf("unexpected lines: %v", kinds)
+	}
+	if dl := h.Lines[0]; dl.Text != "func A() {" {
+		t.Fatalf("unexpected first line: %+v", dl)
+	}
+
+	df[0].Hunks[0].Lines = append(df[0].Hunks[0].Lines[:3], df[0].Hunks[0].Lines[5])
+	h, changed = ignoreWhitespace(df[0].Hunks[0])
+	if changed {
+		t.Fatalf("expected only whitespace changes, got %+v", h.Lines)
+	}
+	if dl := h.Lines[1]; dl.Kind != DiffContext || dl.OldLine != 2 || dl.NewLine != 2 || dl.Text != "\treturn  1" {
+		t.Fatalf("expected the new line shown as context, got %+v", dl)
+	}
+}
+
+// TestHideWhitespaceHunks verifies that hunks changing only whitespace are
+// left out of the diff view while the toggle is on.
+func TestHideWhitespaceHunks(t *testing.T) {
+	files, err := parseUnifiedDiff("--- a/a.go\n+++ b/a.go\n"+
+		"@@ -1,1 +1,1 @@\n-a  b\n+a b\n"+
+		"@@ -9,1 +9,1 @@\n-x\n+y\n", diffStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ReviewView{ReviewModel: &ReviewModel{Mode: ModeDiff, DiffFiles: files}, SelectedPath: "a.go"}
+	updateView(model)
+	if len(model.ViewDiff.Hunks) != 2 || model.ViewDiff.WhitespaceHunks != 0 {
+		t.Fatalf("expected both hunks, got %d", len(model.ViewDiff.Hunks))
+	}
+	key := model.CodeViewKey
+
+	model.HideWhitespace = true
+	updateView(model)
+	if len(model.ViewDiff.Hunks) != 1 || model.ViewDiff.Hunks[0].Index != 1 || model.ViewDiff.WhitespaceHunks != 1 {
+		t.Fatalf("expected only the second hunk, got %+v", model.ViewDiff.Hunks)
+	}
+	if model.CodeViewKey == key {
+		t.Fatal("expected the code view to be re-keyed")
+	}
+}
+
+// TestShowWhitespacePicksRenderer verifies that showing whitespace switches
+// the view to the marking renderer without replacing the shared ones, which
+// background highlighting may be using.
+func TestShowWhitespacePicksRenderer(t *testing.T) {
+	plain, marking := defaultRenderers.code, defaultRenderers.whitespace
+	model := &ReviewView{
+		ReviewModel: &ReviewModel{
+			Mode:  ModeFile,
+			Files: []File{{Path: "a.go", PathSlash: "a.go", Lines: []string{"\treturn 1"}}},
+		},
+		SelectedPath: "a.go",
+		RenderFile:   true,
+	}
+	updateView(model)
+	if html := string(model.ViewFile.Lines[0].HTML); strings.Contains(html, "whitespace") {
+		t.Fatalf("expected no whitespace markers, got %s", html)
+	}
+	model.ShowWhitespace = true
+	updateView(model)
+	if html := string(model.ViewFile.Lines[0].HTML); !strings.Contains(html, `<span class="whitespace">→`) {
+		t.Fatalf("expected the tab to be marked, got %s", html)
+	}
+	if defaultRenderers.code != plain || defaultRenderers.whitespace != marking {
+		t.Fatal("expected the shared renderers to be left in place")
+	}
+}
@@ -0,0 +1,121 @@
+package highlight
+
+import (
+	"container/list"
+	"hash/maphash"
+	"html/template"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// lexerCache remembers the lexer resolved for each path and language, safe
+// for concurrent use, so the hunks of a file do not each run lexer
+// detection. Highlighted output is kept apart in an outputCache.
+type lexerCache struct {
+	mu     sync.Mutex
+	lexers map[[2]string]chroma.Lexer
+}
+
+func newLexerCache() *lexerCache {
+	return &lexerCache{lexers: make(map[[2]string]chroma.Lexer)}
+}
+
+// lexer returns the lexer for path and language, resolving it with lines
+// the first time. Lexers detected from the content alone are not kept, as
+// other content at the same path may be detected differently.
+func (c *lexerCache) lexer(path, language string, lines []string) chroma.Lexer {
+	key := [2]string{path, language}
+	c.mu.Lock()
+	lexer, ok := c.lexers[key]
+	c.mu.Unlock()
+	if ok {
+		return lexer
+	}
+	lexer, analysed := resolveLexer(path, language, lines)
+	if !analysed {
+		c.mu.Lock()
+		c.lexers[key] = lexer
+		c.mu.Unlock()
+	}
+	return lexer
+}
+
+// outputCache is a least-recently-used cache of highlighted lines, safe for
+// concurrent use. Entries are keyed by path, language and a hash of the
+// lines rather than the lines themselves, and the cache is bounded by the
+// bytes of HTML it holds. A path whose content changes gets a new entry; the
+// old one ages out.
+type outputCache struct {
+	mu      sync.Mutex
+	limit   int
+	size    int
+	seed    maphash.Seed
+	order   *list.List
+	entries map[outputKey]*list.Element
+}
+
+type outputKey struct {
+	path     string
+	language string
+	sum      uint64
+}
+
+type outputEntry struct {
+	key      outputKey
+	rendered []template.HTML
+	size     int
+}
+
+// newOutputCache returns a cache holding up to limit bytes of HTML.
+func newOutputCache(limit int) *outputCache {
+	return &outputCache{
+		limit:   limit,
+		seed:    maphash.MakeSeed(),
+		order:   list.New(),
+		entries: make(map[outputKey]*list.Element),
+	}
+}
+
+func (c *outputCache) key(path, language string, lines []string) outputKey {
+	var h maphash.Hash
+	h.SetSeed(c.seed)
+	for _, line := range lines {
+		h.WriteString(line)
+		h.WriteByte('\n')
+	}
+	return outputKey{path: path, language: language, sum: h.Sum64()}
+}
+
+func (c *outputCache) get(key outputKey) ([]template.HTML, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*outputEntry).rendered, true
+}
+
+func (c *outputCache) put(key outputKey, rendered []template.HTML) {
+	entry := &outputEntry{key: key, rendered: rendered}
+	for _, line := range rendered {
+		entry.size += len(line)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.size -= el.Value.(*outputEntry).size
+		c.order.Remove(el)
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	c.size += entry.size
+	for c.size > c.limit && c.order.Len() > 1 {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		evicted := oldest.Value.(*outputEntry)
+		delete(c.entries, evicted.key)
+		c.size -= evicted.size
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"html"
 	"html/template"
-	"regexp"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/alecthomas/chroma/v2"
 	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
@@ -14,12 +14,33 @@ import (
 )
 
 type Renderer struct {
-	formatter *chromahtml.Formatter
-	light     *chroma.Style
-	dark      *chroma.Style
+	formatter         *chromahtml.Formatter
+	light             *chroma.Style
+	dark              *chroma.Style
+	tabWidth          int
+	visibleWhitespace bool
+	lexers            *lexerCache
+	output            *outputCache
 }
 
-func NewRenderer(lightStyle, darkStyle string, tabWidth int) *Renderer {
+// Option configures a Renderer.
+type Option func(*Renderer)
+
+// VisibleWhitespace marks spaces with "·" and tabs with "→" in a
+// span.whitespace, so indentation and trailing whitespace can be told apart.
+func VisibleWhitespace() Option {
+	return func(r *Renderer) { r.visibleWhitespace = true }
+}
+
+// CacheBytes keeps up to limit bytes of highlighted HTML, so the same lines
+// of a path rendered again, as when switching back to a file, are not
+// tokenised again. The returned fragments are shared and must not be
+// modified.
+func CacheBytes(limit int) Option {
+	return func(r *Renderer) { r.output = newOutputCache(limit) }
+}
+
+func NewRenderer(lightStyle, darkStyle string, tabWidth int, opts ...Option) *Renderer {
 	formatter := chromahtml.New(
 		chromahtml.WithClasses(true),
 		chromahtml.TabWidth(tabWidth),
@@ -32,171 +53,271 @@ func NewRenderer(lightStyle, darkStyle string, tabWidth int) *Renderer {
 	if dark == nil {
 		dark = styles.Fallback
 	}
-	return &Renderer{
+	r := &Renderer{
 		formatter: formatter,
 		light:     light,
 		dark:      dark,
+		tabWidth:  tabWidth,
+		lexers:    newLexerCache(),
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
+// RenderLines highlights lines and returns exactly one HTML fragment per
+// input line. Tokens are taken straight from the lexer and split into lines,
+// so no formatter output has to be parsed back apart. Any line whose tokens
+// don't reproduce its text (or every line, if lexing fails) is emitted as
+// escaped plain text rather than dropped.
 func (r *Renderer) RenderLines(path string, lines []string) []template.HTML {
-	lexer := resolveLexer(path, lines)
-	if lexer == nil {
-		return nil
-	}
-	source := strings.Join(lines, "\n")
-	iter, err := lexer.Tokenise(nil, source)
-	if err != nil {
-		return nil
+	return r.RenderLinesAs(path, "", lines)
+}
+
+// RenderLinesAs is RenderLines with the lexer for language, a name or alias
+// as listed by Languages, instead of the one detected from path and content.
+// An empty or unknown language falls back to detection.
+func (r *Renderer) RenderLinesAs(path, language string, lines []string) []template.HTML {
+	if r.output == nil {
+		return r.renderLines(path, language, lines)
 	}
-	var buf bytes.Buffer
-	if err := r.formatter.Format(&buf, r.light, iter); err != nil {
-		return nil
+	key := r.output.key(path, language, lines)
+	if rendered, ok := r.output.get(key); ok {
+		return rendered
 	}
-	return extractChromaLines(buf.String(), len(lines))
+	rendered := r.renderLines(path, language, lines)
+	r.output.put(key, rendered)
+	return rendered
 }
 
-func (r *Renderer) BuildCSS() string {
-	var buf bytes.Buffer
-	_ = r.formatter.WriteCSS(&buf, r.light)
-	lightCSS := scopeChromaCSS(buf.String(), "body.theme-light ")
+func (r *Renderer) renderLines(path, language string, lines []string) []template.HTML {
+	var tokenLines [][]chroma.Token
+	lexer := r.lexers.lexer(path, language, lines)
+	if iter, err := lexer.Tokenise(nil, strings.Join(lines, "\n")); err == nil {
+		tokenLines = chroma.SplitTokensIntoLines(iter.Tokens())
+	}
 
-	buf.Reset()
-	_ = r.formatter.WriteCSS(&buf, r.dark)
-	darkCSS := scopeChromaCSS(buf.String(), "body.theme-dark ")
+	out := make([]template.HTML, 0, len(lines))
+	var b strings.Builder
+	for i, line := range lines {
+		var tokens []chroma.Token
+		if i < len(tokenLines) {
+			tokens = tokenLines[i]
+		}
+		if !tokensMatchLine(tokens, line) {
+			tokens = []chroma.Token{{Type: chroma.Text, Value: line}}
+		}
+		out = append(out, r.renderTokenLine(&b, tokens))
+	}
+	return out
+}
 
-	return lightCSS + "\n" + darkCSS + "\n"
+// tokensMatchLine reports whether the token values, minus the trailing line
+// break, concatenate back to line.
+func tokensMatchLine(tokens []chroma.Token, line string) bool {
+	rest := line
+	for i, tok := range tokens {
+		value := tok.Value
+		if i == len(tokens)-1 {
+			value = strings.TrimRight(value, "\n")
+		}
+		if !strings.HasPrefix(rest, value) {
+			return false
+		}
+		rest = rest[len(value):]
+	}
+	return rest == ""
 }
 
-func resolveLexer(path string, lines []string) chroma.Lexer {
-	lexer := lexers.Match(path)
-	if lexer == nil {
-		joined := strings.Join(lines, "\n")
-		lexer = lexers.Analyse(joined)
+func (r *Renderer) renderTokenLine(b *strings.Builder, tokens []chroma.Token) template.HTML {
+	b.Reset()
+	b.WriteString(`<span class="chroma">`)
+	empty := true
+	col := 0
+	for _, tok := range tokens {
+		value := strings.TrimRight(tok.Value, "\r\n")
+		if value == "" {
+			continue
+		}
+		empty = false
+		cls := tokenClass(tok.Type)
+		if cls != "" {
+			b.WriteString(`<span class="`)
+			b.WriteString(cls)
+			b.WriteString(`">`)
+		}
+		col = r.writeText(b, value, col)
+		if cls != "" {
+			b.WriteString(`</span>`)
+		}
 	}
-	if lexer == nil {
-		lexer = lexers.Fallback
+	if empty {
+		b.WriteString("&nbsp;")
 	}
-	return chroma.Coalesce(lexer)
+	b.WriteString(`</span>`)
+	return template.HTML(b.String())
 }
 
-func extractChromaLines(htmlIn string, expected int) []template.HTML {
-	const lineOpen = `<span class="line">`
-	const clOpen = `<span class="cl">`
-	const lineClose = `</span></span>`
-
-	lines := make([]template.HTML, 0, expected)
-	remaining := htmlIn
-	for len(lines) < expected {
-		lineIdx := strings.Index(remaining, lineOpen)
-		if lineIdx == -1 {
-			break
+// writeText escapes value and makes its whitespace survive HTML collapsing:
+// spaces and tabs become non-breaking spaces, and a whitespace-only run is
+// followed by a zero-width space so it is not trimmed. col is the display
+// column value starts at; tabs expand to the next tab stop and wide
+// characters are wrapped so they take exactly two cells. It returns the
+// column after value. With VisibleWhitespace, runs of whitespace are
+// wrapped in a span.whitespace, with a marker in the first cell of each space
+// or tab.
+func (r *Renderer) writeText(b *strings.Builder, value string, col int) int {
+	tabWidth := max(r.tabWidth, 1)
+	onlyWhitespace := true
+	inRun := false
+	start := 0
+	for i := 0; i < len(value); {
+		c, size := utf8.DecodeRuneInString(value[i:])
+		if c != ' ' && c != '\t' {
+			if inRun {
+				b.WriteString(`</span>`)
+				inRun = false
+			}
+			onlyWhitespace = false
+			end := i + size + clusterExtent(value[i+size:])
+			w := runeWidth(c)
+			if w == 2 {
+				b.WriteString(html.EscapeString(value[start:i]))
+				b.WriteString(`<span class="wide-char">`)
+				b.WriteString(html.EscapeString(value[i:end]))
+				b.WriteString(`</span>`)
+				start = end
+			}
+			col += w
+			i = end
+			continue
 		}
-		remaining = remaining[lineIdx+len(lineOpen):]
-		clIdx := strings.Index(remaining, clOpen)
-		if clIdx == -1 {
-			break
+		b.WriteString(html.EscapeString(value[start:i]))
+		i += size
+		start = i
+		n := 1
+		if c == '\t' {
+			n = tabWidth - col%tabWidth
 		}
-		remaining = remaining[clIdx+len(clOpen):]
-		endIdx := strings.Index(remaining, lineClose)
-		if endIdx == -1 {
-			break
+		if r.visibleWhitespace {
+			if !inRun {
+				b.WriteString(`<span class="whitespace">`)
+				inRun = true
+			}
+			if c == '\t' {
+				b.WriteString("→")
+			} else {
+				b.WriteString("·")
+			}
+			n--
+			col++
 		}
-		lineHTML := fixWhitespaceSpans(remaining[:endIdx])
-		lineHTML = normalizeTextWhitespace(lineHTML)
-		if strings.TrimSpace(lineHTML) == "" {
-			lineHTML = "&nbsp;"
+		for range n {
+			b.WriteString("&nbsp;")
 		}
-		lines = append(lines, template.HTML(`<span class="chroma">`+lineHTML+`</span>`))
-		remaining = remaining[endIdx+len(lineClose):]
+		col += n
 	}
-	return lines
+	if inRun {
+		b.WriteString(`</span>`)
+	}
+	b.WriteString(html.EscapeString(value[start:]))
+	if onlyWhitespace {
+		b.WriteString("&#8203;")
+	}
+	return col
 }
 
-var whitespaceSpanRE = regexp.MustCompile(`<span class="w">(.*?)</span>`)
-
-func fixWhitespaceSpans(input string) string {
-	return whitespaceSpanRE.ReplaceAllStringFunc(input, func(match string) string {
-		sub := whitespaceSpanRE.FindStringSubmatch(match)
-		if len(sub) != 2 {
-			return match
+// clusterExtent returns the byte length of the zero-width runes that attach
+// to the preceding character: combining marks, variation selectors, skin
+// tone modifiers, and zero-width joiners together with the rune each joins.
+func clusterExtent(s string) int {
+	n := 0
+	for n < len(s) {
+		c, size := utf8.DecodeRuneInString(s[n:])
+		if c == zeroWidthJoiner {
+			n += size
+			if n < len(s) {
+				_, next := utf8.DecodeRuneInString(s[n:])
+				n += next
+			}
+			continue
 		}
-		content := sub[1]
-		content = strings.ReplaceAll(content, "\t", "&nbsp;&nbsp;&nbsp;&nbsp;")
-		content = strings.ReplaceAll(content, " ", "&nbsp;")
-		if content == "" {
-			content = "&nbsp;"
+		if runeWidth(c) != 0 && !isEmojiModifier(c) {
+			break
 		}
-		content += "&#8203;"
-		return `<span class="w">` + content + `</span>`
-	})
+		n += size
+	}
+	return n
 }
 
-func scopeChromaCSS(input, prefix string) string {
-	return strings.ReplaceAll(input, ".chroma", prefix+".chroma")
+// tokenClass returns the chroma CSS class for a token type, falling back to
+// its parent categories the same way the chroma HTML formatter does.
+func tokenClass(t chroma.TokenType) string {
+	for t != 0 {
+		if cls, ok := chroma.StandardTypes[t]; ok {
+			return cls
+		}
+		t = t.Parent()
+	}
+	return chroma.StandardTypes[t]
 }
 
-// EscapePlain is exposed for tests and fallbacks.
-func EscapePlain(s string) template.HTML {
-	return template.HTML(html.EscapeString(s))
-}
+func (r *Renderer) BuildCSS() string {
+	var buf bytes.Buffer
+	_ = r.formatter.WriteCSS(&buf, r.light)
+	lightCSS := scopeChromaCSS(buf.String(), "body.theme-light ")
 
-func normalizeTextWhitespace(input string) string {
-	var out strings.Builder
-	out.Grow(len(input) + 32)
+	buf.Reset()
+	_ = r.formatter.WriteCSS(&buf, r.dark)
+	darkCSS := scopeChromaCSS(buf.String(), "body.theme-dark ")
 
-	inTag := false
-	var textBuf strings.Builder
+	return lightCSS + "\n" + darkCSS + "\n"
+}
 
-	flushText := func() {
-		if textBuf.Len() == 0 {
-			return
-		}
-		segment := textBuf.String()
-		textBuf.Reset()
-		var segOut strings.Builder
-		segOut.Grow(len(segment) * 2)
-		onlyWhitespace := true
-		for _, r := range segment {
-			switch r {
-			case ' ':
-				segOut.WriteString("&nbsp;")
-			case '\t':
-				segOut.WriteString("&nbsp;&nbsp;&nbsp;&nbsp;")
-			case '\n', '\r':
-				// skip newlines within a line
-			default:
-				onlyWhitespace = false
-				segOut.WriteRune(r)
-			}
-		}
-		if onlyWhitespace {
-			segOut.WriteString("&#8203;")
-		}
-		out.WriteString(segOut.String())
+// Languages lists the names of the lexers a file can be highlighted as, in
+// sorted order.
+func Languages() []string {
+	return lexers.Names(false)
+}
+
+// LanguageName returns the name of the lexer known as name, which may also be
+// an alias or a file extension, or "" if there is none.
+func LanguageName(name string) string {
+	if name == "" {
+		return ""
+	}
+	if lexer := lexers.Get(name); lexer != nil {
+		return lexer.Config().Name
 	}
+	return ""
+}
 
-	for _, r := range input {
-		switch r {
-		case '<':
-			if !inTag {
-				flushText()
-				inTag = true
-			}
-			out.WriteRune(r)
-		case '>':
-			out.WriteRune(r)
-			if inTag {
-				inTag = false
-			}
-		default:
-			if inTag {
-				out.WriteRune(r)
-			} else {
-				textBuf.WriteRune(r)
-			}
-		}
+// resolveLexer picks the lexer for language, else for path, else by
+// analysing lines, and reports whether it came from the analysis.
+func resolveLexer(path, language string, lines []string) (chroma.Lexer, bool) {
+	var lexer chroma.Lexer
+	if language != "" {
+		lexer = lexers.Get(language)
+	}
+	if lexer == nil {
+		lexer = lexers.Match(path)
+	}
+	if lexer != nil {
+		return chroma.Coalesce(lexer), false
 	}
-	flushText()
-	return out.String()
+	lexer = lexers.Analyse(strings.Join(lines, "\n"))
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	return chroma.Coalesce(lexer), true
+}
+
+func scopeChromaCSS(input, prefix string) string {
+	return strings.ReplaceAll(input, ".chroma", prefix+".chroma")
+}
+
+// EscapePlain is exposed for tests and fallbacks.
+func EscapePlain(s string) template.HTML {
+	return template.HTML(html.EscapeString(s))
 }
@@ -1,8 +1,11 @@
 package highlight
 
 import (
+	"html/template"
 	"strings"
 	"testing"
+
+	"github.com/alecthomas/chroma/v2"
 )
 
 func TestBuildCSSScopesThemes(t *testing.T) {
@@ -49,3 +52,198 @@ func TestRenderLinesPreservesLeadingSpaces(t *testing.T) {
 		}
 	}
 }
+
+func TestRenderLinesBalancedSpans(t *testing.T) {
+	r := NewRenderer("github", "dracula", 4)
+	lines := []string{`foo := "a  b" // c<d`, "", "func main() {}"}
+	rendered := r.RenderLines("test.go", lines)
+	if len(rendered) != len(lines) {
+		t.Fatalf("expected %d rendered lines, got %d", len(lines), len(rendered))
+	}
+	for i, line := range rendered {
+		s := string(line)
+		if strings.Count(s, "<span") != strings.Count(s, "</span>") {
+			t.Fatalf("line %d has unbalanced spans: %s", i, s)
+		}
+	}
+	if !strings.Contains(string(rendered[0]), "c&lt;d") {
+		t.Fatalf("expected escaped text, got: %s", rendered[0])
+	}
+	if string(rendered[1]) != `<span class="chroma">&nbsp;</span>` {
+		t.Fatalf("expected placeholder for empty line, got: %s", rendered[1])
+	}
+	if !strings.Contains(string(rendered[2]), `<span class="kd">func</span>`) {
+		t.Fatalf("expected keyword class, got: %s", rendered[2])
+	}
+}
+
+func BenchmarkRenderLines(b *testing.B) {
+	r := NewRenderer("github", "dracula", 4)
+	lines := strings.Split(strings.Repeat("func main() {\n\tfmt.Println(\"hello,  world\") // greet\n}\n", 200), "\n")
+	b.ReportAllocs()
+	for b.Loop() {
+		r.RenderLines("bench.go", lines)
+	}
+}
+
+func TestRenderLinesReturnsOneFragmentPerLine(t *testing.T) {
+	r := NewRenderer("github", "dracula", 4)
+	tests := map[string][]string{
+		"trailing empty":   {"package main", ""},
+		"only empty":       {""},
+		"unterminated str": {`x := "abc`, `def"`},
+		"no lexer match":   {"???", "", "!!!"},
+	}
+	for name, lines := range tests {
+		rendered := r.RenderLines("file.go", lines)
+		if len(rendered) != len(lines) {
+			t.Errorf("%s: expected %d rendered lines, got %d", name, len(lines), len(rendered))
+		}
+	}
+}
+
+func TestTokensMatchLine(t *testing.T) {
+	toks := []chroma.Token{{Type: chroma.Keyword, Value: "func"}, {Type: chroma.Text, Value: " x\n"}}
+	if !tokensMatchLine(toks, "func x") {
+		t.Fatal("expected tokens to match line")
+	}
+	if tokensMatchLine(toks, "func y") {
+		t.Fatal("expected mismatch for different text")
+	}
+	if tokensMatchLine(nil, "x") {
+		t.Fatal("expected missing tokens not to match non-empty line")
+	}
+}
+
+func TestRenderLinesHonoursTabWidth(t *testing.T) {
+	r := NewRenderer("github", "dracula", 8)
+	rendered := r.RenderLines("test.go", []string{"\tx"})
+	if !strings.Contains(string(rendered[0]), strings.Repeat("&nbsp;", 8)+"&#8203;") {
+		t.Fatalf("expected tab to expand to 8 columns, got: %s", rendered[0])
+	}
+}
+
+func TestRenderLinesVisibleWhitespace(t *testing.T) {
+	r := NewRenderer("github", "dracula", 4, VisibleWhitespace())
+	got := string(r.RenderLines("test.txt", []string{"\t a b  "})[0])
+	want := `<span class="whitespace">→&nbsp;&nbsp;&nbsp;·</span>a<span class="whitespace">·</span>b<span class="whitespace">··</span>`
+	if !strings.Contains(got, want) {
+		t.Fatalf("expected whitespace markers %s, got: %s", want, got)
+	}
+	if plain := string(NewRenderer("github", "dracula", 4).RenderLines("test.txt", []string{"a b"})[0]); strings.Contains(plain, "·") {
+		t.Fatalf("expected no markers by default, got: %s", plain)
+	}
+}
+
+func TestRuneWidth(t *testing.T) {
+	tests := []struct {
+		r    rune
+		want int
+	}{
+		{'a', 1},
+		{'é', 1},
+		{'\u0301', 0}, // combining acute accent
+		{'\ufe0f', 0}, // variation selector
+		{'\u200d', 0}, // zero-width joiner
+		{'中', 2},
+		{'한', 2},
+		{'Ａ', 2},
+		{'😀', 2},
+		{'→', 1},
+	}
+	for _, tt := range tests {
+		if got := runeWidth(tt.r); got != tt.want {
+			t.Errorf("runeWidth(%U) = %d, want %d", tt.r, got, tt.want)
+		}
+	}
+}
+
+// TestRenderLinesWideCharacters verifies that wide characters are pinned to
+// two cells and that tabs after them expand to the correct tab stop.
+func TestRenderLinesWideCharacters(t *testing.T) {
+	r := NewRenderer("github", "dracula", 4)
+	rendered := r.RenderLines("notes.txt", []string{"中\tx", "e\u0301\tx", "👍🏽 ok"})
+
+	first := string(rendered[0])
+	if !strings.Contains(first, `<span class="wide-char">中</span>&nbsp;&nbsp;x`) {
+		t.Fatalf("expected wide char span followed by a two-column tab, got: %s", first)
+	}
+	second := string(rendered[1])
+	if strings.Contains(second, "wide-char") || !strings.Contains(second, "e\u0301&nbsp;&nbsp;&nbsp;x") {
+		t.Fatalf("expected combining mark to take no column, got: %s", second)
+	}
+	third := string(rendered[2])
+	if !strings.Contains(third, `<span class="wide-char">👍🏽</span>`) {
+		t.Fatalf("expected emoji and modifier in one wide span, got: %s", third)
+	}
+}
+
+func TestRenderLinesAsOverridesDetection(t *testing.T) {
+	r := NewRenderer("github", "dracula", 4)
+	lines := []string{"SELECT id FROM users;"}
+	if got := string(r.RenderLines("query.tpl", lines)[0]); strings.Contains(got, `class="k"`) {
+		t.Fatalf("expected no keywords without an override, got: %s", got)
+	}
+	if got := string(r.RenderLinesAs("query.tpl", "sql", lines)[0]); !strings.Contains(got, `class="k"`) {
+		t.Fatalf("expected SQL keywords with the override, got: %s", got)
+	}
+	if LanguageName("sql") != "SQL" || LanguageName("no-such-language") != "" {
+		t.Fatalf("unexpected language names %q, %q", LanguageName("sql"), LanguageName("no-such-language"))
+	}
+}
+
+func TestLexerCacheKeepsResolvedLexers(t *testing.T) {
+	c := newLexerCache()
+	first := c.lexer("a.go", "", []string{"package a"})
+	if again := c.lexer("a.go", "", nil); again != first {
+		t.Fatal("expected the lexer matched by path to be reused")
+	}
+	if sql := c.lexer("a.go", "sql", nil); sql == first || sql.Config().Name != "SQL" {
+		t.Fatalf("expected the language to pick another lexer, got %s", sql.Config().Name)
+	}
+	c.lexer("notes", "", []string{"#!/bin/sh", "echo hi"})
+	if _, ok := c.lexers[[2]string{"notes", ""}]; ok {
+		t.Fatal("expected a lexer detected from content not to be kept")
+	}
+}
+
+func TestRenderLinesAsUsesCache(t *testing.T) {
+	r := NewRenderer("github", "dracula", 4, CacheBytes(1<<20))
+	lines := []string{"package cache"}
+	first := r.RenderLinesAs("a.go", "", lines)
+	if again := r.RenderLinesAs("a.go", "", []string{"package cache"}); &again[0] != &first[0] {
+		t.Fatal("expected the same lines of a path to be served from the cache")
+	}
+	for _, tc := range []struct {
+		path, language string
+		lines          []string
+	}{
+		{"a.go", "", []string{"package other"}},
+		{"a.go", "Go Text Template", lines},
+		{"b.go", "", lines},
+	} {
+		if got := r.RenderLinesAs(tc.path, tc.language, tc.lines); &got[0] == &first[0] {
+			t.Fatalf("expected %s %q %q to be rendered again", tc.path, tc.language, tc.lines)
+		}
+	}
+}
+
+func TestOutputCacheEvictsByBytes(t *testing.T) {
+	c := newOutputCache(10)
+	key := func(s string) outputKey { return c.key(s, "", []string{s}) }
+	c.put(key("a"), []template.HTML{"aaaa"})
+	c.put(key("b"), []template.HTML{"bbbb"})
+	c.get(key("a"))
+	c.put(key("d"), []template.HTML{"dddd"})
+	if _, ok := c.get(key("b")); ok {
+		t.Fatal("expected the least recently used entry to be evicted")
+	}
+	for _, s := range []string{"a", "d"} {
+		if _, ok := c.get(key(s)); !ok {
+			t.Fatalf("expected %s to stay cached", s)
+		}
+	}
+	if c.size != 8 {
+		t.Fatalf("expected 8 bytes cached, got %d", c.size)
+	}
+}
@@ -4,5 +4,5 @@ import "embed"
 
 // FS exposes the embedded UI assets.
 //
-//go:embed template.html styles.css logo.png ai.png
+//go:embed template.html styles.css print.html print.css report.html report.css logo.png ai.png
 var FS embed.FS
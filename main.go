@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -22,17 +24,80 @@ func (l *listFlag) Set(value string) error {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		os.Exit(runSchema(os.Args[2:]))
+	}
+
 	var (
-		host      = flag.String("host", "127.0.0.1", "host to bind")
-		port      = flag.Int("port", 0, "port to bind (0 = random)")
-		prompt    = flag.String("prompt", "", "review prompt/question to display at top")
-		diff      = flag.String("diff", "", "path to unified diff file (or pipe via stdin)")
-		groups    = flag.String("groups", "", "path to JSON file with ordered file groups")
-		ranges    listFlag
-		showHelp  = flag.Bool("help", false, "show help")
-		showSkill = flag.Bool("skill", false, "print agent skill markdown")
+		host       = flag.String("host", "127.0.0.1", "host to bind")
+		port       = flag.Int("port", 0, "port to bind (0 = random)")
+		prompt     = flag.String("prompt", "", "review prompt/question to display at top")
+		promptF    = flag.String("prompt-file", "", "read the review prompt from a file")
+		diff       = flag.String("diff", "", "path to unified diff file (or pipe via stdin)")
+		gitRev     = flag.String("git", "", "review git diff against a revision or range, e.g. HEAD~1..HEAD")
+		staged     = flag.Bool("staged", false, "review the staged changes")
+		untracked  = flag.Bool("untracked", false, "include untracked files with --git")
+		fetchPR    = flag.String("fetch-pr", "", "review the diff of a GitHub pull request, e.g. owner/repo#123")
+		fetchFiles = flag.Bool("fetch-pr-contents", false, "also download the changed files with --fetch-pr")
+		groups     = flag.String("groups", "", "path to JSON file with ordered file groups")
+		rubric     = flag.String("rubric", "", "path to JSON file with criteria to score")
+		summary    = flag.String("summary-file", "", "path to a drafted review summary")
+		previous   = flag.String("previous", "", "path to the previous round's output")
+		seedPath   = flag.String("comments", "", "path to comments to start the review with")
+		tabWidth   = flag.Int("tab-width", 4, "columns per tab when rendering code")
+		strict     = flag.Bool("strict-diff", false, "fail on malformed diff input instead of warning")
+		skipMiss   = flag.Bool("skip-missing", false, "warn about unreadable paths instead of failing")
+		api        = flag.Bool("api", false, "serve an HTTP API for posting replies during the session")
+		share      = flag.Bool("share", false, "serve on the LAN behind a token and print a QR code to join")
+		revName    = flag.String("reviewer-name", "", "name to review under in the browser meatcheck opens")
+		watch      = flag.Bool("watch", false, "reload files as they change on disk while the review is open (checked with stat every 500ms, without blocking the page)")
+		noOpen     = flag.Bool("no-open", false, "print the URL instead of opening a browser")
+		qrCode     = flag.Bool("qr", false, "print a QR code of the URL with --no-open")
+		useTLS     = flag.Bool("tls", false, "serve over HTTPS with a self-signed certificate")
+		tlsCert    = flag.String("tls-cert", "", "PEM certificate file to serve HTTPS with (needs --tls-key)")
+		tlsKey     = flag.String("tls-key", "", "PEM private key file for --tls-cert")
+		ranges     listFlag
+		vars       listFlag
+		excludes   listFlag
+		collapses  listFlag
+		syntaxes   listFlag
+		output     = flag.String("output", "", "write the review to this file instead of stdout")
+		outFormat  = flag.String("output-format", "toon", "review output format: toon, json, markdown or sarif")
+		validate   = flag.Bool("validate", false, "check the printed review against the output schema (toon and json output only)")
+		emit       = flag.String("emit", "", "alternative output: llm-context")
+		budget     = flag.Int("token-budget", 0, "approximate token limit for --emit llm-context")
+		headless   = flag.Bool("headless", false, "skip the server and browser and print a synthetic result")
+		auto       = flag.String("auto", "", "result for --headless: verdict=...,comments-file=...")
+		events     = flag.Bool("events", false, "write lifecycle events to stderr")
+		eventsFD   = flag.Int("events-fd", 0, "write lifecycle events to this file descriptor")
+		lang       = flag.String("lang", "", "UI language (default: the browser's Accept-Language)")
+		deadline   = flag.Duration("deadline", 0, "how long the reviewer has, e.g. 15m; shown as a countdown")
+		onExpiry   = flag.String("deadline-action", string(app.DeadlineWarn), "when the deadline passes: warn or finish")
+		timeout    = flag.Duration("timeout", 0, "finish the review after this long, e.g. 30m, with the comments so far")
+		logo       = flag.String("logo", "", "path to an image replacing the meatcheck logo")
+		avatar     = flag.String("avatar", "", "path to an image replacing the agent avatar")
+		accent     = flag.String("accent-color", "", "#rrggbb colour replacing the theme accent")
+		lint       = flag.Bool("lint-comments", false, "warn before saving comments that are unlikely to be actionable")
+		lintMin    = flag.Int("lint-min-length", app.DefaultLintMinLength, "shortest comment --lint-comments accepts")
+		mermaid    = flag.Bool("mermaid", false, "draw mermaid code blocks in rendered markdown as diagrams")
+		mermaidJS  = flag.String("mermaid-js", "", "path to a local mermaid.min.js to use instead of the CDN build")
+		math       = flag.Bool("math", false, "typeset $...$ and $$...$$ in rendered markdown with KaTeX")
+		katexDir   = flag.String("katex-dir", "", "path to a local KaTeX dist directory to use instead of the CDN build")
+		exportPDF  = flag.String("export-pdf", "", "archive the finished review as a PDF, or HTML if the path ends in .html")
+		exportHTML = flag.String("export-html", "", "write a standalone HTML report of the files or diff with comments inline")
+		githubPR   = flag.String("github-pr", "", "post the comments on finish as a pending review on this pull request, e.g. owner/repo#123 (needs GITHUB_TOKEN)")
+		gitlabMR   = flag.String("gitlab-mr", "", "post the comments on finish as discussions on this merge request, e.g. group/project!123 (needs GITLAB_TOKEN)")
+		showHelp   = flag.Bool("help", false, "show help")
+		showSkill  = flag.Bool("skill", false, "print agent skill markdown")
+		skillTmpl  = flag.String("skill-template", "", "path to a template to render with --skill")
+		skillName  = flag.String("skill-name", "", "skill name for --skill (default meatcheck)")
+		skillNote  = flag.String("skill-notes", "", "path to markdown appended to --skill as team notes")
 	)
 	flag.Var(&ranges, "range", "file section to render (path:start-end), repeatable")
+	flag.Var(&vars, "var", "key=value for the prompt template, repeatable")
+	flag.Var(&excludes, "exclude", "glob for files to leave out of the review, repeatable")
+	flag.Var(&collapses, "collapse-glob", "glob for files to collapse like generated ones, repeatable")
+	flag.Var(&syntaxes, "syntax", "highlight matching files as a language (pattern=language), repeatable")
 	flag.Parse()
 
 	if *showHelp {
@@ -40,7 +105,27 @@ func main() {
 		return
 	}
 	if *showSkill {
-		app.PrintSkill(os.Stdout)
+		opts := app.SkillOptions{Name: *skillName, OutputFormat: *emit}
+		if *skillTmpl != "" {
+			data, err := os.ReadFile(*skillTmpl)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "read skill template: %v\n", err)
+				os.Exit(1)
+			}
+			opts.Template = string(data)
+		}
+		if *skillNote != "" {
+			data, err := os.ReadFile(*skillNote)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "read skill notes: %v\n", err)
+				os.Exit(1)
+			}
+			opts.Notes = string(data)
+		}
+		if err := app.PrintSkill(os.Stdout, opts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 		return
 	}
 
@@ -50,7 +135,25 @@ func main() {
 		os.Exit(1)
 	}
 
-	if flag.NArg() == 0 && stdDiff == "" && *diff == "" {
+	gitMode := *gitRev != "" || *staged
+	if gitMode && (stdDiff != "" || *diff != "") {
+		fmt.Fprintln(os.Stderr, "use either --git/--staged or a diff, not both")
+		os.Exit(2)
+	}
+	if *untracked && !gitMode {
+		fmt.Fprintln(os.Stderr, "--untracked needs --git")
+		os.Exit(2)
+	}
+	if *fetchPR != "" && (gitMode || stdDiff != "" || *diff != "" || flag.NArg() > 0) {
+		fmt.Fprintln(os.Stderr, "use either --fetch-pr or local files or diffs, not both")
+		os.Exit(2)
+	}
+	if *fetchFiles && *fetchPR == "" {
+		fmt.Fprintln(os.Stderr, "--fetch-pr-contents needs --fetch-pr")
+		os.Exit(2)
+	}
+
+	if flag.NArg() == 0 && stdDiff == "" && *diff == "" && !gitMode && *fetchPR == "" {
 		fmt.Fprintln(os.Stderr, "usage: meatcheck <file1> <file2> ...")
 		fmt.Fprintln(os.Stderr, "run with --help for more information")
 		os.Exit(2)
@@ -62,6 +165,54 @@ func main() {
 		os.Exit(1)
 	}
 
+	syntaxRules, err := app.ParseSyntaxFlag(syntaxes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	var fetch *app.GitHubPR
+	if *fetchPR != "" {
+		if fetch, err = app.ParseGitHubPR(*fetchPR); err != nil {
+			fmt.Fprintf(os.Stderr, "--fetch-pr: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	var pr *app.GitHubPR
+	if *githubPR != "" {
+		if pr, err = app.ParseGitHubPR(*githubPR); err != nil {
+			fmt.Fprintf(os.Stderr, "--github-pr: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	var mr *app.GitLabMR
+	if *gitlabMR != "" {
+		if mr, err = app.ParseGitLabMR(*gitlabMR); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	varsMap, err := app.ParseVarFlag(vars)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	promptText := *prompt
+	if *promptF != "" {
+		if promptText != "" {
+			fmt.Fprintln(os.Stderr, "use either --prompt or --prompt-file, not both")
+			os.Exit(2)
+		}
+		data, err := os.ReadFile(*promptF)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read prompt file: %v\n", err)
+			os.Exit(1)
+		}
+		promptText = string(data)
+	}
+
 	var parsedGroups []app.Group
 	if *groups != "" {
 		parsedGroups, err = app.ParseGroupsFile(*groups)
@@ -71,18 +222,193 @@ func main() {
 		}
 	}
 
+	var parsedRubric []app.Criterion
+	if *rubric != "" {
+		parsedRubric, err = app.ParseRubricFile(*rubric)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	var summaryText string
+	if *summary != "" {
+		data, err := os.ReadFile(*summary)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read summary file: %v\n", err)
+			os.Exit(1)
+		}
+		summaryText = string(data)
+	}
+
+	var prev *app.PreviousReview
+	if *previous != "" {
+		prev, err = app.ParsePreviousFile(*previous)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	var seed *app.PreviousReview
+	if *seedPath != "" {
+		seed, err = app.ParseCommentsFile(*seedPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if *qrCode && !*noOpen {
+		fmt.Fprintln(os.Stderr, "--qr requires --no-open")
+		os.Exit(2)
+	}
+
+	var autoReview *app.AutoReview
+	if *auto != "" {
+		if !*headless {
+			fmt.Fprintln(os.Stderr, "--auto requires --headless")
+			os.Exit(2)
+		}
+		if *api {
+			fmt.Fprintln(os.Stderr, "--auto cannot be combined with --api; finish through POST /api/finish instead")
+			os.Exit(2)
+		}
+		autoReview, err = app.ParseAutoFlag(*auto)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if *lang != "" {
+		if err := app.CheckLang(*lang); err != nil {
+			fmt.Fprintf(os.Stderr, "--lang: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if *deadline < 0 {
+		fmt.Fprintln(os.Stderr, "--deadline must not be negative")
+		os.Exit(2)
+	}
+	if *timeout < 0 {
+		fmt.Fprintln(os.Stderr, "--timeout must not be negative")
+		os.Exit(2)
+	}
+	deadlineAction, err := app.ParseDeadlineAction(*onExpiry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "--deadline-action: %v\n", err)
+		os.Exit(2)
+	}
+
+	if (*tlsCert == "") != (*tlsKey == "") {
+		fmt.Fprintln(os.Stderr, "--tls-cert and --tls-key must be given together")
+		os.Exit(2)
+	}
+
+	if *lintMin < 0 {
+		fmt.Fprintln(os.Stderr, "--lint-min-length must not be negative")
+		os.Exit(2)
+	}
+
+	var eventsOut io.Writer
+	switch {
+	case *eventsFD > 0:
+		f := os.NewFile(uintptr(*eventsFD), "events")
+		if _, err := f.Stat(); err != nil {
+			fmt.Fprintf(os.Stderr, "--events-fd %d: %v\n", *eventsFD, err)
+			os.Exit(2)
+		}
+		eventsOut = f
+	case *events:
+		eventsOut = os.Stderr
+	}
+
 	cfg := app.Config{
-		Host:    *host,
-		Port:    *port,
-		Paths:   flag.Args(),
-		Prompt:  *prompt,
-		Diff:    *diff,
-		Ranges:  rangesMap,
-		StdDiff: stdDiff,
-		Groups:  parsedGroups,
+		Host:            *host,
+		Port:            *port,
+		Paths:           flag.Args(),
+		Prompt:          promptText,
+		Diff:            *diff,
+		Ranges:          rangesMap,
+		StdDiff:         stdDiff,
+		Groups:          parsedGroups,
+		TabWidth:        *tabWidth,
+		StrictDiff:      *strict,
+		Git:             *gitRev,
+		GitStaged:       *staged,
+		GitUntracked:    *untracked,
+		FetchPR:         fetch,
+		FetchPRContents: *fetchFiles,
+		SkipMissing:     *skipMiss,
+		Exclude:         excludes,
+		CollapseGlobs:   collapses,
+		Syntax:          syntaxRules,
+		API:             *api,
+		Share:           *share,
+		ReviewerName:    *revName,
+		Watch:           *watch,
+		NoOpen:          *noOpen,
+		QR:              *qrCode,
+		TLS:             *useTLS || *tlsCert != "",
+		TLSCert:         *tlsCert,
+		TLSKey:          *tlsKey,
+		Rubric:          parsedRubric,
+		Vars:            varsMap,
+		Summary:         summaryText,
+		Output:          *output,
+		OutputFormat:    *outFormat,
+		Validate:        *validate,
+		Previous:        prev,
+		Comments:        seed,
+		Emit:            *emit,
+		TokenBudget:     *budget,
+		Headless:        *headless,
+		Auto:            autoReview,
+		Events:          eventsOut,
+		Lang:            *lang,
+		ExportPDF:       *exportPDF,
+		ExportHTML:      *exportHTML,
+		GitHubPR:        pr,
+		GitHubToken:     os.Getenv("GITHUB_TOKEN"),
+		GitLabMR:        mr,
+		GitLabToken:     os.Getenv("GITLAB_TOKEN"),
+		Logo:            *logo,
+		Avatar:          *avatar,
+		AccentColor:     *accent,
+		Deadline:        *deadline,
+		DeadlineAction:  deadlineAction,
+		Timeout:         *timeout,
+		LintComments:    *lint,
+		LintMinLength:   *lintMin,
+		Mermaid:         *mermaid,
+		MermaidJS:       *mermaidJS,
+		Math:            *math,
+		KaTeXDir:        *katexDir,
 	}
 	if err := app.Run(context.Background(), cfg); err != nil {
+		if errors.Is(err, app.ErrChangesRequested) {
+			os.Exit(3)
+		}
+		if errors.Is(err, app.ErrAborted) {
+			os.Exit(130)
+		}
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
 }
+
+// runSchema prints the JSON Schema of the review document.
+func runSchema(args []string) int {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	format := fs.String("output-format", "toon", "output format the schema describes")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := app.PrintSchema(os.Stdout, *format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,191 @@
+// Package review embeds the meatcheck review UI in other Go programs. A
+// Review serves files or a diff on a local port, like the meatcheck
+// command, and hands back the reviewer's comments and verdict instead of
+// printing them:
+//
+//	r, err := review.New([]string{"main.go"}, review.Options{Prompt: "Is this safe?"})
+//	if err != nil {
+//		return err
+//	}
+//	result, err := r.Serve(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	for _, c := range result.Comments {
+//		fmt.Printf("%s:%d %s\n", c.Path, c.StartLine, c.Text)
+//	}
+//
+// Each Review has its own server and rendering settings, so a process may
+// serve several at once.
+package review
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jfyne/meatcheck/internal/app"
+)
+
+// Comment is a comment left during the review. StartLine and EndLine are 0
+// for comments on a whole file.
+type Comment struct {
+	ID int
+	// UID identifies the comment across review rounds.
+	UID       string
+	Path      string
+	StartLine int
+	EndLine   int
+	// Side is "old" or "new" for comments on a diff, and "" otherwise.
+	Side   string
+	Text   string
+	Author string
+	// Disposition is the reviewer's decision on a proposed comment:
+	// "pending", "accepted", "rejected", "needs-discussion" or "".
+	Disposition string
+	// Priority is P0 to P3, or "" when unset; Confidence is a free-form
+	// note on how sure the reviewer is.
+	Priority   string
+	Confidence string
+	Tags       []string
+	// Commit is the ID of the commit of a patch series the comment is on.
+	Commit string
+}
+
+// Reply is a response posted to a comment thread.
+type Reply struct {
+	ID         int
+	CommentID  int
+	CommentUID string
+	Author     string
+	Text       string
+	// Time is when the reply was posted, in RFC 3339 format.
+	Time string
+}
+
+// Verdict is a reviewer's overall conclusion.
+type Verdict string
+
+const (
+	VerdictNone           Verdict = ""
+	VerdictApprove        Verdict = "approve"
+	VerdictComment        Verdict = "comment"
+	VerdictRequestChanges Verdict = "request-changes"
+)
+
+// Result is a finished review.
+type Result struct {
+	// Comments are the comments whose anchors resolve against the reviewed
+	// content; Dropped lists the others.
+	Comments []Comment
+	Dropped  []Comment
+	Replies  []Reply
+	// Verdict combines Verdicts, each reviewer's own.
+	Verdict     Verdict
+	Verdicts    map[string]Verdict
+	Summary     string
+	Scores      map[string]int
+	CompletedBy string
+}
+
+// Options configures a Review. The zero value serves on a random port of
+// 127.0.0.1 and opens the default browser on it.
+type Options struct {
+	// Host and Port are where the review is served; port 0 picks a free
+	// one.
+	Host string
+	Port int
+	// Prompt is shown at the top of the review, as Markdown.
+	Prompt string
+	// Diff is a unified diff to review instead of files.
+	Diff string
+	// TabWidth is how many columns a tab renders as; 0 means 4.
+	TabWidth int
+	// NoBrowser leaves opening the review to the caller, who learns its
+	// URL through Ready.
+	NoBrowser bool
+	// Ready is called with the review's URL once it is being served.
+	Ready func(url string)
+}
+
+// Review is a review session waiting to be served.
+type Review struct {
+	cfg app.Config
+}
+
+// New prepares a review of files, or of opts.Diff when it is set. Paths
+// may name directories, which are walked honouring .gitignore.
+func New(files []string, opts Options) (*Review, error) {
+	if len(files) == 0 && opts.Diff == "" {
+		return nil, errors.New("review: no files or diff to review")
+	}
+	host := opts.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return &Review{cfg: app.Config{
+		Host:      host,
+		Port:      opts.Port,
+		Paths:     files,
+		Prompt:    opts.Prompt,
+		StdDiff:   opts.Diff,
+		TabWidth:  opts.TabWidth,
+		NoBrowser: opts.NoBrowser,
+		Ready:     opts.Ready,
+	}}, nil
+}
+
+// Serve serves the review until a reviewer finishes it and returns the
+// result. Cancelling ctx stops the server and returns ctx's error.
+func (r *Review) Serve(ctx context.Context) (*Result, error) {
+	res, err := app.Review(ctx, r.cfg)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{
+		Comments:    convertComments(res.Comments),
+		Dropped:     convertComments(res.Dropped),
+		Verdict:     Verdict(res.Verdict),
+		Summary:     res.Summary,
+		Scores:      res.Scores,
+		CompletedBy: res.CompletedBy,
+	}
+	for _, r := range res.Replies {
+		result.Replies = append(result.Replies, Reply{
+			ID:         r.ID,
+			CommentID:  r.CommentID,
+			CommentUID: r.CommentUID,
+			Author:     r.Author,
+			Text:       r.Text,
+			Time:       r.Time,
+		})
+	}
+	if res.Verdicts != nil {
+		result.Verdicts = make(map[string]Verdict, len(res.Verdicts))
+		for name, v := range res.Verdicts {
+			result.Verdicts[name] = Verdict(v)
+		}
+	}
+	return result, nil
+}
+
+func convertComments(comments []app.Comment) []Comment {
+	var out []Comment
+	for _, c := range comments {
+		out = append(out, Comment{
+			ID:          c.ID,
+			UID:         c.UID,
+			Path:        c.Path,
+			StartLine:   c.StartLine,
+			EndLine:     c.EndLine,
+			Side:        c.Side,
+			Text:        c.Text,
+			Author:      c.Author,
+			Disposition: string(c.Disposition),
+			Priority:    string(c.Priority),
+			Confidence:  c.Confidence,
+			Tags:        c.Tags,
+			Commit:      c.Commit,
+		})
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewRequiresInput(t *testing.T) {
+	if _, err := New(nil, Options{}); err == nil {
+		t.Fatal("expected an error without files or a diff")
+	}
+}
+
+// TestServeStopsWithContext verifies that the review is served at the URL
+// passed to Ready and that cancelling the context ends Serve.
+func TestServeStopsWithContext(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	status := 0
+	r, err := New([]string{path}, Options{
+		NoBrowser: true,
+		Ready: func(url string) {
+			if resp, err := http.Get(url); err == nil {
+				status = resp.StatusCode
+				resp.Body.Close()
+			}
+			cancel()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Serve(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Serve = %v, want context.Canceled", err)
+	}
+	if status != http.StatusOK {
+		t.Fatalf("GET review page: status %d", status)
+	}
+}
+
+// TestServeConcurrently verifies that two reviews are served side by side
+// in one process, each with its own settings.
+func TestServeConcurrently(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	get := func(url string) int {
+		resp, err := http.Get(url)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	innerCtx, innerCancel := context.WithCancel(ctx)
+	defer innerCancel()
+	var firstURL string
+	var statuses []int
+	var innerErr error
+	second, err := New([]string{path}, Options{
+		NoBrowser: true,
+		TabWidth:  8,
+		Ready: func(url string) {
+			statuses = append(statuses, get(firstURL), get(url))
+			innerCancel()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := New([]string{path}, Options{
+		NoBrowser: true,
+		TabWidth:  2,
+		Ready: func(url string) {
+			firstURL = url
+			_, innerErr = second.Serve(innerCtx)
+			cancel()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Serve(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("first Serve = %v, want context.Canceled", err)
+	}
+	if !errors.Is(innerErr, context.Canceled) {
+		t.Fatalf("second Serve = %v, want context.Canceled", innerErr)
+	}
+	if len(statuses) != 2 || statuses[0] != http.StatusOK || statuses[1] != http.StatusOK {
+		t.Fatalf("GET both reviews: statuses %v", statuses)
+	}
+}